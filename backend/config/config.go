@@ -0,0 +1,445 @@
+package config
+
+import (
+	"fmt"
+	"math"
+	"net"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MinJWTSecretLength is the shortest JWT_SECRET accepted at startup. HS256
+// keys shorter than the 32-byte hash output weaken every token, signature
+// and derived key built on them.
+const MinJWTSecretLength = 32
+
+// OnboardingStepKeys are the onboarding checklist steps ONBOARDING_STEPS may
+// name, in their default order.
+var OnboardingStepKeys = []string{"verify_email", "first_deposit", "first_listing", "first_bid"}
+
+// defaultCORSOrigins are allowed in addition to FRONTEND_URL when
+// CORS_ORIGINS is unset.
+var defaultCORSOrigins = []string{
+	"http://localhost:5173",
+	"http://frontend:5173",
+	"https://kartnagrale.github.io",
+}
+
+// Config is the server's startup configuration, parsed once from the
+// environment by Load and handed to the packages that need it.
+type Config struct {
+	Port        string // PORT
+	DatabaseURL string // DATABASE_URL (required)
+	JWTSecret   []byte // JWT_SECRET (required, at least MinJWTSecretLength bytes)
+
+	// LocalMode is an explicit opt-in for local and demo runs, which may then
+	// omit secrets a real deployment must have, such as DepositSigningSecret.
+	LocalMode bool // LOCAL_MODE
+
+	// DepositSigningSecret keys the payment gateway's deposit signatures. It
+	// must differ from JWTSecret: whoever holds it can only confirm deposits,
+	// not mint access tokens. Required unless LocalMode; when it is empty
+	// every deposit is rejected.
+	DepositSigningSecret []byte // DEPOSIT_SIGNING_SECRET
+
+	// TOTPEncryptionKey seals stored 2FA secrets. Like DepositSigningSecret
+	// it must differ from JWTSecret. Empty leaves 2FA unavailable.
+	TOTPEncryptionKey []byte // TOTP_ENCRYPTION_KEY
+
+	// FrontendURL is the public web app origin, used in emailed links. When
+	// it is empty the server runs in local mode and CORS accepts any origin.
+	FrontendURL string   // FRONTEND_URL
+	CORSOrigins []string // CORS_ORIGINS, comma-separated; FrontendURL is always added
+
+	UploadMaxBytes      int64 // UPLOAD_MAX_BYTES, per file
+	UploadMaxBatchBytes int64 // UPLOAD_MAX_BATCH_BYTES, across a batch upload
+	UploadMaxDimension  int   // UPLOAD_MAX_DIMENSION, pixels per side
+
+	AntiSnipeWindow     time.Duration // ANTI_SNIPE_WINDOW
+	AntiSnipeExtension  time.Duration // ANTI_SNIPE_EXTENSION
+	AuctionMaxDuration  time.Duration // AUCTION_MAX_DURATION
+	AuctionDurationMode string        // AUCTION_DURATION_MODE: "reject" or "cap"
+	SettlementWindow    time.Duration // SETTLEMENT_WINDOW, default for categories without one
+	SweepInterval       time.Duration // AUCTION_SWEEP_INTERVAL
+	SweepBatch          int           // AUCTION_SWEEP_BATCH
+
+	NotifyWorkers int // NOTIFY_WORKERS
+
+	MetricsEnabled bool // METRICS_ENABLED: serve /metrics, unauthenticated
+
+	// Sessions and sign-in.
+	AccessTokenTTL     time.Duration // ACCESS_TOKEN_TTL
+	RefreshTokenTTL    time.Duration // REFRESH_TOKEN_TTL
+	SessionRenewWindow time.Duration // SESSION_RENEW_WINDOW; zero disables sliding sessions
+	SessionIdleTimeout time.Duration // SESSION_IDLE_TIMEOUT; zero leaves only the refresh token's expiry
+	ClaimsCacheTTL     time.Duration // CLAIMS_CACHE_TTL; zero disables the cache
+	AuthRateWindow     time.Duration // AUTH_RATE_WINDOW
+	AuthRateLimitIP    int           // AUTH_RATE_LIMIT_IP, attempts per window
+	AuthRateLimitEmail int           // AUTH_RATE_LIMIT_EMAIL, attempts per window per IP and email
+	ReregisterCooldown time.Duration // REREGISTER_COOLDOWN; zero lets a deleted account's email back at once
+
+	// Money. Limits of zero mean unlimited.
+	MoneyRounding       string        // MONEY_ROUNDING: "half_up" or "half_even"
+	Currency            string        // CURRENCY, ISO 4217
+	MoneyLocale         string        // MONEY_LOCALE; unknown locales format as en-US
+	MaxUserExposure     float64       // MAX_USER_EXPOSURE
+	DailyDepositLimit   float64       // DAILY_DEPOSIT_LIMIT
+	DailyWithdrawLimit  float64       // DAILY_WITHDRAW_LIMIT
+	WithdrawDedupWindow time.Duration // WITHDRAW_DEDUP_WINDOW
+
+	// Listings and auctions.
+	MinBidIncrement            float64       // MIN_BID_INCREMENT, default for categories without one
+	ListingFee                 float64       // LISTING_FEE, default for categories without one
+	FeatureFee                 float64       // FEATURE_FEE
+	FeatureDuration            time.Duration // FEATURE_DURATION
+	MaxAutoRelists             int           // MAX_AUTO_RELISTS
+	WatchlistEndingWindow      time.Duration // WATCHLIST_ENDING_WINDOW
+	SettlementExpiryPolicy     string        // SETTLEMENT_EXPIRY_POLICY: "refund" or "complete"
+	SettlementReminderInterval time.Duration // SETTLEMENT_REMINDER_INTERVAL
+	ChatEditWindow             time.Duration // CHAT_EDIT_WINDOW
+
+	// OnboardingSteps picks and orders the onboarding checklist by step key;
+	// empty shows every step.
+	OnboardingSteps []string // ONBOARDING_STEPS, comma-separated
+
+	// S3 storage for uploads, used when S3Bucket is set; otherwise uploads
+	// stay on local disk. Region, endpoint and public URL are derived from
+	// each other when left empty.
+	S3Bucket          string // S3_BUCKET
+	S3Region          string // S3_REGION
+	S3Endpoint        string // S3_ENDPOINT
+	S3PublicURL       string // S3_PUBLIC_URL
+	S3AccessKeyID     string // S3_ACCESS_KEY_ID
+	S3SecretAccessKey string // S3_SECRET_ACCESS_KEY
+
+	// SMTP relay for outgoing mail, used when SMTPAddr is set.
+	SMTPAddr     string // SMTP_ADDR, host:port
+	SMTPFrom     string // SMTP_FROM
+	SMTPUsername string // SMTP_USERNAME
+	SMTPPassword string // SMTP_PASSWORD
+}
+
+// Local reports whether the server runs without a configured frontend.
+func (c *Config) Local() bool { return c.FrontendURL == "" }
+
+// Default returns the configuration with every optional setting at its
+// default and the required ones empty.
+func Default() *Config {
+	return &Config{
+		Port:                "8080",
+		CORSOrigins:         append([]string(nil), defaultCORSOrigins...),
+		UploadMaxBytes:      5 << 20,
+		UploadMaxBatchBytes: 20 << 20,
+		UploadMaxDimension:  4096,
+		AntiSnipeWindow:     30 * time.Second,
+		AntiSnipeExtension:  60 * time.Second,
+		AuctionMaxDuration:  30 * 24 * time.Hour,
+		AuctionDurationMode: "reject",
+		SettlementWindow:    72 * time.Hour,
+		SweepInterval:       30 * time.Second,
+		SweepBatch:          50,
+		NotifyWorkers:       2,
+
+		AccessTokenTTL:     15 * time.Minute,
+		RefreshTokenTTL:    30 * 24 * time.Hour,
+		SessionRenewWindow: 5 * time.Minute,
+		ClaimsCacheTTL:     30 * time.Second,
+		AuthRateWindow:     15 * time.Minute,
+		AuthRateLimitIP:    20,
+		AuthRateLimitEmail: 5,
+
+		MoneyRounding:       "half_up",
+		Currency:            "INR",
+		MoneyLocale:         "en-IN",
+		WithdrawDedupWindow: 10 * time.Minute,
+
+		MinBidIncrement:            0.01,
+		FeatureFee:                 99,
+		FeatureDuration:            7 * 24 * time.Hour,
+		MaxAutoRelists:             5,
+		WatchlistEndingWindow:      15 * time.Minute,
+		SettlementExpiryPolicy:     "refund",
+		SettlementReminderInterval: 24 * time.Hour,
+		ChatEditWindow:             15 * time.Minute,
+	}
+}
+
+// Load parses the environment into a Config. It checks everything before
+// returning, so one error lists every missing required variable and every
+// malformed value.
+func Load() (*Config, error) {
+	c := Default()
+	var missing, invalid []string
+
+	str := func(key string, dst *string) {
+		if v := strings.TrimSpace(os.Getenv(key)); v != "" {
+			*dst = v
+		}
+	}
+	integer := func(key string, dst *int, min int) {
+		v := strings.TrimSpace(os.Getenv(key))
+		if v == "" {
+			return
+		}
+		n, err := strconv.Atoi(v)
+		if err != nil || n < min {
+			invalid = append(invalid, fmt.Sprintf("%s=%q (want an integer >= %d)", key, v, min))
+			return
+		}
+		*dst = n
+	}
+	size := func(key string, dst *int64) {
+		n := int(*dst)
+		integer(key, &n, 1)
+		*dst = int64(n)
+	}
+	positive := func(key string, d time.Duration) {
+		if d == 0 {
+			invalid = append(invalid, key+" (must be positive)")
+		}
+	}
+	absoluteURL := func(key, v string) {
+		if u, err := url.Parse(v); err != nil || u.Scheme == "" || u.Host == "" {
+			invalid = append(invalid, fmt.Sprintf("%s=%q (want an absolute URL)", key, v))
+		}
+	}
+	amount := func(key string, dst *float64) {
+		v := strings.TrimSpace(os.Getenv(key))
+		if v == "" {
+			return
+		}
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil || f < 0 || math.IsInf(f, 0) || math.IsNaN(f) {
+			invalid = append(invalid, fmt.Sprintf("%s=%q (want a non-negative amount)", key, v))
+			return
+		}
+		*dst = f
+	}
+	duration := func(key string, dst *time.Duration) {
+		v := strings.TrimSpace(os.Getenv(key))
+		if v == "" {
+			return
+		}
+		d, err := time.ParseDuration(v)
+		if err != nil || d < 0 {
+			invalid = append(invalid, fmt.Sprintf("%s=%q (want a duration such as 30s)", key, v))
+			return
+		}
+		*dst = d
+	}
+
+	str("PORT", &c.Port)
+	str("DATABASE_URL", &c.DatabaseURL)
+	if c.DatabaseURL == "" {
+		missing = append(missing, "DATABASE_URL")
+	}
+	c.JWTSecret = []byte(os.Getenv("JWT_SECRET"))
+	switch n := len(c.JWTSecret); {
+	case n == 0:
+		missing = append(missing, "JWT_SECRET")
+	case n < MinJWTSecretLength:
+		invalid = append(invalid, fmt.Sprintf("JWT_SECRET (must be at least %d bytes, got %d)", MinJWTSecretLength, n))
+	}
+
+	if v := strings.TrimSpace(os.Getenv("LOCAL_MODE")); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			invalid = append(invalid, fmt.Sprintf("LOCAL_MODE=%q (want true or false)", v))
+		}
+		c.LocalMode = b
+	}
+
+	c.DepositSigningSecret = []byte(os.Getenv("DEPOSIT_SIGNING_SECRET"))
+	if len(c.DepositSigningSecret) == 0 && !c.LocalMode {
+		missing = append(missing, "DEPOSIT_SIGNING_SECRET")
+	}
+	if n := len(c.DepositSigningSecret); n > 0 && n < MinJWTSecretLength {
+		invalid = append(invalid, fmt.Sprintf("DEPOSIT_SIGNING_SECRET (must be at least %d bytes, got %d)", MinJWTSecretLength, n))
+	}
+	if len(c.DepositSigningSecret) > 0 && string(c.DepositSigningSecret) == string(c.JWTSecret) {
+		invalid = append(invalid, "DEPOSIT_SIGNING_SECRET (must differ from JWT_SECRET)")
+	}
+
+	c.TOTPEncryptionKey = []byte(os.Getenv("TOTP_ENCRYPTION_KEY"))
+	if n := len(c.TOTPEncryptionKey); n > 0 && n < MinJWTSecretLength {
+		invalid = append(invalid, fmt.Sprintf("TOTP_ENCRYPTION_KEY (must be at least %d bytes, got %d)", MinJWTSecretLength, n))
+	}
+	if len(c.TOTPEncryptionKey) > 0 && string(c.TOTPEncryptionKey) == string(c.JWTSecret) {
+		invalid = append(invalid, "TOTP_ENCRYPTION_KEY (must differ from JWT_SECRET)")
+	}
+
+	str("FRONTEND_URL", &c.FrontendURL)
+	c.FrontendURL = strings.TrimSuffix(c.FrontendURL, "/")
+	if v := strings.TrimSpace(os.Getenv("CORS_ORIGINS")); v != "" {
+		c.CORSOrigins = nil
+		for _, o := range strings.Split(v, ",") {
+			if o = strings.TrimSpace(o); o != "" {
+				c.CORSOrigins = append(c.CORSOrigins, o)
+			}
+		}
+	}
+	if c.FrontendURL != "" {
+		c.CORSOrigins = append(c.CORSOrigins, c.FrontendURL)
+	}
+
+	size("UPLOAD_MAX_BYTES", &c.UploadMaxBytes)
+	size("UPLOAD_MAX_BATCH_BYTES", &c.UploadMaxBatchBytes)
+	integer("UPLOAD_MAX_DIMENSION", &c.UploadMaxDimension, 1)
+	if c.UploadMaxBatchBytes < c.UploadMaxBytes {
+		invalid = append(invalid, "UPLOAD_MAX_BATCH_BYTES (must not be below UPLOAD_MAX_BYTES)")
+	}
+
+	duration("ANTI_SNIPE_WINDOW", &c.AntiSnipeWindow)
+	duration("ANTI_SNIPE_EXTENSION", &c.AntiSnipeExtension)
+	duration("AUCTION_MAX_DURATION", &c.AuctionMaxDuration)
+	str("AUCTION_DURATION_MODE", &c.AuctionDurationMode)
+	c.AuctionDurationMode = strings.ToLower(c.AuctionDurationMode)
+	if c.AuctionDurationMode != "reject" && c.AuctionDurationMode != "cap" {
+		invalid = append(invalid, fmt.Sprintf("AUCTION_DURATION_MODE=%q (want reject or cap)", c.AuctionDurationMode))
+	}
+	duration("SETTLEMENT_WINDOW", &c.SettlementWindow)
+	duration("AUCTION_SWEEP_INTERVAL", &c.SweepInterval)
+	positive("AUCTION_SWEEP_INTERVAL", c.SweepInterval)
+	integer("AUCTION_SWEEP_BATCH", &c.SweepBatch, 1)
+	integer("NOTIFY_WORKERS", &c.NotifyWorkers, 1)
+	if v := strings.TrimSpace(os.Getenv("METRICS_ENABLED")); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			invalid = append(invalid, fmt.Sprintf("METRICS_ENABLED=%q (want true or false)", v))
+		}
+		c.MetricsEnabled = b
+	}
+
+	duration("ACCESS_TOKEN_TTL", &c.AccessTokenTTL)
+	duration("REFRESH_TOKEN_TTL", &c.RefreshTokenTTL)
+	duration("SESSION_RENEW_WINDOW", &c.SessionRenewWindow)
+	duration("SESSION_IDLE_TIMEOUT", &c.SessionIdleTimeout)
+	duration("CLAIMS_CACHE_TTL", &c.ClaimsCacheTTL)
+	duration("AUTH_RATE_WINDOW", &c.AuthRateWindow)
+	integer("AUTH_RATE_LIMIT_IP", &c.AuthRateLimitIP, 1)
+	integer("AUTH_RATE_LIMIT_EMAIL", &c.AuthRateLimitEmail, 1)
+	duration("REREGISTER_COOLDOWN", &c.ReregisterCooldown)
+	positive("ACCESS_TOKEN_TTL", c.AccessTokenTTL)
+	positive("REFRESH_TOKEN_TTL", c.RefreshTokenTTL)
+	positive("AUTH_RATE_WINDOW", c.AuthRateWindow)
+
+	str("MONEY_ROUNDING", &c.MoneyRounding)
+	c.MoneyRounding = strings.ToLower(c.MoneyRounding)
+	if c.MoneyRounding != "half_up" && c.MoneyRounding != "half_even" {
+		invalid = append(invalid, fmt.Sprintf("MONEY_ROUNDING=%q (want half_up or half_even)", c.MoneyRounding))
+	}
+	str("CURRENCY", &c.Currency)
+	c.Currency = strings.ToUpper(c.Currency)
+	if !isCurrencyCode(c.Currency) {
+		invalid = append(invalid, fmt.Sprintf("CURRENCY=%q (want a three-letter ISO 4217 code)", c.Currency))
+	}
+	str("MONEY_LOCALE", &c.MoneyLocale)
+	amount("MAX_USER_EXPOSURE", &c.MaxUserExposure)
+	amount("DAILY_DEPOSIT_LIMIT", &c.DailyDepositLimit)
+	amount("DAILY_WITHDRAW_LIMIT", &c.DailyWithdrawLimit)
+	duration("WITHDRAW_DEDUP_WINDOW", &c.WithdrawDedupWindow)
+
+	amount("MIN_BID_INCREMENT", &c.MinBidIncrement)
+	if c.MinBidIncrement == 0 {
+		invalid = append(invalid, "MIN_BID_INCREMENT (must be positive)")
+	}
+	amount("LISTING_FEE", &c.ListingFee)
+	amount("FEATURE_FEE", &c.FeatureFee)
+	duration("FEATURE_DURATION", &c.FeatureDuration)
+	positive("FEATURE_DURATION", c.FeatureDuration)
+	integer("MAX_AUTO_RELISTS", &c.MaxAutoRelists, 0)
+	duration("WATCHLIST_ENDING_WINDOW", &c.WatchlistEndingWindow)
+	str("SETTLEMENT_EXPIRY_POLICY", &c.SettlementExpiryPolicy)
+	c.SettlementExpiryPolicy = strings.ToLower(c.SettlementExpiryPolicy)
+	if c.SettlementExpiryPolicy != "refund" && c.SettlementExpiryPolicy != "complete" {
+		invalid = append(invalid, fmt.Sprintf("SETTLEMENT_EXPIRY_POLICY=%q (want refund or complete)", c.SettlementExpiryPolicy))
+	}
+	duration("SETTLEMENT_REMINDER_INTERVAL", &c.SettlementReminderInterval)
+	duration("CHAT_EDIT_WINDOW", &c.ChatEditWindow)
+	for _, key := range strings.Split(os.Getenv("ONBOARDING_STEPS"), ",") {
+		if key = strings.TrimSpace(key); key == "" {
+			continue
+		}
+		if !contains(OnboardingStepKeys, key) || contains(c.OnboardingSteps, key) {
+			invalid = append(invalid, fmt.Sprintf("ONBOARDING_STEPS (unknown or repeated step %q; want some of %s)", key, strings.Join(OnboardingStepKeys, ", ")))
+			continue
+		}
+		c.OnboardingSteps = append(c.OnboardingSteps, key)
+	}
+
+	str("S3_BUCKET", &c.S3Bucket)
+	str("S3_REGION", &c.S3Region)
+	str("S3_ENDPOINT", &c.S3Endpoint)
+	str("S3_PUBLIC_URL", &c.S3PublicURL)
+	str("S3_ACCESS_KEY_ID", &c.S3AccessKeyID)
+	str("S3_SECRET_ACCESS_KEY", &c.S3SecretAccessKey)
+	c.S3Endpoint = strings.TrimSuffix(c.S3Endpoint, "/")
+	c.S3PublicURL = strings.TrimSuffix(c.S3PublicURL, "/")
+	if c.S3Bucket != "" {
+		if c.S3Region == "" {
+			c.S3Region = "us-east-1"
+		}
+		if c.S3Endpoint == "" {
+			c.S3Endpoint = "https://s3." + c.S3Region + ".amazonaws.com"
+		}
+		if c.S3PublicURL == "" {
+			c.S3PublicURL = c.S3Endpoint + "/" + c.S3Bucket
+		}
+		if c.S3AccessKeyID == "" || c.S3SecretAccessKey == "" {
+			invalid = append(invalid, "S3_ACCESS_KEY_ID and S3_SECRET_ACCESS_KEY (required with S3_BUCKET)")
+		}
+		absoluteURL("S3_ENDPOINT", c.S3Endpoint)
+		absoluteURL("S3_PUBLIC_URL", c.S3PublicURL)
+	}
+
+	str("SMTP_ADDR", &c.SMTPAddr)
+	str("SMTP_FROM", &c.SMTPFrom)
+	str("SMTP_USERNAME", &c.SMTPUsername)
+	c.SMTPPassword = os.Getenv("SMTP_PASSWORD")
+	if c.SMTPAddr != "" {
+		if _, _, err := net.SplitHostPort(c.SMTPAddr); err != nil {
+			invalid = append(invalid, fmt.Sprintf("SMTP_ADDR=%q (want host:port)", c.SMTPAddr))
+		}
+		if c.SMTPFrom == "" {
+			invalid = append(invalid, "SMTP_FROM (required with SMTP_ADDR)")
+		}
+	}
+
+	var problems []string
+	if len(missing) > 0 {
+		problems = append(problems, "missing required environment variables: "+strings.Join(missing, ", "))
+	}
+	if len(invalid) > 0 {
+		problems = append(problems, "invalid environment variables: "+strings.Join(invalid, "; "))
+	}
+	if len(problems) > 0 {
+		return nil, fmt.Errorf("%s", strings.Join(problems, "; "))
+	}
+	return c, nil
+}
+
+// isCurrencyCode reports whether s looks like an ISO 4217 code: three
+// uppercase ASCII letters.
+func isCurrencyCode(s string) bool {
+	if len(s) != 3 {
+		return false
+	}
+	for _, r := range s {
+		if r < 'A' || r > 'Z' {
+			return false
+		}
+	}
+	return true
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
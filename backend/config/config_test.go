@@ -0,0 +1,193 @@
+package config
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+const testSecret = "0123456789abcdef0123456789abcdef"
+
+// setEnv sets the required variables plus any overrides; an empty value
+// clears a variable.
+func setEnv(t *testing.T, overrides map[string]string) {
+	t.Helper()
+	env := map[string]string{
+		"DATABASE_URL":           "postgres://localhost/test",
+		"JWT_SECRET":             testSecret,
+		"DEPOSIT_SIGNING_SECRET": "deposit-0123456789abcdef0123456789",
+	}
+	for k, v := range overrides {
+		env[k] = v
+	}
+	for k, v := range env {
+		t.Setenv(k, v)
+	}
+}
+
+func TestLoadDefaults(t *testing.T) {
+	setEnv(t, nil)
+	c, err := Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Port != "8080" || c.AuctionDurationMode != "reject" || c.SweepInterval != 30*time.Second {
+		t.Errorf("defaults not applied: %+v", c)
+	}
+	if !c.Local() {
+		t.Error("no FRONTEND_URL should mean local mode")
+	}
+	if c.LocalMode || len(c.TOTPEncryptionKey) != 0 {
+		t.Error("optional settings should default to off")
+	}
+}
+
+func TestLoadOverrides(t *testing.T) {
+	setEnv(t, map[string]string{
+		"FRONTEND_URL":          "https://shop.example/",
+		"CORS_ORIGINS":          " https://a.example , https://b.example ",
+		"ANTI_SNIPE_WINDOW":     "45s",
+		"AUCTION_DURATION_MODE": "CAP",
+		"UPLOAD_MAX_BYTES":      "1024",
+		"METRICS_ENABLED":       "true",
+	})
+	c, err := Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.FrontendURL != "https://shop.example" || c.Local() {
+		t.Errorf("FrontendURL = %q", c.FrontendURL)
+	}
+	want := []string{"https://a.example", "https://b.example", "https://shop.example"}
+	if strings.Join(c.CORSOrigins, " ") != strings.Join(want, " ") {
+		t.Errorf("CORSOrigins = %q, want %q", c.CORSOrigins, want)
+	}
+	if c.AntiSnipeWindow != 45*time.Second || c.AuctionDurationMode != "cap" ||
+		c.UploadMaxBytes != 1024 || !c.MetricsEnabled {
+		t.Errorf("overrides not applied: %+v", c)
+	}
+}
+
+func TestLoadRejects(t *testing.T) {
+	for name, tc := range map[string]struct {
+		env  map[string]string
+		want string
+	}{
+		"missing database":       {map[string]string{"DATABASE_URL": ""}, "DATABASE_URL"},
+		"missing jwt secret":     {map[string]string{"JWT_SECRET": ""}, "missing required environment variables: JWT_SECRET"},
+		"short jwt secret":       {map[string]string{"JWT_SECRET": "short"}, "JWT_SECRET (must be at least 32 bytes, got 5)"},
+		"missing deposit secret": {map[string]string{"DEPOSIT_SIGNING_SECRET": ""}, "missing required environment variables: DEPOSIT_SIGNING_SECRET"},
+		"bad local mode":         {map[string]string{"LOCAL_MODE": "maybe"}, "LOCAL_MODE"},
+		"short deposit secret":   {map[string]string{"DEPOSIT_SIGNING_SECRET": "short"}, "DEPOSIT_SIGNING_SECRET (must be at least"},
+		"deposit reuses jwt":     {map[string]string{"DEPOSIT_SIGNING_SECRET": testSecret}, "DEPOSIT_SIGNING_SECRET (must differ from JWT_SECRET)"},
+		"short totp key":         {map[string]string{"TOTP_ENCRYPTION_KEY": "short"}, "TOTP_ENCRYPTION_KEY (must be at least"},
+		"totp reuses jwt":        {map[string]string{"TOTP_ENCRYPTION_KEY": testSecret}, "TOTP_ENCRYPTION_KEY (must differ from JWT_SECRET)"},
+		"bad duration":           {map[string]string{"ANTI_SNIPE_WINDOW": "soon"}, "ANTI_SNIPE_WINDOW"},
+		"negative duration":      {map[string]string{"SETTLEMENT_WINDOW": "-1h"}, "SETTLEMENT_WINDOW"},
+		"zero sweep interval":    {map[string]string{"AUCTION_SWEEP_INTERVAL": "0s"}, "AUCTION_SWEEP_INTERVAL (must be positive)"},
+		"bad duration mode":      {map[string]string{"AUCTION_DURATION_MODE": "stretch"}, "AUCTION_DURATION_MODE"},
+		"batch below upload":     {map[string]string{"UPLOAD_MAX_BYTES": "100", "UPLOAD_MAX_BATCH_BYTES": "50"}, "UPLOAD_MAX_BATCH_BYTES"},
+		"non-numeric size":       {map[string]string{"UPLOAD_MAX_DIMENSION": "big"}, "UPLOAD_MAX_DIMENSION"},
+		"bad metrics flag":       {map[string]string{"METRICS_ENABLED": "sometimes"}, "METRICS_ENABLED"},
+		"zero access ttl":        {map[string]string{"ACCESS_TOKEN_TTL": "0s"}, "ACCESS_TOKEN_TTL (must be positive)"},
+		"bad rate limit":         {map[string]string{"AUTH_RATE_LIMIT_EMAIL": "lots"}, "AUTH_RATE_LIMIT_EMAIL"},
+		"bad cooldown":           {map[string]string{"REREGISTER_COOLDOWN": "a week"}, "REREGISTER_COOLDOWN"},
+		"bad rounding":           {map[string]string{"MONEY_ROUNDING": "bankers"}, "MONEY_ROUNDING"},
+		"bad currency":           {map[string]string{"CURRENCY": "rupees"}, "CURRENCY"},
+		"malformed exposure":     {map[string]string{"MAX_USER_EXPOSURE": "5k"}, "MAX_USER_EXPOSURE"},
+		"negative deposit limit": {map[string]string{"DAILY_DEPOSIT_LIMIT": "-10"}, "DAILY_DEPOSIT_LIMIT"},
+		"malformed withdraw cap": {map[string]string{"DAILY_WITHDRAW_LIMIT": "NaN"}, "DAILY_WITHDRAW_LIMIT"},
+		"zero bid increment":     {map[string]string{"MIN_BID_INCREMENT": "0"}, "MIN_BID_INCREMENT (must be positive)"},
+		"bad feature fee":        {map[string]string{"FEATURE_FEE": "free"}, "FEATURE_FEE"},
+		"bad expiry policy":      {map[string]string{"SETTLEMENT_EXPIRY_POLICY": "ignore"}, "SETTLEMENT_EXPIRY_POLICY"},
+		"unknown onboarding":     {map[string]string{"ONBOARDING_STEPS": "verify_email,first_sale"}, `"first_sale"`},
+		"repeated onboarding":    {map[string]string{"ONBOARDING_STEPS": "first_bid,first_bid"}, `"first_bid"`},
+		"s3 without keys":        {map[string]string{"S3_BUCKET": "uploads"}, "S3_ACCESS_KEY_ID and S3_SECRET_ACCESS_KEY"},
+		"relative s3 endpoint": {map[string]string{
+			"S3_BUCKET": "uploads", "S3_ACCESS_KEY_ID": "id", "S3_SECRET_ACCESS_KEY": "key", "S3_ENDPOINT": "minio:9000",
+		}, "S3_ENDPOINT"},
+		"smtp without port": {map[string]string{"SMTP_ADDR": "mail.example", "SMTP_FROM": "shop@example.com"}, "SMTP_ADDR"},
+		"smtp without from": {map[string]string{"SMTP_ADDR": "mail.example:587"}, "SMTP_FROM (required with SMTP_ADDR)"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			setEnv(t, tc.env)
+			c, err := Load()
+			if err == nil {
+				t.Fatalf("Load accepted it: %+v", c)
+			}
+			if !strings.Contains(err.Error(), tc.want) {
+				t.Errorf("error %q does not mention %q", err, tc.want)
+			}
+		})
+	}
+}
+
+func TestLoadAppSettings(t *testing.T) {
+	setEnv(t, nil)
+	c, err := Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.AccessTokenTTL != 15*time.Minute || c.MoneyRounding != "half_up" || c.Currency != "INR" ||
+		c.MaxUserExposure != 0 || c.SettlementExpiryPolicy != "refund" || c.OnboardingSteps != nil {
+		t.Errorf("defaults not applied: %+v", c)
+	}
+
+	setEnv(t, map[string]string{
+		"ACCESS_TOKEN_TTL":     "5m",
+		"REREGISTER_COOLDOWN":  "720h",
+		"MONEY_ROUNDING":       "HALF_EVEN",
+		"CURRENCY":             "usd",
+		"MAX_USER_EXPOSURE":    "5000",
+		"DAILY_DEPOSIT_LIMIT":  "2500.50",
+		"ONBOARDING_STEPS":     " first_bid , verify_email ",
+		"S3_BUCKET":            "uploads",
+		"S3_REGION":            "ap-south-1",
+		"S3_ACCESS_KEY_ID":     "id",
+		"S3_SECRET_ACCESS_KEY": "key",
+	})
+	c, err = Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.AccessTokenTTL != 5*time.Minute || c.ReregisterCooldown != 720*time.Hour ||
+		c.MoneyRounding != "half_even" || c.Currency != "USD" ||
+		c.MaxUserExposure != 5000 || c.DailyDepositLimit != 2500.50 {
+		t.Errorf("overrides not applied: %+v", c)
+	}
+	if strings.Join(c.OnboardingSteps, ",") != "first_bid,verify_email" {
+		t.Errorf("OnboardingSteps = %q", c.OnboardingSteps)
+	}
+	if c.S3Endpoint != "https://s3.ap-south-1.amazonaws.com" ||
+		c.S3PublicURL != "https://s3.ap-south-1.amazonaws.com/uploads" {
+		t.Errorf("S3 defaults = %q, %q", c.S3Endpoint, c.S3PublicURL)
+	}
+}
+
+func TestLoadReportsEveryProblem(t *testing.T) {
+	setEnv(t, map[string]string{
+		"DATABASE_URL":      "",
+		"JWT_SECRET":        "",
+		"ANTI_SNIPE_WINDOW": "soon",
+	})
+	_, err := Load()
+	if err == nil {
+		t.Fatal("Load accepted it")
+	}
+	for _, want := range []string{"DATABASE_URL", "JWT_SECRET", "ANTI_SNIPE_WINDOW"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q does not mention %s", err, want)
+		}
+	}
+}
+
+func TestLoadLocalModeAllowsMissingDepositSecret(t *testing.T) {
+	setEnv(t, map[string]string{"DEPOSIT_SIGNING_SECRET": "", "LOCAL_MODE": "true"})
+	c, err := Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !c.LocalMode || len(c.DepositSigningSecret) != 0 {
+		t.Errorf("LocalMode = %t, deposit secret %q", c.LocalMode, c.DepositSigningSecret)
+	}
+}
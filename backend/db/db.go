@@ -3,7 +3,6 @@ package db
 import (
 	"context"
 	"fmt"
-	"os"
 
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
@@ -11,9 +10,9 @@ import (
 
 var Pool *pgxpool.Pool
 
-// Connect initialises the pgx connection pool from the DATABASE_URL env var.
-func Connect(ctx context.Context) error {
-	dsn := os.Getenv("DATABASE_URL")
+// Connect initialises the pgx connection pool from dsn, the DATABASE_URL
+// setting.
+func Connect(ctx context.Context, dsn string) error {
 	if dsn == "" {
 		return fmt.Errorf("DATABASE_URL environment variable is not set")
 	}
@@ -25,7 +24,15 @@ func Connect(ctx context.Context) error {
 
 	// Use simple protocol — required for Supabase transaction pooler (port 6543).
 	// The transaction pooler does not support server-side prepared statements.
+	//
+	// Under this mode pgx interpolates arguments into the SQL text client-side,
+	// so the server never infers parameter types. Queries must therefore not
+	// rely on that inference: placeholders reused within one statement or
+	// compared against expressions (LIKE, split_part, ::text comparisons) carry
+	// explicit casts so they behave the same if the exec mode is ever switched
+	// back to the default extended protocol for a direct connection.
 	config.ConnConfig.DefaultQueryExecMode = pgx.QueryExecModeSimpleProtocol
+	config.ConnConfig.Tracer = queryTracer{}
 
 	pool, err := pgxpool.NewWithConfig(ctx, config)
 	if err != nil {
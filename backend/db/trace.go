@@ -0,0 +1,40 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/karti/orange-city-mart/backend/requestid"
+)
+
+type sqlKey struct{}
+
+// queryTracer logs failed queries with the request id from their context, so
+// a 500 in the request log can be matched to the statement behind it.
+// Integrity constraint violations (SQLSTATE class 23) are skipped: handlers
+// use them for expected conflicts such as duplicate signups.
+type queryTracer struct{}
+
+func (queryTracer) TraceQueryStart(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryStartData) context.Context {
+	return context.WithValue(ctx, sqlKey{}, data.SQL)
+}
+
+func (queryTracer) TraceQueryEnd(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryEndData) {
+	if data.Err == nil {
+		return
+	}
+	var pgErr *pgconn.PgError
+	if errors.As(data.Err, &pgErr) && strings.HasPrefix(pgErr.Code, "23") {
+		return
+	}
+	sql, _ := ctx.Value(sqlKey{}).(string)
+	slog.Error("query failed",
+		"request_id", requestid.FromContext(ctx),
+		"err", data.Err,
+		"sql", strings.Join(strings.Fields(sql), " "),
+	)
+}
@@ -0,0 +1,139 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/karti/orange-city-mart/backend/db"
+	authmw "github.com/karti/orange-city-mart/backend/middleware"
+)
+
+// ─────────────────────────────────────────────────────────────────────────────
+// DeleteAccount  DELETE /api/me
+//
+// Body: { "password": "..." }
+// Anonymises the caller's account. Bids, sales and messages stay for the
+// counterparties, attributed to "Deleted user"; the email is freed and listed
+// in deleted_emails so Register can hold it back for REREGISTER_COOLDOWN.
+// Refused with 409 while the account still holds money or has live auctions,
+// holds or settlements, since nobody could finish those afterwards.
+// ─────────────────────────────────────────────────────────────────────────────
+func (h *Handler) DeleteAccount(w http.ResponseWriter, r *http.Request) {
+	userID, ok := authmw.UserIDFromContext(r.Context())
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "unauthorized", "unauthorized")
+		return
+	}
+	var req struct {
+		Password string `json:"password"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Password == "" {
+		writeError(w, http.StatusBadRequest, "missing_fields", "password is required")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	tx, err := db.Pool.Begin(ctx)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "database_error", "database error")
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	var email, passwordHash string
+	var balance float64
+	err = tx.QueryRow(ctx, `
+		SELECT email, password_hash, wallet_balance
+		FROM users WHERE id = $1::uuid FOR UPDATE`, userID,
+	).Scan(&email, &passwordHash, &balance)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "database_error", "database error")
+		return
+	}
+	if bcrypt.CompareHashAndPassword([]byte(passwordHash), []byte(req.Password)) != nil {
+		writeError(w, http.StatusUnauthorized, "invalid_credentials", "incorrect password")
+		return
+	}
+	if balance > 0 {
+		writeError(w, http.StatusConflict, "wallet_not_empty", "withdraw your wallet balance before deleting the account")
+		return
+	}
+
+	var busy bool
+	err = tx.QueryRow(ctx, `
+		SELECT EXISTS (
+		    SELECT 1 FROM bid_holds
+		    WHERE user_id = $1::uuid AND status IN ('SOFT', 'HARD')
+		) OR EXISTS (
+		    SELECT 1 FROM settlements
+		    WHERE (winner_id = $1::uuid OR seller_id = $1::uuid)
+		      AND status IN ('PENDING', 'DISPUTED')
+		) OR EXISTS (
+		    SELECT 1 FROM auctions a JOIN products p ON p.id = a.product_id
+		    WHERE p.seller_id = $1::uuid AND a.status = 'ACTIVE'
+		)`, userID,
+	).Scan(&busy)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "database_error", "database error")
+		return
+	}
+	if busy {
+		writeError(w, http.StatusConflict, "account_busy", "finish your live auctions, bids and settlements before deleting the account")
+		return
+	}
+
+	email = normalizeEmail(email)
+	steps := []struct {
+		sql  string
+		args []any
+	}{
+		{`INSERT INTO deleted_emails (email) VALUES ($1::text)
+		  ON CONFLICT (email) DO UPDATE SET deleted_at = NOW()`, []any{email}},
+		// The placeholder address keeps users.email unique and can't receive
+		// mail; an empty hash never matches a password.
+		{`UPDATE users SET
+		      name = 'Deleted user',
+		      email = 'deleted-' || id::text || '@invalid',
+		      password_hash = '',
+		      upi_id = NULL,
+		      email_verified = FALSE,
+		      totp_secret = NULL, totp_enabled = FALSE, totp_last_step = 0,
+		      email_notifications = FALSE,
+		      updated_at = NOW()
+		  WHERE id = $1::uuid`, []any{userID}},
+		{`UPDATE products SET deleted_at = NOW()
+		  WHERE seller_id = $1::uuid AND type = 'FIXED' AND deleted_at IS NULL`, []any{userID}},
+		{`UPDATE refresh_tokens SET revoked_at = NOW() WHERE user_id = $1::uuid AND revoked_at IS NULL`, []any{userID}},
+		{`DELETE FROM recovery_codes WHERE user_id = $1::uuid`, []any{userID}},
+		{`DELETE FROM password_reset_tokens WHERE user_id = $1::uuid`, []any{userID}},
+		{`DELETE FROM login_tokens WHERE lower(email) = $1::text`, []any{email}},
+		{`DELETE FROM watchlist WHERE user_id = $1::uuid`, []any{userID}},
+	}
+	for _, s := range steps {
+		if _, err := tx.Exec(ctx, s.sql, s.args...); err != nil {
+			logf(ctx, "delete account %s: %v", userID, err)
+			writeError(w, http.StatusInternalServerError, "database_error", "database error")
+			return
+		}
+	}
+	if err := tx.Commit(ctx); err != nil {
+		writeError(w, http.StatusInternalServerError, "database_error", "commit failed")
+		return
+	}
+
+	// The account is gone either way; a surviving access token only lives
+	// until it expires and now belongs to an anonymised user.
+	if token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer "); token != "" {
+		if err := h.Auth.RevokeToken(ctx, token); err != nil {
+			logf(ctx, "delete account %s: revoke access token: %v", userID, err)
+		}
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
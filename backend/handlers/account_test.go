@@ -0,0 +1,142 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/karti/orange-city-mart/backend/config"
+)
+
+// newAccount inserts a user with a real password hash and the given
+// balance, removed again at cleanup along with any deleted_emails entry.
+func newAccount(t *testing.T, password string, balance float64) (id, email string) {
+	t.Helper()
+	ctx := context.Background()
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	email = fmt.Sprintf("gone%d@example.com", time.Now().UnixNano())
+	err = testPool.QueryRow(ctx, `
+		INSERT INTO users (name, email, password_hash, wallet_balance)
+		VALUES ('Test', $1, $2, $3) RETURNING id`, email, string(hash), balance,
+	).Scan(&id)
+	if err != nil {
+		t.Fatalf("insert user: %v", err)
+	}
+	t.Cleanup(func() {
+		testPool.Exec(ctx, `DELETE FROM users WHERE id = $1 OR lower(email) = $2`, id, email)
+		testPool.Exec(ctx, `DELETE FROM deleted_emails WHERE email = $1`, email)
+	})
+	return id, email
+}
+
+// withCooldown returns a Handler with REREGISTER_COOLDOWN set to d.
+func withCooldown(d time.Duration) *Handler {
+	return newTestHandler(func(c *config.Config) { c.ReregisterCooldown = d })
+}
+
+func deleteAccount(h *Handler, userID, password string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodDelete, "/api/me", strings.NewReader(`{"password":"`+password+`"}`))
+	h.DeleteAccount(w, asUser(r, userID))
+	return w
+}
+
+func register(h *Handler, email string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	body := `{"name":"Again","email":"` + email + `","password":"password123"}`
+	h.Register(w, httptest.NewRequest(http.MethodPost, "/api/auth/register", strings.NewReader(body)))
+	return w
+}
+
+// TestDeleteAccountHoldsEmailForCooldown checks that deleting an account
+// anonymises it and keeps its email from being registered again until the
+// cooldown passes, and that a zero cooldown lets it straight back in.
+func TestDeleteAccountHoldsEmailForCooldown(t *testing.T) {
+	requireDB(t)
+	ctx := context.Background()
+	id, email := newAccount(t, "password123", 0)
+
+	if w := deleteAccount(testHandler, id, "wrong-password"); w.Code != http.StatusUnauthorized {
+		t.Fatalf("wrong password = %d, want 401", w.Code)
+	}
+	if w := deleteAccount(testHandler, id, "password123"); w.Code != http.StatusNoContent {
+		t.Fatalf("DeleteAccount = %d: %s", w.Code, w.Body)
+	}
+
+	var name, stored string
+	if err := testPool.QueryRow(ctx, `SELECT name, email FROM users WHERE id = $1`, id).Scan(&name, &stored); err != nil {
+		t.Fatal(err)
+	}
+	if name != "Deleted user" || stored == email {
+		t.Errorf("deleted user = %q <%s>, want anonymised", name, stored)
+	}
+	var listed bool
+	if err := testPool.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM deleted_emails WHERE email = $1)`, email).Scan(&listed); err != nil || !listed {
+		t.Fatalf("deleted_emails has %s = %v (%v)", email, listed, err)
+	}
+
+	w := register(withCooldown(time.Hour), email)
+	if w.Code != http.StatusConflict {
+		t.Fatalf("Register during cooldown = %d, want 409", w.Code)
+	}
+	var body struct {
+		Error struct {
+			Code string `json:"code"`
+		} `json:"error"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &body)
+	if body.Error.Code != "email_in_cooldown" {
+		t.Errorf("error code = %q, want email_in_cooldown", body.Error.Code)
+	}
+
+	if w := register(withCooldown(0), email); w.Code != http.StatusCreated {
+		t.Errorf("Register with cooldown disabled = %d: %s", w.Code, w.Body)
+	}
+}
+
+// TestReregisterAllowedAfterCooldown checks that an email deleted longer ago
+// than the cooldown can be registered again.
+func TestReregisterAllowedAfterCooldown(t *testing.T) {
+	requireDB(t)
+	ctx := context.Background()
+	email := fmt.Sprintf("old%d@example.com", time.Now().UnixNano())
+	t.Cleanup(func() {
+		testPool.Exec(ctx, `DELETE FROM users WHERE lower(email) = $1`, email)
+		testPool.Exec(ctx, `DELETE FROM deleted_emails WHERE email = $1`, email)
+	})
+	_, err := testPool.Exec(ctx, `
+		INSERT INTO deleted_emails (email, deleted_at)
+		VALUES ($1, NOW() - INTERVAL '2 hours')`, email)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if w := register(withCooldown(time.Hour), email); w.Code != http.StatusCreated {
+		t.Errorf("Register after cooldown = %d: %s", w.Code, w.Body)
+	}
+}
+
+// TestDeleteAccountRefusesFundedWallet checks that an account still holding
+// money can't be deleted, so the balance isn't stranded.
+func TestDeleteAccountRefusesFundedWallet(t *testing.T) {
+	requireDB(t)
+	id, email := newAccount(t, "password123", 25)
+
+	if w := deleteAccount(testHandler, id, "password123"); w.Code != http.StatusConflict {
+		t.Fatalf("DeleteAccount with balance = %d, want 409", w.Code)
+	}
+	var stored string
+	if err := testPool.QueryRow(context.Background(), `SELECT email FROM users WHERE id = $1`, id).Scan(&stored); err != nil || stored != email {
+		t.Errorf("email after refused delete = %q (%v), want %q", stored, err, email)
+	}
+}
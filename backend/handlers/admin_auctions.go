@@ -0,0 +1,149 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/jackc/pgx/v5"
+	"github.com/karti/orange-city-mart/backend/db"
+)
+
+// ─────────────────────────────────────────────────────────────────────────────
+// AdminCancelAuction  POST /api/admin/auctions/{id}/cancel
+//
+// Cancels a live or ended-but-unsettled auction. Every SOFT hold is refunded.
+// If the auction already has a PENDING settlement it is voided and the
+// winner's HARD hold refunded too. A COMPLETED settlement can't be undone
+// here; use the settlement refund flow instead.
+// ─────────────────────────────────────────────────────────────────────────────
+func (h *AuctionHandler) AdminCancelAuction(w http.ResponseWriter, r *http.Request) {
+	auctionID := chi.URLParam(r, "id")
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	tx, err := db.Pool.Begin(ctx)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "database_error", "database error")
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	st, err := h.lockAuction(ctx, tx, auctionID)
+	if err == pgx.ErrNoRows {
+		writeError(w, http.StatusNotFound, "auction_not_found", "auction not found")
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "database_error", "database error")
+		return
+	}
+	if st.Status != "ACTIVE" && st.Status != "ENDED" {
+		writeError(w, http.StatusConflict, "auction_not_cancellable", "auction is already "+st.Status)
+		return
+	}
+
+	voided := false
+	if st.Status == "ENDED" {
+		var settlementID, status string
+		err = tx.QueryRow(ctx, `
+			SELECT id, status FROM settlements WHERE auction_id = $1 FOR UPDATE`, auctionID,
+		).Scan(&settlementID, &status)
+		if err != nil && err != pgx.ErrNoRows {
+			writeError(w, http.StatusInternalServerError, "database_error", "database error")
+			return
+		}
+		if err == nil {
+			if status == "COMPLETED" {
+				writeError(w, http.StatusConflict, "settlement_completed", errSettlementCompleted.Error())
+				return
+			}
+			if status == "EXPIRED" {
+				writeError(w, http.StatusConflict, "settlement_expired", errSettlementExpired.Error())
+				return
+			}
+			if status != "PENDING" {
+				writeError(w, http.StatusConflict, "settlement_not_pending", "settlement is "+status+"; resolve it instead")
+				return
+			}
+			if err = releaseHardHolds(ctx, tx, auctionID); err != nil {
+				writeError(w, http.StatusInternalServerError, "database_error", "database error")
+				return
+			}
+			if _, err = tx.Exec(ctx,
+				`UPDATE settlements SET status = 'VOID' WHERE id = $1`, settlementID); err != nil {
+				writeError(w, http.StatusInternalServerError, "database_error", "database error")
+				return
+			}
+			voided = true
+		}
+	}
+
+	if err = updateAuctionVersioned(ctx, tx, st, `status = 'CANCELLED'`); err != nil {
+		writeAuctionError(w, err)
+		return
+	}
+	if err = releaseSoftHolds(ctx, tx, auctionID); err != nil {
+		writeError(w, http.StatusInternalServerError, "database_error", "database error")
+		return
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		writeError(w, http.StatusInternalServerError, "database_error", "commit failed")
+		return
+	}
+
+	h.broadcastAuctionCancelled(auctionID)
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"auction_id":        auctionID,
+		"status":            "CANCELLED",
+		"settlement_voided": voided,
+	})
+}
+
+// releaseHardHolds refunds the HARD holds on an auction (the winner's funds
+// awaiting settlement), crediting each wallet and recording a REFUND.
+func releaseHardHolds(ctx context.Context, tx pgx.Tx, auctionID string) error {
+	rows, err := tx.Query(ctx, `
+		UPDATE bid_holds SET status = 'RELEASED', updated_at = NOW()
+		WHERE auction_id = $1 AND status = 'HARD'
+		RETURNING user_id, amount`, auctionID)
+	if err != nil {
+		return err
+	}
+	type holdRow struct {
+		userID string
+		amount float64
+	}
+	var holds []holdRow
+	for rows.Next() {
+		var h holdRow
+		if err := rows.Scan(&h.userID, &h.amount); err != nil {
+			rows.Close()
+			return err
+		}
+		holds = append(holds, h)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, h := range holds {
+		if _, err := tx.Exec(ctx,
+			`UPDATE users SET wallet_balance = wallet_balance + $1 WHERE id = $2`,
+			h.amount, h.userID); err != nil {
+			return err
+		}
+		if _, err := tx.Exec(ctx, `
+			INSERT INTO transactions (user_id, amount, type, status, reference)
+			VALUES ($1, $2, 'REFUND', 'COMPLETED', $3)`,
+			h.userID, h.amount, auctionID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,113 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/karti/orange-city-mart/backend/db"
+)
+
+// ─────────────────────────────────────────────────────────────────────────────
+// ListHolds  GET /api/admin/holds
+//
+// Escrow exposure across the platform: funds currently held in SOFT and HARD
+// bid holds, as totals and per auction (largest first). An auction is flagged
+// as an anomaly when its holds no longer match its state — a SOFT hold on an
+// auction that is no longer ACTIVE, or a HARD hold on one that never ended
+// with a sale or whose settlement has already completed.
+// ─────────────────────────────────────────────────────────────────────────────
+func (h *Handler) ListHolds(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	type holdTotal struct {
+		Count  int     `json:"count"`
+		Amount float64 `json:"amount"`
+	}
+	totals := map[string]*holdTotal{"SOFT": {}, "HARD": {}}
+
+	rows, err := db.Pool.Query(ctx, `
+		SELECT status, COUNT(*), COALESCE(SUM(amount), 0)::float8
+		FROM bid_holds
+		WHERE status IN ('SOFT', 'HARD')
+		GROUP BY status`)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "database_error", "database error")
+		return
+	}
+	for rows.Next() {
+		var status string
+		var t holdTotal
+		if err := rows.Scan(&status, &t.Count, &t.Amount); err != nil {
+			rows.Close()
+			writeError(w, http.StatusInternalServerError, "database_error", "database error")
+			return
+		}
+		totals[status] = &t
+	}
+	rows.Close()
+	if rows.Err() != nil {
+		writeError(w, http.StatusInternalServerError, "database_error", "database error")
+		return
+	}
+
+	rows, err = db.Pool.Query(ctx, `
+		SELECT a.id, p.title, a.status, a.end_time, s.status,
+		       COUNT(*) FILTER (WHERE h.status = 'SOFT'),
+		       COALESCE(SUM(h.amount) FILTER (WHERE h.status = 'SOFT'), 0)::float8,
+		       COUNT(*) FILTER (WHERE h.status = 'HARD'),
+		       COALESCE(SUM(h.amount) FILTER (WHERE h.status = 'HARD'), 0)::float8,
+		       MIN(h.created_at)
+		FROM bid_holds h
+		JOIN auctions a ON a.id = h.auction_id
+		JOIN products p ON p.id = a.product_id
+		LEFT JOIN settlements s ON s.auction_id = a.id
+		WHERE h.status IN ('SOFT', 'HARD')
+		GROUP BY a.id, p.title, a.status, a.end_time, s.status
+		ORDER BY SUM(h.amount) DESC`)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "database_error", "database error")
+		return
+	}
+	defer rows.Close()
+
+	type auctionHolds struct {
+		AuctionID        string  `json:"auction_id"`
+		Title            string  `json:"title"`
+		AuctionStatus    string  `json:"auction_status"`
+		EndTime          string  `json:"end_time"`
+		SettlementStatus *string `json:"settlement_status"`
+		SoftCount        int     `json:"soft_count"`
+		SoftAmount       float64 `json:"soft_amount"`
+		HardCount        int     `json:"hard_count"`
+		HardAmount       float64 `json:"hard_amount"`
+		OldestHoldAt     string  `json:"oldest_hold_at"`
+		Anomaly          bool    `json:"anomaly"`
+	}
+
+	auctions := []auctionHolds{}
+	for rows.Next() {
+		var a auctionHolds
+		var endTime, oldest time.Time
+		if err := rows.Scan(&a.AuctionID, &a.Title, &a.AuctionStatus, &endTime, &a.SettlementStatus,
+			&a.SoftCount, &a.SoftAmount, &a.HardCount, &a.HardAmount, &oldest); err != nil {
+			writeError(w, http.StatusInternalServerError, "database_error", "database error")
+			return
+		}
+		a.EndTime = endTime.UTC().Format(time.RFC3339)
+		a.OldestHoldAt = oldest.UTC().Format(time.RFC3339)
+		settled := a.SettlementStatus != nil && *a.SettlementStatus == "COMPLETED"
+		a.Anomaly = (a.SoftCount > 0 && a.AuctionStatus != "ACTIVE") ||
+			(a.HardCount > 0 && (a.AuctionStatus != "ENDED" || settled))
+		auctions = append(auctions, a)
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"soft": totals["SOFT"],
+		"hard": totals["HARD"],
+		"total": holdTotal{
+			Count:  totals["SOFT"].Count + totals["HARD"].Count,
+			Amount: h.roundMoney(totals["SOFT"].Amount + totals["HARD"].Amount),
+		},
+		"auctions": auctions,
+	})
+}
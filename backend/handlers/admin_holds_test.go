@@ -0,0 +1,106 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type holdsReport struct {
+	Soft, Hard, Total struct {
+		Count  int     `json:"count"`
+		Amount float64 `json:"amount"`
+	}
+	Auctions []struct {
+		AuctionID  string  `json:"auction_id"`
+		SoftCount  int     `json:"soft_count"`
+		SoftAmount float64 `json:"soft_amount"`
+		HardCount  int     `json:"hard_count"`
+		HardAmount float64 `json:"hard_amount"`
+		Anomaly    bool    `json:"anomaly"`
+	} `json:"auctions"`
+}
+
+func listHolds(t *testing.T) holdsReport {
+	t.Helper()
+	w := httptest.NewRecorder()
+	testHandler.ListHolds(w, httptest.NewRequest(http.MethodGet, "/api/admin/holds", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("ListHolds = %d: %s", w.Code, w.Body)
+	}
+	var rep holdsReport
+	if err := json.Unmarshal(w.Body.Bytes(), &rep); err != nil {
+		t.Fatalf("body %q: %v", w.Body, err)
+	}
+	return rep
+}
+
+// TestListHoldsTotalsSeededHolds seeds holds on three auctions and checks
+// the platform totals grow by exactly the live ones and each auction's
+// figures and anomaly flag are right.
+func TestListHoldsTotalsSeededHolds(t *testing.T) {
+	requireDB(t)
+	ctx := context.Background()
+	seller := newTestUser(t, testPool, 0)
+	a := newTestUser(t, testPool, 0)
+	b := newTestUser(t, testPool, 0)
+	t.Cleanup(func() { testPool.Exec(ctx, `DELETE FROM users WHERE id IN ($1, $2, $3)`, seller, a, b) })
+	before := listHolds(t)
+
+	_, live := newTestAuctionListing(t, seller)
+	_, awaiting := newTestAuctionListing(t, seller)
+	_, settled := newTestAuctionListing(t, seller)
+	for _, s := range []struct {
+		sql  string
+		args []any
+	}{
+		{`UPDATE auctions SET status = 'ENDED' WHERE id IN ($1, $2)`, []any{awaiting, settled}},
+		{`INSERT INTO bid_holds (auction_id, user_id, amount, status) VALUES
+		      ($1, $2, 100, 'SOFT'), ($1, $3, 50, 'SOFT'), ($1, $2, 70, 'RELEASED')`, []any{live, a, b}},
+		{`INSERT INTO bid_holds (auction_id, user_id, amount, status) VALUES
+		      ($1, $3, 200, 'HARD'), ($2, $3, 30, 'HARD')`, []any{awaiting, settled, a}},
+		{`INSERT INTO settlements (auction_id, winner_id, seller_id, amount, status) VALUES
+		      ($1, $3, $4, 200, 'PENDING'), ($2, $3, $4, 30, 'COMPLETED')`, []any{awaiting, settled, a, seller}},
+	} {
+		if _, err := testPool.Exec(ctx, s.sql, s.args...); err != nil {
+			t.Fatalf("seed: %v", err)
+		}
+	}
+
+	after := listHolds(t)
+	near := func(x, y float64) bool { return math.Abs(x-y) < 0.005 }
+	if after.Soft.Count-before.Soft.Count != 2 || !near(after.Soft.Amount-before.Soft.Amount, 150) {
+		t.Errorf("SOFT grew by %d / %.2f, want 2 / 150", after.Soft.Count-before.Soft.Count, after.Soft.Amount-before.Soft.Amount)
+	}
+	if after.Hard.Count-before.Hard.Count != 2 || !near(after.Hard.Amount-before.Hard.Amount, 230) {
+		t.Errorf("HARD grew by %d / %.2f, want 2 / 230", after.Hard.Count-before.Hard.Count, after.Hard.Amount-before.Hard.Amount)
+	}
+	if after.Total.Count != after.Soft.Count+after.Hard.Count || !near(after.Total.Amount, after.Soft.Amount+after.Hard.Amount) {
+		t.Errorf("total %+v is not SOFT + HARD", after.Total)
+	}
+
+	want := map[string]struct {
+		soft, hard float64
+		anomaly    bool
+	}{
+		live:     {150, 0, false},
+		awaiting: {0, 200, false},
+		settled:  {0, 30, true},
+	}
+	for _, got := range after.Auctions {
+		w, ok := want[got.AuctionID]
+		if !ok {
+			continue
+		}
+		delete(want, got.AuctionID)
+		if !near(got.SoftAmount, w.soft) || !near(got.HardAmount, w.hard) || got.Anomaly != w.anomaly {
+			t.Errorf("auction %s = %+v, want soft %.2f hard %.2f anomaly %t", got.AuctionID, got, w.soft, w.hard, w.anomaly)
+		}
+	}
+	if len(want) > 0 {
+		t.Errorf("auctions missing from the report: %v", want)
+	}
+}
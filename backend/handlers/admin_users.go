@@ -0,0 +1,176 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/karti/orange-city-mart/backend/db"
+	authmw "github.com/karti/orange-city-mart/backend/middleware"
+)
+
+const (
+	defaultAdminUsersLimit = 50
+	maxAdminUsersLimit     = 200
+)
+
+// ─────────────────────────────────────────────────────────────────────────────
+// ListUsers  GET /api/admin/users?q=&role=&frozen=true&unverified=true&limit=&offset=
+//
+// Admin user browser, newest accounts first. q matches name or email
+// (case-insensitive substring); role is user or admin; frozen and unverified
+// narrow to those accounts. Responds with {items, total}. Password hashes and
+// 2FA secrets are never selected.
+// ─────────────────────────────────────────────────────────────────────────────
+func (h *Handler) ListUsers(w http.ResponseWriter, r *http.Request) {
+	qs := r.URL.Query()
+
+	limit := defaultAdminUsersLimit
+	if v, err := strconv.Atoi(qs.Get("limit")); err == nil && v > 0 {
+		limit = v
+	}
+	if limit > maxAdminUsersLimit {
+		limit = maxAdminUsersLimit
+	}
+	offset := 0
+	if v := qs.Get("offset"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			writeError(w, http.StatusBadRequest, "invalid_offset", "offset must be a non-negative integer")
+			return
+		}
+		offset = n
+	}
+
+	var conds []string
+	var args []interface{}
+	if q := strings.TrimSpace(qs.Get("q")); q != "" {
+		args = append(args, "%"+escapeLike(strings.ToLower(q))+"%")
+		n := itoa(len(args))
+		conds = append(conds, "(lower(name) LIKE $"+n+" OR lower(email) LIKE $"+n+")")
+	}
+	if role := qs.Get("role"); role != "" {
+		if role != "user" && role != "admin" {
+			writeError(w, http.StatusBadRequest, "invalid_role", "role must be user or admin")
+			return
+		}
+		args = append(args, role)
+		conds = append(conds, "role = $"+itoa(len(args)))
+	}
+	if qs.Get("frozen") == "true" {
+		conds = append(conds, "is_frozen")
+	}
+	if qs.Get("unverified") == "true" {
+		conds = append(conds, "NOT email_verified")
+	}
+	where := ""
+	if len(conds) > 0 {
+		where = "WHERE " + strings.Join(conds, " AND ")
+	}
+
+	ctx := r.Context()
+
+	var total int
+	if err := db.Pool.QueryRow(ctx, `SELECT COUNT(*) FROM users `+where, args...).Scan(&total); err != nil {
+		writeError(w, http.StatusInternalServerError, "database_error", "database error")
+		return
+	}
+
+	args = append(args, limit, offset)
+	rows, err := db.Pool.Query(ctx, `
+		SELECT id, name, email, role, email_verified, is_frozen, wallet_balance, created_at
+		FROM users `+where+`
+		ORDER BY created_at DESC, id
+		LIMIT $`+itoa(len(args)-1)+` OFFSET $`+itoa(len(args)), args...)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "database_error", "database error")
+		return
+	}
+	defer rows.Close()
+
+	type adminUser struct {
+		ID            string  `json:"id"`
+		Name          string  `json:"name"`
+		Email         string  `json:"email"`
+		Role          string  `json:"role"`
+		EmailVerified bool    `json:"email_verified"`
+		IsFrozen      bool    `json:"is_frozen"`
+		WalletBalance float64 `json:"wallet_balance"`
+		JoinedAt      string  `json:"joined_at"`
+	}
+	items := []adminUser{}
+	for rows.Next() {
+		var u adminUser
+		var joined time.Time
+		if err := rows.Scan(&u.ID, &u.Name, &u.Email, &u.Role, &u.EmailVerified,
+			&u.IsFrozen, &u.WalletBalance, &joined); err != nil {
+			continue
+		}
+		u.JoinedAt = joined.UTC().Format(time.RFC3339)
+		items = append(items, u)
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"items": items,
+		"total": total,
+	})
+}
+
+// ─────────────────────────────────────────────────────────────────────────────
+// SetUserFrozen  POST /api/admin/users/{id}/freeze
+//
+// Body: { "frozen": true | false }
+// Freezes or unfreezes an account. A frozen account can't send or receive
+// money (transfers, purchases); the change applies on the user's next request.
+// ─────────────────────────────────────────────────────────────────────────────
+func (h *Handler) SetUserFrozen(w http.ResponseWriter, r *http.Request) {
+	adminID, ok := authmw.UserIDFromContext(r.Context())
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "unauthorized", "unauthorized")
+		return
+	}
+	userID := chi.URLParam(r, "id")
+	if _, err := uuid.Parse(userID); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_user_id", "invalid user id")
+		return
+	}
+
+	var req struct {
+		Frozen *bool `json:"frozen"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Frozen == nil {
+		writeError(w, http.StatusBadRequest, "invalid_body", "frozen must be true or false")
+		return
+	}
+	if userID == adminID && *req.Frozen {
+		writeError(w, http.StatusBadRequest, "cannot_freeze_self", "you cannot freeze your own account")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	var frozen bool
+	err := db.Pool.QueryRow(ctx, `
+		UPDATE users SET is_frozen = $2 WHERE id = $1::uuid RETURNING is_frozen`,
+		userID, *req.Frozen,
+	).Scan(&frozen)
+	if err == pgx.ErrNoRows {
+		writeError(w, http.StatusNotFound, "user_not_found", "user not found")
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "database_error", "database error")
+		return
+	}
+	authmw.InvalidateClaims(userID)
+	logf(ctx, "admin %s set user %s frozen=%t", adminID, userID, frozen)
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{"id": userID, "is_frozen": frozen})
+}
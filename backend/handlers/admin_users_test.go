@@ -0,0 +1,168 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	authmw "github.com/karti/orange-city-mart/backend/middleware"
+)
+
+func TestSetUserFrozenRejectsSelf(t *testing.T) {
+	adminID := uuid.NewString()
+	r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"frozen":true}`))
+	r = withURLParam(asUser(r, adminID), "id", adminID)
+	w := httptest.NewRecorder()
+	testHandler.SetUserFrozen(w, r)
+	if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "cannot_freeze_self") {
+		t.Fatalf("SetUserFrozen(self) = %d: %s", w.Code, w.Body)
+	}
+}
+
+// TestSetUserFrozenAppliesAtOnce checks that freezing and unfreezing reach
+// the user's very next request, not once the claims cache expires.
+func TestSetUserFrozenAppliesAtOnce(t *testing.T) {
+	requireDB(t)
+	ctx := context.Background()
+	adminID := newTestUser(t, testPool, 0)
+	userID := newTestUser(t, testPool, 0)
+	t.Cleanup(func() { testPool.Exec(ctx, `DELETE FROM users WHERE id IN ($1, $2)`, adminID, userID) })
+
+	token, err := testHandler.Auth.SignToken(userID, "user", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	frozen := func() bool {
+		var got bool
+		h := testHandler.Auth.RequireAuth(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			c, _ := authmw.ClaimsFromContext(r.Context())
+			got = c.IsFrozen
+		}))
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.Header.Set("Authorization", "Bearer "+token)
+		h.ServeHTTP(httptest.NewRecorder(), r)
+		return got
+	}
+	set := func(body string) {
+		r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+		r = withURLParam(asUser(r, adminID), "id", userID)
+		w := httptest.NewRecorder()
+		testHandler.SetUserFrozen(w, r)
+		if w.Code != http.StatusOK {
+			t.Fatalf("SetUserFrozen(%s) = %d: %s", body, w.Code, w.Body)
+		}
+	}
+
+	if frozen() {
+		t.Fatal("new user already frozen")
+	}
+	set(`{"frozen":true}`)
+	if !frozen() {
+		t.Fatal("freeze did not apply on the next request")
+	}
+	set(`{"frozen":false}`)
+	if frozen() {
+		t.Fatal("unfreeze did not apply on the next request")
+	}
+}
+
+// listUsers calls ListUsers through the admin route's middleware as the
+// holder of token.
+func listUsers(t *testing.T, token, query string) (int, []map[string]any, int) {
+	t.Helper()
+	h := testHandler.Auth.RequireAuth(authmw.RequireAdmin(http.HandlerFunc(testHandler.ListUsers)))
+	r := httptest.NewRequest(http.MethodGet, "/api/admin/users?"+query, nil)
+	r.Header.Set("Authorization", "Bearer "+token)
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	var body struct {
+		Items []map[string]any `json:"items"`
+		Total int              `json:"total"`
+	}
+	if w.Code == http.StatusOK {
+		if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+			t.Fatalf("body %q: %v", w.Body, err)
+		}
+	}
+	return w.Code, body.Items, body.Total
+}
+
+func TestListUsersSearchAndFilters(t *testing.T) {
+	requireDB(t)
+	ctx := context.Background()
+	adminID := newTestUser(t, testPool, 0)
+	tag := fmt.Sprintf("zq%d", time.Now().UnixNano())
+	var plain, frozen string
+	for _, u := range []struct {
+		id     *string
+		name   string
+		frozen bool
+	}{{&plain, "Plain " + tag, false}, {&frozen, "Frozen " + strings.ToUpper(tag), true}} {
+		if err := testPool.QueryRow(ctx, `
+			INSERT INTO users (name, email, password_hash, is_frozen)
+			VALUES ($1, uuid_generate_v4()::text || '@example.com', 'x', $2) RETURNING id`,
+			u.name, u.frozen).Scan(u.id); err != nil {
+			t.Fatal(err)
+		}
+	}
+	t.Cleanup(func() { testPool.Exec(ctx, `DELETE FROM users WHERE id IN ($1, $2, $3)`, adminID, plain, frozen) })
+	if _, err := testPool.Exec(ctx, `UPDATE users SET role = 'admin' WHERE id = $1`, adminID); err != nil {
+		t.Fatal(err)
+	}
+	token, err := testHandler.Auth.SignToken(adminID, "admin", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	code, items, total := listUsers(t, token, "q="+tag)
+	if code != http.StatusOK || total != 2 || len(items) != 2 {
+		t.Fatalf("search %s = %d, %d items of %d; want both users", tag, code, len(items), total)
+	}
+	// Newest first.
+	if items[0]["id"] != frozen || items[1]["id"] != plain {
+		t.Errorf("order = %v, %v; want newest first", items[0]["id"], items[1]["id"])
+	}
+	for _, it := range items {
+		if _, ok := it["password_hash"]; ok {
+			t.Error("listing exposes password_hash")
+		}
+	}
+
+	if _, items, total := listUsers(t, token, "frozen=true&q="+tag); total != 1 || items[0]["id"] != frozen {
+		t.Errorf("frozen filter = %d users, want only the frozen one", total)
+	}
+	if _, _, total := listUsers(t, token, "role=admin&q="+tag); total != 0 {
+		t.Errorf("role=admin matched %d of the plain users", total)
+	}
+	if _, _, total := listUsers(t, token, "q=%25"+tag); total != 0 {
+		t.Errorf("a literal %% in q matched %d users", total)
+	}
+	if code, _, _ := listUsers(t, token, "role=owner"); code != http.StatusBadRequest {
+		t.Errorf("role=owner = %d, want 400", code)
+	}
+}
+
+func TestListUsersRejectsNonAdmins(t *testing.T) {
+	requireDB(t)
+	ctx := context.Background()
+	userID := newTestUser(t, testPool, 0)
+	t.Cleanup(func() { testPool.Exec(ctx, `DELETE FROM users WHERE id = $1`, userID) })
+
+	// The role claim in the token is not trusted; users.role decides.
+	token, err := testHandler.Auth.SignToken(userID, "admin", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if code, _, _ := listUsers(t, token, ""); code != http.StatusForbidden {
+		t.Errorf("ListUsers as a plain user = %d, want 403", code)
+	}
+	if code, _, _ := listUsers(t, "not-a-token", ""); code != http.StatusUnauthorized {
+		t.Errorf("ListUsers without a valid token = %d, want 401", code)
+	}
+}
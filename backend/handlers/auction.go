@@ -3,10 +3,14 @@ package handlers
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"math"
 	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
 	"github.com/karti/orange-city-mart/backend/db"
 	"github.com/karti/orange-city-mart/backend/hub"
@@ -15,6 +19,7 @@ import (
 
 // AuctionHandler wraps the WebSocket hub so handlers can push events.
 type AuctionHandler struct {
+	*Handler
 	Hub *hub.Hub
 }
 
@@ -39,6 +44,12 @@ type OutbidPayload struct {
 	NewBidder  string  `json:"new_bidder"`
 }
 
+// Refund policies for outbid holds.
+const (
+	refundInstant = "INSTANT" // outbid holds are released as soon as a higher bid lands
+	refundAtEnd   = "AT_END"  // every hold stays in place until the auction ends
+)
+
 // ─────────────────────────────────────────────────────────────────────────────
 // PlaceBid  POST /api/auctions/{id}/bid
 //
@@ -46,9 +57,13 @@ type OutbidPayload struct {
 //  1. Deduct bid amount from bidder's wallet.
 //  2. Insert a bid_holds row with status='SOFT'.
 //  3. Release the previous winner's SOFT hold: credit their wallet back,
-//     mark their bid_hold RELEASED.
-//  4. Update auction current_highest_bid / highest_bidder_id.
+//     mark their bid_hold RELEASED. Auctions with refund_policy AT_END skip
+//     this step and keep every hold until the auction ends.
+//  4. Update auction current_highest_bid / highest_bidder_id, conditional on
+//     the version read under the lock; a mismatch is a retryable 409.
 //  5. Persist the raw bid row (for history).
+//  6. Let standing auto-bids respond, in the same transaction.
+//  7. Extend end_time if the bid landed inside the anti-snipe window.
 //
 // ─────────────────────────────────────────────────────────────────────────────
 func (h *AuctionHandler) PlaceBid(w http.ResponseWriter, r *http.Request) {
@@ -57,17 +72,17 @@ func (h *AuctionHandler) PlaceBid(w http.ResponseWriter, r *http.Request) {
 	// Caller identity comes from JWT, not request body.
 	userID, ok := authmw.UserIDFromContext(r.Context())
 	if !ok {
-		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		writeError(w, http.StatusUnauthorized, "unauthorized", "unauthorized")
 		return
 	}
 
 	var req placeBidRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "invalid request body", http.StatusBadRequest)
+		writeError(w, http.StatusBadRequest, "invalid_body", "invalid request body")
 		return
 	}
 	if req.Amount <= 0 {
-		http.Error(w, "positive amount required", http.StatusBadRequest)
+		writeError(w, http.StatusBadRequest, "invalid_amount", "positive amount required")
 		return
 	}
 
@@ -77,180 +92,93 @@ func (h *AuctionHandler) PlaceBid(w http.ResponseWriter, r *http.Request) {
 	// ── Begin transaction ──────────────────────────────────────────────────
 	tx, err := db.Pool.Begin(ctx)
 	if err != nil {
-		http.Error(w, "database error", http.StatusInternalServerError)
+		writeError(w, http.StatusInternalServerError, "database_error", "database error")
 		return
 	}
 	defer tx.Rollback(ctx)
 
 	// ── Lock auction row ───────────────────────────────────────────────────
-	var (
-		currentHighBid   float64
-		prevHighBidderID *string
-		status           string
-		endTime          time.Time
-	)
-	err = tx.QueryRow(ctx, `
-		SELECT current_highest_bid, highest_bidder_id, status, end_time
-		FROM auctions
-		WHERE id = $1
-		FOR UPDATE`,
-		auctionID,
-	).Scan(&currentHighBid, &prevHighBidderID, &status, &endTime)
+	st, err := h.lockAuction(ctx, tx, auctionID)
 	if err == pgx.ErrNoRows {
-		http.Error(w, "auction not found", http.StatusNotFound)
+		writeError(w, http.StatusNotFound, "auction_not_found", "auction not found")
 		return
 	}
 	if err != nil {
-		http.Error(w, "database error", http.StatusInternalServerError)
+		writeError(w, http.StatusInternalServerError, "database_error", "database error")
 		return
 	}
 
-	if status != "ACTIVE" || time.Now().After(endTime) {
-		http.Error(w, "auction is not active", http.StatusConflict)
-		return
-	}
-	if req.Amount <= currentHighBid {
-		http.Error(w, "bid must be greater than current highest bid", http.StatusConflict)
-		return
-	}
-
-	// ── Lock bidder wallet ─────────────────────────────────────────────────
-	var bidderBalance float64
-	err = tx.QueryRow(ctx, `
-		SELECT wallet_balance FROM users WHERE id = $1 FOR UPDATE`, userID,
-	).Scan(&bidderBalance)
-	if err == pgx.ErrNoRows {
-		http.Error(w, "user not found", http.StatusNotFound)
-		return
-	}
-	if err != nil {
-		http.Error(w, "database error", http.StatusInternalServerError)
+	if st.Status != "ACTIVE" || time.Now().After(st.EndTime) {
+		writeError(w, http.StatusConflict, "auction_not_active", "auction is not active")
 		return
 	}
-	if bidderBalance < req.Amount {
-		http.Error(w, "insufficient wallet balance", http.StatusPaymentRequired)
-		return
-	}
-
-	// ── Release previous highest bidder's soft hold ────────────────────────
-	if prevHighBidderID != nil && *prevHighBidderID != userID {
-		// Mark the previous holder's SOFT hold as RELEASED
-		_, err = tx.Exec(ctx, `
-			UPDATE bid_holds
-			SET status = 'RELEASED', updated_at = NOW()
-			WHERE auction_id = $1 AND user_id = $2 AND status = 'SOFT'`,
-			auctionID, *prevHighBidderID,
-		)
+	if st.MinBidInterval > 0 {
+		next, err := nextBidAllowedAt(ctx, tx, st, userID)
 		if err != nil {
-			http.Error(w, "database error", http.StatusInternalServerError)
+			writeError(w, http.StatusInternalServerError, "database_error", "database error")
 			return
 		}
-
-		// Credit their wallet back
-		_, err = tx.Exec(ctx, `
-			UPDATE users SET wallet_balance = wallet_balance + $1 WHERE id = $2`,
-			currentHighBid, *prevHighBidderID,
-		)
-		if err != nil {
-			http.Error(w, "database error", http.StatusInternalServerError)
-			return
-		}
-
-		// Record refund transaction
-		_, err = tx.Exec(ctx, `
-			INSERT INTO transactions (user_id, amount, type, status, reference)
-			VALUES ($1, $2, 'REFUND', 'COMPLETED', $3)`,
-			*prevHighBidderID, currentHighBid, auctionID,
-		)
-		if err != nil {
-			http.Error(w, "database error", http.StatusInternalServerError)
+		if wait := time.Until(next); wait > 0 {
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+			writeErrorDetails(w, http.StatusTooManyRequests, "bidding_too_fast", "you are bidding too quickly on this auction",
+				map[string]any{"next_allowed_at": next.UTC().Format(time.RFC3339)})
 			return
 		}
 	}
-
-	// ── Deduct from new bidder's wallet (soft-block) ───────────────────────
-	_, err = tx.Exec(ctx, `
-		UPDATE users SET wallet_balance = wallet_balance - $1 WHERE id = $2`,
-		req.Amount, userID,
-	)
-	if err != nil {
-		http.Error(w, "database error", http.StatusInternalServerError)
+	if minBid := h.minNextBid(st.HighBid, st.MinIncrement); req.Amount < minBid {
+		// Carry the figures so the client can offer a one-tap re-bid.
+		writeErrorDetails(w, http.StatusConflict, "bid_too_low", "bid must be at least the current highest bid plus the minimum increment",
+			map[string]any{"current_highest_bid": st.HighBid, "min_next_bid": minBid})
 		return
 	}
 
-	// Record BID_HOLD transaction
-	_, err = tx.Exec(ctx, `
-		INSERT INTO transactions (user_id, amount, type, status, reference)
-		VALUES ($1, $2, 'BID_HOLD', 'COMPLETED', $3)`,
-		userID, req.Amount, auctionID,
-	)
-	if err != nil {
-		http.Error(w, "database error", http.StatusInternalServerError)
+	// ── Apply the bid (wallet, holds, exposure cap, auction, history) ─────
+	placed, err := h.applyBid(ctx, tx, st, userID, req.Amount)
+	if err == pgx.ErrNoRows {
+		writeError(w, http.StatusNotFound, "user_not_found", "user not found")
+		return
+	}
+	if errors.Is(err, errInsufficientFunds) {
+		writeError(w, http.StatusPaymentRequired, "insufficient_balance", "insufficient wallet balance")
+		return
+	}
+	var ee *exposureError
+	if errors.As(err, &ee) {
+		writeExposureExceeded(w, ee.exposure, ee.limit)
 		return
 	}
-
-	// Insert new SOFT bid_hold row for the winner
-	_, err = tx.Exec(ctx, `
-		INSERT INTO bid_holds (auction_id, user_id, amount, status)
-		VALUES ($1, $2, $3, 'SOFT')`,
-		auctionID, userID, req.Amount,
-	)
 	if err != nil {
-		http.Error(w, "database error", http.StatusInternalServerError)
+		writeAuctionError(w, err)
 		return
 	}
 
-	// ── Update auction ─────────────────────────────────────────────────────
-	_, err = tx.Exec(ctx, `
-		UPDATE auctions
-		SET current_highest_bid = $1, highest_bidder_id = $2
-		WHERE id = $3`,
-		req.Amount, userID, auctionID,
-	)
+	// ── Standing auto-bids respond ─────────────────────────────────────────
+	autoPlaced, err := h.resolveAutoBids(ctx, tx, st)
 	if err != nil {
-		http.Error(w, "database error", http.StatusInternalServerError)
+		writeAuctionError(w, err)
 		return
 	}
 
-	// ── Record the raw bid (for history) ───────────────────────────────────
-	_, err = tx.Exec(ctx, `
-		INSERT INTO bids (auction_id, user_id, amount) VALUES ($1, $2, $3)`,
-		auctionID, userID, req.Amount,
-	)
+	// ── Anti-sniping: late bids push end_time out ──────────────────────────
+	extended, err := h.extendIfSniped(ctx, tx, st, placed.PlacedAt)
 	if err != nil {
-		http.Error(w, "database error", http.StatusInternalServerError)
+		writeAuctionError(w, err)
 		return
 	}
 
 	// ── Commit ────────────────────────────────────────────────────────────
 	if err = tx.Commit(ctx); err != nil {
-		http.Error(w, "commit failed", http.StatusInternalServerError)
+		writeError(w, http.StatusInternalServerError, "database_error", "commit failed")
 		return
 	}
 
 	// ── Push WebSocket events (after commit) ─────────────────────────────
-	bidPayloadBytes, _ := json.Marshal(BidPayload{
-		AuctionID: auctionID,
-		Amount:    req.Amount,
-		BidderID:  userID,
-		Timestamp: time.Now().UTC().Format(time.RFC3339),
-	})
-	h.Hub.BroadcastToAuction(auctionID, hub.Message{
-		Type:    hub.TypeBroadcastNewBid,
-		Payload: json.RawMessage(bidPayloadBytes),
-	})
-
-	if prevHighBidderID != nil && *prevHighBidderID != userID {
-		outbidBytes, _ := json.Marshal(OutbidPayload{
-			AuctionID:  auctionID,
-			YourBid:    currentHighBid,
-			NewHighBid: req.Amount,
-			NewBidder:  userID,
-		})
-		h.Hub.SendToUser(*prevHighBidderID, hub.Message{
-			Type:    hub.TypeOutbidAlert,
-			Payload: json.RawMessage(outbidBytes),
-		})
+	h.broadcastBid(placed)
+	for _, pb := range autoPlaced {
+		h.broadcastBid(pb)
+	}
+	if extended {
+		h.broadcastAuctionExtended(st)
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -258,17 +186,19 @@ func (h *AuctionHandler) PlaceBid(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(map[string]interface{}{
 		"success":      true,
 		"auction_id":   auctionID,
-		"new_high_bid": req.Amount,
+		"your_bid":     req.Amount,
+		"new_high_bid": st.HighBid,
+		"is_winning":   st.HighBidderID != nil && *st.HighBidderID == userID,
+		"end_time":     st.EndTime.UTC().Format(time.RFC3339),
 	})
 }
 
 // ─────────────────────────────────────────────────────────────────────────────
 // GetAuction  GET /api/auctions/{id}
 //
-// Also lazily transitions an expired ACTIVE auction to ENDED:
-//   - Winner's SOFT hold → HARD
-//   - All other SOFT holds for this auction → RELEASED + wallet credited
-//   - Creates a settlements row (PENDING)
+// Also lazily transitions an expired ACTIVE auction (see finishAuction).
+// The reserve price itself is never exposed, only whether one exists and
+// whether the current high bid meets it.
 //
 // ─────────────────────────────────────────────────────────────────────────────
 func (h *AuctionHandler) GetAuction(w http.ResponseWriter, r *http.Request) {
@@ -276,14 +206,20 @@ func (h *AuctionHandler) GetAuction(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
 	// Attempt lazy end transition (best-effort, separate transaction)
-	_ = endAuctionIfExpired(ctx, auctionID)
+	if out, _ := h.endAuctionIfExpired(ctx, auctionID); out != nil {
+		h.broadcastAuctionEnded(*out)
+	}
 
 	row := db.Pool.QueryRow(ctx, `
 		SELECT a.id, a.product_id, p.title, p.description, p.image_url,
 		       p.seller_id, u.name AS seller_name,
 		       a.start_price, a.current_highest_bid, a.highest_bidder_id,
-		       a.end_time, a.status,
-		       s.winner_approved_at, s.seller_approved_at, s.status
+		       a.created_at, a.end_time, a.status,
+		       a.buy_now_price, a.anti_snipe,
+		       a.reserve_price IS NOT NULL,
+		       a.reserve_price IS NULL OR a.current_highest_bid >= a.reserve_price,
+		       s.winner_approved_at, s.seller_approved_at, s.status, s.expires_at,
+		       COALESCE(p.category, '')
 		FROM auctions a
 		JOIN products p ON p.id = a.product_id
 		JOIN users u ON u.id = p.seller_id
@@ -293,42 +229,56 @@ func (h *AuctionHandler) GetAuction(w http.ResponseWriter, r *http.Request) {
 	)
 
 	var result struct {
-		ID               string  `json:"id"`
-		ProductID        string  `json:"product_id"`
-		Title            string  `json:"title"`
-		Description      string  `json:"description"`
-		ImageURL         *string `json:"image_url"`
-		SellerID         string  `json:"seller_id"`
-		SellerName       string  `json:"seller_name"`
-		StartPrice       float64 `json:"start_price"`
-		CurrentHighBid   float64 `json:"current_highest_bid"`
-		HighestBidderID  *string `json:"highest_bidder_id"`
-		EndTime          string  `json:"end_time"`
-		Status           string  `json:"status"`
-		WinnerApprovedAt *string `json:"winner_approved_at"`
-		SellerApprovedAt *string `json:"seller_approved_at"`
-		SettlementStatus *string `json:"settlement_status"`
-	}
-
-	var endTime time.Time
+		ID                  string    `json:"id"`
+		ProductID           string    `json:"product_id"`
+		Title               string    `json:"title"`
+		Description         string    `json:"description"`
+		ImageURL            *string   `json:"image_url"`
+		SellerID            string    `json:"seller_id"`
+		SellerName          string    `json:"seller_name"`
+		StartPrice          float64   `json:"start_price"`
+		CurrentHighBid      float64   `json:"current_highest_bid"`
+		HighestBidderID     *string   `json:"highest_bidder_id"`
+		CreatedAt           string    `json:"created_at"`
+		StartTime           string    `json:"start_time"` // bidding opens; the same as created_at until auctions can be scheduled
+		EndTime             string    `json:"end_time"`
+		Status              string    `json:"status"`
+		BuyNowPrice         *float64  `json:"buy_now_price"`
+		AntiSnipe           bool      `json:"anti_snipe"`
+		HasReserve          bool      `json:"has_reserve"`
+		ReserveMet          bool      `json:"reserve_met"`
+		WinnerApprovedAt    *string   `json:"winner_approved_at"`
+		SellerApprovedAt    *string   `json:"seller_approved_at"`
+		SettlementStatus    *string   `json:"settlement_status"`
+		SettlementExpiresAt *string   `json:"settlement_expires_at"` // approval deadline while PENDING
+		MinNextBid          float64   `json:"min_next_bid"`
+		BidLadder           []float64 `json:"bid_ladder"` // quick-bid amounts; empty unless ACTIVE
+	}
+
+	var createdAt, endTime time.Time
 	var winnerApprovedAt, sellerApprovedAt *time.Time
 	var settlementStatus *string
+	var settlementExpiresAt *time.Time
+	var category string
 
 	err := row.Scan(
 		&result.ID, &result.ProductID, &result.Title, &result.Description,
 		&result.ImageURL, &result.SellerID, &result.SellerName,
 		&result.StartPrice, &result.CurrentHighBid,
-		&result.HighestBidderID, &endTime, &result.Status,
-		&winnerApprovedAt, &sellerApprovedAt, &settlementStatus,
+		&result.HighestBidderID, &createdAt, &endTime, &result.Status,
+		&result.BuyNowPrice, &result.AntiSnipe, &result.HasReserve, &result.ReserveMet,
+		&winnerApprovedAt, &sellerApprovedAt, &settlementStatus, &settlementExpiresAt, &category,
 	)
 	if err == pgx.ErrNoRows {
-		http.Error(w, "auction not found", http.StatusNotFound)
+		writeError(w, http.StatusNotFound, "auction_not_found", "auction not found")
 		return
 	}
 	if err != nil {
-		http.Error(w, "database error", http.StatusInternalServerError)
+		writeError(w, http.StatusInternalServerError, "database_error", "database error")
 		return
 	}
+	result.CreatedAt = createdAt.UTC().Format(time.RFC3339)
+	result.StartTime = result.CreatedAt
 	result.EndTime = endTime.UTC().Format(time.RFC3339)
 	if winnerApprovedAt != nil {
 		s := winnerApprovedAt.UTC().Format(time.RFC3339)
@@ -339,165 +289,350 @@ func (h *AuctionHandler) GetAuction(w http.ResponseWriter, r *http.Request) {
 		result.SellerApprovedAt = &s
 	}
 	result.SettlementStatus = settlementStatus
+	if settlementExpiresAt != nil {
+		s := settlementExpiresAt.UTC().Format(time.RFC3339)
+		result.SettlementExpiresAt = &s
+	}
+
+	rules, err := h.loadCategoryRules(ctx, db.Pool, category)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "database_error", "database error")
+		return
+	}
+	result.MinNextBid = h.minNextBid(result.CurrentHighBid, rules.MinIncrement)
+	result.BidLadder = []float64{}
+	if result.Status == "ACTIVE" {
+		result.BidLadder = h.bidLadder(result.CurrentHighBid, rules.MinIncrement)
+	}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(result)
 }
 
+// auctionOutcome describes an auction that has just been closed.
+type auctionOutcome struct {
+	AuctionID         string
+	Status            string
+	WinnerID          *string
+	FinalPrice        float64
+	RelistedAuctionID *string
+	Notifications     []userNotification // stored for the winner and seller
+}
+
 // endAuctionIfExpired is called lazily when an auction page is fetched.
-// It serialises the end-transition inside a DB transaction.
-func endAuctionIfExpired(ctx context.Context, auctionID string) error {
+// It serialises the end-transition inside a DB transaction and returns the
+// outcome, or nil if the auction wasn't due to end.
+func (h *Handler) endAuctionIfExpired(ctx context.Context, auctionID string) (*auctionOutcome, error) {
 	tx, err := db.Pool.Begin(ctx)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	defer tx.Rollback(ctx)
 
+	out, err := h.finishAuction(ctx, tx, auctionID)
+	if err != nil || out == nil {
+		return nil, err
+	}
+	if err = tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// finishAuction transitions an expired ACTIVE auction to ENDED inside tx:
+//   - Winner's latest SOFT hold → HARD
+//   - All other SOFT holds → RELEASED + wallet credited
+//   - Creates a settlements row (PENDING)
+//
+// If the high bid is below the reserve price, nobody wins: every hold is
+// released and the auction is marked ENDED_NO_SALE instead. When there is no
+// sale and the seller enabled auto-relist, a fresh auction with the same terms
+// is opened in the same transaction.
+//
+// It returns nil if the auction is not ACTIVE or hasn't reached end_time.
+func (h *Handler) finishAuction(ctx context.Context, tx pgx.Tx, auctionID string) (*auctionOutcome, error) {
 	var (
 		status          string
 		endTime         time.Time
 		highestBid      float64
 		highestBidderID *string
 		sellerID        string
+		reservePrice    *float64
+		version         int
+		category        string
 	)
-	err = tx.QueryRow(ctx, `
+	err := tx.QueryRow(ctx, `
 		SELECT a.status, a.end_time, a.current_highest_bid, a.highest_bidder_id,
-		       p.seller_id
+		       p.seller_id, a.reserve_price, a.version, COALESCE(p.category, '')
 		FROM auctions a
 		JOIN products p ON p.id = a.product_id
 		WHERE a.id = $1
 		FOR UPDATE`, auctionID,
-	).Scan(&status, &endTime, &highestBid, &highestBidderID, &sellerID)
+	).Scan(&status, &endTime, &highestBid, &highestBidderID, &sellerID, &reservePrice, &version, &category)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	// Only transition ACTIVE auctions whose time has elapsed
 	if status != "ACTIVE" || !time.Now().After(endTime) {
-		return nil
+		return nil, nil
 	}
 
-	// Mark auction ENDED
-	_, err = tx.Exec(ctx, `UPDATE auctions SET status = 'ENDED' WHERE id = $1`, auctionID)
-	if err != nil {
-		return err
+	out := &auctionOutcome{
+		AuctionID:  auctionID,
+		Status:     "ENDED",
+		WinnerID:   highestBidderID,
+		FinalPrice: highestBid,
+	}
+	if highestBidderID != nil && reservePrice != nil && highestBid < *reservePrice {
+		out.Status = "ENDED_NO_SALE"
+		out.WinnerID = nil
+	}
+
+	// Mark auction ENDED (or ENDED_NO_SALE), provided nothing else has
+	// touched it since it was read.
+	st := &auctionState{ID: auctionID, Version: version}
+	if err = updateAuctionVersioned(ctx, tx, st, `status = $3`, out.Status); err != nil {
+		return nil, err
 	}
 
-	if highestBidderID != nil {
-		// Winner's SOFT hold → HARD
+	if out.WinnerID != nil {
+		// Winner's latest SOFT hold → HARD
 		_, err = tx.Exec(ctx, `
 			UPDATE bid_holds SET status = 'HARD', updated_at = NOW()
-			WHERE auction_id = $1 AND user_id = $2 AND status = 'SOFT'`,
+			WHERE id = (
+				SELECT id FROM bid_holds
+				WHERE auction_id = $1 AND user_id = $2 AND status = 'SOFT'
+				ORDER BY created_at DESC
+				LIMIT 1
+			)`,
 			auctionID, *highestBidderID,
 		)
 		if err != nil {
-			return err
+			return nil, err
 		}
+	}
 
-		// Refund all other SOFT holds for this auction
-		rows, err := tx.Query(ctx, `
-			SELECT id, user_id, amount FROM bid_holds
-			WHERE auction_id = $1 AND status = 'SOFT' AND user_id != $2`,
-			auctionID, *highestBidderID,
-		)
+	// Refund every remaining SOFT hold — the losers' holds that AT_END kept
+	// in place (or all holds when the reserve wasn't met).
+	if err = releaseSoftHolds(ctx, tx, auctionID); err != nil {
+		return nil, err
+	}
+
+	if out.WinnerID == nil {
+		out.RelistedAuctionID, err = relistAuction(ctx, tx, auctionID)
 		if err != nil {
-			return err
+			return nil, err
 		}
-		type holdRow struct {
-			id     string
-			userID string
-			amount float64
+	}
+
+	if out.WinnerID != nil {
+		rules, err := h.loadCategoryRules(ctx, tx, category)
+		if err != nil {
+			return nil, err
 		}
-		var others []holdRow
-		for rows.Next() {
-			var h holdRow
-			_ = rows.Scan(&h.id, &h.userID, &h.amount)
-			others = append(others, h)
+		// Create settlement record (idempotent via ON CONFLICT DO NOTHING)
+		_, err = tx.Exec(ctx, `
+			INSERT INTO settlements (auction_id, winner_id, seller_id, amount, expires_at)
+			VALUES ($1, $2, $3, $4, $5)
+			ON CONFLICT (auction_id) DO NOTHING`,
+			auctionID, *highestBidderID, sellerID, highestBid, time.Now().Add(rules.SettlementWindow),
+		)
+		if err != nil {
+			return nil, err
 		}
-		rows.Close()
 
-		for _, h := range others {
-			_, err = tx.Exec(ctx, `
-				UPDATE bid_holds SET status = 'RELEASED', updated_at = NOW() WHERE id = $1`, h.id)
-			if err != nil {
-				return err
-			}
-			_, err = tx.Exec(ctx, `
-				UPDATE users SET wallet_balance = wallet_balance + $1 WHERE id = $2`,
-				h.amount, h.userID)
+		payload := map[string]any{"auction_id": auctionID, "final_price": highestBid}
+		for userID, typ := range map[string]string{*highestBidderID: notifyAuctionWon, sellerID: notifyAuctionSold} {
+			n, err := createNotification(ctx, tx, userID, typ, payload)
 			if err != nil {
-				return err
-			}
-			_, err = tx.Exec(ctx, `
-				INSERT INTO transactions (user_id, amount, type, status, reference)
-				VALUES ($1, $2, 'REFUND', 'COMPLETED', $3)`,
-				h.userID, h.amount, auctionID)
-			if err != nil {
-				return err
+				return nil, err
 			}
+			out.Notifications = append(out.Notifications, userNotification{UserID: userID, Notification: n})
 		}
+	}
 
-		// Create settlement record (idempotent via ON CONFLICT DO NOTHING)
+	return out, nil
+}
+
+// relistAuction opens a new auction for the same product with the same terms
+// and original duration, if the ended auction has auto-relist enabled and
+// relists remaining. Returns the new auction's ID, or nil if none was created.
+func relistAuction(ctx context.Context, tx pgx.Tx, auctionID string) (*string, error) {
+	var newID string
+	err := tx.QueryRow(ctx, `
+		INSERT INTO auctions (product_id, start_price, current_highest_bid, end_time, status,
+		                      refund_policy, reserve_price, auto_relist, relists_remaining,
+		                      buy_now_price, anti_snipe, min_bid_interval)
+		SELECT product_id, start_price, 0, NOW() + (end_time - created_at), 'ACTIVE',
+		       refund_policy, reserve_price, TRUE, relists_remaining - 1,
+		       buy_now_price, anti_snipe, min_bid_interval
+		FROM auctions
+		WHERE id = $1 AND auto_relist AND relists_remaining > 0
+		RETURNING id`, auctionID,
+	).Scan(&newID)
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &newID, nil
+}
+
+// releaseSoftHolds releases every SOFT hold on an auction, crediting each
+// holder's wallet and recording a REFUND transaction.
+func releaseSoftHolds(ctx context.Context, tx pgx.Tx, auctionID string) error {
+	rows, err := tx.Query(ctx, `
+		SELECT id, user_id, amount FROM bid_holds
+		WHERE auction_id = $1 AND status = 'SOFT'`,
+		auctionID,
+	)
+	if err != nil {
+		return err
+	}
+	type holdRow struct {
+		id     string
+		userID string
+		amount float64
+	}
+	var holds []holdRow
+	for rows.Next() {
+		var h holdRow
+		_ = rows.Scan(&h.id, &h.userID, &h.amount)
+		holds = append(holds, h)
+	}
+	rows.Close()
+
+	for _, h := range holds {
 		_, err = tx.Exec(ctx, `
-			INSERT INTO settlements (auction_id, winner_id, seller_id, amount)
-			VALUES ($1, $2, $3, $4)
-			ON CONFLICT (auction_id) DO NOTHING`,
-			auctionID, *highestBidderID, sellerID, highestBid,
-		)
+			UPDATE bid_holds SET status = 'RELEASED', updated_at = NOW() WHERE id = $1`, h.id)
+		if err != nil {
+			return err
+		}
+		_, err = tx.Exec(ctx, `
+			UPDATE users SET wallet_balance = wallet_balance + $1 WHERE id = $2`,
+			h.amount, h.userID)
+		if err != nil {
+			return err
+		}
+		_, err = tx.Exec(ctx, `
+			INSERT INTO transactions (user_id, amount, type, status, reference)
+			VALUES ($1, $2, 'REFUND', 'COMPLETED', $3)`,
+			h.userID, h.amount, auctionID)
 		if err != nil {
 			return err
 		}
 	}
+	return nil
+}
+
+// errEndTimeLocked is returned when an edit tries to move an auction's end_time
+// after bidding has started.
+var errEndTimeLocked = errors.New("end_time cannot be changed once bids exist")
 
-	return tx.Commit(ctx)
+// ensureEndTimeEditable guards the seller edit path: once any bid exists on the
+// auction, end_time is frozen so a seller can neither shorten it to snipe nor
+// extend it to fish for higher bids. Only the bidding engine itself may move
+// end_time after that point. The caller must hold the auction row lock.
+func ensureEndTimeEditable(ctx context.Context, tx pgx.Tx, auctionID string, newEndTime time.Time) error {
+	var (
+		endTime time.Time
+		hasBids bool
+	)
+	err := tx.QueryRow(ctx, `
+		SELECT a.end_time, EXISTS (SELECT 1 FROM bids b WHERE b.auction_id = a.id)
+		FROM auctions a
+		WHERE a.id = $1`, auctionID,
+	).Scan(&endTime, &hasBids)
+	if err != nil {
+		return err
+	}
+	if hasBids && !newEndTime.Equal(endTime) {
+		return errEndTimeLocked
+	}
+	return nil
 }
 
+const (
+	defaultBidHistoryPageSize = 20
+	maxBidHistoryPageSize     = 100
+)
+
 // ─────────────────────────────────────────────────────────────────────────────
-// GetAuctionBids  GET /api/auctions/{id}/bids
-// Returns the last 20 bids for an auction with masked bidder names.
+// GetAuctionBids  GET /api/auctions/{id}/bids?before=&limit=
+//
+// Returns an auction's bids, newest first, with masked bidder names. limit
+// defaults to 20 (max 100); pass the id of the last bid as before to fetch
+// the next page. A signed-in caller sees their own bids unmasked, flagged
+// with is_you.
 // ─────────────────────────────────────────────────────────────────────────────
 func (h *AuctionHandler) GetAuctionBids(w http.ResponseWriter, r *http.Request) {
 	auctionID := chi.URLParam(r, "id")
 	ctx := r.Context()
+	callerID, _ := authmw.UserIDFromContext(ctx)
+	qs := r.URL.Query()
+
+	limit := defaultBidHistoryPageSize
+	if v, err := strconv.Atoi(qs.Get("limit")); err == nil && v > 0 {
+		limit = v
+	}
+	if limit > maxBidHistoryPageSize {
+		limit = maxBidHistoryPageSize
+	}
+	var before interface{}
+	if v := qs.Get("before"); v != "" {
+		if _, err := uuid.Parse(v); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid_before", "before must be a bid id")
+			return
+		}
+		before = v
+	}
 
 	rows, err := db.Pool.Query(ctx, `
-		SELECT b.amount, b.created_at, u.name
+		SELECT b.id, b.user_id, b.amount, b.created_at, u.name
 		FROM bids b
 		JOIN users u ON u.id = b.user_id
 		WHERE b.auction_id = $1
-		ORDER BY b.created_at DESC
-		LIMIT 20`,
-		auctionID,
+		  AND ($2::uuid IS NULL OR (b.created_at, b.id) < (
+		      SELECT created_at, id FROM bids WHERE id = $2::uuid))
+		ORDER BY b.created_at DESC, b.id DESC
+		LIMIT $3::int`,
+		auctionID, before, limit,
 	)
 	if err != nil {
-		http.Error(w, "database error", http.StatusInternalServerError)
+		writeError(w, http.StatusInternalServerError, "database_error", "database error")
 		return
 	}
 	defer rows.Close()
 
 	type BidHistory struct {
+		ID        string  `json:"id"`
 		Amount    float64 `json:"amount"`
 		PlacedAt  string  `json:"placed_at"`
 		BidderTag string  `json:"bidder_tag"`
+		IsYou     bool    `json:"is_you"`
 	}
 
 	var bids []BidHistory
 	for rows.Next() {
+		var id, bidderID, name string
 		var amount float64
 		var placedAt time.Time
-		var name string
-		if err := rows.Scan(&amount, &placedAt, &name); err != nil {
+		if err := rows.Scan(&id, &bidderID, &amount, &placedAt, &name); err != nil {
 			continue
 		}
-		// Mask name: keep first 4 chars then ***
-		tag := name
-		if len(name) > 4 {
-			tag = name[:4] + "***"
+		isYou := callerID != "" && bidderID == callerID
+		tag := maskName(name)
+		if isYou {
+			tag = name
 		}
 		bids = append(bids, BidHistory{
+			ID:        id,
 			Amount:    amount,
 			PlacedAt:  placedAt.UTC().Format(time.RFC3339),
 			BidderTag: tag,
+			IsYou:     isYou,
 		})
 	}
 	if bids == nil {
@@ -508,155 +643,189 @@ func (h *AuctionHandler) GetAuctionBids(w http.ResponseWriter, r *http.Request)
 	json.NewEncoder(w).Encode(bids)
 }
 
+// maskName keeps the first 4 characters of a user's name and hides the rest.
+func maskName(name string) string {
+	if len(name) > 4 {
+		return name[:4] + "***"
+	}
+	return name
+}
+
 // ─────────────────────────────────────────────────────────────────────────────
-// ApproveSettlement  POST /api/auctions/{id}/settle
+// GetMyPosition  GET /api/auctions/{id}/my-position
 //
-// The authenticated caller (winner or seller) records their approval.
-// When both have approved, the hard-blocked amount is transferred to the seller.
+// Returns the caller's participant-specific state on an auction: funds held,
+// latest bid, auto-bid ceiling, whether they lead, and how much they must
+// raise to retake it.
 // ─────────────────────────────────────────────────────────────────────────────
-func (h *AuctionHandler) ApproveSettlement(w http.ResponseWriter, r *http.Request) {
+func (h *AuctionHandler) GetMyPosition(w http.ResponseWriter, r *http.Request) {
 	auctionID := chi.URLParam(r, "id")
 	callerID, ok := authmw.UserIDFromContext(r.Context())
 	if !ok {
-		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		writeError(w, http.StatusUnauthorized, "unauthorized", "unauthorized")
 		return
 	}
-
-	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
 	defer cancel()
 
-	tx, err := db.Pool.Begin(ctx)
-	if err != nil {
-		http.Error(w, "database error", http.StatusInternalServerError)
-		return
-	}
-	defer tx.Rollback(ctx)
-
-	// Lock settlement row
-	var (
-		settlementID     string
-		winnerID         string
-		sellerID         string
-		amount           float64
-		winnerApprovedAt *time.Time
-		sellerApprovedAt *time.Time
-		settlementStatus string
-	)
-	err = tx.QueryRow(ctx, `
-		SELECT id, winner_id, seller_id, amount,
-		       winner_approved_at, seller_approved_at, status
-		FROM settlements
-		WHERE auction_id = $1
-		FOR UPDATE`, auctionID,
-	).Scan(&settlementID, &winnerID, &sellerID, &amount,
-		&winnerApprovedAt, &sellerApprovedAt, &settlementStatus)
+	st, err := h.loadCallerStanding(ctx, auctionID, callerID)
 	if err == pgx.ErrNoRows {
-		http.Error(w, "settlement not found — auction may still be active", http.StatusNotFound)
+		writeError(w, http.StatusNotFound, "auction_not_found", "auction not found")
 		return
 	}
 	if err != nil {
-		http.Error(w, "database error", http.StatusInternalServerError)
+		writeError(w, http.StatusInternalServerError, "database_error", "database error")
 		return
 	}
-	if settlementStatus == "COMPLETED" {
-		http.Error(w, "settlement already completed", http.StatusConflict)
+
+	var holdAmount float64
+	err = db.Pool.QueryRow(ctx, `
+		SELECT COALESCE(SUM(amount), 0) FROM bid_holds
+		WHERE auction_id = $1 AND user_id = $2 AND status IN ('SOFT', 'HARD')`,
+		auctionID, callerID,
+	).Scan(&holdAmount)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "database_error", "database error")
 		return
 	}
 
-	// Record the caller's approval
-	switch callerID {
-	case winnerID:
-		if winnerApprovedAt != nil {
-			http.Error(w, "you have already approved", http.StatusConflict)
-			return
-		}
-		now := time.Now()
-		winnerApprovedAt = &now
-		_, err = tx.Exec(ctx, `
-			UPDATE settlements SET winner_approved_at = NOW() WHERE id = $1`, settlementID)
-	case sellerID:
-		if sellerApprovedAt != nil {
-			http.Error(w, "you have already approved", http.StatusConflict)
-			return
-		}
-		now := time.Now()
-		sellerApprovedAt = &now
-		_, err = tx.Exec(ctx, `
-			UPDATE settlements SET seller_approved_at = NOW() WHERE id = $1`, settlementID)
-	default:
-		http.Error(w, "you are not a party to this settlement", http.StatusForbidden)
+	var (
+		latestBid   *float64
+		latestBidAt *time.Time
+	)
+	err = db.Pool.QueryRow(ctx, `
+		SELECT amount, created_at FROM bids
+		WHERE auction_id = $1 AND user_id = $2
+		ORDER BY created_at DESC
+		LIMIT 1`,
+		auctionID, callerID,
+	).Scan(&latestBid, &latestBidAt)
+	if err != nil && err != pgx.ErrNoRows {
+		writeError(w, http.StatusInternalServerError, "database_error", "database error")
 		return
 	}
-	if err != nil {
-		http.Error(w, "database error", http.StatusInternalServerError)
+
+	var maxProxyBid *float64
+	err = db.Pool.QueryRow(ctx, `
+		SELECT max_amount FROM auto_bids
+		WHERE auction_id = $1 AND user_id = $2`,
+		auctionID, callerID,
+	).Scan(&maxProxyBid)
+	if err != nil && err != pgx.ErrNoRows {
+		writeError(w, http.StatusInternalServerError, "database_error", "database error")
 		return
 	}
 
-	// If both parties approved, execute the transfer
-	bothApproved := winnerApprovedAt != nil && sellerApprovedAt != nil
-	if bothApproved {
-		// Mark settlement COMPLETED
-		_, err = tx.Exec(ctx, `
-			UPDATE settlements SET status = 'COMPLETED' WHERE id = $1`, settlementID)
-		if err != nil {
-			http.Error(w, "database error", http.StatusInternalServerError)
-			return
+	var result struct {
+		AuctionID      string   `json:"auction_id"`
+		HoldAmount     float64  `json:"hold_amount"`
+		LatestBid      *float64 `json:"latest_bid"`
+		LatestBidAt    *string  `json:"latest_bid_at"`
+		MaxProxyBid    *float64 `json:"max_proxy_bid"`
+		IsWinning      bool     `json:"is_winning"`
+		CurrentHighBid float64  `json:"current_highest_bid"`
+		MinNextBid     float64  `json:"min_next_bid"`
+		ToRetake       float64  `json:"to_retake"`
+	}
+	result.AuctionID = auctionID
+	result.HoldAmount = holdAmount
+	result.LatestBid = latestBid
+	result.MaxProxyBid = maxProxyBid
+	if latestBidAt != nil {
+		s := latestBidAt.UTC().Format(time.RFC3339)
+		result.LatestBidAt = &s
+	}
+	result.IsWinning = st.IsLeader
+	result.CurrentHighBid = st.CurrentHighBid
+	result.MinNextBid = st.MinNextBid
+	if !result.IsWinning {
+		// How far the caller must raise over their own latest bid.
+		result.ToRetake = result.MinNextBid
+		if latestBid != nil {
+			result.ToRetake = h.roundMoney(result.MinNextBid - *latestBid)
 		}
+	}
 
-		// Mark the winner's HARD hold as SETTLED
-		_, err = tx.Exec(ctx, `
-			UPDATE bid_holds SET status = 'SETTLED', updated_at = NOW()
-			WHERE auction_id = $1 AND user_id = $2 AND status = 'HARD'`,
-			auctionID, winnerID,
-		)
-		if err != nil {
-			http.Error(w, "database error", http.StatusInternalServerError)
-			return
-		}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
 
-		// Credit the seller's wallet
-		_, err = tx.Exec(ctx, `
-			UPDATE users SET wallet_balance = wallet_balance + $1 WHERE id = $2`,
-			amount, sellerID,
-		)
-		if err != nil {
-			http.Error(w, "database error", http.StatusInternalServerError)
-			return
-		}
+// minNextBid returns the lowest amount PlaceBid will accept over currentHighBid
+// given the category's minimum increment.
+func (h *Handler) minNextBid(currentHighBid, increment float64) float64 {
+	return h.roundMoney(currentHighBid + increment)
+}
 
-		// Record TRANSFER transactions for both parties
-		_, err = tx.Exec(ctx, `
-			INSERT INTO transactions (user_id, amount, type, status, reference)
-			VALUES ($1, $2, 'TRANSFER', 'COMPLETED', $3)`,
-			winnerID, amount, auctionID)
-		if err != nil {
-			http.Error(w, "database error", http.StatusInternalServerError)
-			return
+// bidLadderTiers are the quick-bid raises, in rupees, offered while the high
+// bid is below each tier's bound. They scale with the price so the buttons
+// stay meaningful whatever the category's minimum increment is.
+var bidLadderTiers = []struct {
+	below float64
+	steps []float64
+}{
+	{100, []float64{1, 5, 10}},
+	{1000, []float64{10, 50, 100}},
+	{10000, []float64{50, 100, 500}},
+	{100000, []float64{500, 1000, 5000}},
+	{math.Inf(1), []float64{1000, 5000, 10000}},
+}
+
+// bidLadder suggests next-bid amounts over currentHighBid for quick-bid
+// buttons. The first rung is always minNextBid, followed by the tier's raises
+// that exceed it, so every rung is a bid PlaceBid will accept.
+func (h *Handler) bidLadder(currentHighBid, increment float64) []float64 {
+	ladder := []float64{h.minNextBid(currentHighBid, increment)}
+	for _, tier := range bidLadderTiers {
+		if currentHighBid >= tier.below {
+			continue
 		}
-		_, err = tx.Exec(ctx, `
-			INSERT INTO transactions (user_id, amount, type, status, reference)
-			VALUES ($1, $2, 'TRANSFER', 'COMPLETED', $3)`,
-			sellerID, amount, auctionID)
-		if err != nil {
-			http.Error(w, "database error", http.StatusInternalServerError)
-			return
+		for _, step := range tier.steps {
+			if v := h.roundMoney(currentHighBid + step); v > ladder[len(ladder)-1] {
+				ladder = append(ladder, v)
+			}
 		}
+		break
 	}
+	return ladder
+}
 
-	if err = tx.Commit(ctx); err != nil {
-		http.Error(w, "commit failed", http.StatusInternalServerError)
+// ─────────────────────────────────────────────────────────────────────────────
+// ApproveSettlement  POST /api/auctions/{id}/settle
+//
+// The authenticated caller (winner or seller) records their approval.
+// When both have approved, the hard-blocked amount is transferred to the seller.
+// ─────────────────────────────────────────────────────────────────────────────
+func (h *AuctionHandler) ApproveSettlement(w http.ResponseWriter, r *http.Request) {
+	auctionID := chi.URLParam(r, "id")
+	callerID, ok := authmw.UserIDFromContext(r.Context())
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "unauthorized", "unauthorized")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	res, err := approveSettlement(ctx, auctionID, callerID)
+	if err != nil {
+		status, code := settlementErrorStatus(err)
+		msg := err.Error()
+		if status == http.StatusInternalServerError {
+			msg = "database error"
+		}
+		writeError(w, status, code, msg)
 		return
 	}
+	if res.Status == "COMPLETED" {
+		h.notifySettlementCompletedOffsite(auctionID)
+	}
 
 	resp := map[string]interface{}{
 		"success":           true,
-		"both_approved":     bothApproved,
-		"winner_approved":   winnerApprovedAt != nil,
-		"seller_approved":   sellerApprovedAt != nil,
-		"settlement_status": "PENDING",
-	}
-	if bothApproved {
-		resp["settlement_status"] = "COMPLETED"
+		"both_approved":     res.BothApproved,
+		"winner_approved":   res.WinnerApproved,
+		"seller_approved":   res.SellerApproved,
+		"settlement_status": res.Status,
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -0,0 +1,116 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/jackc/pgx/v5"
+	"github.com/karti/orange-city-mart/backend/db"
+	"github.com/karti/orange-city-mart/backend/hub"
+	authmw "github.com/karti/orange-city-mart/backend/middleware"
+)
+
+// AuctionCancelledPayload is broadcast when the seller pulls a live auction.
+type AuctionCancelledPayload struct {
+	AuctionID string `json:"auction_id"`
+	Status    string `json:"status"`
+}
+
+// ─────────────────────────────────────────────────────────────────────────────
+// CancelAuction  POST /api/auctions/{id}/cancel
+//
+// Lets the seller pull an auction while it is still ACTIVE and before its
+// end_time. The auction is
+// marked CANCELLED and every SOFT hold is refunded. Once a settlement exists
+// the sale stands; only an admin can undo it.
+// ─────────────────────────────────────────────────────────────────────────────
+func (h *AuctionHandler) CancelAuction(w http.ResponseWriter, r *http.Request) {
+	userID, ok := authmw.UserIDFromContext(r.Context())
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "unauthorized", "unauthorized")
+		return
+	}
+	auctionID := chi.URLParam(r, "id")
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	tx, err := db.Pool.Begin(ctx)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "database_error", "database error")
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	st, err := h.lockAuction(ctx, tx, auctionID)
+	if err == pgx.ErrNoRows {
+		writeError(w, http.StatusNotFound, "auction_not_found", "auction not found")
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "database_error", "database error")
+		return
+	}
+	if st.SellerID != userID {
+		writeError(w, http.StatusForbidden, "not_seller", "only the seller can cancel this auction")
+		return
+	}
+
+	var settled bool
+	if err = tx.QueryRow(ctx,
+		`SELECT EXISTS (SELECT 1 FROM settlements WHERE auction_id = $1)`, auctionID,
+	).Scan(&settled); err != nil {
+		writeError(w, http.StatusInternalServerError, "database_error", "database error")
+		return
+	}
+	if settled {
+		writeError(w, http.StatusConflict, "settlement_exists", "auction already has a settlement")
+		return
+	}
+	if st.Status != "ACTIVE" {
+		writeError(w, http.StatusConflict, "auction_not_active", "auction is already "+st.Status)
+		return
+	}
+	if time.Now().After(st.EndTime) {
+		// The sweeper just hasn't closed it yet; the result already stands.
+		writeError(w, http.StatusConflict, "auction_ended", "auction has already ended")
+		return
+	}
+
+	if err = updateAuctionVersioned(ctx, tx, st, `status = 'CANCELLED'`); err != nil {
+		writeAuctionError(w, err)
+		return
+	}
+	if err = releaseSoftHolds(ctx, tx, auctionID); err != nil {
+		writeError(w, http.StatusInternalServerError, "database_error", "database error")
+		return
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		writeError(w, http.StatusInternalServerError, "database_error", "commit failed")
+		return
+	}
+
+	h.broadcastAuctionCancelled(auctionID)
+
+	writeJSON(w, http.StatusOK, map[string]string{
+		"auction_id": auctionID,
+		"status":     "CANCELLED",
+	})
+}
+
+// broadcastAuctionCancelled tells the auction room the auction was pulled, by
+// its seller or an admin. Call only after commit.
+func (h *AuctionHandler) broadcastAuctionCancelled(auctionID string) {
+	payload, _ := json.Marshal(AuctionCancelledPayload{
+		AuctionID: auctionID,
+		Status:    "CANCELLED",
+	})
+	h.Hub.BroadcastToAuction(auctionID, hub.Message{
+		Type:    hub.TypeAuctionCancelled,
+		Payload: json.RawMessage(payload),
+	})
+}
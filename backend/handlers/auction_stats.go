@@ -0,0 +1,84 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/jackc/pgx/v5"
+	"github.com/karti/orange-city-mart/backend/db"
+)
+
+// ─────────────────────────────────────────────────────────────────────────────
+// GetAuctionStats  GET /api/auctions/{id}/stats
+//
+// Aggregate bid statistics for the auction page chart: highest, lowest and
+// average bid, distinct bidder count, and bids per hour over the auction's
+// life. Read-only; clients may cache it briefly.
+// ─────────────────────────────────────────────────────────────────────────────
+func (h *AuctionHandler) GetAuctionStats(w http.ResponseWriter, r *http.Request) {
+	auctionID := chi.URLParam(r, "id")
+	ctx := r.Context()
+
+	type HourBucket struct {
+		Hour  string `json:"hour"`
+		Count int    `json:"count"`
+	}
+	var stats struct {
+		AuctionID      string       `json:"auction_id"`
+		TotalBids      int          `json:"total_bids"`
+		DistinctBidder int          `json:"distinct_bidders"`
+		HighestBid     *float64     `json:"highest_bid"`
+		LowestBid      *float64     `json:"lowest_bid"`
+		AverageBid     *float64     `json:"average_bid"`
+		BidsPerHour    []HourBucket `json:"bids_per_hour"`
+	}
+	stats.AuctionID = auctionID
+
+	err := db.Pool.QueryRow(ctx, `
+		SELECT COUNT(b.id), COUNT(DISTINCT b.user_id),
+		       MAX(b.amount), MIN(b.amount), ROUND(AVG(b.amount), 2)
+		FROM auctions a
+		LEFT JOIN bids b ON b.auction_id = a.id
+		WHERE a.id = $1
+		GROUP BY a.id`, auctionID,
+	).Scan(&stats.TotalBids, &stats.DistinctBidder,
+		&stats.HighestBid, &stats.LowestBid, &stats.AverageBid)
+	if err == pgx.ErrNoRows {
+		writeError(w, http.StatusNotFound, "auction_not_found", "auction not found")
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "database_error", "database error")
+		return
+	}
+
+	// Bucket in UTC: date_trunc on a timestamptz follows the session time
+	// zone, which would put buckets on the half hour under IST.
+	rows, err := db.Pool.Query(ctx, `
+		SELECT date_trunc('hour', created_at AT TIME ZONE 'UTC') AS hour, COUNT(*)
+		FROM bids
+		WHERE auction_id = $1
+		GROUP BY hour
+		ORDER BY hour ASC`, auctionID,
+	)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "database_error", "database error")
+		return
+	}
+	defer rows.Close()
+
+	stats.BidsPerHour = []HourBucket{}
+	for rows.Next() {
+		var hour time.Time
+		var b HourBucket
+		if err := rows.Scan(&hour, &b.Count); err != nil {
+			continue
+		}
+		b.Hour = hour.UTC().Format(time.RFC3339)
+		stats.BidsPerHour = append(stats.BidsPerHour, b)
+	}
+
+	w.Header().Set("Cache-Control", "public, max-age=30")
+	writeJSON(w, http.StatusOK, stats)
+}
@@ -0,0 +1,85 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestAuctionStatsAggregatesAndBuckets(t *testing.T) {
+	requireDB(t)
+	ctx := context.Background()
+	seller := newTestUser(t, testPool, 0)
+	alice := newTestUser(t, testPool, 0)
+	bob := newTestUser(t, testPool, 0)
+	t.Cleanup(func() {
+		testPool.Exec(ctx, `DELETE FROM users WHERE id IN ($1, $2, $3)`, seller, alice, bob)
+	})
+	_, auctionID := newTestAuctionListing(t, seller)
+
+	start := time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC)
+	for _, b := range []struct {
+		userID string
+		amount float64
+		at     time.Duration
+	}{
+		{alice, 20, 5 * time.Minute},
+		{bob, 25, 40 * time.Minute},
+		{alice, 30, 59 * time.Minute},
+		{bob, 45, 3*time.Hour + 10*time.Minute},
+	} {
+		if _, err := testPool.Exec(ctx, `
+			INSERT INTO bids (auction_id, user_id, amount, created_at) VALUES ($1, $2, $3, $4)`,
+			auctionID, b.userID, b.amount, start.Add(b.at)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	h := &AuctionHandler{Handler: testHandler}
+	r := withURLParam(httptest.NewRequest(http.MethodGet, "/", nil), "id", auctionID)
+	w := httptest.NewRecorder()
+	h.GetAuctionStats(w, r)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", w.Code, w.Body)
+	}
+	type bucket struct {
+		Hour  string `json:"hour"`
+		Count int    `json:"count"`
+	}
+	var stats struct {
+		TotalBids       int      `json:"total_bids"`
+		DistinctBidders int      `json:"distinct_bidders"`
+		HighestBid      float64  `json:"highest_bid"`
+		LowestBid       float64  `json:"lowest_bid"`
+		AverageBid      float64  `json:"average_bid"`
+		BidsPerHour     []bucket `json:"bids_per_hour"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &stats); err != nil {
+		t.Fatal(err)
+	}
+	if stats.TotalBids != 4 || stats.DistinctBidders != 2 {
+		t.Errorf("total = %d, distinct = %d; want 4, 2", stats.TotalBids, stats.DistinctBidders)
+	}
+	if stats.HighestBid != 45 || stats.LowestBid != 20 || stats.AverageBid != 30 {
+		t.Errorf("high/low/avg = %v/%v/%v, want 45/20/30", stats.HighestBid, stats.LowestBid, stats.AverageBid)
+	}
+	want := []bucket{{"2026-03-01T10:00:00Z", 3}, {"2026-03-01T13:00:00Z", 1}}
+	if !slices.Equal(stats.BidsPerHour, want) {
+		t.Errorf("bids_per_hour = %v, want %v", stats.BidsPerHour, want)
+	}
+}
+
+func TestAuctionStatsUnknownAuction(t *testing.T) {
+	requireDB(t)
+	h := &AuctionHandler{Handler: testHandler}
+	r := withURLParam(httptest.NewRequest(http.MethodGet, "/", nil), "id", "00000000-0000-0000-0000-000000000000")
+	w := httptest.NewRecorder()
+	h.GetAuctionStats(w, r)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("status %d, want 404", w.Code)
+	}
+}
@@ -0,0 +1,122 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/jackc/pgx/v5"
+	"github.com/karti/orange-city-mart/backend/db"
+	authmw "github.com/karti/orange-city-mart/backend/middleware"
+)
+
+// ─────────────────────────────────────────────────────────────────────────────
+// GetMyStatus  GET /api/auctions/{id}/my-status
+//
+// The caller's standing in an auction: their highest bid, whether they lead,
+// their rank among distinct bidders (by each bidder's best bid, earlier
+// first on ties) and, when not leading, the minimum bid that retakes the
+// lead. 404 if the caller has never bid on it.
+// ─────────────────────────────────────────────────────────────────────────────
+func (h *AuctionHandler) GetMyStatus(w http.ResponseWriter, r *http.Request) {
+	auctionID := chi.URLParam(r, "id")
+	callerID, ok := authmw.UserIDFromContext(r.Context())
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "unauthorized", "unauthorized")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	st, err := h.loadCallerStanding(ctx, auctionID, callerID)
+	if err == pgx.ErrNoRows {
+		writeError(w, http.StatusNotFound, "auction_not_found", "auction not found")
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "database_error", "database error")
+		return
+	}
+
+	var (
+		highestBid  float64
+		rank        int
+		bidderCount int
+	)
+	err = db.Pool.QueryRow(ctx, `
+		WITH best AS (
+		    SELECT DISTINCT ON (user_id) user_id, amount, created_at
+		    FROM bids
+		    WHERE auction_id = $1
+		    ORDER BY user_id, amount DESC, created_at ASC
+		), ranked AS (
+		    SELECT user_id, amount::float8 AS amount,
+		           ROW_NUMBER() OVER (ORDER BY amount DESC, created_at ASC) AS rank,
+		           COUNT(*) OVER () AS bidders
+		    FROM best
+		)
+		SELECT amount, rank, bidders FROM ranked WHERE user_id = $2::uuid`,
+		auctionID, callerID,
+	).Scan(&highestBid, &rank, &bidderCount)
+	if err == pgx.ErrNoRows {
+		writeError(w, http.StatusNotFound, "no_bids", "you have not bid on this auction")
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "database_error", "database error")
+		return
+	}
+
+	var minToRetake *float64
+	if !st.IsLeader && st.Status == "ACTIVE" {
+		minToRetake = &st.MinNextBid
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"auction_id":          auctionID,
+		"auction_status":      st.Status,
+		"highest_bid":         highestBid,
+		"is_leader":           st.IsLeader,
+		"rank":                rank,
+		"bidder_count":        bidderCount,
+		"current_highest_bid": st.CurrentHighBid,
+		"min_to_retake":       minToRetake,
+	})
+}
+
+// callerStanding is the auction-wide state GetMyStatus and GetMyPosition
+// both report from the caller's point of view.
+type callerStanding struct {
+	CurrentHighBid float64
+	Status         string
+	IsLeader       bool
+	MinNextBid     float64 // lowest bid PlaceBid accepts now
+}
+
+// loadCallerStanding reads an auction's high bid and status and works out
+// whether callerID leads it. It returns pgx.ErrNoRows for an unknown auction.
+func (h *Handler) loadCallerStanding(ctx context.Context, auctionID, callerID string) (callerStanding, error) {
+	var (
+		st              callerStanding
+		highestBidderID *string
+		category        string
+	)
+	err := db.Pool.QueryRow(ctx, `
+		SELECT a.current_highest_bid, a.highest_bidder_id, a.status, COALESCE(p.category, '')
+		FROM auctions a
+		JOIN products p ON p.id = a.product_id
+		WHERE a.id = $1`, auctionID,
+	).Scan(&st.CurrentHighBid, &highestBidderID, &st.Status, &category)
+	if err != nil {
+		return callerStanding{}, err
+	}
+	rules, err := h.loadCategoryRules(ctx, db.Pool, category)
+	if err != nil {
+		return callerStanding{}, err
+	}
+	st.IsLeader = highestBidderID != nil && *highestBidderID == callerID
+	st.MinNextBid = h.minNextBid(st.CurrentHighBid, rules.MinIncrement)
+	return st, nil
+}
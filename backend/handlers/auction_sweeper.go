@@ -0,0 +1,138 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/karti/orange-city-mart/backend/db"
+	"github.com/karti/orange-city-mart/backend/hub"
+)
+
+// AuctionEndedPayload is broadcast to the auction room when an auction closes.
+type AuctionEndedPayload struct {
+	AuctionID         string  `json:"auction_id"`
+	Status            string  `json:"status"`
+	WinnerID          *string `json:"winner_id"`
+	FinalPrice        float64 `json:"final_price"`
+	RelistedAuctionID *string `json:"relisted_auction_id,omitempty"`
+}
+
+// RunAuctionSweeper closes expired auctions in the background so winners are
+// settled and losers refunded without waiting for someone to open the page.
+// Every interval it ends up to batch expired auctions, each in its own
+// transaction, then resolves up to batch settlements past their expires_at.
+// It blocks until ctx is cancelled; start it in a goroutine.
+func (h *AuctionHandler) RunAuctionSweeper(ctx context.Context, interval time.Duration, batch int) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			h.sweepOnce(ctx, batch)
+		}
+	}
+}
+
+// sweepOnce runs a single sweeper tick. An auction that fails to close is
+// logged and skipped for the rest of the tick so it can't hold up the others.
+func (h *AuctionHandler) sweepOnce(ctx context.Context, batch int) {
+	closed := 0
+	failed := []string{}
+	for attempt := 0; attempt < batch; attempt++ {
+		id, out, err := h.endNextExpiredAuction(ctx, failed)
+		if err != nil {
+			if id == "" {
+				log.Printf("sweeper: failed to claim an expired auction: %v", err)
+				break
+			}
+			log.Printf("sweeper: failed to end auction %s: %v", id, err)
+			failed = append(failed, id)
+			continue
+		}
+		if out == nil {
+			break
+		}
+		h.broadcastAuctionEnded(*out)
+		closed++
+	}
+	if closed > 0 {
+		log.Printf("sweeper: closed %d expired auction(s)", closed)
+	}
+
+	// Featured periods that have lapsed no longer affect ordering; clear them
+	// so the column reflects reality.
+	if _, err := db.Pool.Exec(ctx, `
+		UPDATE products SET featured_until = NULL
+		WHERE featured_until IS NOT NULL AND featured_until <= NOW()`); err != nil {
+		log.Printf("sweeper: failed to clear expired featuring: %v", err)
+	}
+
+	h.alertWatchersEndingSoon(ctx)
+	h.expireSettlements(ctx, batch)
+}
+
+// endNextExpiredAuction claims one expired ACTIVE auction, other than those
+// in skip, and ends it. SKIP LOCKED lets several backend instances sweep
+// concurrently without processing the same auction twice. It returns the
+// claimed auction's id, also on error once a row was claimed, and a nil
+// outcome when none are due.
+func (h *AuctionHandler) endNextExpiredAuction(ctx context.Context, skip []string) (string, *auctionOutcome, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	tx, err := db.Pool.Begin(ctx)
+	if err != nil {
+		return "", nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	var auctionID string
+	err = tx.QueryRow(ctx, `
+		SELECT id FROM auctions
+		WHERE status = 'ACTIVE' AND end_time < NOW()
+		  AND id <> ALL($1::uuid[])
+		ORDER BY end_time
+		LIMIT 1
+		FOR UPDATE SKIP LOCKED`, skip,
+	).Scan(&auctionID)
+	if err == pgx.ErrNoRows {
+		return "", nil, nil
+	}
+	if err != nil {
+		return "", nil, err
+	}
+
+	out, err := h.finishAuction(ctx, tx, auctionID)
+	if err != nil || out == nil {
+		return auctionID, nil, err
+	}
+	if err = tx.Commit(ctx); err != nil {
+		return auctionID, nil, err
+	}
+	return auctionID, out, nil
+}
+
+// broadcastAuctionEnded pushes an auction_ended event to the auction room.
+func (h *AuctionHandler) broadcastAuctionEnded(out auctionOutcome) {
+	payload, _ := json.Marshal(AuctionEndedPayload{
+		AuctionID:         out.AuctionID,
+		Status:            out.Status,
+		WinnerID:          out.WinnerID,
+		FinalPrice:        out.FinalPrice,
+		RelistedAuctionID: out.RelistedAuctionID,
+	})
+	h.Hub.BroadcastToAuction(out.AuctionID, hub.Message{
+		Type:    hub.TypeAuctionEnded,
+		Payload: json.RawMessage(payload),
+	})
+	for _, un := range out.Notifications {
+		pushNotification(h.Hub, un.UserID, un.Notification)
+	}
+	h.notifyAuctionEndedOffsite(out)
+}
@@ -0,0 +1,306 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBidLadder(t *testing.T) {
+	for _, tc := range []struct {
+		high, increment float64
+		want            []float64
+	}{
+		{50, 0.01, []float64{50.01, 51, 55, 60}},
+		{450, 1, []float64{451, 460, 500, 550}},
+		{2500, 10, []float64{2510, 2550, 2600, 3000}},
+		{45000, 100, []float64{45100, 45500, 46000, 50000}},
+		{250000, 500, []float64{250500, 251000, 255000, 260000}},
+		// Raises at or below the minimum increment are dropped.
+		{450, 50, []float64{500, 550}},
+		// The tier is chosen by the current price, at its boundary too.
+		{100, 1, []float64{101, 110, 150, 200}},
+	} {
+		got := testHandler.bidLadder(tc.high, tc.increment)
+		if !slices.Equal(got, tc.want) {
+			t.Errorf("bidLadder(%v, %v) = %v, want %v", tc.high, tc.increment, got, tc.want)
+		}
+		if got[0] != testHandler.minNextBid(tc.high, tc.increment) {
+			t.Errorf("bidLadder(%v, %v) starts at %v, not the minimum next bid", tc.high, tc.increment, got[0])
+		}
+	}
+}
+
+// TestMyStatusAndMyPositionAgree checks that the two per-caller auction
+// endpoints report the same standing for a bidder who has been outbid.
+func TestMyStatusAndMyPositionAgree(t *testing.T) {
+	requireDB(t)
+	ctx := context.Background()
+	seller := newTestUser(t, testPool, 0)
+	loser := newTestUser(t, testPool, 1000)
+	leader := newTestUser(t, testPool, 1000)
+	t.Cleanup(func() {
+		testPool.Exec(ctx, `DELETE FROM users WHERE id IN ($1, $2, $3)`, seller, loser, leader)
+	})
+
+	tx, err := testPool.Begin(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	auctionID := newTestAuction(t, tx, seller, "")
+	bidOn(t, tx, auctionID, loser, 100)
+	bidOn(t, tx, auctionID, leader, 150)
+	if err := tx.Commit(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	h := &AuctionHandler{Handler: testHandler}
+	get := func(handler http.HandlerFunc, userID string, into any) {
+		r := withURLParam(asUser(httptest.NewRequest(http.MethodGet, "/", nil), userID), "id", auctionID)
+		w := httptest.NewRecorder()
+		handler(w, r)
+		if w.Code != http.StatusOK {
+			t.Fatalf("status %d: %s", w.Code, w.Body)
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), into); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var status struct {
+		IsLeader    bool     `json:"is_leader"`
+		MinToRetake *float64 `json:"min_to_retake"`
+		CurrentHigh float64  `json:"current_highest_bid"`
+	}
+	var position struct {
+		IsWinning   bool    `json:"is_winning"`
+		MinNextBid  float64 `json:"min_next_bid"`
+		CurrentHigh float64 `json:"current_highest_bid"`
+	}
+	get(h.GetMyStatus, loser, &status)
+	get(h.GetMyPosition, loser, &position)
+
+	if status.IsLeader || position.IsWinning {
+		t.Fatalf("outbid bidder reported as leading: status=%+v position=%+v", status, position)
+	}
+	if status.CurrentHigh != 150 || position.CurrentHigh != 150 {
+		t.Errorf("current high = %v / %v, want 150", status.CurrentHigh, position.CurrentHigh)
+	}
+	if status.MinToRetake == nil || *status.MinToRetake != position.MinNextBid {
+		t.Errorf("min_to_retake = %v, min_next_bid = %v", status.MinToRetake, position.MinNextBid)
+	}
+
+	get(h.GetMyStatus, leader, &status)
+	get(h.GetMyPosition, leader, &position)
+	if !status.IsLeader || !position.IsWinning || status.MinToRetake != nil {
+		t.Errorf("leader: status=%+v position=%+v", status, position)
+	}
+}
+
+// TestTooLowBidReportsCurrentFigures checks that a bid which lost the race
+// gets a 409 carrying the new high bid and the minimum that would beat it.
+func TestTooLowBidReportsCurrentFigures(t *testing.T) {
+	requireDB(t)
+	ctx := context.Background()
+	seller := newTestUser(t, testPool, 0)
+	winner := newTestUser(t, testPool, 1000)
+	loser := newTestUser(t, testPool, 1000)
+	t.Cleanup(func() {
+		testPool.Exec(ctx, `DELETE FROM users WHERE id IN ($1, $2, $3)`, seller, winner, loser)
+	})
+
+	tx, err := testPool.Begin(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	auctionID := newTestAuction(t, tx, seller, "")
+	bidOn(t, tx, auctionID, winner, 150)
+	st, err := testHandler.lockAuction(ctx, tx, auctionID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		t.Fatal(err)
+	}
+	wantMin := testHandler.minNextBid(150, st.MinIncrement)
+
+	h := &AuctionHandler{Handler: testHandler}
+	r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"amount": 150}`))
+	r = withURLParam(asUser(r, loser), "id", auctionID)
+	w := httptest.NewRecorder()
+	h.PlaceBid(w, r)
+	if w.Code != http.StatusConflict {
+		t.Fatalf("status %d, want 409: %s", w.Code, w.Body)
+	}
+	var body struct {
+		Error struct {
+			Code string `json:"code"`
+		} `json:"error"`
+		CurrentHigh float64 `json:"current_highest_bid"`
+		MinNextBid  float64 `json:"min_next_bid"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+	if body.Error.Code != "bid_too_low" {
+		t.Errorf("error code = %q, want bid_too_low", body.Error.Code)
+	}
+	if body.CurrentHigh != 150 || body.MinNextBid != wantMin {
+		t.Errorf("current_highest_bid = %v, min_next_bid = %v; want 150, %v", body.CurrentHigh, body.MinNextBid, wantMin)
+	}
+	if balance := walletBalance(t, testPool, loser); balance != 1000 {
+		t.Errorf("loser balance = %v, want 1000 untouched", balance)
+	}
+}
+
+func TestAuctionConflictIsRetryable(t *testing.T) {
+	w := httptest.NewRecorder()
+	writeAuctionError(w, errAuctionConflict)
+	if w.Code != http.StatusConflict || w.Header().Get("Retry-After") != "1" {
+		t.Errorf("status %d, Retry-After %q; want 409, 1", w.Code, w.Header().Get("Retry-After"))
+	}
+}
+
+// TestMyPositionToRetake checks the amount each participant would have to add
+// over their own latest bid to hold the lead.
+func TestMyPositionToRetake(t *testing.T) {
+	requireDB(t)
+	ctx := context.Background()
+	seller := newTestUser(t, testPool, 0)
+	trailer := newTestUser(t, testPool, 1000)
+	leader := newTestUser(t, testPool, 1000)
+	t.Cleanup(func() {
+		testPool.Exec(ctx, `DELETE FROM users WHERE id IN ($1, $2, $3)`, seller, trailer, leader)
+	})
+
+	tx, err := testPool.Begin(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	auctionID := newTestAuction(t, tx, seller, "")
+	bidOn(t, tx, auctionID, trailer, 100)
+	bidOn(t, tx, auctionID, leader, 150)
+	if err := tx.Commit(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	h := &AuctionHandler{Handler: testHandler}
+	position := func(userID string) (isWinning bool, latest *float64, toRetake float64) {
+		r := withURLParam(asUser(httptest.NewRequest(http.MethodGet, "/", nil), userID), "id", auctionID)
+		w := httptest.NewRecorder()
+		h.GetMyPosition(w, r)
+		if w.Code != http.StatusOK {
+			t.Fatalf("status %d: %s", w.Code, w.Body)
+		}
+		var body struct {
+			IsWinning bool     `json:"is_winning"`
+			LatestBid *float64 `json:"latest_bid"`
+			ToRetake  float64  `json:"to_retake"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+			t.Fatal(err)
+		}
+		return body.IsWinning, body.LatestBid, body.ToRetake
+	}
+
+	if winning, latest, toRetake := position(leader); !winning || latest == nil || *latest != 150 || toRetake != 0 {
+		t.Errorf("leader: winning=%v latest=%v to_retake=%v; want true, 150, 0", winning, latest, toRetake)
+	}
+	want := testHandler.roundMoney(testHandler.minNextBid(150, testHandler.Config.MinBidIncrement) - 100)
+	if winning, latest, toRetake := position(trailer); winning || latest == nil || *latest != 100 || toRetake != want {
+		t.Errorf("trailer: winning=%v latest=%v to_retake=%v; want false, 100, %v", winning, latest, toRetake, want)
+	}
+}
+
+// TestNoSaleAutoRelistsUntilCountRunsOut ends an unsold auto-relist auction
+// repeatedly and checks each relist spends one of the remaining count.
+func TestNoSaleAutoRelistsUntilCountRunsOut(t *testing.T) {
+	ctx := context.Background()
+	tx := testTx(t)
+	seller := newTestUser(t, tx, 0)
+	auctionID := newTestAuction(t, tx, seller, "")
+	expire := func(id string) {
+		t.Helper()
+		if _, err := tx.Exec(ctx, `
+			UPDATE auctions SET created_at = NOW() - INTERVAL '2 hours', end_time = NOW() - INTERVAL '1 hour'
+			WHERE id = $1`, id); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := tx.Exec(ctx, `UPDATE auctions SET auto_relist = TRUE, relists_remaining = 2 WHERE id = $1`,
+		auctionID); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, wantRemaining := range []int{1, 0} {
+		expire(auctionID)
+		out, err := testHandler.finishAuction(ctx, tx, auctionID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if out == nil || out.RelistedAuctionID == nil {
+			t.Fatalf("expected a relist with %d remaining, got %+v", wantRemaining, out)
+		}
+		auctionID = *out.RelistedAuctionID
+
+		var status string
+		var remaining int
+		var seconds int64
+		if err := tx.QueryRow(ctx, `
+			SELECT status, relists_remaining, EXTRACT(EPOCH FROM end_time - created_at)::bigint
+			FROM auctions WHERE id = $1`, auctionID).Scan(&status, &remaining, &seconds); err != nil {
+			t.Fatal(err)
+		}
+		if duration := time.Duration(seconds) * time.Second; status != "ACTIVE" || remaining != wantRemaining || duration != time.Hour {
+			t.Errorf("relisted auction: status %s, %d remaining, runs %v; want ACTIVE, %d, 1h",
+				status, remaining, duration, wantRemaining)
+		}
+	}
+
+	expire(auctionID)
+	out, err := testHandler.finishAuction(ctx, tx, auctionID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out == nil || out.RelistedAuctionID != nil {
+		t.Errorf("relisted after the count ran out: %+v", out)
+	}
+}
+
+func TestGetAuctionTimestamps(t *testing.T) {
+	requireDB(t)
+	ctx := context.Background()
+	seller := newTestUser(t, testPool, 0)
+	t.Cleanup(func() { testPool.Exec(ctx, `DELETE FROM users WHERE id = $1`, seller) })
+	_, auctionID := newTestAuctionListing(t, seller)
+	created := time.Date(2026, 2, 3, 4, 5, 6, 0, time.UTC)
+	if _, err := testPool.Exec(ctx, `UPDATE auctions SET created_at = $1 WHERE id = $2`, created, auctionID); err != nil {
+		t.Fatal(err)
+	}
+
+	h := &AuctionHandler{Handler: testHandler}
+	w := httptest.NewRecorder()
+	h.GetAuction(w, withURLParam(httptest.NewRequest(http.MethodGet, "/", nil), "id", auctionID))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", w.Code, w.Body)
+	}
+	var body struct {
+		CreatedAt string `json:"created_at"`
+		StartTime string `json:"start_time"`
+		EndTime   string `json:"end_time"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+	if body.CreatedAt != "2026-02-03T04:05:06Z" || body.StartTime != body.CreatedAt {
+		t.Errorf("created_at = %q, start_time = %q; want both 2026-02-03T04:05:06Z", body.CreatedAt, body.StartTime)
+	}
+	if _, err := time.Parse(time.RFC3339, body.EndTime); err != nil {
+		t.Errorf("end_time %q: %v", body.EndTime, err)
+	}
+}
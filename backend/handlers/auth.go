@@ -4,14 +4,16 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"log"
 	"net/http"
-	"os"
+	"strings"
 	"time"
 
-	"github.com/golang-jwt/jwt/v5"
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/karti/orange-city-mart/backend/db"
+	authmw "github.com/karti/orange-city-mart/backend/middleware"
+	"github.com/karti/orange-city-mart/backend/requestid"
 	"golang.org/x/crypto/bcrypt"
 )
 
@@ -29,8 +31,9 @@ type loginRequest struct {
 }
 
 type authResponse struct {
-	Token string   `json:"token"`
-	User  userInfo `json:"user"`
+	Token        string   `json:"token"`
+	RefreshToken string   `json:"refresh_token"`
+	User         userInfo `json:"user"`
 }
 
 type userInfo struct {
@@ -38,92 +41,131 @@ type userInfo struct {
 	Name          string  `json:"name"`
 	Email         string  `json:"email"`
 	WalletBalance float64 `json:"wallet_balance"`
+	Role          string  `json:"role"`
 }
 
 // ── Helpers ───────────────────────────────────────────────────────────────────
 
-func signJWT(userID string) (string, error) {
-	secret := os.Getenv("JWT_SECRET")
-	claims := jwt.MapClaims{
-		"sub": userID,
-		"exp": time.Now().Add(24 * time.Hour).Unix(),
-		"iat": time.Now().Unix(),
-	}
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte(secret))
-}
-
 func writeJSON(w http.ResponseWriter, status int, v any) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(status)
 	json.NewEncoder(w).Encode(v)
 }
 
+// writeError sends a structured error body, { "error": { "code", "message" } }.
+// code is a stable machine-readable identifier; message is for humans.
+func writeError(w http.ResponseWriter, status int, code, message string) {
+	authmw.WriteError(w, status, code, message)
+}
+
+// writeErrorDetails is writeError with extra top-level fields alongside the
+// envelope, for failures whose figures let the client recover (re-bid at the
+// minimum, retry after a wait).
+func writeErrorDetails(w http.ResponseWriter, status int, code, message string, details map[string]any) {
+	body := map[string]any{"error": map[string]string{"code": code, "message": message}}
+	for k, v := range details {
+		body[k] = v
+	}
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	writeJSON(w, status, body)
+}
+
+// logf logs like log.Printf, prefixed with the request id from ctx so the
+// line can be matched to the request log.
+func logf(ctx context.Context, format string, args ...any) {
+	if id := requestid.FromContext(ctx); id != "" {
+		format = "[" + id + "] " + format
+	}
+	log.Printf(format, args...)
+}
+
 // ── Register ──────────────────────────────────────────────────────────────────
 
 // Register handles POST /api/auth/register
-func Register(w http.ResponseWriter, r *http.Request) {
+func (h *Handler) Register(w http.ResponseWriter, r *http.Request) {
 	var req registerRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "invalid request body", http.StatusBadRequest)
+		writeError(w, http.StatusBadRequest, "invalid_body", "invalid request body")
 		return
 	}
+	req.Email = normalizeEmail(req.Email)
 	if req.Name == "" || req.Email == "" || req.Password == "" {
-		http.Error(w, "name, email and password are required", http.StatusBadRequest)
+		writeError(w, http.StatusBadRequest, "missing_fields", "name, email and password are required")
 		return
 	}
 	if len(req.Password) < 8 {
-		http.Error(w, "password must be at least 8 characters", http.StatusBadRequest)
+		writeError(w, http.StatusBadRequest, "weak_password", "password must be at least 8 characters")
 		return
 	}
 
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	if cooldown := h.Config.ReregisterCooldown; cooldown > 0 {
+		var blocked bool
+		err := db.Pool.QueryRow(ctx, `
+			SELECT EXISTS (
+			    SELECT 1 FROM deleted_emails
+			    WHERE lower(email) = $1::text
+			      AND deleted_at > NOW() - make_interval(secs => $2::float8))`,
+			req.Email, cooldown.Seconds(),
+		).Scan(&blocked)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "database_error", "database error")
+			return
+		}
+		if blocked {
+			writeError(w, http.StatusConflict, "email_in_cooldown", "this email belonged to a recently deleted account and cannot be registered yet")
+			return
+		}
+	}
+
 	hash, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
 	if err != nil {
-		http.Error(w, "internal error", http.StatusInternalServerError)
+		writeError(w, http.StatusInternalServerError, "internal_error", "internal error")
 		return
 	}
 
-	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
-	defer cancel()
-
 	var u userInfo
 	err = db.Pool.QueryRow(ctx, `
 		INSERT INTO users (name, email, password_hash)
 		VALUES ($1, $2, $3)
-		RETURNING id, name, email, wallet_balance`,
+		RETURNING id, name, email, wallet_balance, role`,
 		req.Name, req.Email, string(hash),
-	).Scan(&u.ID, &u.Name, &u.Email, &u.WalletBalance)
+	).Scan(&u.ID, &u.Name, &u.Email, &u.WalletBalance, &u.Role)
 	if err != nil {
 		// Check specifically for PostgreSQL unique constraint violation (duplicate email)
 		var pgErr *pgconn.PgError
 		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
-			http.Error(w, "email already registered", http.StatusConflict)
+			writeError(w, http.StatusConflict, "email_taken", "email already registered")
 			return
 		}
-		http.Error(w, "database error", http.StatusInternalServerError)
+		writeError(w, http.StatusInternalServerError, "database_error", "database error")
 		return
 	}
 
-	token, err := signJWT(u.ID)
+	resp, err := h.newSession(ctx, u)
 	if err != nil {
-		http.Error(w, "could not generate token", http.StatusInternalServerError)
+		writeError(w, http.StatusInternalServerError, "internal_error", "could not generate token")
 		return
 	}
 
-	writeJSON(w, http.StatusCreated, authResponse{Token: token, User: u})
+	writeJSON(w, http.StatusCreated, resp)
 }
 
 // ── Login ─────────────────────────────────────────────────────────────────────
 
 // Login handles POST /api/auth/login
-func Login(w http.ResponseWriter, r *http.Request) {
+// Accounts with 2FA get a challenge token to finish at /api/auth/2fa.
+func (h *Handler) Login(w http.ResponseWriter, r *http.Request) {
 	var req loginRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "invalid request body", http.StatusBadRequest)
+		writeError(w, http.StatusBadRequest, "invalid_body", "invalid request body")
 		return
 	}
+	req.Email = normalizeEmail(req.Email)
 	if req.Email == "" || req.Password == "" {
-		http.Error(w, "email and password are required", http.StatusBadRequest)
+		writeError(w, http.StatusBadRequest, "missing_fields", "email and password are required")
 		return
 	}
 
@@ -133,29 +175,59 @@ func Login(w http.ResponseWriter, r *http.Request) {
 	var u userInfo
 	var passwordHash string
 	err := db.Pool.QueryRow(ctx, `
-		SELECT id, name, email, wallet_balance, password_hash
-		FROM users WHERE email = $1`,
+		SELECT id, name, email, wallet_balance, role, password_hash
+		FROM users WHERE lower(email) = $1`,
 		req.Email,
-	).Scan(&u.ID, &u.Name, &u.Email, &u.WalletBalance, &passwordHash)
+	).Scan(&u.ID, &u.Name, &u.Email, &u.WalletBalance, &u.Role, &passwordHash)
 	if err == pgx.ErrNoRows {
-		http.Error(w, "invalid email or password", http.StatusUnauthorized)
+		writeError(w, http.StatusUnauthorized, "invalid_credentials", "invalid email or password")
 		return
 	}
 	if err != nil {
-		http.Error(w, "database error", http.StatusInternalServerError)
+		writeError(w, http.StatusInternalServerError, "database_error", "database error")
 		return
 	}
 
 	if err := bcrypt.CompareHashAndPassword([]byte(passwordHash), []byte(req.Password)); err != nil {
-		http.Error(w, "invalid email or password", http.StatusUnauthorized)
+		writeError(w, http.StatusUnauthorized, "invalid_credentials", "invalid email or password")
 		return
 	}
 
-	token, err := signJWT(u.ID)
+	h.signIn(ctx, w, u)
+}
+
+// ── Check Email ───────────────────────────────────────────────────────────────
+
+// emailCheckDelay slows CheckEmail down so bulk enumeration is expensive.
+const emailCheckDelay = 300 * time.Millisecond
+
+// CheckEmail handles GET /api/auth/check-email?email=
+// Reports whether an email is free to register, for inline signup feedback.
+// This necessarily reveals registered emails, so the route is rate limited
+// per IP and every answer is deliberately delayed.
+func (h *Handler) CheckEmail(w http.ResponseWriter, r *http.Request) {
+	email := normalizeEmail(r.URL.Query().Get("email"))
+	if email == "" || !strings.Contains(email, "@") {
+		writeError(w, http.StatusBadRequest, "invalid_email", "valid email is required")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	var taken bool
+	err := db.Pool.QueryRow(ctx,
+		`SELECT EXISTS (SELECT 1 FROM users WHERE lower(email) = $1)`, email,
+	).Scan(&taken)
 	if err != nil {
-		http.Error(w, "could not generate token", http.StatusInternalServerError)
+		writeError(w, http.StatusInternalServerError, "database_error", "database error")
 		return
 	}
 
-	writeJSON(w, http.StatusOK, authResponse{Token: token, User: u})
+	select {
+	case <-time.After(emailCheckDelay):
+	case <-ctx.Done():
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{"email": email, "available": !taken})
 }
@@ -0,0 +1,130 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	authmw "github.com/karti/orange-city-mart/backend/middleware"
+)
+
+func TestWriteErrorDetailsKeepsEnvelope(t *testing.T) {
+	w := httptest.NewRecorder()
+	writeErrorDetails(w, http.StatusConflict, "bid_too_low", "too low", map[string]any{"min_next_bid": 110.5})
+
+	if w.Code != http.StatusConflict {
+		t.Fatalf("status = %d", w.Code)
+	}
+	var body struct {
+		Error struct {
+			Code    string `json:"code"`
+			Message string `json:"message"`
+		} `json:"error"`
+		MinNextBid float64 `json:"min_next_bid"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatalf("body %q: %v", w.Body, err)
+	}
+	if body.Error.Code != "bid_too_low" || body.Error.Message != "too low" || body.MinNextBid != 110.5 {
+		t.Fatalf("body = %+v", body)
+	}
+}
+
+// TestEmailIsNormalisedAcrossAuth checks that Register, CheckEmail and Login
+// agree on an email regardless of case and surrounding space.
+func TestEmailIsNormalisedAcrossAuth(t *testing.T) {
+	requireDB(t)
+	ctx := context.Background()
+	local := fmt.Sprintf("Mixed%d", time.Now().UnixNano())
+	email := strings.ToLower(local) + "@example.com"
+	t.Cleanup(func() { testPool.Exec(ctx, `DELETE FROM users WHERE lower(email) = $1`, email) })
+
+	post := func(h http.HandlerFunc, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		h(w, httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body)))
+		return w
+	}
+
+	w := post(testHandler.Register, `{"name":"Test","email":"  `+local+`@Example.COM ","password":"password123"}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Register = %d: %s", w.Code, w.Body)
+	}
+	var stored string
+	if err := testPool.QueryRow(ctx, `SELECT email FROM users WHERE lower(email) = $1`, email).Scan(&stored); err != nil {
+		t.Fatal(err)
+	}
+	if stored != email {
+		t.Errorf("stored email = %q, want %q", stored, email)
+	}
+
+	if w := post(testHandler.Register, `{"name":"Test","email":"`+strings.ToUpper(email)+`","password":"password123"}`); w.Code != http.StatusConflict {
+		t.Errorf("re-Register in another case = %d, want 409", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	testHandler.CheckEmail(w, httptest.NewRequest(http.MethodGet, "/?email="+url.QueryEscape(strings.ToUpper(email)), nil))
+	var check struct {
+		Available bool `json:"available"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &check); err != nil || check.Available {
+		t.Errorf("CheckEmail = %d %s, want taken", w.Code, w.Body)
+	}
+
+	if w := post(testHandler.Login, `{"email":" `+strings.ToUpper(email)+`","password":"password123"}`); w.Code != http.StatusOK {
+		t.Errorf("Login in another case = %d: %s", w.Code, w.Body)
+	}
+}
+
+func checkEmail(h http.Handler, email string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/auth/check-email?email="+url.QueryEscape(email), nil))
+	return w
+}
+
+func TestCheckEmailTakenAndAvailable(t *testing.T) {
+	requireDB(t)
+	ctx := context.Background()
+	userID := newTestUser(t, testPool, 0)
+	t.Cleanup(func() { testPool.Exec(ctx, `DELETE FROM users WHERE id = $1`, userID) })
+	var taken string
+	if err := testPool.QueryRow(ctx, `SELECT email FROM users WHERE id = $1`, userID).Scan(&taken); err != nil {
+		t.Fatal(err)
+	}
+
+	for email, want := range map[string]bool{
+		taken: false,
+		fmt.Sprintf("free%d@example.com", time.Now().UnixNano()): true,
+	} {
+		w := checkEmail(http.HandlerFunc(testHandler.CheckEmail), email)
+		var got struct {
+			Available bool `json:"available"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &got); w.Code != http.StatusOK || err != nil {
+			t.Fatalf("CheckEmail(%s) = %d: %s", email, w.Code, w.Body)
+		}
+		if got.Available != want {
+			t.Errorf("CheckEmail(%s) available = %t, want %t", email, got.Available, want)
+		}
+	}
+}
+
+// TestCheckEmailRateLimited checks the route's per-IP limiter turns away
+// lookups past the allowance, before the handler runs.
+func TestCheckEmailRateLimited(t *testing.T) {
+	h := authmw.NewIPRateLimiter(2, time.Minute).Limit(http.HandlerFunc(testHandler.CheckEmail))
+	for i := 0; i < 2; i++ {
+		if w := checkEmail(h, "not-an-email"); w.Code != http.StatusBadRequest {
+			t.Fatalf("lookup %d = %d, want the handler's 400", i+1, w.Code)
+		}
+	}
+	w := checkEmail(h, "not-an-email")
+	if w.Code != http.StatusTooManyRequests || w.Header().Get("Retry-After") == "" {
+		t.Errorf("lookup past the limit = %d (Retry-After %q), want 429", w.Code, w.Header().Get("Retry-After"))
+	}
+}
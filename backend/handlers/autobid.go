@@ -0,0 +1,240 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/jackc/pgx/v5"
+	"github.com/karti/orange-city-mart/backend/db"
+	authmw "github.com/karti/orange-city-mart/backend/middleware"
+)
+
+// maxAutoBidRounds bounds resolveAutoBids when auto-bids drop out for lack
+// of funds and the contest has to be re-evaluated.
+const maxAutoBidRounds = 10
+
+// autoBid is a standing proxy-bid ceiling.
+type autoBid struct {
+	UserID    string
+	MaxAmount float64
+}
+
+// ─────────────────────────────────────────────────────────────────────────────
+// SetAutoBid  POST /api/auctions/{id}/autobid
+//
+// Stores (or replaces) the caller's maximum bid for an auction. The engine
+// then bids on their behalf, one minimum increment at a time, whenever they
+// lose the lead — up to the ceiling. Only the amount currently needed is
+// soft-blocked, never the full ceiling.
+// ─────────────────────────────────────────────────────────────────────────────
+func (h *AuctionHandler) SetAutoBid(w http.ResponseWriter, r *http.Request) {
+	auctionID := chi.URLParam(r, "id")
+	userID, ok := authmw.UserIDFromContext(r.Context())
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "unauthorized", "unauthorized")
+		return
+	}
+
+	var req struct {
+		MaxAmount float64 `json:"max_amount"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.MaxAmount <= 0 {
+		writeError(w, http.StatusBadRequest, "invalid_amount", "positive max_amount required")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	tx, err := db.Pool.Begin(ctx)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "database_error", "database error")
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	st, err := h.lockAuction(ctx, tx, auctionID)
+	if err == pgx.ErrNoRows {
+		writeError(w, http.StatusNotFound, "auction_not_found", "auction not found")
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "database_error", "database error")
+		return
+	}
+	if st.Status != "ACTIVE" || time.Now().After(st.EndTime) {
+		writeError(w, http.StatusConflict, "auction_not_active", "auction is not active")
+		return
+	}
+	minBid := h.minNextBid(st.HighBid, st.MinIncrement)
+	if req.MaxAmount < minBid {
+		writeErrorDetails(w, http.StatusConflict, "max_amount_too_low", "max_amount must be at least the minimum next bid",
+			map[string]any{"current_highest_bid": st.HighBid, "min_next_bid": minBid})
+		return
+	}
+	// Refuse up front a ceiling whose very next bid the caller couldn't take
+	// on, rather than storing an auto-bid that would only ever be skipped.
+	if st.HighBidderID == nil || *st.HighBidderID != userID {
+		_, held, err := softHold(ctx, tx, auctionID, userID)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "database_error", "database error")
+			return
+		}
+		if ok, exposure, limit, err := h.checkExposure(ctx, tx, userID, h.roundMoney(minBid-held)); err != nil {
+			writeError(w, http.StatusInternalServerError, "database_error", "database error")
+			return
+		} else if !ok {
+			writeExposureExceeded(w, exposure, limit)
+			return
+		}
+	}
+
+	// created_at is kept on update so tie-breaking priority isn't lost.
+	_, err = tx.Exec(ctx, `
+		INSERT INTO auto_bids (auction_id, user_id, max_amount)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (auction_id, user_id)
+		DO UPDATE SET max_amount = EXCLUDED.max_amount, updated_at = NOW()`,
+		auctionID, userID, req.MaxAmount,
+	)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "database_error", "database error")
+		return
+	}
+
+	placed, err := h.resolveAutoBids(ctx, tx, st)
+	if err != nil {
+		writeAuctionError(w, err)
+		return
+	}
+
+	extended := false
+	if len(placed) > 0 {
+		extended, err = h.extendIfSniped(ctx, tx, st, placed[0].PlacedAt)
+		if err != nil {
+			writeAuctionError(w, err)
+			return
+		}
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		writeError(w, http.StatusInternalServerError, "database_error", "commit failed")
+		return
+	}
+
+	for _, pb := range placed {
+		h.broadcastBid(pb)
+	}
+	if extended {
+		h.broadcastAuctionExtended(st)
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"success":      true,
+		"auction_id":   auctionID,
+		"max_amount":   req.MaxAmount,
+		"new_high_bid": st.HighBid,
+		"is_winning":   st.HighBidderID != nil && *st.HighBidderID == userID,
+	})
+}
+
+// resolveAutoBids lets standing auto-bids respond to the current state of a
+// locked auction and returns the bids it placed, in order.
+//
+// The strongest auto-bid (highest ceiling; earliest created on a tie) ends up
+// leading. The runner-up is first bid up to its own ceiling so the history
+// reflects the contest, then the strongest bids one increment above it, capped
+// at its ceiling. An auto-bid never fires for the user already leading, and an
+// auto-bid whose owner can't fund the required amount, or take it on within
+// their exposure cap, is skipped.
+func (h *Handler) resolveAutoBids(ctx context.Context, tx pgx.Tx, st *auctionState) ([]placedBid, error) {
+	var placed []placedBid
+	skip := map[string]bool{}
+
+	for round := 0; round < maxAutoBidRounds; round++ {
+		cands, err := loadAutoBids(ctx, tx, st.ID, h.minNextBid(st.HighBid, st.MinIncrement), skip)
+		if err != nil {
+			return placed, err
+		}
+		if len(cands) == 0 {
+			return placed, nil
+		}
+
+		leader := ""
+		if st.HighBidderID != nil {
+			leader = *st.HighBidderID
+		}
+		top := cands[0]
+		price := h.minNextBid(st.HighBid, st.MinIncrement)
+
+		if len(cands) > 1 {
+			runner := cands[1]
+			if runner.MaxAmount == top.MaxAmount {
+				// Equal ceilings: the earlier auto-bid takes it at the ceiling.
+				price = top.MaxAmount
+			} else {
+				if runner.UserID != leader {
+					pb, err := h.applyBid(ctx, tx, st, runner.UserID, runner.MaxAmount)
+					if cannotFundBid(err) {
+						skip[runner.UserID] = true
+						continue
+					}
+					if err != nil {
+						return placed, err
+					}
+					placed = append(placed, pb)
+					leader = runner.UserID
+				}
+				price = h.minNextBid(runner.MaxAmount, st.MinIncrement)
+				if price > top.MaxAmount {
+					// The top ceiling can't clear the runner-up by a full increment.
+					continue
+				}
+			}
+		}
+
+		if top.UserID == leader {
+			return placed, nil
+		}
+		pb, err := h.applyBid(ctx, tx, st, top.UserID, price)
+		if cannotFundBid(err) {
+			skip[top.UserID] = true
+			continue
+		}
+		if err != nil {
+			return placed, err
+		}
+		return append(placed, pb), nil
+	}
+	return placed, nil
+}
+
+// loadAutoBids returns the auto-bids on an auction able to reach minBid,
+// strongest first, excluding users in skip.
+func loadAutoBids(ctx context.Context, tx pgx.Tx, auctionID string, minBid float64, skip map[string]bool) ([]autoBid, error) {
+	rows, err := tx.Query(ctx, `
+		SELECT user_id, max_amount FROM auto_bids
+		WHERE auction_id = $1 AND max_amount >= $2
+		ORDER BY max_amount DESC, created_at ASC, id ASC`,
+		auctionID, minBid,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []autoBid
+	for rows.Next() {
+		var ab autoBid
+		if err := rows.Scan(&ab.UserID, &ab.MaxAmount); err != nil {
+			return nil, err
+		}
+		if !skip[ab.UserID] {
+			out = append(out, ab)
+		}
+	}
+	return out, rows.Err()
+}
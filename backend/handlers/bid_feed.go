@@ -0,0 +1,92 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/karti/orange-city-mart/backend/db"
+)
+
+const (
+	defaultFeedLimit = 20
+	maxFeedLimit     = 50
+)
+
+// ─────────────────────────────────────────────────────────────────────────────
+// GetBidFeed  GET /api/feed/bids?limit=20&before=<RFC3339>
+//
+// Most recent bids across all live auctions for the homepage activity ticker.
+// Bidders are masked the same way as on the auction page; bids on auctions
+// that have ended or whose listing was deleted are left out. Page backwards by passing the placed_at of the
+// last item as `before`.
+// ─────────────────────────────────────────────────────────────────────────────
+func (h *Handler) GetBidFeed(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+
+	limit := defaultFeedLimit
+	if v, err := strconv.Atoi(q.Get("limit")); err == nil && v > 0 {
+		limit = v
+	}
+	if limit > maxFeedLimit {
+		limit = maxFeedLimit
+	}
+
+	before := time.Now()
+	if v := q.Get("before"); v != "" {
+		t, err := time.Parse(time.RFC3339Nano, v)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "invalid_before", "before must be an RFC3339 timestamp")
+			return
+		}
+		before = t
+	}
+
+	rows, err := db.Pool.Query(r.Context(), `
+		SELECT b.auction_id, p.id, p.title, b.amount, b.created_at, u.name
+		FROM bids b
+		JOIN auctions a ON a.id = b.auction_id
+		JOIN products p ON p.id = a.product_id
+		JOIN users u ON u.id = b.user_id
+		WHERE a.status = 'ACTIVE'
+		  AND a.end_time > NOW()
+		  AND p.deleted_at IS NULL
+		  AND b.created_at < $1
+		ORDER BY b.created_at DESC
+		LIMIT $2`,
+		before, limit,
+	)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "database_error", "database error")
+		return
+	}
+	defer rows.Close()
+
+	type FeedItem struct {
+		AuctionID    string  `json:"auction_id"`
+		ProductID    string  `json:"product_id"`
+		ProductTitle string  `json:"product_title"`
+		Amount       float64 `json:"amount"`
+		PlacedAt     string  `json:"placed_at"`
+		BidderTag    string  `json:"bidder_tag"`
+	}
+
+	items := []FeedItem{}
+	for rows.Next() {
+		var it FeedItem
+		var placedAt time.Time
+		var name string
+		if err := rows.Scan(&it.AuctionID, &it.ProductID, &it.ProductTitle, &it.Amount, &placedAt, &name); err != nil {
+			continue
+		}
+		it.PlacedAt = placedAt.UTC().Format(time.RFC3339Nano)
+		it.BidderTag = maskName(name)
+		items = append(items, it)
+	}
+
+	// Public and identical for every visitor, so let proxies hold it briefly.
+	w.Header().Set("Cache-Control", "public, max-age=5")
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(items)
+}
@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// TestBidFeedRecentLiveBidsMasked seeds bids on two live auctions, an ended
+// one and a deleted listing, and checks the feed lists only the live bids,
+// newest first, under masked bidder tags.
+func TestBidFeedRecentLiveBidsMasked(t *testing.T) {
+	requireDB(t)
+	ctx := context.Background()
+	seller := newTestUser(t, testPool, 0)
+	bidder := newTestUser(t, testPool, 0)
+	t.Cleanup(func() { testPool.Exec(ctx, `DELETE FROM users WHERE id IN ($1, $2)`, seller, bidder) })
+	if _, err := testPool.Exec(ctx, `UPDATE users SET name = 'Alexandra' WHERE id = $1`, bidder); err != nil {
+		t.Fatal(err)
+	}
+
+	_, first := newTestAuctionListing(t, seller)
+	_, second := newTestAuctionListing(t, seller)
+	_, ended := newTestAuctionListing(t, seller)
+	deletedProduct, deleted := newTestAuctionListing(t, seller)
+	if _, err := testPool.Exec(ctx, `UPDATE auctions SET status = 'ENDED' WHERE id = $1`, ended); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := testPool.Exec(ctx, `UPDATE products SET deleted_at = NOW() WHERE id = $1`, deletedProduct); err != nil {
+		t.Fatal(err)
+	}
+
+	// Backdated so the page below holds only these bids.
+	base := time.Date(2001, 5, 1, 12, 0, 0, 0, time.UTC)
+	for i, auctionID := range []string{first, ended, second, deleted, first} {
+		if _, err := testPool.Exec(ctx, `
+			INSERT INTO bids (auction_id, user_id, amount, created_at) VALUES ($1, $2, $3, $4)`,
+			auctionID, bidder, 10*(i+1), base.Add(time.Duration(i)*time.Minute)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	w := httptest.NewRecorder()
+	testHandler.GetBidFeed(w, httptest.NewRequest(http.MethodGet,
+		"/api/feed/bids?before="+base.Add(time.Hour).Format(time.RFC3339), nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", w.Code, w.Body)
+	}
+	var items []struct {
+		AuctionID string  `json:"auction_id"`
+		Amount    float64 `json:"amount"`
+		BidderTag string  `json:"bidder_tag"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &items); err != nil {
+		t.Fatal(err)
+	}
+	want := []struct {
+		auctionID string
+		amount    float64
+	}{{first, 50}, {second, 30}, {first, 10}}
+	if len(items) != len(want) {
+		t.Fatalf("feed = %+v, want %d items", items, len(want))
+	}
+	for i, it := range items {
+		if it.AuctionID != want[i].auctionID || it.Amount != want[i].amount {
+			t.Errorf("item %d = %s %.2f, want %s %.2f", i, it.AuctionID, it.Amount, want[i].auctionID, want[i].amount)
+		}
+		if it.BidderTag != "Alex***" {
+			t.Errorf("item %d bidder tag = %q, want Alex***", i, it.BidderTag)
+		}
+	}
+}
@@ -0,0 +1,407 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/karti/orange-city-mart/backend/hub"
+	"github.com/karti/orange-city-mart/backend/metrics"
+)
+
+// errInsufficientFunds is returned by applyBid when the bidder's wallet can't
+// cover the bid amount.
+var errInsufficientFunds = errors.New("insufficient wallet balance")
+
+// cannotFundBid reports whether applyBid refused a bid because its bidder
+// can't take on the amount, as opposed to failing outright.
+func cannotFundBid(err error) bool {
+	var ee *exposureError
+	return errors.Is(err, errInsufficientFunds) || errors.As(err, &ee)
+}
+
+// errAuctionConflict means the auction row changed between being read and
+// being written: a conditional update on its version matched nothing. The
+// request is safe to retry.
+var errAuctionConflict = errors.New("auction was modified concurrently, please retry")
+
+// writeAuctionError reports an error from the bidding engine: a retryable 409
+// for errAuctionConflict, otherwise a 500.
+func writeAuctionError(w http.ResponseWriter, err error) {
+	if errors.Is(err, errAuctionConflict) {
+		w.Header().Set("Retry-After", "1")
+		writeError(w, http.StatusConflict, "auction_conflict", err.Error())
+		return
+	}
+	writeError(w, http.StatusInternalServerError, "database_error", "database error")
+}
+
+// auctionState is the locked view of an auction row that the bidding engine
+// reads and mutates inside one transaction.
+type auctionState struct {
+	ID               string
+	HighBid          float64
+	HighBidderID     *string
+	Status           string
+	EndTime          time.Time
+	CreatedAt        time.Time
+	RefundPolicy     string
+	MinIncrement     float64
+	SellerID         string
+	BuyNowPrice      *float64
+	AntiSnipe        bool
+	MinBidInterval   time.Duration
+	SettlementWindow time.Duration
+	Version          int // auctions.version as of the last read or write
+}
+
+// placedBid describes one accepted bid, for the post-commit WebSocket events.
+type placedBid struct {
+	AuctionID    string
+	Amount       float64
+	BidderID     string
+	PrevBidderID *string
+	PrevAmount   float64
+	PlacedAt     time.Time
+	Outbid       *userNotification // stored for PrevBidderID when they lost the lead
+}
+
+// lockAuction loads an auction FOR UPDATE along with its category rules.
+func (h *Handler) lockAuction(ctx context.Context, tx pgx.Tx, auctionID string) (*auctionState, error) {
+	st := &auctionState{ID: auctionID}
+	var category string
+	var minBidInterval int
+	err := tx.QueryRow(ctx, `
+		SELECT a.current_highest_bid, a.highest_bidder_id, a.status, a.end_time,
+		       a.created_at, a.refund_policy, COALESCE(p.category, ''),
+		       p.seller_id, a.buy_now_price, a.anti_snipe, a.min_bid_interval,
+		       a.version
+		FROM auctions a
+		JOIN products p ON p.id = a.product_id
+		WHERE a.id = $1
+		FOR UPDATE OF a`,
+		auctionID,
+	).Scan(&st.HighBid, &st.HighBidderID, &st.Status, &st.EndTime,
+		&st.CreatedAt, &st.RefundPolicy, &category,
+		&st.SellerID, &st.BuyNowPrice, &st.AntiSnipe, &minBidInterval,
+		&st.Version)
+	if err != nil {
+		return nil, err
+	}
+
+	rules, err := h.loadCategoryRules(ctx, tx, category)
+	if err != nil {
+		return nil, err
+	}
+	st.MinIncrement = rules.MinIncrement
+	st.SettlementWindow = rules.SettlementWindow
+	st.MinBidInterval = time.Duration(minBidInterval) * time.Second
+	return st, nil
+}
+
+// nextBidAllowedAt is when userID may next bid on the auction under its
+// min_bid_interval: their last bid's time plus the interval. A user who
+// hasn't bid yet gets the zero time.
+func nextBidAllowedAt(ctx context.Context, tx pgx.Tx, st *auctionState, userID string) (time.Time, error) {
+	var last *time.Time
+	err := tx.QueryRow(ctx,
+		`SELECT MAX(created_at) FROM bids WHERE auction_id = $1 AND user_id = $2`,
+		st.ID, userID,
+	).Scan(&last)
+	if err != nil || last == nil {
+		return time.Time{}, err
+	}
+	return last.Add(st.MinBidInterval), nil
+}
+
+// applyBid runs the soft-block flow for one bid on an already locked and
+// validated auction, and advances st to reflect the new leader.
+// A bidder keeps at most one SOFT hold per auction: a raise (a leader
+// bidding again, or an AT_END bidder coming back) tops the existing hold up
+// to amount and deducts only the difference.
+// Returns pgx.ErrNoRows if the bidder doesn't exist, errInsufficientFunds
+// if their wallet can't cover the extra funds and an *exposureError if the
+// extra would take them past maxUserExposure.
+func (h *Handler) applyBid(ctx context.Context, tx pgx.Tx, st *auctionState, userID string, amount float64) (placedBid, error) {
+	placed := placedBid{
+		AuctionID:    st.ID,
+		Amount:       amount,
+		BidderID:     userID,
+		PrevBidderID: st.HighBidderID,
+		PrevAmount:   st.HighBid,
+		PlacedAt:     time.Now(),
+	}
+
+	// ── Lock bidder wallet ─────────────────────────────────────────────────
+	var bidderBalance float64
+	err := tx.QueryRow(ctx, `
+		SELECT wallet_balance FROM users WHERE id = $1 FOR UPDATE`, userID,
+	).Scan(&bidderBalance)
+	if err != nil {
+		return placed, err
+	}
+
+	// The bidder's existing SOFT hold, if any, already covers part of amount.
+	holdID, held, err := softHold(ctx, tx, st.ID, userID)
+	if err != nil {
+		return placed, err
+	}
+	extra := h.roundMoney(amount - held)
+	if bidderBalance < extra {
+		return placed, errInsufficientFunds
+	}
+	// Only the extra is new exposure; the existing hold is already counted.
+	if ok, exposure, limit, err := h.checkExposure(ctx, tx, userID, extra); err != nil {
+		return placed, err
+	} else if !ok {
+		return placed, &exposureError{exposure: exposure, limit: limit}
+	}
+
+	// ── Release previous highest bidder's soft hold ────────────────────────
+	prev := st.HighBidderID
+	if st.RefundPolicy == refundInstant && prev != nil && *prev != userID {
+		if err = releaseUserSoftHolds(ctx, tx, st.ID, *prev); err != nil {
+			return placed, err
+		}
+	}
+
+	// ── Deduct the extra from the bidder's wallet (soft-block) ────────────
+	_, err = tx.Exec(ctx, `
+		UPDATE users SET wallet_balance = wallet_balance - $1 WHERE id = $2`,
+		extra, userID,
+	)
+	if err != nil {
+		return placed, err
+	}
+
+	// Record BID_HOLD transaction
+	_, err = tx.Exec(ctx, `
+		INSERT INTO transactions (user_id, amount, type, status, reference)
+		VALUES ($1, $2, 'BID_HOLD', 'COMPLETED', $3)`,
+		userID, extra, st.ID,
+	)
+	if err != nil {
+		return placed, err
+	}
+
+	// Top up the bidder's SOFT hold, or open one
+	if holdID != nil {
+		_, err = tx.Exec(ctx, `
+			UPDATE bid_holds SET amount = $2, updated_at = NOW() WHERE id = $1`,
+			*holdID, amount,
+		)
+	} else {
+		_, err = tx.Exec(ctx, `
+			INSERT INTO bid_holds (auction_id, user_id, amount, status)
+			VALUES ($1, $2, $3, 'SOFT')`,
+			st.ID, userID, amount,
+		)
+	}
+	if err != nil {
+		return placed, err
+	}
+
+	// Persist the outbid alert so the previous leader sees it even if offline.
+	if prev != nil && *prev != userID {
+		n, err := createNotification(ctx, tx, *prev, notifyOutbid, OutbidPayload{
+			AuctionID:  st.ID,
+			YourBid:    st.HighBid,
+			NewHighBid: amount,
+			NewBidder:  userID,
+		})
+		if err != nil {
+			return placed, err
+		}
+		placed.Outbid = &userNotification{UserID: *prev, Notification: n}
+	}
+
+	// ── Update auction ─────────────────────────────────────────────────────
+	if err = updateAuctionVersioned(ctx, tx, st,
+		`current_highest_bid = $3, highest_bidder_id = $4`, amount, userID); err != nil {
+		return placed, err
+	}
+
+	// ── Record the raw bid (for history) ───────────────────────────────────
+	_, err = tx.Exec(ctx, `
+		INSERT INTO bids (auction_id, user_id, amount) VALUES ($1, $2, $3)`,
+		st.ID, userID, amount,
+	)
+	if err != nil {
+		return placed, err
+	}
+
+	st.HighBid = amount
+	st.HighBidderID = &userID
+	return placed, nil
+}
+
+// softHold returns the id and amount of userID's SOFT hold on an auction,
+// or a nil id when they hold nothing there.
+func softHold(ctx context.Context, tx pgx.Tx, auctionID, userID string) (*string, float64, error) {
+	var id *string
+	var amount float64
+	err := tx.QueryRow(ctx, `
+		SELECT id, amount FROM bid_holds
+		WHERE auction_id = $1 AND user_id = $2 AND status = 'SOFT'
+		ORDER BY created_at DESC
+		LIMIT 1`,
+		auctionID, userID,
+	).Scan(&id, &amount)
+	if err == pgx.ErrNoRows {
+		return nil, 0, nil
+	}
+	return id, amount, err
+}
+
+// releaseUserSoftHolds releases userID's SOFT holds on an auction and credits
+// back everything they held, recording one REFUND.
+func releaseUserSoftHolds(ctx context.Context, tx pgx.Tx, auctionID, userID string) error {
+	var refund float64
+	err := tx.QueryRow(ctx, `
+		WITH released AS (
+		    UPDATE bid_holds
+		    SET status = 'RELEASED', updated_at = NOW()
+		    WHERE auction_id = $1 AND user_id = $2 AND status = 'SOFT'
+		    RETURNING amount
+		)
+		SELECT COALESCE(SUM(amount), 0)::float8 FROM released`,
+		auctionID, userID,
+	).Scan(&refund)
+	if err != nil || refund == 0 {
+		return err
+	}
+
+	_, err = tx.Exec(ctx, `
+		UPDATE users SET wallet_balance = wallet_balance + $1 WHERE id = $2`,
+		refund, userID,
+	)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(ctx, `
+		INSERT INTO transactions (user_id, amount, type, status, reference)
+		VALUES ($1, $2, 'REFUND', 'COMPLETED', $3)`,
+		userID, refund, auctionID,
+	)
+	return err
+}
+
+// updateAuctionVersioned applies set (a SET clause whose placeholders start
+// at $3) to st's auction only if its version still matches st.Version, and
+// advances st.Version. It returns errAuctionConflict when the row moved on.
+// Every write to an auction row goes through it, so a write planned from a
+// stale read — a version read before the row was locked, or carried over from
+// an earlier statement that already changed the row — fails instead of
+// silently overwriting the newer state.
+func updateAuctionVersioned(ctx context.Context, tx pgx.Tx, st *auctionState, set string, args ...any) error {
+	err := tx.QueryRow(ctx,
+		`UPDATE auctions SET `+set+` WHERE id = $1 AND version = $2 RETURNING version`,
+		append([]any{st.ID, st.Version}, args...)...,
+	).Scan(&st.Version)
+	if err == pgx.ErrNoRows {
+		return errAuctionConflict
+	}
+	return err
+}
+
+// broadcastBid pushes the new-bid event to the auction room and, when someone
+// else lost the lead, a targeted outbid alert. Call only after commit.
+func (h *AuctionHandler) broadcastBid(pb placedBid) {
+	metrics.BidPlaced()
+	bidPayloadBytes, _ := json.Marshal(BidPayload{
+		AuctionID: pb.AuctionID,
+		Amount:    pb.Amount,
+		BidderID:  pb.BidderID,
+		Timestamp: pb.PlacedAt.UTC().Format(time.RFC3339),
+	})
+	h.Hub.BroadcastToAuction(pb.AuctionID, hub.Message{
+		Type:    hub.TypeBroadcastNewBid,
+		Payload: json.RawMessage(bidPayloadBytes),
+	})
+
+	if pb.PrevBidderID != nil && *pb.PrevBidderID != pb.BidderID {
+		outbidBytes, _ := json.Marshal(OutbidPayload{
+			AuctionID:  pb.AuctionID,
+			YourBid:    pb.PrevAmount,
+			NewHighBid: pb.Amount,
+			NewBidder:  pb.BidderID,
+		})
+		h.Hub.SendToUser(*pb.PrevBidderID, hub.Message{
+			Type:    hub.TypeOutbidAlert,
+			Payload: json.RawMessage(outbidBytes),
+		})
+	}
+	if pb.Outbid != nil {
+		pushNotification(h.Hub, pb.Outbid.UserID, pb.Outbid.Notification)
+	}
+
+	h.alertWatchers(pb)
+	h.notifyOutbidOffsite(pb)
+}
+
+var errAuctionTooLong = errors.New("auction duration exceeds the maximum")
+
+// clampAuctionEnd enforces AUCTION_MAX_DURATION, the longest an auction may
+// run measured from when it was created, on a requested end time for an
+// auction starting at start. With AUCTION_DURATION_MODE=cap an over-long end
+// is pulled back to the limit; otherwise (the default, "reject") it returns
+// errAuctionTooLong.
+func (h *Handler) clampAuctionEnd(start, end time.Time) (time.Time, error) {
+	limit := start.Add(h.Config.AuctionMaxDuration)
+	if !end.After(limit) {
+		return end, nil
+	}
+	if h.Config.AuctionDurationMode == "cap" {
+		return limit, nil
+	}
+	return time.Time{}, errAuctionTooLong
+}
+
+// extendIfSniped implements anti-sniping: when a bid lands within
+// ANTI_SNIPE_WINDOW of end_time, end_time moves out by ANTI_SNIPE_EXTENSION,
+// capped at the auction's maximum total duration. Auctions created with
+// anti_snipe off keep a hard deadline. It reports whether end_time moved;
+// st.EndTime is updated in place.
+func (h *Handler) extendIfSniped(ctx context.Context, tx pgx.Tx, st *auctionState, bidAt time.Time) (bool, error) {
+	window := h.Config.AntiSnipeWindow
+	extension := h.Config.AntiSnipeExtension
+	if !st.AntiSnipe || window <= 0 || extension <= 0 || st.EndTime.Sub(bidAt) > window {
+		return false, nil
+	}
+
+	newEnd := bidAt.Add(extension)
+	if limit := st.CreatedAt.Add(h.Config.AuctionMaxDuration); newEnd.After(limit) {
+		newEnd = limit
+	}
+	if !newEnd.After(st.EndTime) {
+		return false, nil
+	}
+
+	if err := updateAuctionVersioned(ctx, tx, st, `end_time = $3`, newEnd); err != nil {
+		return false, err
+	}
+	st.EndTime = newEnd
+	return true, nil
+}
+
+// AuctionExtendedPayload is broadcast when anti-sniping moves end_time.
+type AuctionExtendedPayload struct {
+	AuctionID string `json:"auction_id"`
+	EndTime   string `json:"end_time"`
+}
+
+// broadcastAuctionExtended tells the auction room about a new end_time so
+// clients can update their countdown. Call only after commit.
+func (h *AuctionHandler) broadcastAuctionExtended(st *auctionState) {
+	payload, _ := json.Marshal(AuctionExtendedPayload{
+		AuctionID: st.ID,
+		EndTime:   st.EndTime.UTC().Format(time.RFC3339),
+	})
+	h.Hub.BroadcastToAuction(st.ID, hub.Message{
+		Type:    hub.TypeAuctionExtended,
+		Payload: json.RawMessage(payload),
+	})
+}
@@ -0,0 +1,291 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/karti/orange-city-mart/backend/config"
+	"github.com/karti/orange-city-mart/backend/hub"
+)
+
+// bidOn locks the auction and applies one bid, failing the test on error.
+func bidOn(t *testing.T, tx pgx.Tx, auctionID, userID string, amount float64) {
+	t.Helper()
+	ctx := context.Background()
+	st, err := testHandler.lockAuction(ctx, tx, auctionID)
+	if err != nil {
+		t.Fatalf("lock auction: %v", err)
+	}
+	if _, err := testHandler.applyBid(ctx, tx, st, userID, amount); err != nil {
+		t.Fatalf("bid %.2f: %v", amount, err)
+	}
+}
+
+func softHolds(t *testing.T, tx pgx.Tx, auctionID, userID string) (count int, total float64) {
+	t.Helper()
+	err := tx.QueryRow(context.Background(), `
+		SELECT COUNT(*), COALESCE(SUM(amount), 0)::float8 FROM bid_holds
+		WHERE auction_id = $1 AND user_id = $2 AND status = 'SOFT'`,
+		auctionID, userID,
+	).Scan(&count, &total)
+	if err != nil {
+		t.Fatalf("read holds: %v", err)
+	}
+	return count, total
+}
+
+func expectBalance(t *testing.T, tx pgx.Tx, userID string, want float64) {
+	t.Helper()
+	if got := walletBalance(t, tx, userID); got != want {
+		t.Errorf("balance = %.2f, want %.2f", got, want)
+	}
+}
+
+func TestApplyBidInstantSelfRaiseThenOutbid(t *testing.T) {
+	tx := testTx(t)
+	seller := newTestUser(t, tx, 0)
+	a := newTestUser(t, tx, 1000)
+	b := newTestUser(t, tx, 1000)
+	auction := newTestAuction(t, tx, seller, refundInstant)
+
+	bidOn(t, tx, auction, a, 100)
+	expectBalance(t, tx, a, 900)
+
+	// Raising your own lead deducts only the difference.
+	bidOn(t, tx, auction, a, 150)
+	expectBalance(t, tx, a, 850)
+	if n, total := softHolds(t, tx, auction, a); n != 1 || total != 150 {
+		t.Fatalf("after self-raise: %d holds totalling %.2f, want 1 of 150", n, total)
+	}
+
+	// Being outbid refunds everything that was held.
+	bidOn(t, tx, auction, b, 200)
+	expectBalance(t, tx, a, 1000)
+	expectBalance(t, tx, b, 800)
+	if n, _ := softHolds(t, tx, auction, a); n != 0 {
+		t.Fatalf("outbid bidder still has %d SOFT holds", n)
+	}
+}
+
+func TestApplyBidAtEndRebidThenClose(t *testing.T) {
+	tx := testTx(t)
+	ctx := context.Background()
+	seller := newTestUser(t, tx, 0)
+	a := newTestUser(t, tx, 1000)
+	b := newTestUser(t, tx, 1000)
+	auction := newTestAuction(t, tx, seller, refundAtEnd)
+
+	bidOn(t, tx, auction, a, 100)
+	bidOn(t, tx, auction, a, 150)
+	bidOn(t, tx, auction, b, 200)
+	// AT_END keeps a's hold in place while they are outbid.
+	expectBalance(t, tx, a, 850)
+	expectBalance(t, tx, b, 800)
+
+	// Coming back tops the existing hold up instead of adding another.
+	bidOn(t, tx, auction, a, 250)
+	expectBalance(t, tx, a, 750)
+	if n, total := softHolds(t, tx, auction, a); n != 1 || total != 250 {
+		t.Fatalf("after re-bid: %d holds totalling %.2f, want 1 of 250", n, total)
+	}
+
+	if _, err := tx.Exec(ctx, `UPDATE auctions SET end_time = $2 WHERE id = $1`,
+		auction, time.Now().Add(-time.Second)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := testHandler.finishAuction(ctx, tx, auction); err != nil {
+		t.Fatalf("finish: %v", err)
+	}
+
+	// The loser gets everything back; the winner's single hold goes HARD.
+	expectBalance(t, tx, b, 1000)
+	expectBalance(t, tx, a, 750)
+	var hard float64
+	if err := tx.QueryRow(ctx, `
+		SELECT COALESCE(SUM(amount), 0)::float8 FROM bid_holds
+		WHERE auction_id = $1 AND user_id = $2 AND status = 'HARD'`, auction, a,
+	).Scan(&hard); err != nil {
+		t.Fatal(err)
+	}
+	if hard != 250 {
+		t.Errorf("winner's HARD hold = %.2f, want 250", hard)
+	}
+}
+
+func TestApplyBidRaiseNeedsOnlyTheDifference(t *testing.T) {
+	tx := testTx(t)
+	seller := newTestUser(t, tx, 0)
+	a := newTestUser(t, tx, 160)
+	auction := newTestAuction(t, tx, seller, refundInstant)
+
+	bidOn(t, tx, auction, a, 100)
+	// 60 left in the wallet covers a raise to 160 but not to 161.
+	st, err := testHandler.lockAuction(context.Background(), tx, auction)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := testHandler.applyBid(context.Background(), tx, st, a, 161); err != errInsufficientFunds {
+		t.Fatalf("raise to 161: err = %v, want errInsufficientFunds", err)
+	}
+	bidOn(t, tx, auction, a, 160)
+	expectBalance(t, tx, a, 0)
+}
+
+func TestUpdateAuctionVersionedRejectsStaleVersion(t *testing.T) {
+	tx := testTx(t)
+	ctx := context.Background()
+	seller := newTestUser(t, tx, 0)
+	auction := newTestAuction(t, tx, seller, refundInstant)
+
+	st, err := testHandler.lockAuction(ctx, tx, auction)
+	if err != nil {
+		t.Fatalf("lock auction: %v", err)
+	}
+	stale := *st
+
+	// A versioned write advances st; the copy taken before it is now stale.
+	if err := updateAuctionVersioned(ctx, tx, st, `start_price = $3`, 20); err != nil {
+		t.Fatalf("fresh write: %v", err)
+	}
+	if st.Version != stale.Version+1 {
+		t.Fatalf("version = %d after one write, want %d", st.Version, stale.Version+1)
+	}
+	if err := updateAuctionVersioned(ctx, tx, &stale, `status = 'CANCELLED'`); err != errAuctionConflict {
+		t.Fatalf("stale write: err = %v, want errAuctionConflict", err)
+	}
+	var status string
+	tx.QueryRow(ctx, `SELECT status FROM auctions WHERE id = $1`, auction).Scan(&status)
+	if status != "ACTIVE" {
+		t.Fatalf("stale write changed status to %s", status)
+	}
+}
+
+func TestClampAuctionEnd(t *testing.T) {
+	withMode := func(mode string) *Handler {
+		return newTestHandler(func(c *config.Config) {
+			c.AuctionMaxDuration = 7 * 24 * time.Hour
+			c.AuctionDurationMode = mode
+		})
+	}
+
+	start := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	limit := start.Add(7 * 24 * time.Hour)
+
+	for _, mode := range []string{"reject", "cap"} {
+		h := withMode(mode)
+		for _, end := range []time.Time{start.Add(time.Hour), limit} {
+			if got, err := h.clampAuctionEnd(start, end); err != nil || !got.Equal(end) {
+				t.Errorf("%s: clampAuctionEnd(%v) = %v, %v; want it unchanged", mode, end, got, err)
+			}
+		}
+	}
+
+	over := limit.Add(time.Second)
+	if _, err := withMode("reject").clampAuctionEnd(start, over); err != errAuctionTooLong {
+		t.Errorf("reject: err = %v, want errAuctionTooLong", err)
+	}
+	if got, err := withMode("cap").clampAuctionEnd(start, over); err != nil || !got.Equal(limit) {
+		t.Errorf("cap: clampAuctionEnd = %v, %v; want %v", got, err, limit)
+	}
+}
+
+// TestLateBidExtendsOnlyAntiSnipeAuctions checks that a bid inside
+// ANTI_SNIPE_WINDOW pushes end_time out when the auction allows it, and
+// leaves a hard deadline alone.
+func TestLateBidExtendsOnlyAntiSnipeAuctions(t *testing.T) {
+	tx := testTx(t)
+	ctx := context.Background()
+	seller := newTestUser(t, tx, 0)
+	bidder := newTestUser(t, tx, 1000)
+
+	for _, antiSnipe := range []bool{true, false} {
+		auction := newTestAuction(t, tx, seller, refundInstant)
+		end := time.Now().Add(testHandler.Config.AntiSnipeWindow / 2).Truncate(time.Microsecond)
+		if _, err := tx.Exec(ctx, `UPDATE auctions SET end_time = $2, anti_snipe = $3 WHERE id = $1`,
+			auction, end, antiSnipe); err != nil {
+			t.Fatal(err)
+		}
+
+		st, err := testHandler.lockAuction(ctx, tx, auction)
+		if err != nil {
+			t.Fatalf("lock auction: %v", err)
+		}
+		placed, err := testHandler.applyBid(ctx, tx, st, bidder, 100)
+		if err != nil {
+			t.Fatalf("bid: %v", err)
+		}
+		extended, err := testHandler.extendIfSniped(ctx, tx, st, placed.PlacedAt)
+		if err != nil {
+			t.Fatalf("extend: %v", err)
+		}
+
+		var stored time.Time
+		tx.QueryRow(ctx, `SELECT end_time FROM auctions WHERE id = $1`, auction).Scan(&stored)
+		if antiSnipe {
+			want := placed.PlacedAt.Add(testHandler.Config.AntiSnipeExtension)
+			if !extended || stored.Sub(want).Abs() > time.Millisecond {
+				t.Errorf("anti-snipe on: extended %t to %v, want %v", extended, stored, want)
+			}
+		} else if extended || !stored.Equal(end) {
+			t.Errorf("anti-snipe off: extended %t, end_time %v, want it left at %v", extended, stored, end)
+		}
+	}
+}
+
+// TestMinBidIntervalThrottlesOnlyTheSameBidder places two bids from one user
+// back to back on an auction with a minimum interval, then one from another
+// user, through PlaceBid.
+func TestMinBidIntervalThrottlesOnlyTheSameBidder(t *testing.T) {
+	requireDB(t)
+	ctx := context.Background()
+	seller := newTestUser(t, testPool, 0)
+	eager := newTestUser(t, testPool, 1000)
+	other := newTestUser(t, testPool, 1000)
+	t.Cleanup(func() {
+		testPool.Exec(ctx, `DELETE FROM users WHERE id IN ($1, $2, $3)`, seller, eager, other)
+	})
+	_, auctionID := newTestAuctionListing(t, seller)
+	if _, err := testPool.Exec(ctx, `UPDATE auctions SET min_bid_interval = 60 WHERE id = $1`, auctionID); err != nil {
+		t.Fatal(err)
+	}
+
+	h := &AuctionHandler{Handler: testHandler, Hub: hub.NewHub(nil)}
+	bid := func(userID string, amount float64) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(fmt.Sprintf(`{"amount": %v}`, amount)))
+		r = withURLParam(asUser(r, userID), "id", auctionID)
+		w := httptest.NewRecorder()
+		h.PlaceBid(w, r)
+		return w
+	}
+
+	if w := bid(eager, 20); w.Code != http.StatusOK {
+		t.Fatalf("first bid: status %d: %s", w.Code, w.Body)
+	}
+	w := bid(eager, 30)
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("repeat bid: status %d, want 429: %s", w.Code, w.Body)
+	}
+	if retry, _ := strconv.Atoi(w.Header().Get("Retry-After")); retry < 1 || retry > 60 {
+		t.Errorf("Retry-After = %q, want within the 60s interval", w.Header().Get("Retry-After"))
+	}
+	var body struct {
+		NextAllowedAt string `json:"next_allowed_at"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &body)
+	if next, err := time.Parse(time.RFC3339, body.NextAllowedAt); err != nil || time.Until(next) > time.Minute {
+		t.Errorf("next_allowed_at = %q, want a time within the interval", body.NextAllowedAt)
+	}
+
+	if w := bid(other, 30); w.Code != http.StatusOK {
+		t.Errorf("other bidder: status %d: %s", w.Code, w.Body)
+	}
+}
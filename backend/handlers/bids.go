@@ -11,10 +11,10 @@ import (
 
 // ListMyBids handles GET /api/bids (requires auth)
 // Returns all bids placed by the authenticated user, enriched with auction+product info.
-func ListMyBids(w http.ResponseWriter, r *http.Request) {
+func (h *Handler) ListMyBids(w http.ResponseWriter, r *http.Request) {
 	userID, ok := authmw.UserIDFromContext(r.Context())
 	if !ok {
-		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		writeError(w, http.StatusUnauthorized, "unauthorized", "unauthorized")
 		return
 	}
 
@@ -32,7 +32,7 @@ func ListMyBids(w http.ResponseWriter, r *http.Request) {
 		WHERE b.user_id = $1
 		ORDER BY b.created_at DESC`, userID)
 	if err != nil {
-		http.Error(w, "database error", http.StatusInternalServerError)
+		writeError(w, http.StatusInternalServerError, "database_error", "database error")
 		return
 	}
 	defer rows.Close()
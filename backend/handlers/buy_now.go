@@ -0,0 +1,170 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/jackc/pgx/v5"
+	"github.com/karti/orange-city-mart/backend/db"
+	authmw "github.com/karti/orange-city-mart/backend/middleware"
+)
+
+// ─────────────────────────────────────────────────────────────────────────────
+// BuyNow  POST /api/auctions/{id}/buynow
+//
+// Ends an auction immediately at its buy-now price:
+//  1. Release every SOFT hold (the current leader's included) with refunds.
+//  2. Deduct the buy-now price from the buyer and place a HARD hold for it.
+//  3. Record the purchase as the final bid and mark the auction ENDED.
+//  4. Create the PENDING settlement.
+//
+// Rejected once the high bid already exceeds the buy-now price.
+// ─────────────────────────────────────────────────────────────────────────────
+func (h *AuctionHandler) BuyNow(w http.ResponseWriter, r *http.Request) {
+	auctionID := chi.URLParam(r, "id")
+	buyerID, ok := authmw.UserIDFromContext(r.Context())
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "unauthorized", "unauthorized")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	tx, err := db.Pool.Begin(ctx)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "database_error", "database error")
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	st, err := h.lockAuction(ctx, tx, auctionID)
+	if err == pgx.ErrNoRows {
+		writeError(w, http.StatusNotFound, "auction_not_found", "auction not found")
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "database_error", "database error")
+		return
+	}
+	if st.Status != "ACTIVE" || time.Now().After(st.EndTime) {
+		writeError(w, http.StatusConflict, "auction_not_active", "auction is not active")
+		return
+	}
+	if st.BuyNowPrice == nil {
+		writeError(w, http.StatusConflict, "no_buy_now", "auction has no buy-now price")
+		return
+	}
+	price := *st.BuyNowPrice
+	if st.HighBid > price {
+		writeError(w, http.StatusConflict, "buy_now_exceeded", "current bid already exceeds the buy-now price")
+		return
+	}
+	if st.SellerID == buyerID {
+		writeError(w, http.StatusForbidden, "own_listing", "you cannot buy your own listing")
+		return
+	}
+
+	// ── Release outstanding holds ──────────────────────────────────────────
+	if err = releaseSoftHolds(ctx, tx, auctionID); err != nil {
+		writeError(w, http.StatusInternalServerError, "database_error", "database error")
+		return
+	}
+
+	// ── Charge the buyer (after any refund of their own holds) ─────────────
+	var balance float64
+	err = tx.QueryRow(ctx,
+		`SELECT wallet_balance FROM users WHERE id = $1 FOR UPDATE`, buyerID,
+	).Scan(&balance)
+	if err == pgx.ErrNoRows {
+		writeError(w, http.StatusNotFound, "user_not_found", "user not found")
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "database_error", "database error")
+		return
+	}
+	if balance < price {
+		writeError(w, http.StatusPaymentRequired, "insufficient_balance", "insufficient wallet balance")
+		return
+	}
+	if ok, exposure, limit, err := h.checkExposure(ctx, tx, buyerID, price); err != nil {
+		writeError(w, http.StatusInternalServerError, "database_error", "database error")
+		return
+	} else if !ok {
+		writeExposureExceeded(w, exposure, limit)
+		return
+	}
+	_, err = tx.Exec(ctx,
+		`UPDATE users SET wallet_balance = wallet_balance - $1 WHERE id = $2`,
+		price, buyerID,
+	)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "database_error", "database error")
+		return
+	}
+	_, err = tx.Exec(ctx, `
+		INSERT INTO transactions (user_id, amount, type, status, reference)
+		VALUES ($1, $2, 'BID_HOLD', 'COMPLETED', $3)`,
+		buyerID, price, auctionID,
+	)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "database_error", "database error")
+		return
+	}
+	_, err = tx.Exec(ctx, `
+		INSERT INTO bid_holds (auction_id, user_id, amount, status)
+		VALUES ($1, $2, $3, 'HARD')`,
+		auctionID, buyerID, price,
+	)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "database_error", "database error")
+		return
+	}
+
+	// ── End the auction ────────────────────────────────────────────────────
+	_, err = tx.Exec(ctx, `
+		INSERT INTO bids (auction_id, user_id, amount) VALUES ($1, $2, $3)`,
+		auctionID, buyerID, price,
+	)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "database_error", "database error")
+		return
+	}
+	err = updateAuctionVersioned(ctx, tx, st,
+		`status = 'ENDED', current_highest_bid = $3, highest_bidder_id = $4, end_time = NOW()`,
+		price, buyerID)
+	if err != nil {
+		writeAuctionError(w, err)
+		return
+	}
+	_, err = tx.Exec(ctx, `
+		INSERT INTO settlements (auction_id, winner_id, seller_id, amount, expires_at)
+		VALUES ($1, $2, $3, $4, $5)`,
+		auctionID, buyerID, st.SellerID, price, time.Now().Add(st.SettlementWindow),
+	)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "database_error", "database error")
+		return
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		writeError(w, http.StatusInternalServerError, "database_error", "commit failed")
+		return
+	}
+
+	h.broadcastAuctionEnded(auctionOutcome{
+		AuctionID:  auctionID,
+		Status:     "ENDED",
+		WinnerID:   &buyerID,
+		FinalPrice: price,
+	})
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"success":     true,
+		"auction_id":  auctionID,
+		"final_price": price,
+	})
+}
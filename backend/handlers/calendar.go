@@ -0,0 +1,113 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/karti/orange-city-mart/backend/db"
+)
+
+const (
+	maxCalendarRange = 31 * 24 * time.Hour
+	maxCalendarItems = 500
+)
+
+// ─────────────────────────────────────────────────────────────────────────────
+// GetAuctionCalendar  GET /api/auctions/calendar?from=&to=
+//
+// Returns ACTIVE auctions ending within [from, to), grouped by the UTC day of
+// their end_time. Dates are YYYY-MM-DD or RFC3339; from defaults to today and
+// to to a week later. The range is capped at 31 days.
+// ─────────────────────────────────────────────────────────────────────────────
+func (h *AuctionHandler) GetAuctionCalendar(w http.ResponseWriter, r *http.Request) {
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+
+	from, ok := parseDateParam(r.URL.Query().Get("from"), today)
+	if !ok {
+		writeError(w, http.StatusBadRequest, "invalid_from", "invalid from date")
+		return
+	}
+	to, ok := parseDateParam(r.URL.Query().Get("to"), from.Add(7*24*time.Hour))
+	if !ok {
+		writeError(w, http.StatusBadRequest, "invalid_to", "invalid to date")
+		return
+	}
+	if !to.After(from) {
+		writeError(w, http.StatusBadRequest, "invalid_date_range", "to must be after from")
+		return
+	}
+	if to.Sub(from) > maxCalendarRange {
+		writeError(w, http.StatusBadRequest, "invalid_date_range", "date range may not exceed 31 days")
+		return
+	}
+
+	ctx := r.Context()
+	rows, err := db.Pool.Query(ctx, `
+		SELECT a.id, a.current_highest_bid, a.end_time,
+		       p.id, p.title, p.image_url, p.category
+		FROM auctions a
+		JOIN products p ON p.id = a.product_id
+		WHERE a.status = 'ACTIVE' AND a.end_time >= $1 AND a.end_time < $2
+		ORDER BY a.end_time ASC
+		LIMIT $3`,
+		from, to, maxCalendarItems,
+	)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "database_error", "database error")
+		return
+	}
+	defer rows.Close()
+
+	type CalendarItem struct {
+		AuctionID  string  `json:"auction_id"`
+		ProductID  string  `json:"product_id"`
+		Title      string  `json:"title"`
+		ImageURL   *string `json:"image_url"`
+		Category   *string `json:"category"`
+		CurrentBid float64 `json:"current_bid"`
+		EndTime    string  `json:"end_time"`
+	}
+	type CalendarDay struct {
+		Date     string         `json:"date"`
+		Auctions []CalendarItem `json:"auctions"`
+	}
+
+	days := []CalendarDay{}
+	for rows.Next() {
+		var it CalendarItem
+		var endTime time.Time
+		if err := rows.Scan(&it.AuctionID, &it.CurrentBid, &endTime,
+			&it.ProductID, &it.Title, &it.ImageURL, &it.Category); err != nil {
+			continue
+		}
+		it.EndTime = endTime.UTC().Format(time.RFC3339)
+
+		// Rows arrive ordered by end_time, so a new day starts a new bucket.
+		day := endTime.UTC().Format("2006-01-02")
+		if len(days) == 0 || days[len(days)-1].Date != day {
+			days = append(days, CalendarDay{Date: day})
+		}
+		days[len(days)-1].Auctions = append(days[len(days)-1].Auctions, it)
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"from": from.Format(time.RFC3339),
+		"to":   to.Format(time.RFC3339),
+		"days": days,
+	})
+}
+
+// parseDateParam parses a YYYY-MM-DD or RFC3339 query value, returning def
+// when the value is empty. The bool is false if the value is malformed.
+func parseDateParam(v string, def time.Time) (time.Time, bool) {
+	if v == "" {
+		return def, true
+	}
+	if t, err := time.Parse("2006-01-02", v); err == nil {
+		return t, true
+	}
+	if t, err := time.Parse(time.RFC3339, v); err == nil {
+		return t.UTC(), true
+	}
+	return time.Time{}, false
+}
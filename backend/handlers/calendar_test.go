@@ -0,0 +1,93 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+)
+
+// getCalendar calls GetAuctionCalendar with the raw query string.
+func getCalendar(query string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	h := &AuctionHandler{Handler: testHandler}
+	h.GetAuctionCalendar(w, httptest.NewRequest(http.MethodGet, "/api/auctions/calendar?"+query, nil))
+	return w
+}
+
+func TestCalendarRejectsBadRanges(t *testing.T) {
+	for _, query := range []string{
+		"from=2026-03-10&to=2026-03-10",
+		"from=2026-03-10&to=2026-03-01",
+		"from=2026-03-01&to=2026-04-02",
+		"from=tomorrow",
+	} {
+		if w := getCalendar(query); w.Code != http.StatusBadRequest {
+			t.Errorf("%s: status %d, want 400", query, w.Code)
+		}
+	}
+}
+
+// TestCalendarBucketsByEndDay seeds auctions around a UTC midnight and checks
+// each lands in the bucket for the day it ends, with ended auctions and those
+// outside the range left out.
+func TestCalendarBucketsByEndDay(t *testing.T) {
+	requireDB(t)
+	ctx := context.Background()
+	seller := newTestUser(t, testPool, 0)
+	t.Cleanup(func() { testPool.Exec(ctx, `DELETE FROM users WHERE id = $1`, seller) })
+
+	// Far enough ahead that no other fixture ends in the range.
+	day := time.Date(2091, 3, 14, 0, 0, 0, 0, time.UTC)
+	endingAt := func(end time.Time, status string) string {
+		t.Helper()
+		_, auctionID := newTestAuctionListing(t, seller)
+		if _, err := testPool.Exec(ctx, `UPDATE auctions SET end_time = $1, status = $2 WHERE id = $3`,
+			end, status, auctionID); err != nil {
+			t.Fatal(err)
+		}
+		return auctionID
+	}
+	morning := endingAt(day.Add(10*time.Hour), "ACTIVE")
+	lateNight := endingAt(day.Add(23*time.Hour+59*time.Minute), "ACTIVE")
+	nextDay := endingAt(day.Add(24*time.Hour+15*time.Minute), "ACTIVE")
+	endingAt(day.Add(12*time.Hour), "CANCELLED")
+	endingAt(day.Add(3*24*time.Hour), "ACTIVE")
+
+	w := getCalendar("from=2091-03-14&to=2091-03-16")
+	if w.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", w.Code, w.Body)
+	}
+	var body struct {
+		Days []struct {
+			Date     string `json:"date"`
+			Auctions []struct {
+				AuctionID string `json:"auction_id"`
+			} `json:"auctions"`
+		} `json:"days"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+	got := map[string][]string{}
+	for _, d := range body.Days {
+		for _, a := range d.Auctions {
+			got[d.Date] = append(got[d.Date], a.AuctionID)
+		}
+	}
+	want := map[string][]string{
+		"2091-03-14": {morning, lateNight},
+		"2091-03-15": {nextDay},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("days = %v, want %v", got, want)
+	}
+	for date, ids := range want {
+		if !slices.Equal(got[date], ids) {
+			t.Errorf("%s: %v, want %v", date, got[date], ids)
+		}
+	}
+}
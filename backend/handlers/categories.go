@@ -0,0 +1,76 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/karti/orange-city-mart/backend/db"
+)
+
+// categoriesTTL bounds how stale the cached category list may get. Product
+// writes invalidate it early.
+const categoriesTTL = 5 * time.Minute
+
+var categoryCache struct {
+	mu      sync.Mutex
+	body    []byte
+	expires time.Time
+}
+
+// invalidateCategories drops the cached GetCategories response.
+func invalidateCategories() {
+	categoryCache.mu.Lock()
+	categoryCache.body = nil
+	categoryCache.mu.Unlock()
+}
+
+// ── Categories ────────────────────────────────────────────────────────────────
+// GET /api/categories
+// Distinct product categories with the number of active listings in each:
+// fixed-price products plus auctions that are still running.
+func (h *Handler) GetCategories(w http.ResponseWriter, r *http.Request) {
+	categoryCache.mu.Lock()
+	body, fresh := categoryCache.body, time.Now().Before(categoryCache.expires)
+	categoryCache.mu.Unlock()
+
+	if body == nil || !fresh {
+		rows, err := db.Pool.Query(r.Context(), `
+			SELECT p.category, COUNT(*)
+			FROM products p
+			WHERE p.deleted_at IS NULL
+			  AND p.category IS NOT NULL
+			  AND ((p.type = 'FIXED' AND p.status = 'AVAILABLE') OR EXISTS (
+			      SELECT 1 FROM auctions a WHERE a.product_id = p.id AND a.status = 'ACTIVE'))
+			GROUP BY p.category
+			ORDER BY p.category`)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "database_error", "database error")
+			return
+		}
+		defer rows.Close()
+
+		type Category struct {
+			Name  string `json:"name"`
+			Count int    `json:"count"`
+		}
+		categories := []Category{}
+		for rows.Next() {
+			var c Category
+			if err := rows.Scan(&c.Name, &c.Count); err != nil {
+				continue
+			}
+			categories = append(categories, c)
+		}
+
+		body, _ = json.Marshal(categories)
+		categoryCache.mu.Lock()
+		categoryCache.body = body
+		categoryCache.expires = time.Now().Add(categoriesTTL)
+		categoryCache.mu.Unlock()
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(body)
+}
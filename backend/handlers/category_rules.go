@@ -0,0 +1,64 @@
+package handlers
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// querier is satisfied by both *pgxpool.Pool and pgx.Tx.
+type querier interface {
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
+// categoryRules is the policy applied to listings in one category. Any field
+// left NULL in the category_rules table falls back to the global default.
+type categoryRules struct {
+	MinIncrement     float64
+	ListingFee       float64
+	SettlementWindow time.Duration
+}
+
+// defaultCategoryRules returns the global policy from the startup
+// configuration.
+func (h *Handler) defaultCategoryRules() categoryRules {
+	return categoryRules{
+		MinIncrement:     h.Config.MinBidIncrement,
+		ListingFee:       h.roundMoney(h.Config.ListingFee),
+		SettlementWindow: h.Config.SettlementWindow,
+	}
+}
+
+// loadCategoryRules resolves the effective rules for a category, overlaying any
+// per-category overrides on the global defaults.
+func (h *Handler) loadCategoryRules(ctx context.Context, q querier, category string) (categoryRules, error) {
+	rules := h.defaultCategoryRules()
+
+	var (
+		minIncrement *float64
+		listingFee   *float64
+		windowSecs   *float64
+	)
+	err := q.QueryRow(ctx, `
+		SELECT min_increment, listing_fee, EXTRACT(EPOCH FROM settlement_window)::float8
+		FROM category_rules WHERE category = $1`, category,
+	).Scan(&minIncrement, &listingFee, &windowSecs)
+	if err == pgx.ErrNoRows {
+		return rules, nil
+	}
+	if err != nil {
+		return rules, err
+	}
+
+	if minIncrement != nil {
+		rules.MinIncrement = *minIncrement
+	}
+	if listingFee != nil {
+		rules.ListingFee = *listingFee
+	}
+	if windowSecs != nil {
+		rules.SettlementWindow = time.Duration(*windowSecs * float64(time.Second))
+	}
+	return rules, nil
+}
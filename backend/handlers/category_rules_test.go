@@ -0,0 +1,74 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// TestCategoryIncrementOverridesDefault checks that a category_rules
+// min_increment is what PlaceBid enforces, while a category without a row
+// keeps the configured default.
+func TestCategoryIncrementOverridesDefault(t *testing.T) {
+	requireDB(t)
+	ctx := context.Background()
+	seller := newTestUser(t, testPool, 0)
+	leader := newTestUser(t, testPool, 1000)
+	bidder := newTestUser(t, testPool, 1000)
+	category := "test-" + seller
+	t.Cleanup(func() {
+		testPool.Exec(ctx, `DELETE FROM category_rules WHERE category = $1`, category)
+		testPool.Exec(ctx, `DELETE FROM users WHERE id IN ($1, $2, $3)`, seller, leader, bidder)
+	})
+	if _, err := testPool.Exec(ctx,
+		`INSERT INTO category_rules (category, min_increment) VALUES ($1, 25)`, category); err != nil {
+		t.Fatal(err)
+	}
+
+	tx, err := testPool.Begin(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	custom := newTestAuction(t, tx, seller, "")
+	plain := newTestAuction(t, tx, seller, "")
+	if _, err := tx.Exec(ctx, `
+		UPDATE products SET category = $1
+		WHERE id = (SELECT product_id FROM auctions WHERE id = $2)`, category, custom); err != nil {
+		t.Fatal(err)
+	}
+	bidOn(t, tx, custom, leader, 100)
+	for id, want := range map[string]float64{custom: 25, plain: testHandler.Config.MinBidIncrement} {
+		st, err := testHandler.lockAuction(ctx, tx, id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if st.MinIncrement != want {
+			t.Errorf("auction %s: min increment = %v, want %v", id, st.MinIncrement, want)
+		}
+	}
+	if err := tx.Commit(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	// A raise that clears the default but not the category's increment.
+	h := &AuctionHandler{Handler: testHandler}
+	r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"amount": 110}`))
+	r = withURLParam(asUser(r, bidder), "id", custom)
+	w := httptest.NewRecorder()
+	h.PlaceBid(w, r)
+	if w.Code != http.StatusConflict {
+		t.Fatalf("status %d, want 409: %s", w.Code, w.Body)
+	}
+	var body struct {
+		MinNextBid float64 `json:"min_next_bid"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+	if body.MinNextBid != 125 {
+		t.Errorf("min_next_bid = %v, want 125", body.MinNextBid)
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"time"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
 	"github.com/karti/orange-city-mart/backend/db"
 	"github.com/karti/orange-city-mart/backend/hub"
@@ -17,6 +18,7 @@ import (
 
 // ChatHandler needs the hub to broadcast messages in real-time.
 type ChatHandler struct {
+	*Handler
 	Hub *hub.Hub
 }
 
@@ -28,19 +30,32 @@ func roomID(a, b string) string {
 }
 
 // ─────────────────────────────────────────────────────────────────────────────
-// GetConversations  GET /api/chat/conversations
+// GetConversations  GET /api/chat/conversations?product_id=
 //
 // Returns all rooms the caller has exchanged messages with, including the
-// other party's name and a preview of the last message.
+// other party's name, a preview of the last message and how many of the other
+// party's messages arrived since the caller last read the room, and whether
+// the other party is connected right now. Rooms the caller has hidden are
+// skipped unless a message arrived after they were hidden. product_id keeps
+// only rooms with at least one message about that listing.
 // ─────────────────────────────────────────────────────────────────────────────
 func (h *ChatHandler) GetConversations(w http.ResponseWriter, r *http.Request) {
 	callerID, ok := authmw.UserIDFromContext(r.Context())
 	if !ok {
-		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		writeError(w, http.StatusUnauthorized, "unauthorized", "unauthorized")
 		return
 	}
 	ctx := r.Context()
 
+	var productID *string
+	if v := r.URL.Query().Get("product_id"); v != "" {
+		if _, err := uuid.Parse(v); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid_product_id", "invalid product_id")
+			return
+		}
+		productID = &v
+	}
+
 	type Conversation struct {
 		RoomID       string  `json:"room_id"`
 		OtherUserID  string  `json:"other_user_id"`
@@ -49,34 +64,56 @@ func (h *ChatHandler) GetConversations(w http.ResponseWriter, r *http.Request) {
 		LastImageURL *string `json:"last_image_url"`
 		LastAt       string  `json:"last_at"`
 		UnreadCount  int     `json:"unread_count"`
+		OtherOnline  bool    `json:"other_online"`
 	}
 
 	// Find all rooms for this caller, get the latest message per room,
-	// and resolve the other party's name.
+	// and resolve the other party's name. $1 is referenced several times, so
+	// each use carries an explicit ::text cast: under extended protocol the
+	// server must deduce one consistent type for it, and under the pooler's
+	// simple protocol it is interpolated as a quoted literal either way.
 	rows, err := db.Pool.Query(ctx, `
 		WITH latest AS (
 			SELECT DISTINCT ON (room_id)
 			       room_id, body, image_url, created_at
 			FROM messages
-			WHERE room_id LIKE '%' || $1 || '%'
-			ORDER BY room_id, created_at DESC
+			WHERE room_id LIKE '%' || $1::text || '%'
+			ORDER BY room_id, created_at DESC, seq DESC
 		)
 		SELECT l.room_id, l.body, l.image_url, l.created_at,
-		       u.id, u.name
+		       u.id, u.name,
+		       (SELECT COUNT(*) FROM messages m
+		        WHERE m.room_id = l.room_id
+		          AND m.sender_id::text <> $1::text
+		          AND m.created_at > COALESCE(rr.last_read_at, '-infinity'::timestamptz))
 		FROM latest l
 		JOIN users u ON (
 		    -- derive the other user ID from the room_id string
 		    u.id::text = CASE
-		        WHEN split_part(l.room_id, '_', 1) = $1
+		        WHEN split_part(l.room_id, '_', 1) = $1::text
 		            THEN split_part(l.room_id, '_', 2)
 		        ELSE split_part(l.room_id, '_', 1)
 		    END
 		)
+		-- rooms the caller hid stay hidden until a newer message arrives
+		LEFT JOIN conversation_hides ch
+		       ON ch.room_id = l.room_id AND ch.user_id::text = $1::text
+		LEFT JOIN room_reads rr
+		       ON rr.room_id = l.room_id AND rr.user_id::text = $1::text
+		WHERE (ch.hidden_at IS NULL OR l.created_at > ch.hidden_at)
+		  -- conversations with users the caller blocked are left out
+		  AND NOT EXISTS (
+		      SELECT 1 FROM user_blocks b
+		      WHERE b.blocker_id::text = $1::text AND b.blocked_id = u.id)
+		  -- optionally only rooms where some message is about the product
+		  AND ($2::uuid IS NULL OR EXISTS (
+		      SELECT 1 FROM messages pm
+		      WHERE pm.room_id = l.room_id AND pm.product_id = $2::uuid))
 		ORDER BY l.created_at DESC`,
-		callerID,
+		callerID, productID,
 	)
 	if err != nil {
-		http.Error(w, "database error", http.StatusInternalServerError)
+		writeError(w, http.StatusInternalServerError, "database_error", "database error")
 		return
 	}
 	defer rows.Close()
@@ -86,11 +123,12 @@ func (h *ChatHandler) GetConversations(w http.ResponseWriter, r *http.Request) {
 		var c Conversation
 		var lastAt time.Time
 		err := rows.Scan(&c.RoomID, &c.LastBody, &c.LastImageURL, &lastAt,
-			&c.OtherUserID, &c.OtherName)
+			&c.OtherUserID, &c.OtherName, &c.UnreadCount)
 		if err != nil {
 			continue
 		}
 		c.LastAt = lastAt.UTC().Format(time.RFC3339)
+		c.OtherOnline = h.Hub.IsOnline(c.OtherUserID)
 		convos = append(convos, c)
 	}
 	if convos == nil {
@@ -104,40 +142,61 @@ func (h *ChatHandler) GetConversations(w http.ResponseWriter, r *http.Request) {
 // ─────────────────────────────────────────────────────────────────────────────
 // GetMessages  GET /api/chat/rooms/{roomId}/messages
 //
-// Returns the last 50 messages for a room, oldest-first.
+// Returns up to 50 messages for a room, oldest-first, with has_more set when
+// older ones exist. Pass before=<message id or RFC3339 timestamp> (normally
+// the oldest id already loaded) to page back through history.
 // Validates that the caller is a member of the room.
 // ─────────────────────────────────────────────────────────────────────────────
 func (h *ChatHandler) GetMessages(w http.ResponseWriter, r *http.Request) {
 	callerID, ok := authmw.UserIDFromContext(r.Context())
 	if !ok {
-		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		writeError(w, http.StatusUnauthorized, "unauthorized", "unauthorized")
 		return
 	}
 	rid := chi.URLParam(r, "roomId")
 
 	// Security: caller must be one of the two members of the room.
 	if !strings.Contains(rid, callerID) {
-		http.Error(w, "forbidden", http.StatusForbidden)
+		writeError(w, http.StatusForbidden, "forbidden", "forbidden")
 		return
 	}
 
 	ctx := r.Context()
 
+	// The cursor is either a message id, compared on (created_at, seq) so
+	// messages sharing a timestamp aren't skipped, or a bare timestamp.
+	before := r.URL.Query().Get("before")
+	var beforeID, beforeAt *string
+	if before != "" {
+		if _, err := uuid.Parse(before); err == nil {
+			beforeID = &before
+		} else if _, err := time.Parse(time.RFC3339Nano, before); err == nil {
+			beforeAt = &before
+		} else {
+			writeError(w, http.StatusBadRequest, "invalid_before", "before must be a message id or RFC3339 timestamp")
+			return
+		}
+	}
+
 	rows, err := db.Pool.Query(ctx, `
 		SELECT m.id, m.sender_id, u.name AS sender_name,
-		       m.body, m.image_url, m.created_at
+		       m.body, m.image_url, m.created_at, m.seq, m.edited_at, m.deleted_at,
+		       m.product_id
 		FROM (
 		    SELECT * FROM messages
-		    WHERE room_id = $1
-		    ORDER BY created_at DESC
-		    LIMIT 50
+		    WHERE room_id = $1::text
+		      AND ($2::uuid IS NULL OR (created_at, seq) <
+		           (SELECT c.created_at, c.seq FROM messages c WHERE c.id = $2::uuid AND c.room_id = $1::text))
+		      AND ($3::timestamptz IS NULL OR created_at < $3::timestamptz)
+		    ORDER BY created_at DESC, seq DESC
+		    LIMIT $4
 		) m
 		JOIN users u ON u.id = m.sender_id
-		ORDER BY m.created_at ASC`,
-		rid,
+		ORDER BY m.created_at ASC, m.seq ASC`,
+		rid, beforeID, beforeAt, chatPageSize+1,
 	)
 	if err != nil {
-		http.Error(w, "database error", http.StatusInternalServerError)
+		writeError(w, http.StatusInternalServerError, "database_error", "database error")
 		return
 	}
 	defer rows.Close()
@@ -149,86 +208,149 @@ func (h *ChatHandler) GetMessages(w http.ResponseWriter, r *http.Request) {
 		Body       *string `json:"body"`
 		ImageURL   *string `json:"image_url"`
 		CreatedAt  string  `json:"created_at"`
+		Seq        int64   `json:"seq"`
+		EditedAt   *string `json:"edited_at"`
+		Deleted    bool    `json:"deleted"`
+		ProductID  *string `json:"product_id"`
 	}
 
 	var msgs []Msg
 	for rows.Next() {
 		var m Msg
 		var createdAt time.Time
+		var editedAt, deletedAt *time.Time
 		if err := rows.Scan(&m.ID, &m.SenderID, &m.SenderName,
-			&m.Body, &m.ImageURL, &createdAt); err != nil {
+			&m.Body, &m.ImageURL, &createdAt, &m.Seq, &editedAt, &deletedAt, &m.ProductID); err != nil {
 			continue
 		}
-		m.CreatedAt = createdAt.UTC().Format(time.RFC3339)
+		m.CreatedAt = createdAt.UTC().Format(time.RFC3339Nano)
+		if editedAt != nil {
+			s := editedAt.UTC().Format(time.RFC3339)
+			m.EditedAt = &s
+		}
+		m.Deleted = deletedAt != nil
 		msgs = append(msgs, m)
 	}
 	if msgs == nil {
 		msgs = []Msg{}
 	}
 
+	// One extra row was fetched to learn whether older history exists; it is
+	// the oldest, so it sits at the front.
+	hasMore := len(msgs) > chatPageSize
+	if hasMore {
+		msgs = msgs[1:]
+	}
+
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(msgs)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"messages": msgs,
+		"has_more": hasMore,
+	})
 }
 
+// chatPageSize is how many messages GetMessages returns per call.
+const chatPageSize = 50
+
 // ─────────────────────────────────────────────────────────────────────────────
 // SendMessage  POST /api/chat/rooms/{roomId}/messages
 //
 // Persists a message (text body or image_url) and broadcasts it to all
-// WebSocket clients in the room.
+// WebSocket clients in the room. An optional product_id tags the message
+// with the listing it is about; it must be sold by one of the two members.
 // ─────────────────────────────────────────────────────────────────────────────
 func (h *ChatHandler) SendMessage(w http.ResponseWriter, r *http.Request) {
 	callerID, ok := authmw.UserIDFromContext(r.Context())
 	if !ok {
-		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		writeError(w, http.StatusUnauthorized, "unauthorized", "unauthorized")
 		return
 	}
 	rid := chi.URLParam(r, "roomId")
 
 	if !strings.Contains(rid, callerID) {
-		http.Error(w, "forbidden", http.StatusForbidden)
+		writeError(w, http.StatusForbidden, "forbidden", "forbidden")
 		return
 	}
 
 	var req struct {
-		Body     *string `json:"body"`
-		ImageURL *string `json:"image_url"`
+		Body      *string `json:"body"`
+		ImageURL  *string `json:"image_url"`
+		ProductID *string `json:"product_id"` // listing the message is about
+		TempID    string  `json:"temp_id"`    // client's optimistic id, echoed back
 	}
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "invalid request body", http.StatusBadRequest)
+		writeError(w, http.StatusBadRequest, "invalid_body", "invalid request body")
 		return
 	}
 	if req.Body == nil && req.ImageURL == nil {
-		http.Error(w, "body or image_url required", http.StatusBadRequest)
+		writeError(w, http.StatusBadRequest, "empty_message", "body or image_url required")
 		return
 	}
+	if req.ProductID != nil {
+		if _, err := uuid.Parse(*req.ProductID); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid_product_id", "invalid product_id")
+			return
+		}
+	}
 
 	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
 	defer cancel()
 
+	// A message can only be about a listing sold by one of the room's members.
+	if req.ProductID != nil {
+		var sellerID string
+		err := db.Pool.QueryRow(ctx,
+			`SELECT seller_id FROM products WHERE id = $1 AND deleted_at IS NULL`, *req.ProductID,
+		).Scan(&sellerID)
+		if err == pgx.ErrNoRows {
+			writeError(w, http.StatusNotFound, "product_not_found", "product not found")
+			return
+		}
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "database_error", "database error")
+			return
+		}
+		if sellerID != callerID && sellerID != hub.RoomPeer(rid, callerID) {
+			writeError(w, http.StatusBadRequest, "product_not_in_room", "product is not sold by a member of this room")
+			return
+		}
+	}
+
 	// Fetch sender name for the WS broadcast payload.
 	var senderName string
 	err := db.Pool.QueryRow(ctx, `SELECT name FROM users WHERE id = $1`, callerID).
 		Scan(&senderName)
 	if err == pgx.ErrNoRows {
-		http.Error(w, "user not found", http.StatusNotFound)
+		writeError(w, http.StatusNotFound, "user_not_found", "user not found")
 		return
 	}
 	if err != nil {
-		http.Error(w, "database error", http.StatusInternalServerError)
+		writeError(w, http.StatusInternalServerError, "database_error", "database error")
 		return
 	}
 
-	// Persist the message.
+	// Persist the message, unless the recipient has blocked the sender.
 	var msgID string
 	var createdAt time.Time
+	var seq int64
+	blocked := false
 	err = db.Pool.QueryRow(ctx, `
-		INSERT INTO messages (room_id, sender_id, body, image_url)
-		VALUES ($1, $2, $3, $4)
-		RETURNING id, created_at`,
-		rid, callerID, req.Body, req.ImageURL,
-	).Scan(&msgID, &createdAt)
+		INSERT INTO messages (room_id, sender_id, body, image_url, product_id)
+		SELECT $1::text, $2::uuid, $3::text, $4::text, $6::uuid
+		WHERE NOT EXISTS (
+		    SELECT 1 FROM user_blocks
+		    WHERE blocker_id::text = $5::text AND blocked_id = $2::uuid)
+		RETURNING id, created_at, seq`,
+		rid, callerID, req.Body, req.ImageURL, hub.RoomPeer(rid, callerID), req.ProductID,
+	).Scan(&msgID, &createdAt, &seq)
+	if err == pgx.ErrNoRows {
+		// Answer as if it was sent so the block isn't revealed.
+		blocked = true
+		msgID, createdAt = uuid.NewString(), time.Now()
+		seq, err = hub.NextMessageSeq(ctx, db.Pool)
+	}
 	if err != nil {
-		http.Error(w, "database error", http.StatusInternalServerError)
+		writeError(w, http.StatusInternalServerError, "database_error", "database error")
 		return
 	}
 
@@ -240,6 +362,9 @@ func (h *ChatHandler) SendMessage(w http.ResponseWriter, r *http.Request) {
 		Body       *string `json:"body"`
 		ImageURL   *string `json:"image_url"`
 		CreatedAt  string  `json:"created_at"`
+		Seq        int64   `json:"seq"`
+		ProductID  *string `json:"product_id"`
+		TempID     string  `json:"temp_id,omitempty"`
 	}
 
 	payload := ChatMsgPayload{
@@ -249,15 +374,96 @@ func (h *ChatHandler) SendMessage(w http.ResponseWriter, r *http.Request) {
 		SenderName: senderName,
 		Body:       req.Body,
 		ImageURL:   req.ImageURL,
-		CreatedAt:  createdAt.UTC().Format(time.RFC3339),
+		CreatedAt:  createdAt.UTC().Format(time.RFC3339Nano),
+		Seq:        seq,
+		ProductID:  req.ProductID,
+		TempID:     req.TempID,
+	}
+	if !blocked {
+		payloadBytes, _ := json.Marshal(payload)
+		h.Hub.BroadcastToChat(rid, hub.Message{
+			Type:    hub.TypeChatMessage,
+			Payload: json.RawMessage(payloadBytes),
+		})
 	}
-	payloadBytes, _ := json.Marshal(payload)
-	h.Hub.BroadcastToChat(rid, hub.Message{
-		Type:    hub.TypeChatMessage,
-		Payload: json.RawMessage(payloadBytes),
-	})
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusCreated)
 	json.NewEncoder(w).Encode(payload)
 }
+
+// ─────────────────────────────────────────────────────────────────────────────
+// HideConversation  POST /api/chat/rooms/{roomId}/hide
+//
+// Removes a room from the caller's conversation list without affecting the
+// other participant. The next message in the room brings it back.
+// ─────────────────────────────────────────────────────────────────────────────
+func (h *ChatHandler) HideConversation(w http.ResponseWriter, r *http.Request) {
+	callerID, ok := authmw.UserIDFromContext(r.Context())
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "unauthorized", "unauthorized")
+		return
+	}
+	rid := chi.URLParam(r, "roomId")
+
+	if !strings.Contains(rid, callerID) {
+		writeError(w, http.StatusForbidden, "forbidden", "forbidden")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	_, err := db.Pool.Exec(ctx, `
+		INSERT INTO conversation_hides (room_id, user_id, hidden_at)
+		VALUES ($1, $2, NOW())
+		ON CONFLICT (room_id, user_id) DO UPDATE SET hidden_at = NOW()`,
+		rid, callerID,
+	)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "database_error", "database error")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]bool{"success": true})
+}
+
+// ─────────────────────────────────────────────────────────────────────────────
+// MarkRoomRead  POST /api/chat/rooms/{roomId}/read
+//
+// Marks everything in the room up to its latest message as read by the
+// caller, resetting that room's unread_count. The read position never moves
+// backwards.
+// ─────────────────────────────────────────────────────────────────────────────
+func (h *ChatHandler) MarkRoomRead(w http.ResponseWriter, r *http.Request) {
+	callerID, ok := authmw.UserIDFromContext(r.Context())
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "unauthorized", "unauthorized")
+		return
+	}
+	rid := chi.URLParam(r, "roomId")
+
+	if !strings.Contains(rid, callerID) {
+		writeError(w, http.StatusForbidden, "forbidden", "forbidden")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	_, err := db.Pool.Exec(ctx, `
+		INSERT INTO room_reads (room_id, user_id, last_read_at)
+		SELECT $1::text, $2::uuid, COALESCE(MAX(created_at), NOW()) FROM messages WHERE room_id = $1::text
+		ON CONFLICT (room_id, user_id)
+		DO UPDATE SET last_read_at = GREATEST(room_reads.last_read_at, EXCLUDED.last_read_at)`,
+		rid, callerID,
+	)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "database_error", "database error")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]bool{"success": true})
+}
@@ -0,0 +1,98 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/karti/orange-city-mart/backend/db"
+	authmw "github.com/karti/orange-city-mart/backend/middleware"
+)
+
+// ─────────────────────────────────────────────────────────────────────────────
+// BlockUser  POST /api/chat/blocks
+//
+// Stops the given user's messages from reaching the caller. The blocked user
+// isn't told: their sends still appear to succeed but are never stored.
+// ─────────────────────────────────────────────────────────────────────────────
+func (h *ChatHandler) BlockUser(w http.ResponseWriter, r *http.Request) {
+	callerID, ok := authmw.UserIDFromContext(r.Context())
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "unauthorized", "unauthorized")
+		return
+	}
+
+	var req struct {
+		UserID string `json:"user_id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_body", "invalid request body")
+		return
+	}
+	if _, err := uuid.Parse(req.UserID); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_user_id", "invalid user_id")
+		return
+	}
+	if req.UserID == callerID {
+		writeError(w, http.StatusBadRequest, "self_block", "cannot block yourself")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	tag, err := db.Pool.Exec(ctx, `
+		INSERT INTO user_blocks (blocker_id, blocked_id)
+		SELECT $1::uuid, id FROM users WHERE id = $2::uuid
+		ON CONFLICT DO NOTHING`,
+		callerID, req.UserID,
+	)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "database_error", "database error")
+		return
+	}
+	if tag.RowsAffected() == 0 {
+		// Either unknown user or already blocked; only the former is an error.
+		var exists bool
+		if err := db.Pool.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM users WHERE id = $1)`, req.UserID).Scan(&exists); err != nil {
+			writeError(w, http.StatusInternalServerError, "database_error", "database error")
+			return
+		}
+		if !exists {
+			writeError(w, http.StatusNotFound, "user_not_found", "user not found")
+			return
+		}
+	}
+
+	writeJSON(w, http.StatusOK, map[string]bool{"success": true})
+}
+
+// UnblockUser handles DELETE /api/chat/blocks/{userId}
+func (h *ChatHandler) UnblockUser(w http.ResponseWriter, r *http.Request) {
+	callerID, ok := authmw.UserIDFromContext(r.Context())
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "unauthorized", "unauthorized")
+		return
+	}
+	blockedID := chi.URLParam(r, "userId")
+	if _, err := uuid.Parse(blockedID); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_user_id", "invalid user id")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	_, err := db.Pool.Exec(ctx,
+		`DELETE FROM user_blocks WHERE blocker_id = $1 AND blocked_id = $2`, callerID, blockedID,
+	)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "database_error", "database error")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
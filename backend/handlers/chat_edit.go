@@ -0,0 +1,200 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/jackc/pgx/v5"
+	"github.com/karti/orange-city-mart/backend/db"
+	authmw "github.com/karti/orange-city-mart/backend/middleware"
+)
+
+var (
+	errMessageNotFound   = errors.New("message not found")
+	errNotMessageSender  = errors.New("only the sender can change this message")
+	errEditWindowExpired = errors.New("message can no longer be changed")
+)
+
+// lockOwnMessage locks a live message in roomID and checks that callerID sent
+// it within CHAT_EDIT_WINDOW, how long after sending a message its sender may
+// still edit or delete it.
+func (h *Handler) lockOwnMessage(ctx context.Context, tx pgx.Tx, roomID, msgID, callerID string) error {
+	var senderID string
+	var createdAt time.Time
+	err := tx.QueryRow(ctx, `
+		SELECT sender_id, created_at FROM messages
+		WHERE id = $1 AND room_id = $2 AND deleted_at IS NULL
+		FOR UPDATE`, msgID, roomID,
+	).Scan(&senderID, &createdAt)
+	if err == pgx.ErrNoRows {
+		return errMessageNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if senderID != callerID {
+		return errNotMessageSender
+	}
+	if time.Since(createdAt) > h.Config.ChatEditWindow {
+		return errEditWindowExpired
+	}
+	return nil
+}
+
+// messageErrorStatus maps a lockOwnMessage error to its HTTP status and
+// error code.
+func messageErrorStatus(err error) (int, string) {
+	switch {
+	case errors.Is(err, errMessageNotFound):
+		return http.StatusNotFound, "message_not_found"
+	case errors.Is(err, errNotMessageSender):
+		return http.StatusForbidden, "not_message_sender"
+	case errors.Is(err, errEditWindowExpired):
+		return http.StatusForbidden, "edit_window_expired"
+	}
+	return http.StatusInternalServerError, "database_error"
+}
+
+// ─────────────────────────────────────────────────────────────────────────────
+// EditMessage  PATCH /api/chat/rooms/{roomId}/messages/{id}
+//
+// Replaces the text of the caller's own message, within CHAT_EDIT_WINDOW of
+// sending it, and broadcasts chat_edited to the room.
+// ─────────────────────────────────────────────────────────────────────────────
+func (h *ChatHandler) EditMessage(w http.ResponseWriter, r *http.Request) {
+	callerID, ok := authmw.UserIDFromContext(r.Context())
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "unauthorized", "unauthorized")
+		return
+	}
+	rid := chi.URLParam(r, "roomId")
+	msgID := chi.URLParam(r, "id")
+
+	if !strings.Contains(rid, callerID) {
+		writeError(w, http.StatusForbidden, "forbidden", "forbidden")
+		return
+	}
+
+	var req struct {
+		Body string `json:"body"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || strings.TrimSpace(req.Body) == "" {
+		writeError(w, http.StatusBadRequest, "empty_message", "body is required")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	tx, err := db.Pool.Begin(ctx)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "database_error", "database error")
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	if err := h.lockOwnMessage(ctx, tx, rid, msgID, callerID); err != nil {
+		status, code := messageErrorStatus(err)
+		msg := err.Error()
+		if status == http.StatusInternalServerError {
+			msg = "database error"
+		}
+		writeError(w, status, code, msg)
+		return
+	}
+
+	var imageURL *string
+	var editedAt time.Time
+	err = tx.QueryRow(ctx, `
+		UPDATE messages SET body = $1, edited_at = NOW()
+		WHERE id = $2
+		RETURNING image_url, edited_at`,
+		req.Body, msgID,
+	).Scan(&imageURL, &editedAt)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "database_error", "database error")
+		return
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		writeError(w, http.StatusInternalServerError, "database_error", "commit failed")
+		return
+	}
+
+	h.broadcastMessageEdited(rid, msgID, &req.Body, imageURL, editedAt)
+
+	writeJSON(w, http.StatusOK, ChatEditedPayload{
+		ID:       msgID,
+		RoomID:   rid,
+		Body:     &req.Body,
+		ImageURL: imageURL,
+		EditedAt: editedAt.UTC().Format(time.RFC3339),
+	})
+}
+
+// ─────────────────────────────────────────────────────────────────────────────
+// DeleteMessage  DELETE /api/chat/rooms/{roomId}/messages/{id}
+//
+// Soft-deletes the caller's own message within CHAT_EDIT_WINDOW: the row
+// stays so ordering is intact, but its body and image are blanked. Broadcasts
+// chat_deleted to the room.
+// ─────────────────────────────────────────────────────────────────────────────
+func (h *ChatHandler) DeleteMessage(w http.ResponseWriter, r *http.Request) {
+	callerID, ok := authmw.UserIDFromContext(r.Context())
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "unauthorized", "unauthorized")
+		return
+	}
+	rid := chi.URLParam(r, "roomId")
+	msgID := chi.URLParam(r, "id")
+
+	if !strings.Contains(rid, callerID) {
+		writeError(w, http.StatusForbidden, "forbidden", "forbidden")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	tx, err := db.Pool.Begin(ctx)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "database_error", "database error")
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	if err := h.lockOwnMessage(ctx, tx, rid, msgID, callerID); err != nil {
+		status, code := messageErrorStatus(err)
+		msg := err.Error()
+		if status == http.StatusInternalServerError {
+			msg = "database error"
+		}
+		writeError(w, status, code, msg)
+		return
+	}
+
+	var deletedAt time.Time
+	err = tx.QueryRow(ctx, `
+		UPDATE messages SET body = NULL, image_url = NULL, deleted_at = NOW()
+		WHERE id = $1
+		RETURNING deleted_at`, msgID,
+	).Scan(&deletedAt)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "database_error", "database error")
+		return
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		writeError(w, http.StatusInternalServerError, "database_error", "commit failed")
+		return
+	}
+
+	h.broadcastMessageDeleted(rid, msgID, deletedAt)
+
+	w.WriteHeader(http.StatusNoContent)
+}
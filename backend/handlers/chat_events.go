@@ -0,0 +1,55 @@
+package handlers
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/karti/orange-city-mart/backend/hub"
+)
+
+// ChatEditedPayload is broadcast to a room when a message's body changes.
+type ChatEditedPayload struct {
+	ID       string  `json:"id"`
+	RoomID   string  `json:"room_id"`
+	Body     *string `json:"body"`
+	ImageURL *string `json:"image_url"`
+	EditedAt string  `json:"edited_at"`
+}
+
+// ChatDeletedPayload is broadcast to a room when a message is removed, so
+// clients can redact it in place.
+type ChatDeletedPayload struct {
+	ID        string `json:"id"`
+	RoomID    string `json:"room_id"`
+	DeletedAt string `json:"deleted_at"`
+}
+
+// broadcastMessageEdited tells the room about an edited message. Call only
+// after the edit is committed.
+func (h *ChatHandler) broadcastMessageEdited(roomID, msgID string, body, imageURL *string, editedAt time.Time) {
+	payload, _ := json.Marshal(ChatEditedPayload{
+		ID:       msgID,
+		RoomID:   roomID,
+		Body:     body,
+		ImageURL: imageURL,
+		EditedAt: editedAt.UTC().Format(time.RFC3339),
+	})
+	h.Hub.BroadcastToChat(roomID, hub.Message{
+		Type:    hub.TypeChatEdited,
+		Payload: json.RawMessage(payload),
+	})
+}
+
+// broadcastMessageDeleted tells the room a message was deleted. Call only
+// after the delete is committed.
+func (h *ChatHandler) broadcastMessageDeleted(roomID, msgID string, deletedAt time.Time) {
+	payload, _ := json.Marshal(ChatDeletedPayload{
+		ID:        msgID,
+		RoomID:    roomID,
+		DeletedAt: deletedAt.UTC().Format(time.RFC3339),
+	})
+	h.Hub.BroadcastToChat(roomID, hub.Message{
+		Type:    hub.TypeChatDeleted,
+		Payload: json.RawMessage(payload),
+	})
+}
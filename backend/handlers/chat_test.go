@@ -0,0 +1,274 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/karti/orange-city-mart/backend/hub"
+)
+
+// newChatHandler returns a ChatHandler whose hub stores messages in testPool.
+func newChatHandler(t *testing.T) *ChatHandler {
+	t.Helper()
+	requireDB(t)
+	h := hub.NewHub(testPool)
+	go h.Run()
+	return &ChatHandler{Handler: testHandler, Hub: h}
+}
+
+// chatSocket opens a websocket for userID in roomID on h and waits until the
+// hub has registered it, so broadcasts made afterwards reach it.
+func chatSocket(t *testing.T, h *hub.Hub, userID, roomID string) *websocket.Conn {
+	t.Helper()
+	upgrader := websocket.Upgrader{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		h.NewClient(userID, "", roomID, conn)
+	}))
+	t.Cleanup(srv.Close)
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	for deadline := time.Now().Add(5 * time.Second); !h.IsOnline(userID); time.Sleep(10 * time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatal("socket never registered")
+		}
+	}
+	return conn
+}
+
+// readFrame returns the payload of the next frame of type typ on conn,
+// skipping presence and other unrelated frames.
+func readFrame(t *testing.T, conn *websocket.Conn, typ string, into any) {
+	t.Helper()
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	for {
+		var m hub.Message
+		if err := conn.ReadJSON(&m); err != nil {
+			t.Fatalf("no %s frame: %v", typ, err)
+		}
+		if m.Type == typ {
+			if err := json.Unmarshal(m.Payload, into); err != nil {
+				t.Fatal(err)
+			}
+			return
+		}
+	}
+}
+
+func TestSendMessageEchoesTempID(t *testing.T) {
+	h := newChatHandler(t)
+	alice := newTestUser(t, testPool, 0)
+	bob := newTestUser(t, testPool, 0)
+	t.Cleanup(func() { testPool.Exec(context.Background(), `DELETE FROM users WHERE id IN ($1, $2)`, alice, bob) })
+	room := roomID(alice, bob)
+	conn := chatSocket(t, h.Hub, alice, room)
+
+	r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"body": "hi", "temp_id": "tmp-rest"}`))
+	r = withURLParam(asUser(r, alice), "roomId", room)
+	w := httptest.NewRecorder()
+	h.SendMessage(w, r)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("status %d: %s", w.Code, w.Body)
+	}
+
+	var msg struct {
+		ID     string `json:"id"`
+		TempID string `json:"temp_id"`
+	}
+	readFrame(t, conn, hub.TypeChatMessage, &msg)
+	if msg.TempID != "tmp-rest" || msg.ID == "" {
+		t.Errorf("broadcast = %+v, want temp_id tmp-rest and a server id", msg)
+	}
+}
+
+func TestChatSendFrameEchoesTempID(t *testing.T) {
+	h := newChatHandler(t)
+	alice := newTestUser(t, testPool, 0)
+	bob := newTestUser(t, testPool, 0)
+	t.Cleanup(func() { testPool.Exec(context.Background(), `DELETE FROM users WHERE id IN ($1, $2)`, alice, bob) })
+	room := roomID(alice, bob)
+	conn := chatSocket(t, h.Hub, alice, room)
+
+	if err := conn.WriteJSON(map[string]any{
+		"type":    "chat_send",
+		"payload": map[string]any{"body": "hi", "temp_id": "tmp-ws"},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	var msg struct {
+		ID     string `json:"id"`
+		TempID string `json:"temp_id"`
+	}
+	readFrame(t, conn, hub.TypeChatMessage, &msg)
+	if msg.TempID != "tmp-ws" || msg.ID == "" {
+		t.Errorf("broadcast = %+v, want temp_id tmp-ws and a server id", msg)
+	}
+}
+
+// TestEditAndDeleteBroadcastToRoom checks that the other member of the room
+// hears about an edit and then a delete of the sender's message.
+func TestEditAndDeleteBroadcastToRoom(t *testing.T) {
+	h := newChatHandler(t)
+	ctx := context.Background()
+	alice := newTestUser(t, testPool, 0)
+	bob := newTestUser(t, testPool, 0)
+	t.Cleanup(func() { testPool.Exec(ctx, `DELETE FROM users WHERE id IN ($1, $2)`, alice, bob) })
+	room := roomID(alice, bob)
+	var msgID string
+	if err := testPool.QueryRow(ctx, `
+		INSERT INTO messages (room_id, sender_id, body) VALUES ($1, $2, 'helo') RETURNING id`,
+		room, alice).Scan(&msgID); err != nil {
+		t.Fatal(err)
+	}
+	peer := chatSocket(t, h.Hub, bob, room)
+
+	call := func(handler http.HandlerFunc, method, body string) {
+		t.Helper()
+		r := httptest.NewRequest(method, "/", strings.NewReader(body))
+		r = withURLParam(withURLParam(asUser(r, alice), "roomId", room), "id", msgID)
+		w := httptest.NewRecorder()
+		handler(w, r)
+		if w.Code >= 300 {
+			t.Fatalf("%s: status %d: %s", method, w.Code, w.Body)
+		}
+	}
+
+	call(h.EditMessage, http.MethodPatch, `{"body": "hello"}`)
+	var edited ChatEditedPayload
+	readFrame(t, peer, hub.TypeChatEdited, &edited)
+	if edited.ID != msgID || edited.RoomID != room || edited.Body == nil || *edited.Body != "hello" || edited.EditedAt == "" {
+		t.Errorf("chat_edited = %+v, want the new body for %s", edited, msgID)
+	}
+
+	call(h.DeleteMessage, http.MethodDelete, "")
+	var deleted ChatDeletedPayload
+	readFrame(t, peer, hub.TypeChatDeleted, &deleted)
+	if deleted.ID != msgID || deleted.RoomID != room || deleted.DeletedAt == "" {
+		t.Errorf("chat_deleted = %+v, want %s redacted", deleted, msgID)
+	}
+}
+
+// conversationRooms lists the room ids in userID's conversation list.
+func conversationRooms(t *testing.T, h *ChatHandler, userID string) []string {
+	t.Helper()
+	w := httptest.NewRecorder()
+	h.GetConversations(w, asUser(httptest.NewRequest(http.MethodGet, "/api/chat/conversations", nil), userID))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", w.Code, w.Body)
+	}
+	var convos []struct {
+		RoomID string `json:"room_id"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &convos); err != nil {
+		t.Fatal(err)
+	}
+	var rooms []string
+	for _, c := range convos {
+		rooms = append(rooms, c.RoomID)
+	}
+	return rooms
+}
+
+func TestHiddenConversationReturnsOnNewMessage(t *testing.T) {
+	h := newChatHandler(t)
+	ctx := context.Background()
+	alice := newTestUser(t, testPool, 0)
+	bob := newTestUser(t, testPool, 0)
+	t.Cleanup(func() { testPool.Exec(ctx, `DELETE FROM users WHERE id IN ($1, $2)`, alice, bob) })
+	room := roomID(alice, bob)
+	if _, err := testPool.Exec(ctx, `
+		INSERT INTO messages (room_id, sender_id, body, created_at)
+		VALUES ($1, $2, 'hi', NOW() - INTERVAL '1 minute')`, room, bob); err != nil {
+		t.Fatal(err)
+	}
+
+	r := withURLParam(asUser(httptest.NewRequest(http.MethodPost, "/", nil), alice), "roomId", room)
+	w := httptest.NewRecorder()
+	h.HideConversation(w, r)
+	if w.Code != http.StatusOK {
+		t.Fatalf("hide: status %d: %s", w.Code, w.Body)
+	}
+	if got := conversationRooms(t, h, alice); len(got) != 0 {
+		t.Errorf("hidden room still listed for the caller: %v", got)
+	}
+	if got := conversationRooms(t, h, bob); !slices.Equal(got, []string{room}) {
+		t.Errorf("other member's list = %v, want the room unaffected", got)
+	}
+
+	if _, err := testPool.Exec(ctx, `
+		INSERT INTO messages (room_id, sender_id, body) VALUES ($1, $2, 'still there?')`, room, bob); err != nil {
+		t.Fatal(err)
+	}
+	if got := conversationRooms(t, h, alice); !slices.Equal(got, []string{room}) {
+		t.Errorf("after a new message: %v, want the room back", got)
+	}
+}
+
+// TestSameTimestampMessagesKeepInsertionOrder stores a burst of messages
+// sharing one created_at and checks history returns them in insertion order,
+// with increasing seq, on every fetch.
+func TestSameTimestampMessagesKeepInsertionOrder(t *testing.T) {
+	h := newChatHandler(t)
+	ctx := context.Background()
+	alice := newTestUser(t, testPool, 0)
+	bob := newTestUser(t, testPool, 0)
+	t.Cleanup(func() { testPool.Exec(ctx, `DELETE FROM users WHERE id IN ($1, $2)`, alice, bob) })
+	room := roomID(alice, bob)
+
+	at := time.Date(2026, 4, 1, 9, 30, 0, 123000000, time.UTC)
+	var want []string
+	for i, body := range []string{"one", "two", "three", "four"} {
+		sender := alice
+		if i%2 == 1 {
+			sender = bob
+		}
+		var id string
+		if err := testPool.QueryRow(ctx, `
+			INSERT INTO messages (room_id, sender_id, body, created_at) VALUES ($1, $2, $3, $4)
+			RETURNING id`, room, sender, body, at).Scan(&id); err != nil {
+			t.Fatal(err)
+		}
+		want = append(want, id)
+	}
+
+	for fetch := 0; fetch < 3; fetch++ {
+		r := withURLParam(asUser(httptest.NewRequest(http.MethodGet, "/", nil), alice), "roomId", room)
+		w := httptest.NewRecorder()
+		h.GetMessages(w, r)
+		if w.Code != http.StatusOK {
+			t.Fatalf("status %d: %s", w.Code, w.Body)
+		}
+		var body struct {
+			Messages []struct {
+				ID  string `json:"id"`
+				Seq int64  `json:"seq"`
+			} `json:"messages"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for i, m := range body.Messages {
+			got = append(got, m.ID)
+			if i > 0 && m.Seq <= body.Messages[i-1].Seq {
+				t.Errorf("fetch %d: seq %d after %d", fetch, m.Seq, body.Messages[i-1].Seq)
+			}
+		}
+		if !slices.Equal(got, want) {
+			t.Fatalf("fetch %d: order %v, want %v", fetch, got, want)
+		}
+	}
+}
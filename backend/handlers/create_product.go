@@ -3,6 +3,7 @@ package handlers
 import (
 	"encoding/json"
 	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/karti/orange-city-mart/backend/db"
@@ -11,35 +12,84 @@ import (
 
 // ── Create Product ─────────────────────────────────────────────────────────────
 // POST /api/products  (requires auth)
-func CreateProduct(w http.ResponseWriter, r *http.Request) {
+func (h *Handler) CreateProduct(w http.ResponseWriter, r *http.Request) {
 	userID, ok := authmw.UserIDFromContext(r.Context())
 	if !ok || userID == "" {
-		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		writeError(w, http.StatusUnauthorized, "unauthorized", "unauthorized")
 		return
 	}
 
 	var body struct {
-		Title       string  `json:"title"`
-		Description string  `json:"description"`
-		Category    string  `json:"category"`
-		Type        string  `json:"type"`        // FIXED | AUCTION
-		Price       float64 `json:"price"`       // used for FIXED; start_price for AUCTION
-		StartPrice  float64 `json:"start_price"` // optional, for AUCTION
-		EndTime     string  `json:"end_time"`    // RFC3339, for AUCTION
-		Location    string  `json:"location"`
-		ImageURL    string  `json:"image_url"`
+		Title        string  `json:"title"`
+		Description  string  `json:"description"`
+		Category     string  `json:"category"`
+		Type         string  `json:"type"`             // FIXED | AUCTION
+		Price        float64 `json:"price"`            // used for FIXED; start_price for AUCTION
+		StartPrice   float64 `json:"start_price"`      // optional, for AUCTION
+		EndTime      string  `json:"end_time"`         // RFC3339, for AUCTION
+		RefundPolicy string  `json:"refund_policy"`    // INSTANT (default) | AT_END, for AUCTION
+		ReservePrice float64 `json:"reserve_price"`    // optional hidden floor, for AUCTION
+		AutoRelist   int     `json:"auto_relist"`      // times to relist on no sale, for AUCTION
+		BuyNowPrice  float64 `json:"buy_now_price"`    // optional instant-win price, for AUCTION
+		AntiSnipe    *bool   `json:"anti_snipe"`       // extend on late bids (default true), for AUCTION
+		MinInterval  int     `json:"min_bid_interval"` // seconds between one user's bids, for AUCTION
+		Quantity     *int    `json:"quantity"`         // units in stock (default 1), for FIXED
+		Location     string  `json:"location"`
+		ImageURL     string  `json:"image_url"`
 	}
 	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
-		http.Error(w, "invalid JSON", http.StatusBadRequest)
+		writeError(w, http.StatusBadRequest, "invalid_body", "invalid JSON")
 		return
 	}
 
 	if body.Title == "" || body.Category == "" || body.Location == "" {
-		http.Error(w, "title, category, and location are required", http.StatusBadRequest)
+		writeError(w, http.StatusBadRequest, "missing_fields", "title, category, and location are required")
 		return
 	}
 	if body.Type != "FIXED" && body.Type != "AUCTION" {
-		http.Error(w, "type must be FIXED or AUCTION", http.StatusBadRequest)
+		writeError(w, http.StatusBadRequest, "invalid_type", "type must be FIXED or AUCTION")
+		return
+	}
+	quantity := 1
+	if body.Quantity != nil {
+		quantity = *body.Quantity
+		if quantity < 1 {
+			writeError(w, http.StatusBadRequest, "invalid_quantity", "quantity must be at least 1")
+			return
+		}
+		if body.Type == "AUCTION" && quantity != 1 {
+			writeError(w, http.StatusBadRequest, "invalid_quantity", "an auction lists a single item")
+			return
+		}
+	}
+	if body.RefundPolicy == "" {
+		body.RefundPolicy = refundInstant
+	}
+	if body.RefundPolicy != refundInstant && body.RefundPolicy != refundAtEnd {
+		writeError(w, http.StatusBadRequest, "invalid_refund_policy", "refund_policy must be INSTANT or AT_END")
+		return
+	}
+	if body.ReservePrice < 0 {
+		writeError(w, http.StatusBadRequest, "invalid_reserve_price", "reserve_price must not be negative")
+		return
+	}
+	if body.BuyNowPrice < 0 || (body.BuyNowPrice > 0 && body.BuyNowPrice < body.ReservePrice) {
+		writeError(w, http.StatusBadRequest, "invalid_buy_now_price", "buy_now_price must be positive and not below reserve_price")
+		return
+	}
+	if maxRelists := h.Config.MaxAutoRelists; body.AutoRelist < 0 || body.AutoRelist > maxRelists {
+		writeError(w, http.StatusBadRequest, "invalid_auto_relist", "auto_relist must be between 0 and "+strconv.Itoa(maxRelists))
+		return
+	}
+
+	if body.ImageURL != "" && !validImageURL(r.Context(), userID, body.ImageURL) {
+		writeError(w, http.StatusBadRequest, "invalid_image_url", "image_url must be an image uploaded via /api/upload")
+		return
+	}
+
+	antiSnipe := body.AntiSnipe == nil || *body.AntiSnipe
+	if body.MinInterval < 0 {
+		writeError(w, http.StatusBadRequest, "invalid_min_bid_interval", "min_bid_interval must not be negative")
 		return
 	}
 
@@ -51,47 +101,140 @@ func CreateProduct(w http.ResponseWriter, r *http.Request) {
 		effectivePrice = body.StartPrice
 	}
 
+	// Parse end_time up front so a bad value can't leave a half-created listing
+	var endTime time.Time
+	if body.Type == "AUCTION" {
+		var err error
+		endTime, err = parseEndTime(body.EndTime)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "invalid_end_time", "invalid end_time format")
+			return
+		}
+		if !endTime.After(time.Now()) {
+			writeError(w, http.StatusBadRequest, "invalid_end_time", "end_time must be in the future")
+			return
+		}
+		endTime, err = h.clampAuctionEnd(time.Now(), endTime)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "auction_too_long", "end_time is further out than the maximum auction duration of "+h.Config.AuctionMaxDuration.String())
+			return
+		}
+	}
+
+	tx, err := db.Pool.Begin(ctx)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "database_error", "database error")
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	rules, err := h.loadCategoryRules(ctx, tx, body.Category)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "database_error", "database error")
+		return
+	}
+
+	if ok, exposure, limit, err := h.checkExposure(ctx, tx, userID, effectivePrice*float64(quantity)); err != nil {
+		writeError(w, http.StatusInternalServerError, "database_error", "database error")
+		return
+	} else if !ok {
+		writeExposureExceeded(w, exposure, limit)
+		return
+	}
+
 	// Insert product
 	var productID string
-	err := db.Pool.QueryRow(ctx, `
-		INSERT INTO products (seller_id, title, description, category, type, price, image_url, location)
-		VALUES ($1,$2,$3,$4,$5,$6,$7,$8)
+	err = tx.QueryRow(ctx, `
+		INSERT INTO products (seller_id, title, description, category, type, price, image_url, location, quantity)
+		VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9)
 		RETURNING id`,
 		userID, body.Title, body.Description, body.Category,
-		body.Type, effectivePrice, nullableString(body.ImageURL), body.Location,
+		body.Type, effectivePrice, nullableString(body.ImageURL), body.Location, quantity,
 	).Scan(&productID)
 	if err != nil {
-		http.Error(w, "could not create product: "+err.Error(), http.StatusInternalServerError)
+		writeError(w, http.StatusInternalServerError, "database_error", "could not create product: "+err.Error())
 		return
 	}
 
-	// If AUCTION, insert auction row
-	if body.Type == "AUCTION" {
-		endTime, err := time.Parse(time.RFC3339, body.EndTime)
+	// Charge the category's listing fee, if any
+	if rules.ListingFee > 0 {
+		var balance float64
+		err = tx.QueryRow(ctx,
+			`SELECT wallet_balance FROM users WHERE id = $1 FOR UPDATE`, userID,
+		).Scan(&balance)
 		if err != nil {
-			// Try datetime-local format (no timezone)
-			endTime, err = time.ParseInLocation("2006-01-02T15:04", body.EndTime, time.Local)
-			if err != nil {
-				http.Error(w, "invalid end_time format", http.StatusBadRequest)
-				return
-			}
+			writeError(w, http.StatusNotFound, "user_not_found", "user not found")
+			return
+		}
+		if balance < rules.ListingFee {
+			writeError(w, http.StatusPaymentRequired, "insufficient_balance", "insufficient wallet balance for listing fee")
+			return
 		}
-		_, err = db.Pool.Exec(ctx, `
-			INSERT INTO auctions (product_id, start_price, current_highest_bid, end_time, status)
-			VALUES ($1,$2,$3,$4,'ACTIVE')`,
-			productID, effectivePrice, 0, endTime,
+		_, err = tx.Exec(ctx,
+			`UPDATE users SET wallet_balance = wallet_balance - $1 WHERE id = $2`,
+			rules.ListingFee, userID,
 		)
 		if err != nil {
-			http.Error(w, "could not create auction: "+err.Error(), http.StatusInternalServerError)
+			writeError(w, http.StatusInternalServerError, "database_error", "database error")
+			return
+		}
+		_, err = tx.Exec(ctx, `
+			INSERT INTO transactions (user_id, amount, type, status, reference)
+			VALUES ($1, $2, 'LISTING_FEE', 'COMPLETED', $3)`,
+			userID, rules.ListingFee, productID,
+		)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "database_error", "database error")
 			return
 		}
 	}
 
+	// If AUCTION, insert auction row
+	if body.Type == "AUCTION" {
+		_, err = tx.Exec(ctx, `
+			INSERT INTO auctions (product_id, start_price, current_highest_bid, end_time, status,
+			                      refund_policy, reserve_price, auto_relist, relists_remaining, buy_now_price,
+			                      anti_snipe, min_bid_interval)
+			VALUES ($1,$2,$3,$4,'ACTIVE',$5,$6,$7,$8,$9,$10,$11)`,
+			productID, effectivePrice, 0, endTime, body.RefundPolicy,
+			nullableAmount(body.ReservePrice), body.AutoRelist > 0, body.AutoRelist,
+			nullableAmount(body.BuyNowPrice), antiSnipe, body.MinInterval,
+		)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "database_error", "could not create auction: "+err.Error())
+			return
+		}
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		writeError(w, http.StatusInternalServerError, "database_error", "commit failed")
+		return
+	}
+	invalidateCategories()
+
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusCreated)
 	json.NewEncoder(w).Encode(map[string]string{"id": productID})
 }
 
+// parseEndTime accepts an RFC3339 end_time or the browser's datetime-local
+// format (no timezone, interpreted in server local time).
+func parseEndTime(s string) (time.Time, error) {
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		t, err = time.ParseInLocation("2006-01-02T15:04", s, time.Local)
+	}
+	return t, err
+}
+
+// nullableAmount returns nil if f is zero (for optional NUMERIC columns).
+func nullableAmount(f float64) interface{} {
+	if f == 0 {
+		return nil
+	}
+	return f
+}
+
 // nullableString returns nil if s is empty (for nullable TEXT columns).
 func nullableString(s string) interface{} {
 	if s == "" {
@@ -0,0 +1,240 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/jackc/pgx/v5"
+	"github.com/karti/orange-city-mart/backend/db"
+	authmw "github.com/karti/orange-city-mart/backend/middleware"
+)
+
+const (
+	notifySettlementDisputed = "settlement_disputed"
+	notifyDisputeResolved    = "dispute_resolved"
+)
+
+// ─────────────────────────────────────────────────────────────────────────────
+// DisputeSettlement  POST /api/auctions/{id}/dispute
+//
+// Body: { "reason": "item never arrived" }
+// Either party to a PENDING settlement can raise a dispute. The settlement
+// moves to DISPUTED, which freezes the winner's HARD hold: no approval or
+// expiry can release it until an admin resolves the dispute. The other party
+// is notified.
+// ─────────────────────────────────────────────────────────────────────────────
+func (h *AuctionHandler) DisputeSettlement(w http.ResponseWriter, r *http.Request) {
+	auctionID := chi.URLParam(r, "id")
+	callerID, ok := authmw.UserIDFromContext(r.Context())
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "unauthorized", "unauthorized")
+		return
+	}
+
+	var req struct {
+		Reason string `json:"reason"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_body", "invalid request body")
+		return
+	}
+	req.Reason = strings.TrimSpace(req.Reason)
+	if req.Reason == "" {
+		writeError(w, http.StatusBadRequest, "missing_reason", "reason is required")
+		return
+	}
+	if len(req.Reason) > 1000 {
+		writeError(w, http.StatusBadRequest, "reason_too_long", "reason must be at most 1000 characters")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	tx, err := db.Pool.Begin(ctx)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "database_error", "database error")
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	var settlementID, winnerID, sellerID, status string
+	err = tx.QueryRow(ctx, `
+		SELECT id, winner_id, seller_id, status
+		FROM settlements
+		WHERE auction_id = $1
+		FOR UPDATE`, auctionID,
+	).Scan(&settlementID, &winnerID, &sellerID, &status)
+	if err == pgx.ErrNoRows {
+		writeError(w, http.StatusNotFound, "settlement_not_found", errSettlementNotFound.Error())
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "database_error", "database error")
+		return
+	}
+
+	var otherID string
+	switch callerID {
+	case winnerID:
+		otherID = sellerID
+	case sellerID:
+		otherID = winnerID
+	default:
+		writeError(w, http.StatusForbidden, "not_settlement_party", errNotSettlementParty.Error())
+		return
+	}
+	if status != "PENDING" {
+		writeError(w, http.StatusConflict, "settlement_not_pending", "only a pending settlement can be disputed")
+		return
+	}
+
+	if _, err = tx.Exec(ctx, `
+		UPDATE settlements
+		SET status = 'DISPUTED', disputed_at = NOW(), disputed_by = $2, dispute_reason = $3
+		WHERE id = $1`, settlementID, callerID, req.Reason); err != nil {
+		writeError(w, http.StatusInternalServerError, "database_error", "database error")
+		return
+	}
+	n, err := createNotification(ctx, tx, otherID, notifySettlementDisputed, map[string]any{
+		"auction_id": auctionID,
+		"from":       callerID,
+		"reason":     req.Reason,
+	})
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "database_error", "database error")
+		return
+	}
+	if err = tx.Commit(ctx); err != nil {
+		writeError(w, http.StatusInternalServerError, "database_error", "commit failed")
+		return
+	}
+
+	pushNotification(h.Hub, otherID, n)
+
+	writeJSON(w, http.StatusOK, map[string]string{
+		"auction_id":        auctionID,
+		"settlement_status": "DISPUTED",
+	})
+}
+
+// ─────────────────────────────────────────────────────────────────────────────
+// ResolveDispute  POST /api/admin/auctions/{id}/dispute/resolve
+//
+// Body: { "action": "complete" | "refund", "note": "optional" }
+// Closes a DISPUTED settlement. complete transfers the held funds to the
+// seller exactly as a mutual approval would; refund returns them to the
+// winner with a REFUND transaction and marks the settlement REFUNDED. Both
+// parties are notified of the outcome.
+// ─────────────────────────────────────────────────────────────────────────────
+func (h *AuctionHandler) ResolveDispute(w http.ResponseWriter, r *http.Request) {
+	adminID, ok := authmw.UserIDFromContext(r.Context())
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "unauthorized", "unauthorized")
+		return
+	}
+	auctionID := chi.URLParam(r, "id")
+
+	var req struct {
+		Action string `json:"action"`
+		Note   string `json:"note"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_body", "invalid request body")
+		return
+	}
+	if req.Action != "complete" && req.Action != "refund" {
+		writeError(w, http.StatusBadRequest, "invalid_action", "action must be complete or refund")
+		return
+	}
+	req.Note = strings.TrimSpace(req.Note)
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	tx, err := db.Pool.Begin(ctx)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "database_error", "database error")
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	var settlementID, winnerID, sellerID, status string
+	var amount float64
+	err = tx.QueryRow(ctx, `
+		SELECT id, winner_id, seller_id, status, amount::float8
+		FROM settlements
+		WHERE auction_id = $1
+		FOR UPDATE`, auctionID,
+	).Scan(&settlementID, &winnerID, &sellerID, &status, &amount)
+	if err == pgx.ErrNoRows {
+		writeError(w, http.StatusNotFound, "settlement_not_found", errSettlementNotFound.Error())
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "database_error", "database error")
+		return
+	}
+	if status != "DISPUTED" {
+		writeError(w, http.StatusConflict, "not_disputed", "settlement is not disputed")
+		return
+	}
+
+	newStatus := "COMPLETED"
+	if req.Action == "complete" {
+		err = completeSettlement(ctx, tx, settlementID, auctionID, winnerID, sellerID, amount)
+	} else {
+		newStatus = "REFUNDED"
+		if err = releaseHardHolds(ctx, tx, auctionID); err == nil {
+			_, err = tx.Exec(ctx,
+				`UPDATE settlements SET status = 'REFUNDED' WHERE id = $1`, settlementID)
+		}
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "database_error", "database error")
+		return
+	}
+	if _, err = tx.Exec(ctx, `
+		UPDATE settlements SET dispute_resolved_by = $2, dispute_resolved_at = NOW(), dispute_resolution = $3
+		WHERE id = $1`, settlementID, adminID, nullableString(req.Note)); err != nil {
+		writeError(w, http.StatusInternalServerError, "database_error", "database error")
+		return
+	}
+
+	payload := map[string]any{
+		"auction_id":        auctionID,
+		"settlement_status": newStatus,
+		"amount":            amount,
+		"note":              req.Note,
+	}
+	var notices []userNotification
+	for _, userID := range []string{winnerID, sellerID} {
+		n, err := createNotification(ctx, tx, userID, notifyDisputeResolved, payload)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "database_error", "database error")
+			return
+		}
+		notices = append(notices, userNotification{UserID: userID, Notification: n})
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		writeError(w, http.StatusInternalServerError, "database_error", "commit failed")
+		return
+	}
+
+	for _, un := range notices {
+		pushNotification(h.Hub, un.UserID, un.Notification)
+	}
+	if newStatus == "COMPLETED" {
+		h.notifySettlementCompletedOffsite(auctionID)
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"auction_id":        auctionID,
+		"settlement_status": newStatus,
+	})
+}
@@ -0,0 +1,318 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/jackc/pgx/v5"
+	"github.com/karti/orange-city-mart/backend/db"
+	authmw "github.com/karti/orange-city-mart/backend/middleware"
+)
+
+// listingLock is the locked view of a product and its live auction, if any.
+type listingLock struct {
+	SellerID  string
+	Type      string
+	Price     float64
+	Status    string // AVAILABLE or SOLD_OUT (FIXED listings only)
+	Quantity  int    // units left (FIXED listings only)
+	AuctionID *string
+	Version   int       // auction version, for updateAuctionVersioned
+	CreatedAt time.Time // auction created_at
+	HasBids   bool
+}
+
+// auction is the locked live auction as an auctionState, so writes to it can
+// go through updateAuctionVersioned. Only call it when AuctionID is set.
+func (l *listingLock) auction() *auctionState {
+	return &auctionState{ID: *l.AuctionID, Version: l.Version}
+}
+
+// lockListing locks a non-deleted product and its ACTIVE auction for editing.
+// Returns pgx.ErrNoRows when the product doesn't exist or was deleted.
+func lockListing(ctx context.Context, tx pgx.Tx, productID string) (*listingLock, error) {
+	l := &listingLock{}
+	err := tx.QueryRow(ctx, `
+		SELECT seller_id, type, price, status, quantity FROM products
+		WHERE id = $1 AND deleted_at IS NULL
+		FOR UPDATE`, productID,
+	).Scan(&l.SellerID, &l.Type, &l.Price, &l.Status, &l.Quantity)
+	if err != nil {
+		return nil, err
+	}
+
+	var auctionID string
+	err = tx.QueryRow(ctx, `
+		SELECT a.id, a.version, a.created_at, EXISTS (SELECT 1 FROM bids b WHERE b.auction_id = a.id)
+		FROM auctions a
+		WHERE a.product_id = $1 AND a.status = 'ACTIVE'
+		FOR UPDATE OF a`, productID,
+	).Scan(&auctionID, &l.Version, &l.CreatedAt, &l.HasBids)
+	if err == pgx.ErrNoRows {
+		return l, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	l.AuctionID = &auctionID
+	return l, nil
+}
+
+// keepsImage reports whether url is already the product's image, so an edit
+// that resends it needn't prove ownership of the upload again.
+func keepsImage(ctx context.Context, productID, url string) bool {
+	var same bool
+	err := db.Pool.QueryRow(ctx,
+		`SELECT EXISTS (SELECT 1 FROM products WHERE id = $1 AND image_url = $2)`, productID, url,
+	).Scan(&same)
+	return err == nil && same
+}
+
+// ─────────────────────────────────────────────────────────────────────────────
+// UpdateProduct  PUT /api/products/{id}
+//
+// Seller-only partial update; omitted fields are left as they are. Once the
+// live auction has bids its type, start price and end_time are frozen, and a
+// sold-out listing can't be edited at all: it has buyers, and reopening it
+// (say as an auction) would sell stock that no longer exists.
+// Switching AUCTION → FIXED cancels the bid-less auction; FIXED → AUCTION
+// opens a new one and requires end_time and a single unit in stock.
+// ─────────────────────────────────────────────────────────────────────────────
+func (h *Handler) UpdateProduct(w http.ResponseWriter, r *http.Request) {
+	userID, ok := authmw.UserIDFromContext(r.Context())
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "unauthorized", "unauthorized")
+		return
+	}
+	productID := chi.URLParam(r, "id")
+
+	var body struct {
+		Title       *string  `json:"title"`
+		Description *string  `json:"description"`
+		Category    *string  `json:"category"`
+		Type        *string  `json:"type"`
+		Price       *float64 `json:"price"`
+		EndTime     *string  `json:"end_time"`
+		Location    *string  `json:"location"`
+		ImageURL    *string  `json:"image_url"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_body", "invalid JSON")
+		return
+	}
+	if body.Type != nil && *body.Type != "FIXED" && *body.Type != "AUCTION" {
+		writeError(w, http.StatusBadRequest, "invalid_type", "type must be FIXED or AUCTION")
+		return
+	}
+	if (body.Title != nil && *body.Title == "") || (body.Category != nil && *body.Category == "") ||
+		(body.Location != nil && *body.Location == "") {
+		writeError(w, http.StatusBadRequest, "missing_fields", "title, category, and location cannot be empty")
+		return
+	}
+	if body.Price != nil && *body.Price < 0 {
+		writeError(w, http.StatusBadRequest, "invalid_price", "price must not be negative")
+		return
+	}
+	if body.ImageURL != nil && *body.ImageURL != "" && !keepsImage(r.Context(), productID, *body.ImageURL) &&
+		!validImageURL(r.Context(), userID, *body.ImageURL) {
+		writeError(w, http.StatusBadRequest, "invalid_image_url", "image_url must be an image uploaded via /api/upload")
+		return
+	}
+	var endTime *time.Time
+	if body.EndTime != nil {
+		t, err := parseEndTime(*body.EndTime)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "invalid_end_time", "invalid end_time format")
+			return
+		}
+		if !t.After(time.Now()) {
+			writeError(w, http.StatusBadRequest, "invalid_end_time", "end_time must be in the future")
+			return
+		}
+		endTime = &t
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	tx, err := db.Pool.Begin(ctx)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "database_error", "database error")
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	l, err := lockListing(ctx, tx, productID)
+	if err == pgx.ErrNoRows {
+		writeError(w, http.StatusNotFound, "product_not_found", "product not found")
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "database_error", "database error")
+		return
+	}
+	if l.SellerID != userID {
+		writeError(w, http.StatusForbidden, "not_seller", "only the seller can edit this product")
+		return
+	}
+	if l.Status == "SOLD_OUT" {
+		writeError(w, http.StatusConflict, "listing_sold_out", "a sold-out listing cannot be edited")
+		return
+	}
+
+	newType := l.Type
+	if body.Type != nil {
+		newType = *body.Type
+	}
+	price := l.Price
+	if body.Price != nil {
+		price = *body.Price
+	}
+	if l.HasBids && (newType != l.Type || (body.Price != nil && *body.Price != l.Price)) {
+		writeError(w, http.StatusConflict, "auction_has_bids", "type and price cannot be changed once the auction has bids")
+		return
+	}
+
+	switch {
+	case l.Type == "AUCTION" && newType == "FIXED" && l.AuctionID != nil:
+		err = updateAuctionVersioned(ctx, tx, l.auction(), `status = 'CANCELLED'`)
+
+	case l.Type == "FIXED" && newType == "AUCTION":
+		if endTime == nil {
+			writeError(w, http.StatusBadRequest, "missing_end_time", "end_time is required to switch to an auction")
+			return
+		}
+		if l.Quantity != 1 {
+			writeError(w, http.StatusBadRequest, "invalid_quantity", "an auction lists a single item")
+			return
+		}
+		end, capErr := h.clampAuctionEnd(time.Now(), *endTime)
+		if capErr != nil {
+			writeError(w, http.StatusBadRequest, "auction_too_long", "end_time is further out than the maximum auction duration of "+h.Config.AuctionMaxDuration.String())
+			return
+		}
+		_, err = tx.Exec(ctx, `
+			INSERT INTO auctions (product_id, start_price, current_highest_bid, end_time, status)
+			VALUES ($1, $2, 0, $3, 'ACTIVE')`,
+			productID, price, end,
+		)
+
+	case newType == "AUCTION" && l.AuctionID != nil:
+		if endTime != nil {
+			err = ensureEndTimeEditable(ctx, tx, *l.AuctionID, *endTime)
+			if errors.Is(err, errEndTimeLocked) {
+				writeError(w, http.StatusConflict, "end_time_locked", err.Error())
+				return
+			}
+			if err != nil {
+				writeError(w, http.StatusInternalServerError, "database_error", "database error")
+				return
+			}
+			end, capErr := h.clampAuctionEnd(l.CreatedAt, *endTime)
+			if capErr != nil {
+				writeError(w, http.StatusBadRequest, "auction_too_long", "end_time is further out than the maximum auction duration of "+h.Config.AuctionMaxDuration.String())
+				return
+			}
+			endTime = &end
+		}
+		err = updateAuctionVersioned(ctx, tx, l.auction(),
+			`start_price = $3, end_time = COALESCE($4::timestamptz, end_time)`, price, endTime)
+	}
+	if err != nil {
+		writeAuctionError(w, err)
+		return
+	}
+
+	_, err = tx.Exec(ctx, `
+		UPDATE products SET
+			title       = COALESCE($1::text, title),
+			description = COALESCE($2::text, description),
+			category    = COALESCE($3::text, category),
+			type        = $4,
+			price       = $5,
+			location    = COALESCE($6::text, location),
+			image_url   = COALESCE($7::text, image_url)
+		WHERE id = $8`,
+		body.Title, body.Description, body.Category, newType, price,
+		body.Location, body.ImageURL, productID,
+	)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "database_error", "database error")
+		return
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		writeError(w, http.StatusInternalServerError, "database_error", "commit failed")
+		return
+	}
+	invalidateCategories()
+
+	writeJSON(w, http.StatusOK, map[string]string{"id": productID})
+}
+
+// ─────────────────────────────────────────────────────────────────────────────
+// DeleteProduct  DELETE /api/products/{id}
+//
+// Seller-only soft delete. Refused while the product's live auction has bids;
+// a bid-less live auction is cancelled along with the listing.
+// ─────────────────────────────────────────────────────────────────────────────
+func (h *Handler) DeleteProduct(w http.ResponseWriter, r *http.Request) {
+	userID, ok := authmw.UserIDFromContext(r.Context())
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "unauthorized", "unauthorized")
+		return
+	}
+	productID := chi.URLParam(r, "id")
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	tx, err := db.Pool.Begin(ctx)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "database_error", "database error")
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	l, err := lockListing(ctx, tx, productID)
+	if err == pgx.ErrNoRows {
+		writeError(w, http.StatusNotFound, "product_not_found", "product not found")
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "database_error", "database error")
+		return
+	}
+	if l.SellerID != userID {
+		writeError(w, http.StatusForbidden, "not_seller", "only the seller can delete this product")
+		return
+	}
+	if l.HasBids {
+		writeError(w, http.StatusConflict, "auction_has_bids", "cannot delete a product whose auction has bids")
+		return
+	}
+
+	if l.AuctionID != nil {
+		if err = updateAuctionVersioned(ctx, tx, l.auction(), `status = 'CANCELLED'`); err != nil {
+			writeAuctionError(w, err)
+			return
+		}
+	}
+	_, err = tx.Exec(ctx, `UPDATE products SET deleted_at = NOW() WHERE id = $1`, productID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "database_error", "database error")
+		return
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		writeError(w, http.StatusInternalServerError, "database_error", "commit failed")
+		return
+	}
+	invalidateCategories()
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,131 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newTestFixedProduct inserts a committed FIXED listing for sellerID and
+// returns its id. Deleting the seller removes it.
+func newTestFixedProduct(t *testing.T, sellerID string, quantity int, status string) string {
+	t.Helper()
+	var id string
+	err := testPool.QueryRow(context.Background(), `
+		INSERT INTO products (seller_id, title, type, price, quantity, status)
+		VALUES ($1, 'Test item', 'FIXED', 100, $2, $3) RETURNING id`,
+		sellerID, quantity, status,
+	).Scan(&id)
+	if err != nil {
+		t.Fatalf("insert product: %v", err)
+	}
+	return id
+}
+
+// updateProduct calls UpdateProduct as userID and returns the status code.
+func updateProduct(t *testing.T, userID, productID, body string) int {
+	t.Helper()
+	r := httptest.NewRequest(http.MethodPut, "/api/products/"+productID, strings.NewReader(body))
+	r = withURLParam(asUser(r, userID), "id", productID)
+	w := httptest.NewRecorder()
+	testHandler.UpdateProduct(w, r)
+	return w.Code
+}
+
+func TestUpdateProductRejectsSoldOutListing(t *testing.T) {
+	requireDB(t)
+	ctx := context.Background()
+	seller := newTestUser(t, testPool, 0)
+	t.Cleanup(func() { testPool.Exec(ctx, `DELETE FROM users WHERE id = $1`, seller) })
+	productID := newTestFixedProduct(t, seller, 0, "SOLD_OUT")
+
+	end := time.Now().Add(24 * time.Hour).UTC().Format(time.RFC3339)
+	if got := updateProduct(t, seller, productID, `{"type":"AUCTION","end_time":"`+end+`"}`); got != http.StatusConflict {
+		t.Fatalf("switching a sold-out listing to an auction = %d, want 409", got)
+	}
+	if got := updateProduct(t, seller, productID, `{"title":"Renamed"}`); got != http.StatusConflict {
+		t.Fatalf("editing a sold-out listing = %d, want 409", got)
+	}
+	var auctions int
+	testPool.QueryRow(ctx, `SELECT COUNT(*) FROM auctions WHERE product_id = $1`, productID).Scan(&auctions)
+	if auctions != 0 {
+		t.Fatalf("sold-out listing reopened as %d auction(s)", auctions)
+	}
+}
+
+func TestUpdateProductRejectsMultiUnitAuction(t *testing.T) {
+	requireDB(t)
+	ctx := context.Background()
+	seller := newTestUser(t, testPool, 0)
+	t.Cleanup(func() { testPool.Exec(ctx, `DELETE FROM users WHERE id = $1`, seller) })
+	productID := newTestFixedProduct(t, seller, 3, "AVAILABLE")
+
+	end := time.Now().Add(24 * time.Hour).UTC().Format(time.RFC3339)
+	if got := updateProduct(t, seller, productID, `{"type":"AUCTION","end_time":"`+end+`"}`); got != http.StatusBadRequest {
+		t.Fatalf("switching a 3-unit listing to an auction = %d, want 400", got)
+	}
+
+	single := newTestFixedProduct(t, seller, 1, "AVAILABLE")
+	if got := updateProduct(t, seller, single, `{"type":"AUCTION","end_time":"`+end+`"}`); got != http.StatusOK {
+		t.Fatalf("switching a 1-unit listing to an auction = %d, want 200", got)
+	}
+}
+
+// newTestAuctionListing commits an ACTIVE auction ending in a day for a new
+// product listed by sellerID and returns the product and auction ids.
+// Deleting the seller removes both.
+func newTestAuctionListing(t *testing.T, sellerID string) (productID, auctionID string) {
+	t.Helper()
+	ctx := context.Background()
+	if err := testPool.QueryRow(ctx, `
+		INSERT INTO products (seller_id, title, type, price) VALUES ($1, 'Lot', 'AUCTION', 10)
+		RETURNING id`, sellerID).Scan(&productID); err != nil {
+		t.Fatalf("insert product: %v", err)
+	}
+	if err := testPool.QueryRow(ctx, `
+		INSERT INTO auctions (product_id, start_price, end_time) VALUES ($1, 10, NOW() + INTERVAL '1 day')
+		RETURNING id`, productID).Scan(&auctionID); err != nil {
+		t.Fatalf("insert auction: %v", err)
+	}
+	return productID, auctionID
+}
+
+func TestUpdateProductLocksEndTimeOnceBidOn(t *testing.T) {
+	requireDB(t)
+	ctx := context.Background()
+	seller := newTestUser(t, testPool, 0)
+	bidder := newTestUser(t, testPool, 0)
+	t.Cleanup(func() { testPool.Exec(ctx, `DELETE FROM users WHERE id IN ($1, $2)`, seller, bidder) })
+	endTime := func(auctionID string) time.Time {
+		var end time.Time
+		testPool.QueryRow(ctx, `SELECT end_time FROM auctions WHERE id = $1`, auctionID).Scan(&end)
+		return end
+	}
+	later := `{"end_time":"` + time.Now().Add(48*time.Hour).UTC().Format(time.RFC3339) + `"}`
+
+	// Without bids the seller may still move the end.
+	quiet, quietAuction := newTestAuctionListing(t, seller)
+	before := endTime(quietAuction)
+	if got := updateProduct(t, seller, quiet, later); got != http.StatusOK {
+		t.Fatalf("moving end_time before any bid = %d, want 200", got)
+	}
+	if endTime(quietAuction).Equal(before) {
+		t.Error("end_time unchanged after an allowed edit")
+	}
+
+	busy, busyAuction := newTestAuctionListing(t, seller)
+	if _, err := testPool.Exec(ctx, `
+		INSERT INTO bids (auction_id, user_id, amount) VALUES ($1, $2, 12)`, busyAuction, bidder); err != nil {
+		t.Fatal(err)
+	}
+	before = endTime(busyAuction)
+	if got := updateProduct(t, seller, busy, later); got != http.StatusConflict {
+		t.Fatalf("moving end_time after a bid = %d, want 409", got)
+	}
+	if !endTime(busyAuction).Equal(before) {
+		t.Error("end_time moved despite the 409")
+	}
+}
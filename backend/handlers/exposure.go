@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// userExposure is the user's current exposure: their SOFT and HARD bid holds
+// plus each live listing's value — a fixed-price product's price times the
+// units left, or the higher of an active auction's start price and current
+// bid.
+func userExposure(ctx context.Context, q querier, userID string) (float64, error) {
+	var exposure float64
+	err := q.QueryRow(ctx, `
+		SELECT (
+		    SELECT COALESCE(SUM(amount), 0) FROM bid_holds
+		    WHERE user_id = $1::uuid AND status IN ('SOFT', 'HARD')
+		) + (
+		    SELECT COALESCE(SUM(CASE WHEN p.type = 'FIXED' THEN p.price * p.quantity
+		                             ELSE GREATEST(a.current_highest_bid, a.start_price) END), 0)
+		    FROM products p
+		    LEFT JOIN LATERAL (
+		        SELECT * FROM auctions
+		        WHERE product_id = p.id
+		        ORDER BY created_at DESC
+		        LIMIT 1
+		    ) a ON TRUE
+		    WHERE p.seller_id = $1::uuid AND p.deleted_at IS NULL
+		      AND ((p.type = 'FIXED' AND p.status = 'AVAILABLE') OR a.status = 'ACTIVE')
+		)::float8`, userID,
+	).Scan(&exposure)
+	return exposure, err
+}
+
+// checkExposure reports whether adding amount keeps the user within
+// MAX_USER_EXPOSURE, the cap on the total value one user may have in flight
+// (zero means unlimited), along with their current exposure and the limit.
+// It locks the user's row first, so two holds or listings racing for the
+// same user can't both pass against the same starting exposure; every path
+// that adds exposure (bids, auto-bids, buy-now, new listings) must call it in
+// the transaction that adds it.
+func (h *Handler) checkExposure(ctx context.Context, tx pgx.Tx, userID string, amount float64) (ok bool, exposure, limit float64, err error) {
+	limit = h.Config.MaxUserExposure
+	if limit <= 0 {
+		return true, 0, 0, nil
+	}
+	if _, err = tx.Exec(ctx, `SELECT 1 FROM users WHERE id = $1 FOR UPDATE`, userID); err != nil {
+		return false, 0, limit, err
+	}
+	exposure, err = userExposure(ctx, tx, userID)
+	if err != nil {
+		return false, 0, limit, err
+	}
+	return h.roundMoney(exposure+amount) <= limit, exposure, limit, nil
+}
+
+// exposureError is returned by applyBid when the bid would take the bidder
+// past maxUserExposure.
+type exposureError struct {
+	exposure, limit float64
+}
+
+func (e *exposureError) Error() string {
+	return fmt.Sprintf("exposure %.2f would exceed the limit of %.2f", e.exposure, e.limit)
+}
+
+// writeExposureExceeded rejects an action that would take the user past
+// maxUserExposure, carrying the figures so the UI can explain why.
+func writeExposureExceeded(w http.ResponseWriter, exposure, limit float64) {
+	writeErrorDetails(w, http.StatusConflict, "exposure_limit_exceeded", "this would exceed your maximum total exposure",
+		map[string]any{"current_exposure": exposure, "exposure_limit": limit})
+}
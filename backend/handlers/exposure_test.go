@@ -0,0 +1,160 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/karti/orange-city-mart/backend/config"
+)
+
+// listFixed gives sellerID a live fixed-price listing worth value, which
+// counts toward their exposure.
+func listFixed(t *testing.T, q querier, sellerID string, value float64) {
+	t.Helper()
+	var id string
+	err := q.QueryRow(context.Background(), `
+		INSERT INTO products (seller_id, title, type, price) VALUES ($1, 'Listing', 'FIXED', $2)
+		RETURNING id`, sellerID, value,
+	).Scan(&id)
+	if err != nil {
+		t.Fatalf("insert listing: %v", err)
+	}
+}
+
+// withExposureCap returns a Handler enforcing MAX_USER_EXPOSURE=limit.
+func withExposureCap(limit float64) *Handler {
+	return newTestHandler(func(c *config.Config) { c.MaxUserExposure = limit })
+}
+
+func tryBid(t *testing.T, h *Handler, tx pgx.Tx, auctionID, userID string, amount float64) error {
+	t.Helper()
+	ctx := context.Background()
+	st, err := h.lockAuction(ctx, tx, auctionID)
+	if err != nil {
+		t.Fatalf("lock auction: %v", err)
+	}
+	_, err = h.applyBid(ctx, tx, st, userID, amount)
+	return err
+}
+
+func TestApplyBidStopsAtExposureCap(t *testing.T) {
+	h := withExposureCap(500)
+	tx := testTx(t)
+	seller := newTestUser(t, tx, 0)
+	bidder := newTestUser(t, tx, 1000)
+	listFixed(t, tx, bidder, 400)
+	auction := newTestAuction(t, tx, seller, refundInstant)
+
+	var ee *exposureError
+	if err := tryBid(t, h, tx, auction, bidder, 150); !errors.As(err, &ee) {
+		t.Fatalf("bid taking exposure to 550: err = %v, want exposureError", err)
+	}
+	if err := tryBid(t, h, tx, auction, bidder, 100); err != nil {
+		t.Fatalf("bid taking exposure to exactly 500: %v", err)
+	}
+	// A raise only adds the difference, which still crosses the cap.
+	if err := tryBid(t, h, tx, auction, bidder, 120); !errors.As(err, &ee) {
+		t.Fatalf("raise taking exposure to 520: err = %v, want exposureError", err)
+	}
+	expectBalance(t, tx, bidder, 900)
+}
+
+func TestResolveAutoBidsSkipsCappedBidder(t *testing.T) {
+	h := withExposureCap(500)
+	tx := testTx(t)
+	ctx := context.Background()
+	seller := newTestUser(t, tx, 0)
+	capped := newTestUser(t, tx, 1000)
+	free := newTestUser(t, tx, 1000)
+	listFixed(t, tx, capped, 480)
+	auction := newTestAuction(t, tx, seller, refundInstant)
+
+	// capped has the higher ceiling but can take on only 20 more.
+	for _, ab := range []struct {
+		user string
+		max  float64
+	}{{capped, 300}, {free, 200}} {
+		if _, err := tx.Exec(ctx, `
+			INSERT INTO auto_bids (auction_id, user_id, max_amount) VALUES ($1, $2, $3)`,
+			auction, ab.user, ab.max); err != nil {
+			t.Fatalf("insert auto-bid: %v", err)
+		}
+	}
+
+	st, err := h.lockAuction(ctx, tx, auction)
+	if err != nil {
+		t.Fatalf("lock auction: %v", err)
+	}
+	if _, err := h.resolveAutoBids(ctx, tx, st); err != nil {
+		t.Fatalf("resolve: %v", err)
+	}
+	if st.HighBidderID == nil || *st.HighBidderID != free {
+		t.Fatalf("leader = %v, want the uncapped bidder", st.HighBidderID)
+	}
+	if n, _ := softHolds(t, tx, auction, capped); n != 0 {
+		t.Fatalf("capped bidder holds %d SOFT holds", n)
+	}
+}
+
+func TestCreateProductStopsAtExposureCap(t *testing.T) {
+	h := withExposureCap(500)
+	requireDB(t)
+	ctx := context.Background()
+	seller := newTestUser(t, testPool, 0)
+	t.Cleanup(func() { testPool.Exec(ctx, `DELETE FROM users WHERE id = $1`, seller) })
+	listFixed(t, testPool, seller, 450)
+
+	create := func(price string) int {
+		body := `{"title":"T","category":"Misc","location":"Nagpur","type":"FIXED","price":` + price + `}`
+		r := asUser(httptest.NewRequest(http.MethodPost, "/api/products", strings.NewReader(body)), seller)
+		w := httptest.NewRecorder()
+		h.CreateProduct(w, r)
+		return w.Code
+	}
+	if got := create("100"); got != http.StatusConflict {
+		t.Fatalf("listing taking exposure to 550 = %d, want 409", got)
+	}
+	if got := create("50"); got != http.StatusCreated {
+		t.Fatalf("listing taking exposure to 500 = %d, want 201", got)
+	}
+}
+
+func TestBuyNowStopsAtExposureCap(t *testing.T) {
+	h := withExposureCap(500)
+	requireDB(t)
+	ctx := context.Background()
+	seller := newTestUser(t, testPool, 0)
+	buyer := newTestUser(t, testPool, 1000)
+	t.Cleanup(func() { testPool.Exec(ctx, `DELETE FROM users WHERE id IN ($1, $2)`, seller, buyer) })
+	listFixed(t, testPool, buyer, 400)
+
+	var productID, auctionID string
+	if err := testPool.QueryRow(ctx, `
+		INSERT INTO products (seller_id, title, type, price) VALUES ($1, 'Lot', 'AUCTION', 10) RETURNING id`,
+		seller).Scan(&productID); err != nil {
+		t.Fatal(err)
+	}
+	if err := testPool.QueryRow(ctx, `
+		INSERT INTO auctions (product_id, start_price, end_time, buy_now_price)
+		VALUES ($1, 10, NOW() + INTERVAL '1 hour', 200) RETURNING id`,
+		productID).Scan(&auctionID); err != nil {
+		t.Fatal(err)
+	}
+
+	r := httptest.NewRequest(http.MethodPost, "/api/auctions/"+auctionID+"/buynow", nil)
+	r = withURLParam(asUser(r, buyer), "id", auctionID)
+	w := httptest.NewRecorder()
+	(&AuctionHandler{Handler: h}).BuyNow(w, r)
+	if w.Code != http.StatusConflict {
+		t.Fatalf("buy-now taking exposure to 600 = %d, want 409: %s", w.Code, w.Body)
+	}
+	if got := walletBalance(t, testPool, buyer); got != 1000 {
+		t.Fatalf("buyer balance = %.2f after a refused buy-now", got)
+	}
+}
@@ -0,0 +1,125 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/jackc/pgx/v5"
+	"github.com/karti/orange-city-mart/backend/db"
+	authmw "github.com/karti/orange-city-mart/backend/middleware"
+)
+
+// ── Feature Product ───────────────────────────────────────────────────────────
+// POST /api/products/{id}/feature  (requires auth)
+//
+// Charges the seller FEATURE_FEE from their wallet and pins the listing to the
+// top of ListProducts for FEATURE_DURATION. Featuring an already-featured
+// listing extends the current period. A sold-out listing, or an auction that
+// is no longer taking bids, is refused with 409 before anything is charged.
+func (h *Handler) FeatureProduct(w http.ResponseWriter, r *http.Request) {
+	userID, ok := authmw.UserIDFromContext(r.Context())
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "unauthorized", "unauthorized")
+		return
+	}
+	productID := chi.URLParam(r, "id")
+
+	fee := h.roundMoney(h.Config.FeatureFee)
+	duration := h.Config.FeatureDuration
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	tx, err := db.Pool.Begin(ctx)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "database_error", "database error")
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	var sellerID, productType, status string
+	var live bool
+	err = tx.QueryRow(ctx, `
+		SELECT p.seller_id, p.type, p.status,
+		       EXISTS (SELECT 1 FROM auctions a
+		               WHERE a.product_id = p.id AND a.status = 'ACTIVE' AND a.end_time > NOW())
+		FROM products p WHERE p.id = $1 AND p.deleted_at IS NULL FOR UPDATE`, productID,
+	).Scan(&sellerID, &productType, &status, &live)
+	if err == pgx.ErrNoRows {
+		writeError(w, http.StatusNotFound, "product_not_found", "product not found")
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "database_error", "database error")
+		return
+	}
+	if sellerID != userID {
+		writeError(w, http.StatusForbidden, "not_seller", "only the seller can feature this listing")
+		return
+	}
+	if status == "SOLD_OUT" {
+		writeError(w, http.StatusConflict, "listing_sold_out", "a sold-out listing cannot be featured")
+		return
+	}
+	if productType == "AUCTION" && !live {
+		writeError(w, http.StatusConflict, "auction_ended", "an auction that has ended cannot be featured")
+		return
+	}
+
+	var balance float64
+	err = tx.QueryRow(ctx,
+		`SELECT wallet_balance FROM users WHERE id = $1 FOR UPDATE`, userID,
+	).Scan(&balance)
+	if err != nil {
+		writeError(w, http.StatusNotFound, "user_not_found", "user not found")
+		return
+	}
+	if balance < fee {
+		writeError(w, http.StatusPaymentRequired, "insufficient_balance", "insufficient wallet balance")
+		return
+	}
+
+	_, err = tx.Exec(ctx,
+		`UPDATE users SET wallet_balance = wallet_balance - $1 WHERE id = $2`,
+		fee, userID,
+	)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "database_error", "database error")
+		return
+	}
+	_, err = tx.Exec(ctx, `
+		INSERT INTO transactions (user_id, amount, type, status, reference)
+		VALUES ($1, $2, 'FEATURE_FEE', 'COMPLETED', $3)`,
+		userID, fee, productID,
+	)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "database_error", "database error")
+		return
+	}
+
+	var featuredUntil time.Time
+	err = tx.QueryRow(ctx, `
+		UPDATE products
+		SET featured_until = GREATEST(COALESCE(featured_until, NOW()), NOW()) + make_interval(secs => $1)
+		WHERE id = $2
+		RETURNING featured_until`,
+		duration.Seconds(), productID,
+	).Scan(&featuredUntil)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "database_error", "database error")
+		return
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		writeError(w, http.StatusInternalServerError, "database_error", "commit failed")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"success":        true,
+		"featured_until": featuredUntil.UTC().Format(time.RFC3339),
+		"fee":            fee,
+	})
+}
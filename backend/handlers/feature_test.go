@@ -0,0 +1,91 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// featureProduct calls FeatureProduct as userID and returns the status code.
+func featureProduct(userID, productID string) int {
+	r := httptest.NewRequest(http.MethodPost, "/api/products/"+productID+"/feature", nil)
+	r = withURLParam(asUser(r, userID), "id", productID)
+	w := httptest.NewRecorder()
+	testHandler.FeatureProduct(w, r)
+	return w.Code
+}
+
+func TestFeaturedListingsSortFirstUntilExpiry(t *testing.T) {
+	requireDB(t)
+	ctx := context.Background()
+	seller := newTestUser(t, testPool, 500)
+	t.Cleanup(func() { testPool.Exec(ctx, `DELETE FROM users WHERE id = $1`, seller) })
+	tag := fmt.Sprintf("feat%d", time.Now().UnixNano())
+	var older, newer string
+	for _, id := range []*string{&older, &newer} {
+		if err := testPool.QueryRow(ctx, `
+			INSERT INTO products (seller_id, title, type, price) VALUES ($1, $2, 'FIXED', 10)
+			RETURNING id`, seller, tag).Scan(id); err != nil {
+			t.Fatal(err)
+		}
+	}
+	order := func() []string {
+		code, items := listProducts(t, "q="+tag)
+		if code != http.StatusOK {
+			t.Fatalf("ListProducts = %d", code)
+		}
+		var ids []string
+		for _, p := range items {
+			ids = append(ids, p.ID)
+		}
+		return ids
+	}
+
+	if got := order(); len(got) != 2 || got[0] != newer {
+		t.Fatalf("before featuring: %v, want newest first", got)
+	}
+	if code := featureProduct(seller, older); code != http.StatusOK {
+		t.Fatalf("FeatureProduct = %d", code)
+	}
+	if got := order(); len(got) != 2 || got[0] != older {
+		t.Fatalf("after featuring: %v, want the featured listing first", got)
+	}
+	if got := walletBalance(t, testPool, seller); got != 500-testHandler.Config.FeatureFee {
+		t.Errorf("balance = %.2f, want the fee charged once", got)
+	}
+
+	if _, err := testPool.Exec(ctx, `
+		UPDATE products SET featured_until = NOW() - INTERVAL '1 second' WHERE id = $1`, older); err != nil {
+		t.Fatal(err)
+	}
+	if got := order(); len(got) != 2 || got[0] != newer {
+		t.Errorf("after the period lapsed: %v, want newest first again", got)
+	}
+}
+
+func TestFeatureProductRejectsFinishedListings(t *testing.T) {
+	requireDB(t)
+	ctx := context.Background()
+	seller := newTestUser(t, testPool, 500)
+	t.Cleanup(func() { testPool.Exec(ctx, `DELETE FROM users WHERE id = $1`, seller) })
+
+	soldOut := newTestFixedProduct(t, seller, 0, "SOLD_OUT")
+	_, endedAuction := newTestAuctionListing(t, seller)
+	var ended string
+	if err := testPool.QueryRow(ctx, `
+		UPDATE auctions SET status = 'ENDED', end_time = NOW() - INTERVAL '1 minute'
+		WHERE id = $1 RETURNING product_id`, endedAuction).Scan(&ended); err != nil {
+		t.Fatal(err)
+	}
+	for name, id := range map[string]string{"sold-out listing": soldOut, "ended auction": ended} {
+		if code := featureProduct(seller, id); code != http.StatusConflict {
+			t.Errorf("featuring a %s = %d, want 409", name, code)
+		}
+	}
+	if got := walletBalance(t, testPool, seller); got != 500 {
+		t.Errorf("balance = %.2f, want nothing charged", got)
+	}
+}
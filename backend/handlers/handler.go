@@ -0,0 +1,14 @@
+package handlers
+
+import (
+	"github.com/karti/orange-city-mart/backend/config"
+	authmw "github.com/karti/orange-city-mart/backend/middleware"
+)
+
+// Handler serves the endpoints that don't need the WebSocket hub. It carries
+// the configuration loaded at startup and the token issuer built from it;
+// main constructs one and AuctionHandler and ChatHandler embed it.
+type Handler struct {
+	Config *config.Config
+	Auth   *authmw.Auth
+}
@@ -0,0 +1,292 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/jpeg"
+	"image/png"
+	"net/http"
+)
+
+var (
+	errNotImage      = errors.New("file is not a valid JPEG, PNG or WEBP image")
+	errImageTooLarge = errors.New("image dimensions are too large")
+)
+
+// processedImage is an upload after sanitising, ready to store.
+type processedImage struct {
+	Data        []byte
+	ContentType string
+	Ext         string
+	Width       int
+	Height      int
+}
+
+// processImage identifies an upload by its bytes rather than its declared
+// type, caps its width and height at maxDim pixels (UPLOAD_MAX_DIMENSION) and
+// strips metadata such as EXIF/GPS. The size is checked before the pixels are
+// decoded, so a small file can't expand into a huge bitmap.
+// JPEG and PNG are decoded and re-encoded; the standard library has no WEBP
+// codec, so WEBP files are rewritten with their EXIF and XMP chunks dropped.
+// Because stripping EXIF also drops a JPEG's Orientation tag, the rotation it
+// describes is applied to the pixels first so photos stay upright.
+func processImage(data []byte, maxDim int) (*processedImage, error) {
+	switch http.DetectContentType(data) {
+	case "image/jpeg":
+		return reencode(data, maxDim, jpegOrientation(data), "image/jpeg", ".jpg", func(buf *bytes.Buffer, img image.Image) error {
+			return jpeg.Encode(buf, img, &jpeg.Options{Quality: 90})
+		})
+	case "image/png":
+		return reencode(data, maxDim, 1, "image/png", ".png", func(buf *bytes.Buffer, img image.Image) error {
+			return png.Encode(buf, img)
+		})
+	case "image/webp":
+		return stripWebP(data, maxDim)
+	}
+	return nil, errNotImage
+}
+
+// imageErrorCode is the API error code for a processImage failure.
+func imageErrorCode(err error) string {
+	if errors.Is(err, errImageTooLarge) {
+		return "image_too_large"
+	}
+	return "invalid_image"
+}
+
+func reencode(data []byte, maxDim, orientation int, contentType, ext string, encode func(*bytes.Buffer, image.Image) error) (*processedImage, error) {
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, errNotImage
+	}
+	if err := checkDimensions(cfg.Width, cfg.Height, maxDim); err != nil {
+		return nil, err
+	}
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, errNotImage
+	}
+	img = orient(img, orientation)
+	var buf bytes.Buffer
+	if err := encode(&buf, img); err != nil {
+		return nil, err
+	}
+	return &processedImage{
+		Data:        buf.Bytes(),
+		ContentType: contentType,
+		Ext:         ext,
+		Width:       img.Bounds().Dx(),
+		Height:      img.Bounds().Dy(),
+	}, nil
+}
+
+// jpegOrientation returns the EXIF Orientation (1-8) of a JPEG, or 1 when it
+// has none or its metadata can't be read.
+func jpegOrientation(data []byte) int {
+	if len(data) < 4 || data[0] != 0xff || data[1] != 0xd8 {
+		return 1
+	}
+	for off := 2; off+4 <= len(data); {
+		if data[off] != 0xff {
+			return 1
+		}
+		marker := data[off+1]
+		switch {
+		case marker == 0xff: // fill byte
+			off++
+			continue
+		case marker == 0x01 || marker >= 0xd0 && marker <= 0xd8: // no length
+			off += 2
+			continue
+		case marker == 0xda || marker == 0xd9: // metadata ends at the scan
+			return 1
+		}
+		size := int(binary.BigEndian.Uint16(data[off+2 : off+4]))
+		end := off + 2 + size
+		if size < 2 || end > len(data) {
+			return 1
+		}
+		if marker == 0xe1 {
+			if o := exifOrientation(data[off+4 : end]); o != 0 {
+				return o
+			}
+		}
+		off = end
+	}
+	return 1
+}
+
+// exifOrientation reads the Orientation tag from IFD0 of an APP1 Exif
+// segment, returning 0 if the segment isn't Exif or has no valid tag.
+func exifOrientation(seg []byte) int {
+	if len(seg) < 14 || string(seg[:6]) != "Exif\x00\x00" {
+		return 0
+	}
+	tiff := seg[6:]
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 0
+	}
+	ifd := int(order.Uint32(tiff[4:8]))
+	if ifd < 8 || ifd+2 > len(tiff) {
+		return 0
+	}
+	for i, n := 0, int(order.Uint16(tiff[ifd:])); i < n; i++ {
+		entry := ifd + 2 + i*12
+		if entry+12 > len(tiff) {
+			return 0
+		}
+		if order.Uint16(tiff[entry:]) == 0x0112 {
+			if o := int(order.Uint16(tiff[entry+8:])); o >= 1 && o <= 8 {
+				return o
+			}
+			return 0
+		}
+	}
+	return 0
+}
+
+// orient returns img transformed as EXIF orientation o prescribes for
+// display: mirrored for 2 and 4, rotated for 3, 6 and 8, and transposed for
+// 5 and 7. Orientation 1 returns img unchanged.
+func orient(img image.Image, o int) image.Image {
+	if o < 2 || o > 8 {
+		return img
+	}
+	b := img.Bounds()
+	src := image.NewRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	draw.Draw(src, src.Bounds(), img, b.Min, draw.Src)
+	w, h := b.Dx(), b.Dy()
+
+	dw, dh := w, h
+	if o >= 5 {
+		dw, dh = h, w
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, dw, dh))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			var dx, dy int
+			switch o {
+			case 2:
+				dx, dy = w-1-x, y
+			case 3:
+				dx, dy = w-1-x, h-1-y
+			case 4:
+				dx, dy = x, h-1-y
+			case 5:
+				dx, dy = y, x
+			case 6:
+				dx, dy = h-1-y, x
+			case 7:
+				dx, dy = h-1-y, w-1-x
+			case 8:
+				dx, dy = y, w-1-x
+			}
+			si, di := src.PixOffset(x, y), dst.PixOffset(dx, dy)
+			copy(dst.Pix[di:di+4], src.Pix[si:si+4])
+		}
+	}
+	return dst
+}
+
+func checkDimensions(w, h, limit int) error {
+	if w > limit || h > limit {
+		return fmt.Errorf("%w (%dx%d, max %dx%d)", errImageTooLarge, w, h, limit, limit)
+	}
+	if w <= 0 || h <= 0 {
+		return errNotImage
+	}
+	return nil
+}
+
+// stripWebP reads a WEBP's dimensions from its bitstream header and rebuilds
+// the RIFF container without EXIF and XMP chunks.
+func stripWebP(data []byte, maxDim int) (*processedImage, error) {
+	if len(data) < 12 || string(data[0:4]) != "RIFF" || string(data[8:12]) != "WEBP" {
+		return nil, errNotImage
+	}
+
+	var out bytes.Buffer
+	width, height := 0, 0
+	for off := 12; off < len(data); {
+		if off+8 > len(data) {
+			return nil, errNotImage
+		}
+		fourCC := string(data[off : off+4])
+		size := int(binary.LittleEndian.Uint32(data[off+4 : off+8]))
+		end := off + 8 + size
+		if size < 0 || end > len(data) {
+			return nil, errNotImage
+		}
+		payload := data[off+8 : end]
+		padded := end + size%2
+		if padded > len(data) {
+			padded = len(data)
+		}
+
+		switch fourCC {
+		case "VP8X":
+			if size < 10 {
+				return nil, errNotImage
+			}
+			width = int(uint32(payload[4])|uint32(payload[5])<<8|uint32(payload[6])<<16) + 1
+			height = int(uint32(payload[7])|uint32(payload[8])<<8|uint32(payload[9])<<16) + 1
+		case "VP8 ":
+			if width == 0 {
+				if size < 10 || payload[3] != 0x9d || payload[4] != 0x01 || payload[5] != 0x2a {
+					return nil, errNotImage
+				}
+				width = int(binary.LittleEndian.Uint16(payload[6:8]) & 0x3fff)
+				height = int(binary.LittleEndian.Uint16(payload[8:10]) & 0x3fff)
+			}
+		case "VP8L":
+			if width == 0 {
+				if size < 5 || payload[0] != 0x2f {
+					return nil, errNotImage
+				}
+				bits := binary.LittleEndian.Uint32(payload[1:5])
+				width = int(bits&0x3fff) + 1
+				height = int((bits>>14)&0x3fff) + 1
+			}
+		}
+
+		if fourCC != "EXIF" && fourCC != "XMP " {
+			chunk := data[off:padded]
+			if fourCC == "VP8X" {
+				// Clear the EXIF (0x08) and XMP (0x04) presence flags.
+				chunk = append([]byte(nil), chunk...)
+				chunk[8] &^= 0x08 | 0x04
+			}
+			out.Write(chunk)
+		}
+		off = padded
+	}
+
+	if err := checkDimensions(width, height, maxDim); err != nil {
+		return nil, err
+	}
+
+	body := out.Bytes()
+	result := make([]byte, 12, 12+len(body))
+	copy(result, "RIFF")
+	binary.LittleEndian.PutUint32(result[4:8], uint32(4+len(body)))
+	copy(result[8:12], "WEBP")
+	result = append(result, body...)
+
+	return &processedImage{
+		Data:        result,
+		ContentType: "image/webp",
+		Ext:         ".webp",
+		Width:       width,
+		Height:      height,
+	}, nil
+}
@@ -0,0 +1,187 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"testing"
+)
+
+// testJPEG encodes a w×h JPEG whose left half is red and right half blue,
+// with an EXIF Orientation tag when orientation is non-zero.
+func testJPEG(t *testing.T, w, h, orientation int) []byte {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			c := color.RGBA{255, 0, 0, 255}
+			if x >= w/2 {
+				c = color.RGBA{0, 0, 255, 255}
+			}
+			img.Set(x, y, c)
+		}
+	}
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: 100}); err != nil {
+		t.Fatal(err)
+	}
+	data := buf.Bytes()
+	if orientation == 0 {
+		return data
+	}
+
+	// Big-endian TIFF with one IFD0 entry: Orientation, SHORT, count 1.
+	tiff := []byte("MM\x00\x2a\x00\x00\x00\x08\x00\x01\x01\x12\x00\x03\x00\x00\x00\x01")
+	tiff = binary.BigEndian.AppendUint16(tiff, uint16(orientation))
+	tiff = append(tiff, 0, 0, 0, 0, 0, 0)
+	seg := append([]byte("Exif\x00\x00"), tiff...)
+	app1 := []byte{0xff, 0xe1}
+	app1 = binary.BigEndian.AppendUint16(app1, uint16(len(seg)+2))
+	app1 = append(app1, seg...)
+
+	out := append([]byte(nil), data[:2]...)
+	out = append(out, app1...)
+	return append(out, data[2:]...)
+}
+
+func isRed(c color.Color) bool {
+	r, _, b, _ := c.RGBA()
+	return r > 0xc000 && b < 0x4000
+}
+
+func TestProcessImageAppliesJPEGOrientation(t *testing.T) {
+	data := testJPEG(t, 32, 16, 6)
+	if o := jpegOrientation(data); o != 6 {
+		t.Fatalf("jpegOrientation = %d, want 6", o)
+	}
+
+	p, err := processImage(data, testHandler.Config.UploadMaxDimension)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Width != 16 || p.Height != 32 {
+		t.Fatalf("size = %dx%d, want 16x32", p.Width, p.Height)
+	}
+	img, err := jpeg.Decode(bytes.NewReader(p.Data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Rotated 90° clockwise, the red left half ends up on top.
+	if !isRed(img.At(8, 4)) || isRed(img.At(8, 28)) {
+		t.Error("image was not rotated clockwise")
+	}
+	if jpegOrientation(p.Data) != 1 {
+		t.Error("orientation tag survived re-encoding")
+	}
+}
+
+func TestOrientMapsEveryOrientation(t *testing.T) {
+	// A 2×1 image: red then blue.
+	src := image.NewRGBA(image.Rect(0, 0, 2, 1))
+	src.Set(0, 0, color.RGBA{255, 0, 0, 255})
+	src.Set(1, 0, color.RGBA{0, 0, 255, 255})
+
+	// Where the red pixel lands for each orientation.
+	for o, want := range map[int]image.Point{
+		1: {0, 0}, 2: {1, 0}, 3: {1, 0}, 4: {0, 0},
+		5: {0, 0}, 6: {0, 0}, 7: {0, 1}, 8: {0, 1},
+	} {
+		got := orient(src, o)
+		b := got.Bounds()
+		if o >= 5 && (b.Dx() != 1 || b.Dy() != 2) || o < 5 && (b.Dx() != 2 || b.Dy() != 1) {
+			t.Errorf("orientation %d: size %v", o, b.Size())
+			continue
+		}
+		if !isRed(got.At(want.X, want.Y)) {
+			t.Errorf("orientation %d: red pixel not at %v", o, want)
+		}
+	}
+}
+
+func TestJPEGOrientationWithoutExif(t *testing.T) {
+	if o := jpegOrientation(testJPEG(t, 8, 8, 0)); o != 1 {
+		t.Errorf("jpegOrientation = %d, want 1", o)
+	}
+	if o := jpegOrientation([]byte("not a jpeg")); o != 1 {
+		t.Errorf("jpegOrientation(garbage) = %d, want 1", o)
+	}
+}
+
+func TestProcessImageSniffsContent(t *testing.T) {
+	for name, data := range map[string][]byte{
+		"text":      []byte("definitely not an image"),
+		"html":      []byte("<html><body>hi</body></html>"),
+		"truncated": testJPEG(t, 16, 16, 0)[:40],
+		"bad webp":  []byte("RIFF\x10\x00\x00\x00WEBPVP8L\x05\x00\x00\x00"),
+		"empty":     nil,
+	} {
+		if _, err := processImage(data, testHandler.Config.UploadMaxDimension); err == nil {
+			t.Errorf("%s: processImage accepted it", name)
+		} else if imageErrorCode(err) != "invalid_image" {
+			t.Errorf("%s: error code %q", name, imageErrorCode(err))
+		}
+	}
+}
+
+func TestProcessImageRejectsLargeDimensions(t *testing.T) {
+	_, err := processImage(testJPEG(t, 32, 16, 0), 20)
+	if err == nil || imageErrorCode(err) != "image_too_large" {
+		t.Fatalf("err = %v, want image_too_large", err)
+	}
+	if _, err := processImage(testJPEG(t, 16, 16, 0), 20); err != nil {
+		t.Fatalf("image within the limit rejected: %v", err)
+	}
+}
+
+func TestProcessImagePNG(t *testing.T) {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewNRGBA(image.Rect(0, 0, 5, 3))); err != nil {
+		t.Fatal(err)
+	}
+	p, err := processImage(buf.Bytes(), testHandler.Config.UploadMaxDimension)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.ContentType != "image/png" || p.Ext != ".png" || p.Width != 5 || p.Height != 3 {
+		t.Errorf("got %s %s %dx%d", p.ContentType, p.Ext, p.Width, p.Height)
+	}
+}
+
+func TestProcessImageStripsWebPMetadata(t *testing.T) {
+	chunk := func(fourCC string, payload []byte) []byte {
+		c := append([]byte(fourCC), 0, 0, 0, 0)
+		binary.LittleEndian.PutUint32(c[4:], uint32(len(payload)))
+		c = append(c, payload...)
+		if len(payload)%2 == 1 {
+			c = append(c, 0)
+		}
+		return c
+	}
+	// A 3×2 lossless bitstream header; the pixel data isn't decoded.
+	vp8l := []byte{0x2f, 0, 0, 0, 0}
+	binary.LittleEndian.PutUint32(vp8l[1:], 2|1<<14)
+
+	var body []byte
+	body = append(body, chunk("VP8L", vp8l)...)
+	body = append(body, chunk("EXIF", []byte("GPS 51.5N 0.1W"))...)
+	body = append(body, chunk("XMP ", []byte("<x:xmpmeta/>"))...)
+	data := append([]byte("RIFF\x00\x00\x00\x00WEBP"), body...)
+	binary.LittleEndian.PutUint32(data[4:8], uint32(4+len(body)))
+
+	p, err := processImage(data, testHandler.Config.UploadMaxDimension)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.ContentType != "image/webp" || p.Width != 3 || p.Height != 2 {
+		t.Errorf("got %s %dx%d", p.ContentType, p.Width, p.Height)
+	}
+	if bytes.Contains(p.Data, []byte("EXIF")) || bytes.Contains(p.Data, []byte("GPS")) || bytes.Contains(p.Data, []byte("xmpmeta")) {
+		t.Error("metadata chunks survived")
+	}
+	if got := binary.LittleEndian.Uint32(p.Data[4:8]); int(got) != len(p.Data)-8 {
+		t.Errorf("RIFF size = %d, want %d", got, len(p.Data)-8)
+	}
+}
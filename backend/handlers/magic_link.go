@@ -0,0 +1,172 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/karti/orange-city-mart/backend/db"
+	authmw "github.com/karti/orange-city-mart/backend/middleware"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	magicLinkTTL        = 15 * time.Minute
+	magicLinkRateWindow = 15 * time.Minute
+	magicLinkRateLimit  = 3 // links per email per window
+)
+
+// normalizeEmail lower-cases and trims an email so lookups are case-insensitive.
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// ── Request Magic Link ────────────────────────────────────────────────────────
+
+// RequestMagicLink handles POST /api/auth/magic-link
+// Issues a single-use, short-lived login token for the email and delivers it
+// out of band. Always responds 200 so the endpoint can't be used to probe
+// which emails are registered.
+func (h *Handler) RequestMagicLink(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Email string `json:"email"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_body", "invalid request body")
+		return
+	}
+	email := normalizeEmail(req.Email)
+	if email == "" || !strings.Contains(email, "@") {
+		writeError(w, http.StatusBadRequest, "invalid_email", "valid email is required")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	// Rate limit per email
+	var recent int
+	err := db.Pool.QueryRow(ctx, `
+		SELECT COUNT(*) FROM login_tokens
+		WHERE email = $1 AND created_at > $2`,
+		email, time.Now().Add(-magicLinkRateWindow),
+	).Scan(&recent)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "database_error", "database error")
+		return
+	}
+	if recent >= magicLinkRateLimit {
+		w.Header().Set("Retry-After", strconv.Itoa(int(magicLinkRateWindow.Seconds())))
+		writeError(w, http.StatusTooManyRequests, "rate_limited", "too many login links requested, try again later")
+		return
+	}
+
+	raw, hash, err := newOpaqueToken()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "internal_error", "internal error")
+		return
+	}
+	_, err = db.Pool.Exec(ctx, `
+		INSERT INTO login_tokens (email, token_hash, expires_at)
+		VALUES ($1, $2, $3)`,
+		email, hash, time.Now().Add(magicLinkTTL),
+	)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "database_error", "database error")
+		return
+	}
+
+	link := fmt.Sprintf("%s/login/magic?token=%s", h.Config.FrontendURL, raw)
+	sendMailAsync(ctx, email, "Your Orange City Mart sign-in link", "Sign in with this link. It expires in 15 minutes.\n\n"+link)
+
+	writeJSON(w, http.StatusOK, map[string]bool{"success": true})
+}
+
+// ── Magic Login ───────────────────────────────────────────────────────────────
+
+// MagicLogin handles POST /api/auth/magic-login
+// Exchanges a magic-link token for a JWT, consuming the token. A user who
+// doesn't exist yet is created; either way the email is marked verified,
+// since following the link proves ownership of the inbox.
+func (h *Handler) MagicLogin(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Token string `json:"token"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Token == "" {
+		writeError(w, http.StatusBadRequest, "missing_token", "token is required")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	tx, err := db.Pool.Begin(ctx)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "database_error", "database error")
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	// Consume the token atomically so it can only ever be used once.
+	var email string
+	err = tx.QueryRow(ctx, `
+		UPDATE login_tokens SET used_at = NOW()
+		WHERE token_hash = $1 AND used_at IS NULL AND expires_at > NOW()
+		RETURNING email`,
+		hashToken(req.Token),
+	).Scan(&email)
+	if err == pgx.ErrNoRows {
+		writeError(w, http.StatusUnauthorized, "invalid_token", "invalid or expired token")
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "database_error", "database error")
+		return
+	}
+
+	var u userInfo
+	err = tx.QueryRow(ctx, `
+		UPDATE users SET email_verified = TRUE
+		WHERE lower(email) = $1
+		RETURNING id, name, email, wallet_balance, role`, email,
+	).Scan(&u.ID, &u.Name, &u.Email, &u.WalletBalance, &u.Role)
+	if err == pgx.ErrNoRows {
+		// First sign-in: create the account with an unusable password.
+		raw, _, tokErr := newOpaqueToken()
+		if tokErr != nil {
+			writeError(w, http.StatusInternalServerError, "internal_error", "internal error")
+			return
+		}
+		hash, hashErr := bcrypt.GenerateFromPassword([]byte(raw), bcrypt.DefaultCost)
+		if hashErr != nil {
+			writeError(w, http.StatusInternalServerError, "internal_error", "internal error")
+			return
+		}
+		name := email[:strings.Index(email, "@")]
+		err = tx.QueryRow(ctx, `
+			INSERT INTO users (name, email, password_hash, email_verified)
+			VALUES ($1, $2, $3, TRUE)
+			RETURNING id, name, email, wallet_balance, role`,
+			name, email, string(hash),
+		).Scan(&u.ID, &u.Name, &u.Email, &u.WalletBalance, &u.Role)
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "database_error", "database error")
+		return
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		writeError(w, http.StatusInternalServerError, "database_error", "commit failed")
+		return
+	}
+	// The account may have just become verified; don't let a cached
+	// unverified claim outlive that.
+	authmw.InvalidateClaims(u.ID)
+
+	h.signIn(ctx, w, u)
+}
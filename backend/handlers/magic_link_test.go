@@ -0,0 +1,133 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	authmw "github.com/karti/orange-city-mart/backend/middleware"
+)
+
+// newLoginToken stores a magic-link token for email that expires after ttl,
+// which may be negative, and returns the raw token.
+func newLoginToken(t *testing.T, email string, ttl time.Duration) string {
+	t.Helper()
+	raw, hash, err := newOpaqueToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := testPool.Exec(context.Background(), `
+		INSERT INTO login_tokens (email, token_hash, expires_at) VALUES ($1, $2, $3)`,
+		email, hash, time.Now().Add(ttl)); err != nil {
+		t.Fatal(err)
+	}
+	return raw
+}
+
+func magicLogin(token string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	testHandler.MagicLogin(w, httptest.NewRequest(http.MethodPost, "/api/auth/magic-login", strings.NewReader(`{"token":"`+token+`"}`)))
+	return w
+}
+
+// TestMagicLoginRefreshesCachedClaims checks that a user who verifies their
+// email through a magic link is treated as verified on their very next
+// request, not once the claims cache expires.
+func TestMagicLoginRefreshesCachedClaims(t *testing.T) {
+	requireDB(t)
+	ctx := context.Background()
+	userID := newTestUser(t, testPool, 0)
+	t.Cleanup(func() { testPool.Exec(ctx, `DELETE FROM users WHERE id = $1`, userID) })
+	var email string
+	if err := testPool.QueryRow(ctx, `SELECT email FROM users WHERE id = $1`, userID).Scan(&email); err != nil {
+		t.Fatal(err)
+	}
+
+	token, err := testHandler.Auth.SignToken(userID, "user", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	verified := func() bool {
+		var got bool
+		h := testHandler.Auth.RequireAuth(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			c, _ := authmw.ClaimsFromContext(r.Context())
+			got = c.EmailVerified
+		}))
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		if w.Code != http.StatusOK {
+			t.Fatalf("RequireAuth = %d: %s", w.Code, w.Body)
+		}
+		return got
+	}
+
+	if verified() {
+		t.Fatal("new user already verified")
+	}
+
+	raw := newLoginToken(t, email, time.Minute)
+	w := magicLogin(raw)
+	if w.Code != http.StatusOK {
+		t.Fatalf("MagicLogin = %d: %s", w.Code, w.Body)
+	}
+
+	if !verified() {
+		t.Fatal("freshly verified user still sees the cached unverified claims")
+	}
+}
+
+// TestMagicLoginExchangesTokenOnce checks that a link signs a new address
+// up as a verified user and then can't be used again.
+func TestMagicLoginExchangesTokenOnce(t *testing.T) {
+	requireDB(t)
+	ctx := context.Background()
+	email := fmt.Sprintf("magic%d@example.com", time.Now().UnixNano())
+	t.Cleanup(func() {
+		testPool.Exec(ctx, `DELETE FROM users WHERE lower(email) = $1`, email)
+		testPool.Exec(ctx, `DELETE FROM login_tokens WHERE email = $1`, email)
+	})
+	raw := newLoginToken(t, email, 15*time.Minute)
+
+	w := magicLogin(raw)
+	if w.Code != http.StatusOK {
+		t.Fatalf("MagicLogin = %d: %s", w.Code, w.Body)
+	}
+	var resp authResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if userID, err := testHandler.Auth.VerifyToken(ctx, resp.Token); err != nil || userID != resp.User.ID {
+		t.Errorf("issued token verifies as %q (%v), want %q", userID, err, resp.User.ID)
+	}
+	var verified bool
+	if err := testPool.QueryRow(ctx, `SELECT email_verified FROM users WHERE lower(email) = $1`, email).Scan(&verified); err != nil || !verified {
+		t.Errorf("created user verified = %t (%v), want true", verified, err)
+	}
+
+	if w := magicLogin(raw); w.Code != http.StatusUnauthorized {
+		t.Errorf("reused token = %d, want 401", w.Code)
+	}
+}
+
+func TestMagicLoginRejectsExpiredToken(t *testing.T) {
+	requireDB(t)
+	ctx := context.Background()
+	email := fmt.Sprintf("late%d@example.com", time.Now().UnixNano())
+	t.Cleanup(func() { testPool.Exec(ctx, `DELETE FROM login_tokens WHERE email = $1`, email) })
+
+	if w := magicLogin(newLoginToken(t, email, -time.Minute)); w.Code != http.StatusUnauthorized {
+		t.Fatalf("expired token = %d, want 401: %s", w.Code, w.Body)
+	}
+	var created bool
+	testPool.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM users WHERE lower(email) = $1)`, email).Scan(&created)
+	if created {
+		t.Error("expired token created an account")
+	}
+}
@@ -0,0 +1,95 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/smtp"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/karti/orange-city-mart/backend/config"
+)
+
+// Mailer delivers account emails (magic links, password resets). Swap the
+// implementation with SetMailer; the default just logs the message.
+type Mailer interface {
+	Send(ctx context.Context, to, subject, body string) error
+}
+
+var mailer Mailer = LogMailer{}
+
+// SetMailer replaces the Mailer used by the auth handlers.
+func SetMailer(m Mailer) { mailer = m }
+
+// mailSendTimeout bounds a background send started by sendMailAsync.
+const mailSendTimeout = 30 * time.Second
+
+// sendMailAsync delivers a message without making the request wait for the
+// mail transport, so response time doesn't depend on whether (or how slowly)
+// mail went out. Failures are only logged: reporting them would reveal that
+// the account exists.
+func sendMailAsync(ctx context.Context, to, subject, body string) {
+	ctx = context.WithoutCancel(ctx)
+	go func() {
+		ctx, cancel := context.WithTimeout(ctx, mailSendTimeout)
+		defer cancel()
+		if err := mailer.Send(ctx, to, subject, body); err != nil {
+			logf(ctx, "mail: send %q to %s failed: %v", subject, to, err)
+		}
+	}()
+}
+
+// LogMailer writes messages to the server log instead of sending them.
+// Useful locally and until a mail transport is configured. Link tokens are
+// redacted, since anyone who can read the log could otherwise use them.
+type LogMailer struct{}
+
+func (LogMailer) Send(_ context.Context, to, subject, body string) error {
+	log.Printf("mail to %s: %s\n%s", to, subject, redactTokens(body))
+	return nil
+}
+
+var tokenParam = regexp.MustCompile(`([?&]token=)[^&\s]+`)
+
+// redactTokens blanks the value of every token query parameter in s.
+func redactTokens(s string) string {
+	return tokenParam.ReplaceAllString(s, "${1}[redacted]")
+}
+
+// SMTPMailer sends plain-text mail through an SMTP relay with PLAIN auth.
+type SMTPMailer struct {
+	Addr     string // host:port
+	From     string
+	Username string
+	Password string
+}
+
+// NewSMTPMailer builds an SMTPMailer from the SMTP_* settings. It returns nil
+// when SMTP_ADDR is unset.
+func NewSMTPMailer(cfg *config.Config) *SMTPMailer {
+	if cfg.SMTPAddr == "" {
+		return nil
+	}
+	return &SMTPMailer{
+		Addr:     cfg.SMTPAddr,
+		From:     cfg.SMTPFrom,
+		Username: cfg.SMTPUsername,
+		Password: cfg.SMTPPassword,
+	}
+}
+
+func (m *SMTPMailer) Send(_ context.Context, to, subject, body string) error {
+	var auth smtp.Auth
+	if m.Username != "" {
+		host := m.Addr
+		if i := strings.LastIndex(host, ":"); i >= 0 {
+			host = host[:i]
+		}
+		auth = smtp.PlainAuth("", m.Username, m.Password, host)
+	}
+	msg := fmt.Sprintf("From: %s\r\nTo: %s\r\nSubject: %s\r\nContent-Type: text/plain; charset=UTF-8\r\n\r\n%s\r\n",
+		m.From, to, subject, body)
+	return smtp.SendMail(m.Addr, auth, m.From, []string{to}, []byte(msg))
+}
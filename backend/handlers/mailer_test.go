@@ -0,0 +1,46 @@
+package handlers
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRedactTokens(t *testing.T) {
+	body := "Reset here:\n\nhttps://mart.example/reset-password?token=abc123DEF_-x\n" +
+		"or https://mart.example/login/magic?ref=mail&token=zzz&x=1"
+	got := redactTokens(body)
+	if strings.Contains(got, "abc123DEF_-x") || strings.Contains(got, "zzz") {
+		t.Fatalf("token left in %q", got)
+	}
+	if !strings.Contains(got, "?token=[redacted]") || !strings.Contains(got, "&token=[redacted]&x=1") {
+		t.Fatalf("unexpected redaction %q", got)
+	}
+}
+
+type chanMailer chan string
+
+func (c chanMailer) Send(ctx context.Context, to, subject, body string) error {
+	c <- to
+	return nil
+}
+
+func TestSendMailAsyncOutlivesRequest(t *testing.T) {
+	sent := make(chanMailer, 1)
+	SetMailer(sent)
+	defer SetMailer(LogMailer{})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	sendMailAsync(ctx, "a@b.com", "s", "b")
+	cancel() // the request finishing must not abort the send
+
+	select {
+	case to := <-sent:
+		if to != "a@b.com" {
+			t.Fatalf("sent to %q", to)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("mail was never sent")
+	}
+}
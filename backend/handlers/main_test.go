@@ -0,0 +1,174 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/karti/orange-city-mart/backend/config"
+	"github.com/karti/orange-city-mart/backend/db"
+	authmw "github.com/karti/orange-city-mart/backend/middleware"
+)
+
+// testHandler serves requests under the default test configuration; tests
+// that need a setting changed build their own with newTestHandler.
+var testHandler *Handler
+
+// newTestHandler returns a Handler on a fresh copy of the test configuration
+// after tweak, if any, has adjusted it.
+func newTestHandler(tweak func(c *config.Config)) *Handler {
+	c := config.Default()
+	c.JWTSecret = []byte("test-secret-0123456789abcdef0123456789")
+	if tweak != nil {
+		tweak(c)
+	}
+	return &Handler{Config: c, Auth: authmw.NewAuth(c)}
+}
+
+// testPool is a pool on a throwaway schema loaded from schema.sql, or nil
+// when TEST_DATABASE_URL is unset; database tests then skip.
+var testPool *pgxpool.Pool
+
+func TestMain(m *testing.M) {
+	testHandler = newTestHandler(nil)
+
+	dsn := os.Getenv("TEST_DATABASE_URL")
+	if dsn == "" {
+		os.Exit(m.Run())
+	}
+
+	ctx := context.Background()
+	schema := fmt.Sprintf("test_%d", time.Now().UnixNano())
+	admin, err := pgx.Connect(ctx, dsn)
+	if err != nil {
+		log.Fatalf("connect test database: %v", err)
+	}
+	if _, err := admin.Exec(ctx, `CREATE EXTENSION IF NOT EXISTS "uuid-ossp"`); err != nil {
+		log.Fatalf("create extension: %v", err)
+	}
+	if _, err := admin.Exec(ctx, "CREATE SCHEMA "+schema); err != nil {
+		log.Fatalf("create schema: %v", err)
+	}
+
+	cfg, err := pgxpool.ParseConfig(dsn)
+	if err != nil {
+		log.Fatalf("parse TEST_DATABASE_URL: %v", err)
+	}
+	cfg.ConnConfig.DefaultQueryExecMode = pgx.QueryExecModeSimpleProtocol
+	cfg.ConnConfig.RuntimeParams["search_path"] = schema + ",public"
+	testPool, err = pgxpool.NewWithConfig(ctx, cfg)
+	if err != nil {
+		log.Fatalf("open test pool: %v", err)
+	}
+	ddl, err := os.ReadFile("../schema.sql")
+	if err != nil {
+		log.Fatalf("read schema: %v", err)
+	}
+	if _, err := testPool.Exec(ctx, string(ddl)); err != nil {
+		log.Fatalf("load schema: %v", err)
+	}
+	db.Pool = testPool
+
+	code := m.Run()
+
+	testPool.Close()
+	admin.Exec(ctx, "DROP SCHEMA "+schema+" CASCADE")
+	admin.Close(ctx)
+	os.Exit(code)
+}
+
+// requireDB skips the test when no database is configured.
+func requireDB(t *testing.T) {
+	t.Helper()
+	if testPool == nil {
+		t.Skip("TEST_DATABASE_URL not set")
+	}
+}
+
+// asUser returns r carrying userID as the authenticated caller.
+func asUser(r *http.Request, userID string) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), authmw.UserIDKey, userID))
+}
+
+// withURLParam returns r with a chi URL parameter set, as the router would.
+func withURLParam(r *http.Request, key, value string) *http.Request {
+	rctx := chi.RouteContext(r.Context())
+	if rctx == nil {
+		rctx = chi.NewRouteContext()
+		r = r.WithContext(context.WithValue(r.Context(), chi.RouteCtxKey, rctx))
+	}
+	rctx.URLParams.Add(key, value)
+	return r
+}
+
+// testTx opens a transaction on the test database that is rolled back when
+// the test ends, skipping the test when no database is configured.
+func testTx(t *testing.T) pgx.Tx {
+	t.Helper()
+	requireDB(t)
+	ctx := context.Background()
+	tx, err := testPool.Begin(ctx)
+	if err != nil {
+		t.Fatalf("begin: %v", err)
+	}
+	t.Cleanup(func() { tx.Rollback(ctx) })
+	return tx
+}
+
+// newTestUser inserts a user with the given wallet balance and returns its id.
+// Pass testPool instead of a transaction for rows a handler must see.
+func newTestUser(t *testing.T, q querier, balance float64) string {
+	t.Helper()
+	var id string
+	err := q.QueryRow(context.Background(), `
+		INSERT INTO users (name, email, password_hash, wallet_balance)
+		VALUES ('Test', uuid_generate_v4()::text || '@example.com', 'x', $1)
+		RETURNING id`, balance,
+	).Scan(&id)
+	if err != nil {
+		t.Fatalf("insert user: %v", err)
+	}
+	return id
+}
+
+// newTestAuction inserts an ACTIVE auction ending in an hour for a new
+// product listed by sellerID, and returns the auction id.
+func newTestAuction(t *testing.T, tx pgx.Tx, sellerID, refundPolicy string) string {
+	t.Helper()
+	ctx := context.Background()
+	var productID, auctionID string
+	err := tx.QueryRow(ctx, `
+		INSERT INTO products (seller_id, title, type, price)
+		VALUES ($1, 'Test item', 'AUCTION', 10) RETURNING id`, sellerID,
+	).Scan(&productID)
+	if err != nil {
+		t.Fatalf("insert product: %v", err)
+	}
+	err = tx.QueryRow(ctx, `
+		INSERT INTO auctions (product_id, start_price, end_time, refund_policy)
+		VALUES ($1, 10, NOW() + INTERVAL '1 hour', $2) RETURNING id`,
+		productID, refundPolicy,
+	).Scan(&auctionID)
+	if err != nil {
+		t.Fatalf("insert auction: %v", err)
+	}
+	return auctionID
+}
+
+// walletBalance reads a user's wallet_balance.
+func walletBalance(t *testing.T, q querier, userID string) float64 {
+	t.Helper()
+	var b float64
+	if err := q.QueryRow(context.Background(),
+		`SELECT wallet_balance::float8 FROM users WHERE id = $1`, userID).Scan(&b); err != nil {
+		t.Fatalf("read balance: %v", err)
+	}
+	return b
+}
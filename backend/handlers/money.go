@@ -0,0 +1,96 @@
+package handlers
+
+import (
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Rounding modes for MONEY_ROUNDING. They only differ on exact halves of a
+// paisa: half_up sends 0.005 to 0.01, half_even (banker's rounding) sends it
+// to the nearest even paisa, 0.00, so halves don't systematically favour one
+// side over many operations.
+const (
+	roundHalfUp   = "half_up"
+	roundHalfEven = "half_even"
+)
+
+// roundMoney rounds a float amount to two decimal places using the
+// configured rounding mode. Every computed amount (fees, refunds, bid steps)
+// goes through here so the arithmetic is reproducible.
+func (h *Handler) roundMoney(f float64) float64 {
+	// Snap away binary noise first so that 1.005, stored as
+	// 1.00499999..., is treated as the exact half it was written as.
+	cents := math.Round(f*100*1e6) / 1e6
+	if h.Config.MoneyRounding == roundHalfEven {
+		return math.RoundToEven(cents) / 100
+	}
+	return math.Round(cents) / 100
+}
+
+var currencySymbols = map[string]string{
+	"INR": "₹",
+	"USD": "$",
+	"EUR": "€",
+	"GBP": "£",
+	"JPY": "¥",
+}
+
+// localeFormat describes how a locale writes an amount.
+type localeFormat struct {
+	group, decimal string
+	indian         bool // group as 12,34,567 rather than 1,234,567
+	symbolAfter    bool // "1.234,56 €" rather than "€1,234.56"
+}
+
+var localeFormats = map[string]localeFormat{
+	"en-IN": {group: ",", decimal: ".", indian: true},
+	"hi-IN": {group: ",", decimal: ".", indian: true},
+	"en-US": {group: ",", decimal: "."},
+	"en-GB": {group: ",", decimal: "."},
+	"de-DE": {group: ".", decimal: ",", symbolAfter: true},
+	"fr-FR": {group: " ", decimal: ",", symbolAfter: true},
+	"es-ES": {group: ".", decimal: ",", symbolAfter: true},
+}
+
+// formatMoney renders an amount in the platform currency (CURRENCY) for the
+// configured MONEY_LOCALE, e.g. "₹12,34,567.50" for en-IN or "1.234.567,50 €"
+// for de-DE with EUR. Unknown locales fall back to en-US conventions.
+func (h *Handler) formatMoney(f float64) string {
+	lf, ok := localeFormats[h.Config.MoneyLocale]
+	if !ok {
+		lf = localeFormats["en-US"]
+	}
+
+	neg := f < 0
+	cents := int64(math.Round(math.Abs(h.roundMoney(f)) * 100))
+	whole := strconv.FormatInt(cents/100, 10)
+	frac := strconv.FormatInt(100+cents%100, 10)[1:]
+
+	// Group from the right: threes, or for the Indian system a three then twos.
+	var groups []string
+	for size := 3; len(whole) > size; {
+		groups = append([]string{whole[len(whole)-size:]}, groups...)
+		whole = whole[:len(whole)-size]
+		if lf.indian {
+			size = 2
+		}
+	}
+	groups = append([]string{whole}, groups...)
+	num := strings.Join(groups, lf.group) + lf.decimal + frac
+
+	code := h.Config.Currency
+	symbol, ok := currencySymbols[code]
+	if !ok {
+		symbol = code + " "
+	}
+	if lf.symbolAfter {
+		num += " " + strings.TrimSpace(symbol)
+	} else {
+		num = symbol + num
+	}
+	if neg {
+		num = "-" + num
+	}
+	return num
+}
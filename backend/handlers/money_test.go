@@ -0,0 +1,121 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/karti/orange-city-mart/backend/config"
+)
+
+func TestRoundMoneyHalves(t *testing.T) {
+	halfEven := newTestHandler(func(c *config.Config) { c.MoneyRounding = "half_even" })
+	for _, tc := range []struct {
+		in               float64
+		halfUp, halfEven float64
+	}{
+		{1.005, 1.01, 1.00},
+		{1.015, 1.02, 1.02},
+		{0.125, 0.13, 0.12},
+		{2.675, 2.68, 2.68},
+		{-1.005, -1.01, -1.00},
+		{1.0049, 1.00, 1.00},
+		{1.0051, 1.01, 1.01},
+		{99.999, 100, 100},
+	} {
+		if got := testHandler.roundMoney(tc.in); got != tc.halfUp {
+			t.Errorf("half_up roundMoney(%v) = %v, want %v", tc.in, got, tc.halfUp)
+		}
+		if got := halfEven.roundMoney(tc.in); got != tc.halfEven {
+			t.Errorf("half_even roundMoney(%v) = %v, want %v", tc.in, got, tc.halfEven)
+		}
+	}
+}
+
+func TestFormatMoney(t *testing.T) {
+	for _, tc := range []struct {
+		locale, currency string
+		in               float64
+		want             string
+	}{
+		{"en-IN", "INR", 999, "₹999.00"},
+		{"en-IN", "INR", 100000, "₹1,00,000.00"},
+		{"en-US", "USD", 1234567.5, "$1,234,567.50"},
+		{"de-DE", "EUR", 1234567.5, "1.234.567,50 €"},
+		{"fr-FR", "EUR", 1234.5, "1\u202f234,50 €"},
+		{"xx-XX", "CHF", 1234.5, "CHF 1,234.50"},
+		{"en-IN", "INR", -1234.005, "-₹1,234.01"},
+		{"en-IN", "INR", 0, "₹0.00"},
+	} {
+		h := newTestHandler(func(c *config.Config) { c.MoneyLocale, c.Currency = tc.locale, tc.currency })
+		if got := h.formatMoney(tc.in); got != tc.want {
+			t.Errorf("formatMoney(%v) in %s/%s = %q, want %q", tc.in, tc.locale, tc.currency, got, tc.want)
+		}
+	}
+	if got := testHandler.formatMoney(1234567.5); got != "₹12,34,567.50" {
+		t.Errorf("formatMoney under the default config = %q, want ₹12,34,567.50", got)
+	}
+}
+
+func TestFormatAmount(t *testing.T) {
+	for in, want := range map[float64]string{
+		100:     "100.00",
+		0.1:     "0.10",
+		1234.5:  "1234.50",
+		99.999:  "100.00",
+		-12.345: "-12.35",
+	} {
+		if got := formatAmount(in); got != want {
+			t.Errorf("formatAmount(%v) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+// TestResponsesCarryConfiguredCurrency checks that product and wallet
+// responses name the configured currency and format amounts for the
+// configured locale.
+func TestResponsesCarryConfiguredCurrency(t *testing.T) {
+	requireDB(t)
+	ctx := context.Background()
+	seller := newTestUser(t, testPool, 1234.5)
+	t.Cleanup(func() { testPool.Exec(ctx, `DELETE FROM users WHERE id = $1`, seller) })
+	productID := newTestFixedProduct(t, seller, 1, "AVAILABLE")
+
+	for _, tc := range []struct {
+		locale, currency     string
+		price, walletBalance string
+	}{
+		{"en-US", "USD", "$100.00", "$1,234.50"},
+		{"de-DE", "EUR", "100,00 €", "1.234,50 €"},
+	} {
+		h := newTestHandler(func(c *config.Config) { c.MoneyLocale, c.Currency = tc.locale, tc.currency })
+
+		w := httptest.NewRecorder()
+		h.GetProduct(w, withURLParam(httptest.NewRequest(http.MethodGet, "/api/products/"+productID, nil), "id", productID))
+		var product struct {
+			Currency       string `json:"currency"`
+			PriceFormatted string `json:"price_formatted"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &product); err != nil || w.Code != http.StatusOK {
+			t.Fatalf("GetProduct = %d: %s", w.Code, w.Body)
+		}
+		if product.Currency != tc.currency || product.PriceFormatted != tc.price {
+			t.Errorf("%s product = %s %q, want %s %q", tc.locale, product.Currency, product.PriceFormatted, tc.currency, tc.price)
+		}
+
+		w = httptest.NewRecorder()
+		h.GetWallet(w, asUser(httptest.NewRequest(http.MethodGet, "/api/wallet", nil), seller))
+		var wallet struct {
+			Currency         string `json:"currency"`
+			BalanceFormatted string `json:"balance_formatted"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &wallet); err != nil || w.Code != http.StatusOK {
+			t.Fatalf("GetWallet = %d: %s", w.Code, w.Body)
+		}
+		if wallet.Currency != tc.currency || wallet.BalanceFormatted != tc.walletBalance {
+			t.Errorf("%s wallet = %s %q, want %s %q", tc.locale, wallet.Currency, wallet.BalanceFormatted, tc.currency, tc.walletBalance)
+		}
+	}
+}
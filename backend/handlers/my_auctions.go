@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/karti/orange-city-mart/backend/db"
+	authmw "github.com/karti/orange-city-mart/backend/middleware"
+)
+
+// ─────────────────────────────────────────────────────────────────────────────
+// ListMyAuctions  GET /api/my/auctions?status=ACTIVE
+//
+// Seller control panel: every auction on the caller's products with its
+// current high bid, bid count, live viewer count from the hub, time left and
+// whether the reserve has been met. status optionally filters by auction
+// status.
+// ─────────────────────────────────────────────────────────────────────────────
+func (h *AuctionHandler) ListMyAuctions(w http.ResponseWriter, r *http.Request) {
+	userID, ok := authmw.UserIDFromContext(r.Context())
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "unauthorized", "unauthorized")
+		return
+	}
+
+	status := r.URL.Query().Get("status")
+	switch status {
+	case "", "ACTIVE", "ENDED", "ENDED_NO_SALE", "CANCELLED":
+	default:
+		writeError(w, http.StatusBadRequest, "invalid_status", "status must be ACTIVE, ENDED, ENDED_NO_SALE or CANCELLED")
+		return
+	}
+
+	rows, err := db.Pool.Query(r.Context(), `
+		SELECT a.id, p.id, p.title, a.status, a.current_highest_bid, a.end_time,
+		       (SELECT COUNT(*) FROM bids b WHERE b.auction_id = a.id),
+		       a.reserve_price IS NOT NULL,
+		       a.reserve_price IS NULL OR a.current_highest_bid >= a.reserve_price
+		FROM auctions a
+		JOIN products p ON p.id = a.product_id
+		WHERE p.seller_id = $1
+		  AND ($2::text = '' OR a.status = $2::text)
+		ORDER BY a.end_time DESC`,
+		userID, status,
+	)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "database_error", "database error")
+		return
+	}
+	defer rows.Close()
+
+	type MyAuction struct {
+		AuctionID        string  `json:"auction_id"`
+		ProductID        string  `json:"product_id"`
+		Title            string  `json:"title"`
+		Status           string  `json:"status"`
+		CurrentHighBid   float64 `json:"current_highest_bid"`
+		BidCount         int     `json:"bid_count"`
+		ViewerCount      int     `json:"viewer_count"`
+		EndTime          string  `json:"end_time"`
+		SecondsRemaining int64   `json:"seconds_remaining"`
+		HasReserve       bool    `json:"has_reserve"`
+		ReserveMet       bool    `json:"reserve_met"`
+	}
+
+	auctions := []MyAuction{}
+	for rows.Next() {
+		var a MyAuction
+		var endTime time.Time
+		err := rows.Scan(&a.AuctionID, &a.ProductID, &a.Title, &a.Status, &a.CurrentHighBid,
+			&endTime, &a.BidCount, &a.HasReserve, &a.ReserveMet)
+		if err != nil {
+			continue
+		}
+		a.EndTime = endTime.UTC().Format(time.RFC3339)
+		if a.Status == "ACTIVE" {
+			a.ViewerCount = h.Hub.AuctionViewers(a.AuctionID)
+			if left := time.Until(endTime); left > 0 {
+				a.SecondsRemaining = int64(left.Seconds())
+			}
+		}
+		auctions = append(auctions, a)
+	}
+
+	writeJSON(w, http.StatusOK, auctions)
+}
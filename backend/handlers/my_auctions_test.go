@@ -0,0 +1,72 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/karti/orange-city-mart/backend/hub"
+)
+
+func TestListMyAuctionsOwnWithBidCounts(t *testing.T) {
+	requireDB(t)
+	ctx := context.Background()
+	seller := newTestUser(t, testPool, 0)
+	other := newTestUser(t, testPool, 0)
+	bidder := newTestUser(t, testPool, 0)
+	t.Cleanup(func() {
+		testPool.Exec(ctx, `DELETE FROM users WHERE id IN ($1, $2, $3)`, seller, other, bidder)
+	})
+
+	_, busy := newTestAuctionListing(t, seller)
+	_, quiet := newTestAuctionListing(t, seller)
+	_, ended := newTestAuctionListing(t, seller)
+	newTestAuctionListing(t, other)
+	if _, err := testPool.Exec(ctx, `UPDATE auctions SET status = 'ENDED' WHERE id = $1`, ended); err != nil {
+		t.Fatal(err)
+	}
+	for _, amount := range []float64{20, 30} {
+		if _, err := testPool.Exec(ctx, `INSERT INTO bids (auction_id, user_id, amount) VALUES ($1, $2, $3)`,
+			busy, bidder, amount); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	h := &AuctionHandler{Handler: testHandler, Hub: hub.NewHub(nil)}
+	list := func(query string) map[string]int {
+		t.Helper()
+		w := httptest.NewRecorder()
+		h.ListMyAuctions(w, asUser(httptest.NewRequest(http.MethodGet, "/api/my/auctions?"+query, nil), seller))
+		if w.Code != http.StatusOK {
+			t.Fatalf("status %d: %s", w.Code, w.Body)
+		}
+		var items []struct {
+			AuctionID string `json:"auction_id"`
+			BidCount  int    `json:"bid_count"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &items); err != nil {
+			t.Fatal(err)
+		}
+		counts := map[string]int{}
+		for _, it := range items {
+			counts[it.AuctionID] = it.BidCount
+		}
+		return counts
+	}
+
+	got := list("")
+	want := map[string]int{busy: 2, quiet: 0, ended: 0}
+	if len(got) != len(want) {
+		t.Fatalf("listed %v, want only the caller's %v", got, want)
+	}
+	for id, n := range want {
+		if got[id] != n {
+			t.Errorf("auction %s: bid_count %d, want %d", id, got[id], n)
+		}
+	}
+	if got := list("status=ACTIVE"); len(got) != 2 || got[busy] != 2 {
+		t.Errorf("status=ACTIVE listed %v, want the two active auctions", got)
+	}
+}
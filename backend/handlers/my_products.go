@@ -0,0 +1,121 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/karti/orange-city-mart/backend/db"
+	authmw "github.com/karti/orange-city-mart/backend/middleware"
+)
+
+// myProductStatusFilters maps a ?status= value on ListMyProducts to its
+// condition over p (product), a (latest auction) and s (its settlement).
+var myProductStatusFilters = map[string]string{
+	"active": "(a.status = 'ACTIVE' OR (a.id IS NULL AND p.type = 'FIXED' AND p.status = 'AVAILABLE'))",
+	"ended":  "(a.status <> 'ACTIVE' AND s.status IS DISTINCT FROM 'COMPLETED')",
+	"sold":   "(s.status = 'COMPLETED' OR p.status = 'SOLD_OUT')",
+}
+
+// myProductSorts maps a ?sort= value on ListMyProducts to its ORDER BY.
+var myProductSorts = map[string]string{
+	"newest":      "p.created_at DESC, p.id DESC",
+	"ending_soon": "COALESCE(a.end_time, 'infinity'::timestamptz) ASC, p.id ASC",
+}
+
+// ─────────────────────────────────────────────────────────────────────────────
+// ListMyProducts  GET /api/my-products?status=&sort=
+//
+// Seller dashboard: every live listing the caller owns with its latest
+// auction's status, high bid and bid count, and the settlement state.
+// status is active, ended or sold; sort is newest (default) or ending_soon.
+// ─────────────────────────────────────────────────────────────────────────────
+func (h *Handler) ListMyProducts(w http.ResponseWriter, r *http.Request) {
+	userID, ok := authmw.UserIDFromContext(r.Context())
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "unauthorized", "unauthorized")
+		return
+	}
+	qs := r.URL.Query()
+
+	where := []string{"p.seller_id = $1::uuid", "p.deleted_at IS NULL"}
+	if v := strings.ToLower(qs.Get("status")); v != "" {
+		cond, ok := myProductStatusFilters[v]
+		if !ok {
+			writeError(w, http.StatusBadRequest, "invalid_status", "status must be one of active, ended, sold")
+			return
+		}
+		where = append(where, cond)
+	}
+	sort := strings.ToLower(qs.Get("sort"))
+	if sort == "" {
+		sort = "newest"
+	}
+	orderBy, ok := myProductSorts[sort]
+	if !ok {
+		writeError(w, http.StatusBadRequest, "invalid_sort", "sort must be one of newest, ending_soon")
+		return
+	}
+
+	rows, err := db.Pool.Query(r.Context(), `
+		SELECT p.id, p.title, p.image_url, p.type, p.price, p.status, p.quantity, p.created_at,
+		       a.id, a.status, a.current_highest_bid, a.end_time,
+		       (SELECT COUNT(*) FROM bids b WHERE b.auction_id = a.id),
+		       s.status
+		FROM products p
+		-- a product may have several auctions once relisted; show the latest
+		LEFT JOIN LATERAL (
+		    SELECT * FROM auctions
+		    WHERE product_id = p.id
+		    ORDER BY created_at DESC
+		    LIMIT 1
+		) a ON TRUE
+		LEFT JOIN settlements s ON s.auction_id = a.id
+		WHERE `+strings.Join(where, " AND ")+`
+		ORDER BY `+orderBy,
+		userID,
+	)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "database_error", "database error")
+		return
+	}
+	defer rows.Close()
+
+	type MyProduct struct {
+		ProductID        string   `json:"product_id"`
+		Title            string   `json:"title"`
+		ImageURL         *string  `json:"image_url"`
+		Type             string   `json:"type"`
+		Price            float64  `json:"price"`
+		Status           string   `json:"status"`   // FIXED listings: AVAILABLE or SOLD_OUT
+		Quantity         int      `json:"quantity"` // units left, FIXED listings
+		CreatedAt        string   `json:"created_at"`
+		AuctionID        *string  `json:"auction_id"`
+		AuctionStatus    *string  `json:"auction_status"`
+		CurrentHighBid   *float64 `json:"current_highest_bid"`
+		EndTime          *string  `json:"end_time"`
+		BidCount         int      `json:"bid_count"`
+		SettlementStatus *string  `json:"settlement_status"`
+	}
+
+	products := []MyProduct{}
+	for rows.Next() {
+		var p MyProduct
+		var createdAt time.Time
+		var endTime *time.Time
+		err := rows.Scan(&p.ProductID, &p.Title, &p.ImageURL, &p.Type, &p.Price, &p.Status, &p.Quantity, &createdAt,
+			&p.AuctionID, &p.AuctionStatus, &p.CurrentHighBid, &endTime,
+			&p.BidCount, &p.SettlementStatus)
+		if err != nil {
+			continue
+		}
+		p.CreatedAt = createdAt.UTC().Format(time.RFC3339)
+		if endTime != nil {
+			s := endTime.UTC().Format(time.RFC3339)
+			p.EndTime = &s
+		}
+		products = append(products, p)
+	}
+
+	writeJSON(w, http.StatusOK, products)
+}
@@ -0,0 +1,94 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/karti/orange-city-mart/backend/db"
+	authmw "github.com/karti/orange-city-mart/backend/middleware"
+)
+
+// ─────────────────────────────────────────────────────────────────────────────
+// ListMyPurchases  GET /api/my-purchases
+//
+// Buyer history: every auction the caller won (they are the settlement's
+// winner) and every fixed-price product they bought, newest first, with the
+// settlement state and the chat room id shared with the seller. total_paid
+// sums completed purchases net of post-sale refunds; total_pending is still
+// held awaiting settlement.
+// ─────────────────────────────────────────────────────────────────────────────
+func (h *Handler) ListMyPurchases(w http.ResponseWriter, r *http.Request) {
+	userID, ok := authmw.UserIDFromContext(r.Context())
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "unauthorized", "unauthorized")
+		return
+	}
+
+	rows, err := db.Pool.Query(r.Context(), `
+		SELECT 'AUCTION', s.auction_id, p.id, p.title, p.image_url, 1,
+		       s.amount::float8, s.refunded_amount::float8, s.status, s.created_at,
+		       p.seller_id, u.name
+		FROM settlements s
+		JOIN auctions a ON a.id = s.auction_id
+		JOIN products p ON p.id = a.product_id
+		JOIN users u ON u.id = p.seller_id
+		WHERE s.winner_id = $1::uuid
+		UNION ALL
+		SELECT 'FIXED', NULL::uuid, p.id, p.title, p.image_url, pu.quantity,
+		       pu.amount::float8, 0::float8, pu.status, pu.created_at,
+		       pu.seller_id, u.name
+		FROM purchases pu
+		JOIN products p ON p.id = pu.product_id
+		JOIN users u ON u.id = pu.seller_id
+		WHERE pu.buyer_id = $1::uuid
+		ORDER BY 10 DESC`, userID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "database_error", "database error")
+		return
+	}
+	defer rows.Close()
+
+	type Purchase struct {
+		Kind             string  `json:"kind"` // AUCTION or FIXED
+		AuctionID        *string `json:"auction_id"`
+		ProductID        string  `json:"product_id"`
+		ProductTitle     string  `json:"product_title"`
+		ProductImageURL  *string `json:"product_image_url"`
+		Quantity         int     `json:"quantity"`
+		Amount           float64 `json:"amount"`
+		RefundedAmount   float64 `json:"refunded_amount"`
+		SettlementStatus string  `json:"settlement_status"`
+		PurchasedAt      string  `json:"purchased_at"`
+		SellerID         string  `json:"seller_id"`
+		SellerName       string  `json:"seller_name"`
+		ChatRoomID       string  `json:"chat_room_id"`
+	}
+
+	purchases := []Purchase{}
+	var totalPaid, totalPending float64
+	for rows.Next() {
+		var p Purchase
+		var createdAt time.Time
+		err := rows.Scan(&p.Kind, &p.AuctionID, &p.ProductID, &p.ProductTitle, &p.ProductImageURL, &p.Quantity,
+			&p.Amount, &p.RefundedAmount, &p.SettlementStatus, &createdAt,
+			&p.SellerID, &p.SellerName)
+		if err != nil {
+			continue
+		}
+		p.PurchasedAt = createdAt.UTC().Format(time.RFC3339)
+		p.ChatRoomID = roomID(userID, p.SellerID)
+		switch p.SettlementStatus {
+		case "COMPLETED":
+			totalPaid += p.Amount - p.RefundedAmount
+		case "PENDING", "DISPUTED":
+			totalPending += p.Amount
+		}
+		purchases = append(purchases, p)
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"items":         purchases,
+		"total_paid":    h.roundMoney(totalPaid),
+		"total_pending": h.roundMoney(totalPending),
+	})
+}
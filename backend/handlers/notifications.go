@@ -0,0 +1,192 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/karti/orange-city-mart/backend/db"
+	"github.com/karti/orange-city-mart/backend/hub"
+	authmw "github.com/karti/orange-city-mart/backend/middleware"
+)
+
+// Notification types.
+const (
+	notifySettlementReminder = "settlement_reminder"
+	notifyOutbid             = "outbid"
+	notifyAuctionWon         = "auction_won"
+	notifyAuctionSold        = "auction_sold"
+)
+
+const (
+	defaultNotificationPageSize = 50
+	maxNotificationPageSize     = 200
+)
+
+// notification is an event persisted for one user, so it survives them
+// being offline, and pushed over the WebSocket when they are connected.
+type notification struct {
+	ID        string          `json:"id"`
+	Type      string          `json:"type"`
+	Payload   json.RawMessage `json:"payload"`
+	CreatedAt string          `json:"created_at"`
+	ReadAt    *string         `json:"read_at"`
+}
+
+// userNotification is a stored notification waiting to be pushed to its
+// recipient once the transaction that created it commits.
+type userNotification struct {
+	UserID       string
+	Notification notification
+}
+
+// createNotification stores a notification for userID through q. Pass the
+// transaction that makes the event happen so both commit together, then
+// deliver it with pushNotification.
+func createNotification(ctx context.Context, q querier, userID, typ string, payload any) (notification, error) {
+	n := notification{Type: typ}
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return n, err
+	}
+	n.Payload = data
+
+	var createdAt time.Time
+	err = q.QueryRow(ctx, `
+		INSERT INTO notifications (user_id, type, payload)
+		VALUES ($1::uuid, $2::text, $3::jsonb)
+		RETURNING id, created_at`,
+		userID, typ, string(data),
+	).Scan(&n.ID, &createdAt)
+	n.CreatedAt = createdAt.UTC().Format(time.RFC3339)
+	return n, err
+}
+
+// pushNotification sends n to userID if they are connected. Call only after
+// commit.
+func pushNotification(h *hub.Hub, userID string, n notification) {
+	data, _ := json.Marshal(n)
+	h.SendToUser(userID, hub.Message{
+		Type:    hub.TypeNotification,
+		Payload: json.RawMessage(data),
+	})
+}
+
+// ─────────────────────────────────────────────────────────────────────────────
+// ListNotifications  GET /api/notifications?unread=true&limit=
+//
+// The caller's notifications, newest first, with the total unread count.
+// unread=true limits the list to ones not yet marked read.
+// ─────────────────────────────────────────────────────────────────────────────
+func (h *Handler) ListNotifications(w http.ResponseWriter, r *http.Request) {
+	userID, ok := authmw.UserIDFromContext(r.Context())
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "unauthorized", "unauthorized")
+		return
+	}
+	qs := r.URL.Query()
+
+	limit := defaultNotificationPageSize
+	if v, err := strconv.Atoi(qs.Get("limit")); err == nil && v > 0 {
+		limit = v
+	}
+	if limit > maxNotificationPageSize {
+		limit = maxNotificationPageSize
+	}
+	unreadOnly := qs.Get("unread") == "true"
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	rows, err := db.Pool.Query(ctx, `
+		SELECT id, type, payload, created_at, read_at
+		FROM notifications
+		WHERE user_id = $1::uuid AND (NOT $2::bool OR read_at IS NULL)
+		ORDER BY created_at DESC, id DESC
+		LIMIT $3::int`, userID, unreadOnly, limit)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "database_error", "database error")
+		return
+	}
+	defer rows.Close()
+
+	items := []notification{}
+	for rows.Next() {
+		var n notification
+		var payload []byte
+		var createdAt time.Time
+		var readAt *time.Time
+		if err := rows.Scan(&n.ID, &n.Type, &payload, &createdAt, &readAt); err != nil {
+			writeError(w, http.StatusInternalServerError, "database_error", "database error")
+			return
+		}
+		n.Payload = payload
+		n.CreatedAt = createdAt.UTC().Format(time.RFC3339)
+		if readAt != nil {
+			s := readAt.UTC().Format(time.RFC3339)
+			n.ReadAt = &s
+		}
+		items = append(items, n)
+	}
+	rows.Close()
+
+	var unread int
+	if err := db.Pool.QueryRow(ctx, `
+		SELECT COUNT(*) FROM notifications WHERE user_id = $1::uuid AND read_at IS NULL`, userID,
+	).Scan(&unread); err != nil {
+		writeError(w, http.StatusInternalServerError, "database_error", "database error")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"items":        items,
+		"unread_count": unread,
+	})
+}
+
+// ─────────────────────────────────────────────────────────────────────────────
+// MarkNotificationRead  POST /api/notifications/{id}/read
+//
+// Marks one of the caller's notifications read. Idempotent: an already read
+// notification keeps its original read_at.
+// ─────────────────────────────────────────────────────────────────────────────
+func (h *Handler) MarkNotificationRead(w http.ResponseWriter, r *http.Request) {
+	userID, ok := authmw.UserIDFromContext(r.Context())
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "unauthorized", "unauthorized")
+		return
+	}
+	id := chi.URLParam(r, "id")
+	if _, err := uuid.Parse(id); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_notification_id", "invalid notification id")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	var readAt time.Time
+	err := db.Pool.QueryRow(ctx, `
+		UPDATE notifications SET read_at = COALESCE(read_at, NOW())
+		WHERE id = $1::uuid AND user_id = $2::uuid
+		RETURNING read_at`, id, userID,
+	).Scan(&readAt)
+	if err == pgx.ErrNoRows {
+		writeError(w, http.StatusNotFound, "notification_not_found", "notification not found")
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "database_error", "database error")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]string{
+		"id":      id,
+		"read_at": readAt.UTC().Format(time.RFC3339),
+	})
+}
@@ -0,0 +1,189 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/karti/orange-city-mart/backend/db"
+	authmw "github.com/karti/orange-city-mart/backend/middleware"
+)
+
+// Notifier delivers key events (outbids, auction results, completed
+// settlements) off-platform. Swap the implementation with SetNotifier; the
+// default drops everything. Users opt in with users.email_notifications.
+type Notifier interface {
+	Notify(ctx context.Context, to, subject, body string) error
+}
+
+var notifier Notifier = NopNotifier{}
+
+// SetNotifier replaces the Notifier used for off-platform delivery.
+func SetNotifier(n Notifier) { notifier = n }
+
+// NopNotifier discards every notification.
+type NopNotifier struct{}
+
+func (NopNotifier) Notify(context.Context, string, string, string) error { return nil }
+
+// SMTPNotifier emails notifications through an SMTPMailer.
+type SMTPNotifier struct {
+	Mailer *SMTPMailer
+}
+
+func (n SMTPNotifier) Notify(ctx context.Context, to, subject, body string) error {
+	return n.Mailer.Send(ctx, to, subject, body)
+}
+
+// notifyQueue holds pending off-platform deliveries. Each job does its own
+// lookups, so handlers only pay for a channel send.
+var notifyQueue = make(chan func(context.Context), 256)
+
+// RunNotifyWorkers drains notifyQueue with workers goroutines until ctx is
+// cancelled.
+func RunNotifyWorkers(ctx context.Context, workers int) {
+	for i := 0; i < workers; i++ {
+		go func() {
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case job := <-notifyQueue:
+					jobCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+					job(jobCtx)
+					cancel()
+				}
+			}
+		}()
+	}
+}
+
+// enqueueNotify schedules job without blocking. When the queue is full the
+// job is dropped; these notifications are best-effort.
+func enqueueNotify(job func(context.Context)) {
+	select {
+	case notifyQueue <- job:
+	default:
+		log.Printf("notifier: queue full, dropping notification")
+	}
+}
+
+// notifyUser sends one notification to userID if they have opted in.
+func notifyUser(ctx context.Context, userID, subject, body string) {
+	var email string
+	var enabled bool
+	err := db.Pool.QueryRow(ctx,
+		`SELECT email, email_notifications FROM users WHERE id = $1::uuid`, userID,
+	).Scan(&email, &enabled)
+	if err != nil {
+		log.Printf("notifier: load user %s: %v", userID, err)
+		return
+	}
+	if !enabled {
+		return
+	}
+	if err := notifier.Notify(ctx, email, subject, body); err != nil {
+		log.Printf("notifier: send to %s: %v", userID, err)
+	}
+}
+
+// notifyOutbidOffsite tells the previous leader they were outbid. Call only
+// after commit.
+func (h *Handler) notifyOutbidOffsite(pb placedBid) {
+	if pb.PrevBidderID == nil || *pb.PrevBidderID == pb.BidderID {
+		return
+	}
+	userID := *pb.PrevBidderID
+	enqueueNotify(func(ctx context.Context) {
+		notifyUser(ctx, userID, "You've been outbid",
+			"Someone bid "+h.formatMoney(pb.Amount)+" on an auction you were leading with "+
+				h.formatMoney(pb.PrevAmount)+".\n\nAuction: "+pb.AuctionID)
+	})
+}
+
+// notifyAuctionEndedOffsite tells the winner they won and every other
+// bidder they lost. Call only after commit.
+func (h *Handler) notifyAuctionEndedOffsite(out auctionOutcome) {
+	if out.Status != "ENDED" || out.WinnerID == nil {
+		return
+	}
+	winnerID := *out.WinnerID
+	enqueueNotify(func(ctx context.Context) {
+		notifyUser(ctx, winnerID, "You won the auction",
+			"Your bid of "+h.formatMoney(out.FinalPrice)+" won.\n\nAuction: "+out.AuctionID)
+
+		rows, err := db.Pool.Query(ctx, `
+			SELECT DISTINCT user_id FROM bids
+			WHERE auction_id = $1::uuid AND user_id <> $2::uuid`, out.AuctionID, winnerID)
+		if err != nil {
+			log.Printf("notifier: load bidders for %s: %v", out.AuctionID, err)
+			return
+		}
+		var losers []string
+		for rows.Next() {
+			var id string
+			if rows.Scan(&id) == nil {
+				losers = append(losers, id)
+			}
+		}
+		rows.Close()
+		for _, id := range losers {
+			notifyUser(ctx, id, "Auction ended",
+				"An auction you bid on sold for "+h.formatMoney(out.FinalPrice)+
+					". Your held funds have been returned.\n\nAuction: "+out.AuctionID)
+		}
+	})
+}
+
+// notifySettlementCompletedOffsite tells both parties the sale has settled.
+// Call only after commit.
+func (h *Handler) notifySettlementCompletedOffsite(auctionID string) {
+	enqueueNotify(func(ctx context.Context) {
+		var winnerID, sellerID string
+		var amount float64
+		err := db.Pool.QueryRow(ctx, `
+			SELECT winner_id, seller_id, amount::float8 FROM settlements WHERE auction_id = $1::uuid`,
+			auctionID,
+		).Scan(&winnerID, &sellerID, &amount)
+		if err != nil {
+			log.Printf("notifier: load settlement %s: %v", auctionID, err)
+			return
+		}
+		notifyUser(ctx, winnerID, "Purchase settled",
+			"Your payment of "+h.formatMoney(amount)+" has been released to the seller.\n\nAuction: "+auctionID)
+		notifyUser(ctx, sellerID, "Sale settled",
+			h.formatMoney(amount)+" has been credited to your wallet.\n\nAuction: "+auctionID)
+	})
+}
+
+// UpdateNotificationPreferences handles PUT /api/me/notification-preferences
+// Body: { "email": true }. Opts the caller in or out of email notifications.
+func (h *Handler) UpdateNotificationPreferences(w http.ResponseWriter, r *http.Request) {
+	userID, ok := authmw.UserIDFromContext(r.Context())
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "unauthorized", "unauthorized")
+		return
+	}
+
+	var req struct {
+		Email *bool `json:"email"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Email == nil {
+		writeError(w, http.StatusBadRequest, "invalid_body", "email must be true or false")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	if _, err := db.Pool.Exec(ctx,
+		`UPDATE users SET email_notifications = $2::bool, updated_at = NOW() WHERE id = $1::uuid`,
+		userID, *req.Email); err != nil {
+		writeError(w, http.StatusInternalServerError, "database_error", "database error")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]bool{"email": *req.Email})
+}
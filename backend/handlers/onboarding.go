@@ -0,0 +1,92 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/karti/orange-city-mart/backend/db"
+	authmw "github.com/karti/orange-city-mart/backend/middleware"
+)
+
+// onboardingStep is one item on the new-user checklist. done is an EXISTS-
+// style boolean expression over $1, the user id. The keys must match
+// config.OnboardingStepKeys, which ONBOARDING_STEPS is validated against.
+type onboardingStep struct {
+	Key   string
+	Title string
+	done  string
+}
+
+var onboardingSteps = []onboardingStep{
+	{"verify_email", "Verify your email address",
+		`SELECT email_verified FROM users WHERE id = $1::uuid`},
+	{"first_deposit", "Add money to your wallet",
+		`SELECT EXISTS (SELECT 1 FROM transactions
+		 WHERE user_id = $1::uuid AND type = 'DEPOSIT' AND status = 'COMPLETED')`},
+	{"first_listing", "Create your first listing",
+		`SELECT EXISTS (SELECT 1 FROM products WHERE seller_id = $1::uuid)`},
+	{"first_bid", "Place your first bid",
+		`SELECT EXISTS (SELECT 1 FROM bids WHERE user_id = $1::uuid)`},
+}
+
+// enabledOnboardingSteps returns the checklist in display order. The
+// ONBOARDING_STEPS setting picks and orders a subset.
+func (h *Handler) enabledOnboardingSteps() []onboardingStep {
+	if len(h.Config.OnboardingSteps) == 0 {
+		return onboardingSteps
+	}
+	var out []onboardingStep
+	for _, key := range h.Config.OnboardingSteps {
+		for _, s := range onboardingSteps {
+			if s.Key == key {
+				out = append(out, s)
+				break
+			}
+		}
+	}
+	return out
+}
+
+// ─────────────────────────────────────────────────────────────────────────────
+// GetOnboarding  GET /api/onboarding
+//
+// The caller's activation checklist: each step with a done flag computed
+// from their data, plus completed/total counts for a progress widget.
+// ─────────────────────────────────────────────────────────────────────────────
+func (h *Handler) GetOnboarding(w http.ResponseWriter, r *http.Request) {
+	userID, ok := authmw.UserIDFromContext(r.Context())
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "unauthorized", "unauthorized")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	type stepItem struct {
+		Key   string `json:"key"`
+		Title string `json:"title"`
+		Done  bool   `json:"done"`
+	}
+
+	steps := []stepItem{}
+	completed := 0
+	for _, s := range h.enabledOnboardingSteps() {
+		var done bool
+		if err := db.Pool.QueryRow(ctx, s.done, userID).Scan(&done); err != nil {
+			writeError(w, http.StatusInternalServerError, "database_error", "database error")
+			return
+		}
+		if done {
+			completed++
+		}
+		steps = append(steps, stepItem{Key: s.Key, Title: s.Title, Done: done})
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"steps":     steps,
+		"completed": completed,
+		"total":     len(steps),
+	})
+}
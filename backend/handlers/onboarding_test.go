@@ -0,0 +1,93 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/karti/orange-city-mart/backend/config"
+)
+
+// TestOnboardingStepsMatchConfig checks that every step ONBOARDING_STEPS may
+// name has a checklist entry, so a value that passes config.Load is honoured.
+func TestOnboardingStepsMatchConfig(t *testing.T) {
+	if len(onboardingSteps) != len(config.OnboardingStepKeys) {
+		t.Fatalf("%d checklist steps, %d configurable keys", len(onboardingSteps), len(config.OnboardingStepKeys))
+	}
+	for i, s := range onboardingSteps {
+		if s.Key != config.OnboardingStepKeys[i] {
+			t.Errorf("step %d = %q, config lists %q", i, s.Key, config.OnboardingStepKeys[i])
+		}
+	}
+
+	h := newTestHandler(func(c *config.Config) { c.OnboardingSteps = []string{"first_bid", "verify_email"} })
+	got := h.enabledOnboardingSteps()
+	if len(got) != 2 || got[0].Key != "first_bid" || got[1].Key != "verify_email" {
+		t.Errorf("enabledOnboardingSteps = %+v, want first_bid then verify_email", got)
+	}
+}
+
+// onboarding returns userID's checklist as key → done, with the completed
+// count.
+func onboarding(t *testing.T, userID string) (map[string]bool, int) {
+	t.Helper()
+	w := httptest.NewRecorder()
+	testHandler.GetOnboarding(w, asUser(httptest.NewRequest(http.MethodGet, "/api/onboarding", nil), userID))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", w.Code, w.Body)
+	}
+	var body struct {
+		Steps []struct {
+			Key  string `json:"key"`
+			Done bool   `json:"done"`
+		} `json:"steps"`
+		Completed int `json:"completed"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+	done := map[string]bool{}
+	for _, s := range body.Steps {
+		done[s.Key] = s.Done
+	}
+	return done, body.Completed
+}
+
+func TestOnboardingChecklist(t *testing.T) {
+	requireDB(t)
+	ctx := context.Background()
+	user := newTestUser(t, testPool, 0)
+	t.Cleanup(func() { testPool.Exec(ctx, `DELETE FROM users WHERE id = $1`, user) })
+
+	done, completed := onboarding(t, user)
+	if len(done) != len(onboardingSteps) || completed != 0 {
+		t.Fatalf("new user: %v with %d completed, want every step open", done, completed)
+	}
+	for key, d := range done {
+		if d {
+			t.Errorf("new user: %s done", key)
+		}
+	}
+
+	if _, err := testPool.Exec(ctx, `UPDATE users SET email_verified = TRUE WHERE id = $1`, user); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := testPool.Exec(ctx, `
+		INSERT INTO transactions (user_id, amount, type, status) VALUES ($1, 100, 'DEPOSIT', 'PENDING')`, user); err != nil {
+		t.Fatal(err)
+	}
+	newTestFixedProduct(t, user, 1, "AVAILABLE")
+
+	done, completed = onboarding(t, user)
+	want := map[string]bool{"verify_email": true, "first_deposit": false, "first_listing": true, "first_bid": false}
+	for key, d := range want {
+		if done[key] != d {
+			t.Errorf("%s done = %v, want %v", key, done[key], d)
+		}
+	}
+	if completed != 2 {
+		t.Errorf("completed = %d, want 2", completed)
+	}
+}
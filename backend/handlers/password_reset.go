@@ -0,0 +1,139 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/karti/orange-city-mart/backend/db"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const passwordResetTTL = time.Hour
+
+// ── Forgot Password ───────────────────────────────────────────────────────────
+
+// ForgotPassword handles POST /api/auth/forgot-password
+// Emails a single-use reset token to the account, if there is one. Always
+// responds 200 so the endpoint can't be used to probe registered emails.
+func (h *Handler) ForgotPassword(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Email string `json:"email"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_body", "invalid request body")
+		return
+	}
+	email := normalizeEmail(req.Email)
+	if email == "" {
+		writeError(w, http.StatusBadRequest, "missing_email", "email is required")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	// Known and unknown emails cost the same: one token and one statement
+	// that inserts only when the account exists. The email itself goes out
+	// in the background, so SMTP latency can't reveal the account either.
+	raw, hash, err := newOpaqueToken()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "internal_error", "internal error")
+		return
+	}
+	var userID string
+	err = db.Pool.QueryRow(ctx, `
+		INSERT INTO password_reset_tokens (user_id, token_hash, expires_at)
+		SELECT id, $2::text, $3::timestamptz FROM users WHERE lower(email) = $1::text
+		RETURNING user_id`,
+		email, hash, time.Now().Add(passwordResetTTL),
+	).Scan(&userID)
+	if err == pgx.ErrNoRows {
+		writeJSON(w, http.StatusOK, map[string]bool{"success": true})
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "database_error", "database error")
+		return
+	}
+
+	link := fmt.Sprintf("%s/reset-password?token=%s", h.Config.FrontendURL, raw)
+	body := "Use this link to choose a new password. It expires in one hour.\n\n" + link
+	sendMailAsync(ctx, email, "Reset your Orange City Mart password", body)
+
+	writeJSON(w, http.StatusOK, map[string]bool{"success": true})
+}
+
+// ── Reset Password ────────────────────────────────────────────────────────────
+
+// ResetPassword handles POST /api/auth/reset-password
+// Consumes a reset token and sets the new password. Existing refresh tokens
+// are revoked so other sessions have to sign in again.
+func (h *Handler) ResetPassword(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Token    string `json:"token"`
+		Password string `json:"password"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Token == "" {
+		writeError(w, http.StatusBadRequest, "missing_fields", "token and password are required")
+		return
+	}
+	if len(req.Password) < 8 {
+		writeError(w, http.StatusBadRequest, "weak_password", "password must be at least 8 characters")
+		return
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "internal_error", "internal error")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	tx, err := db.Pool.Begin(ctx)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "database_error", "database error")
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	var userID string
+	err = tx.QueryRow(ctx, `
+		UPDATE password_reset_tokens SET used_at = NOW()
+		WHERE token_hash = $1 AND used_at IS NULL AND expires_at > NOW()
+		RETURNING user_id`,
+		hashToken(req.Token),
+	).Scan(&userID)
+	if err == pgx.ErrNoRows {
+		writeError(w, http.StatusUnauthorized, "invalid_token", "invalid or expired token")
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "database_error", "database error")
+		return
+	}
+
+	if _, err = tx.Exec(ctx, `UPDATE users SET password_hash = $1 WHERE id = $2`, string(hash), userID); err != nil {
+		writeError(w, http.StatusInternalServerError, "database_error", "database error")
+		return
+	}
+	_, err = tx.Exec(ctx,
+		`UPDATE refresh_tokens SET revoked_at = NOW() WHERE user_id = $1 AND revoked_at IS NULL`, userID,
+	)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "database_error", "database error")
+		return
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		writeError(w, http.StatusInternalServerError, "database_error", "commit failed")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]bool{"success": true})
+}
@@ -1,59 +1,201 @@
 package handlers
 
 import (
+	"encoding/base64"
 	"encoding/json"
+	"fmt"
+	"math"
 	"net/http"
 	"strconv"
 	"strings"
 	"time"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 	"github.com/karti/orange-city-mart/backend/db"
 )
 
 // ── List Products ─────────────────────────────────────────────────────────────
-// GET /api/products?q=&category=&type=&limit=
-func ListProducts(w http.ResponseWriter, r *http.Request) {
+// GET /api/products?q=&category=&type=&min_price=&max_price=&created_from=&created_to=&sort=&limit=&cursor=
+// min_price/max_price bound the listing price, or an auction's current bid
+// once it has one.
+// sort is one of newest (default), price_asc, price_desc or ending_soon.
+// Under newest, listings with an active featured_until period come first.
+// created_from/created_to take YYYY-MM-DD (inclusive) or RFC3339.
+// Responds with {items, next_cursor, total}; pass next_cursor back as cursor
+// to fetch the following page.
+func (h *Handler) ListProducts(w http.ResponseWriter, r *http.Request) {
 	q := strings.TrimSpace(r.URL.Query().Get("q"))
 	category := strings.TrimSpace(r.URL.Query().Get("category"))
 	pType := strings.TrimSpace(r.URL.Query().Get("type")) // FIXED | AUCTION
 
+	createdFrom, ok := parseDateParam(r.URL.Query().Get("created_from"), time.Time{})
+	if !ok {
+		writeError(w, http.StatusBadRequest, "invalid_created_from", "created_from must be YYYY-MM-DD or RFC3339")
+		return
+	}
+	createdToRaw := r.URL.Query().Get("created_to")
+	createdTo, ok := parseDateParam(createdToRaw, time.Time{})
+	if !ok {
+		writeError(w, http.StatusBadRequest, "invalid_created_to", "created_to must be YYYY-MM-DD or RFC3339")
+		return
+	}
+	if !createdFrom.IsZero() && !createdTo.IsZero() && createdFrom.After(createdTo) {
+		writeError(w, http.StatusBadRequest, "invalid_date_range", "created_from must not be after created_to")
+		return
+	}
+
+	minPrice, ok := parsePriceParam(r.URL.Query().Get("min_price"))
+	if !ok {
+		writeError(w, http.StatusBadRequest, "invalid_min_price", "min_price must be a non-negative number")
+		return
+	}
+	maxPrice, ok := parsePriceParam(r.URL.Query().Get("max_price"))
+	if !ok {
+		writeError(w, http.StatusBadRequest, "invalid_max_price", "max_price must be a non-negative number")
+		return
+	}
+	if minPrice != nil && maxPrice != nil && *minPrice > *maxPrice {
+		writeError(w, http.StatusBadRequest, "invalid_price_range", "min_price must not exceed max_price")
+		return
+	}
+
+	sortName := r.URL.Query().Get("sort")
+	if sortName == "" {
+		sortName = "newest"
+	}
+	sort, ok := productSorts[sortName]
+	if !ok {
+		writeError(w, http.StatusBadRequest, "invalid_sort", "sort must be one of newest, price_asc, price_desc, ending_soon")
+		return
+	}
+
+	limit := defaultProductPageSize
+	if v, err := strconv.Atoi(r.URL.Query().Get("limit")); err == nil && v > 0 {
+		limit = v
+	}
+	if limit > maxProductPageSize {
+		limit = maxProductPageSize
+	}
+	var after *productCursor
+	if v := r.URL.Query().Get("cursor"); v != "" {
+		c, err := decodeProductCursor(v)
+		if err != nil || c.Sort != sortName || !validCursorKey(sort.cast, c.Key) {
+			writeError(w, http.StatusBadRequest, "invalid_cursor", "invalid cursor")
+			return
+		}
+		after = &c
+	}
+
 	ctx := r.Context()
 
-	// Build a dynamic query
+	// Build a dynamic query. Every placeholder is used exactly once and cast
+	// explicitly, so the statement means the same thing under the pooler's
+	// simple protocol (client-side interpolation) and under extended protocol
+	// (server-side type inference).
 	args := []any{}
-	where := []string{"1=1"}
+	where := []string{"p.deleted_at IS NULL", "p.status = 'AVAILABLE'"}
 	i := 1
 
 	if q != "" {
-		where = append(where, "p.title ILIKE $"+itoa(i))
+		where = append(where, "p.title ILIKE $"+itoa(i)+"::text")
 		args = append(args, "%"+q+"%")
 		i++
 	}
 	if category != "" && category != "All" {
-		where = append(where, "p.category = $"+itoa(i))
+		where = append(where, "p.category = $"+itoa(i)+"::text")
 		args = append(args, category)
 		i++
 	}
 	if pType != "" && pType != "All" {
-		where = append(where, "p.type = $"+itoa(i))
+		where = append(where, "p.type = $"+itoa(i)+"::text")
 		args = append(args, pType)
 		i++
 	}
 
+	if minPrice != nil {
+		where = append(where, listingPriceExpr+" >= $"+itoa(i)+"::numeric")
+		args = append(args, *minPrice)
+		i++
+	}
+	if maxPrice != nil {
+		where = append(where, listingPriceExpr+" <= $"+itoa(i)+"::numeric")
+		args = append(args, *maxPrice)
+		i++
+	}
+
+	if !createdFrom.IsZero() {
+		where = append(where, "p.created_at >= $"+itoa(i)+"::timestamptz")
+		args = append(args, createdFrom)
+		i++
+	}
+	if !createdTo.IsZero() {
+		// A bare date covers that whole day.
+		if len(createdToRaw) == len("2006-01-02") {
+			where = append(where, "p.created_at < $"+itoa(i)+"::timestamptz")
+			args = append(args, createdTo.Add(24*time.Hour))
+		} else {
+			where = append(where, "p.created_at <= $"+itoa(i)+"::timestamptz")
+			args = append(args, createdTo)
+		}
+		i++
+	}
+
+	const from = `
+		FROM products p
+		LEFT JOIN auctions a ON a.product_id = p.id AND a.status = 'ACTIVE'`
+
+	// The total ignores the cursor so it stays the same across pages.
+	var total int
+	err := db.Pool.QueryRow(ctx, `SELECT COUNT(*)`+from+`
+		WHERE `+strings.Join(where, " AND "), args...,
+	).Scan(&total)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "database_error", "database error")
+		return
+	}
+
+	// Keyset pagination on the full sort key so rows inserted between page
+	// requests can't shift later pages.
+	// The sort expression itself only ever comes from the productSorts
+	// allowlist; the cursor's values travel as parameters.
+	cmp, dir := ">", "ASC"
+	if sort.desc {
+		cmp, dir = "<", "DESC"
+	}
+	if after != nil {
+		if sort.featuredFirst {
+			where = append(where, "(COALESCE(p.featured_until > NOW(), FALSE), "+sort.expr+", p.id) "+cmp+
+				" ($"+itoa(i)+"::boolean, $"+itoa(i+1)+"::"+sort.cast+", $"+itoa(i+2)+"::uuid)")
+			args = append(args, after.Featured, after.Key, after.ID)
+			i += 3
+		} else {
+			where = append(where, "("+sort.expr+", p.id) "+cmp+
+				" ($"+itoa(i)+"::"+sort.cast+", $"+itoa(i+1)+"::uuid)")
+			args = append(args, after.Key, after.ID)
+			i += 2
+		}
+	}
+	orderBy := sort.expr + " " + dir + ", p.id " + dir
+	if sort.featuredFirst {
+		orderBy = "featured " + dir + ", " + orderBy
+	}
+
 	query := `
-		SELECT p.id, p.title, p.description, p.category, p.type, p.price,
+		SELECT p.id, p.title, p.description, p.category, p.type, p.price, p.quantity,
 		       p.image_url, p.location, p.created_at,
-		       a.id, a.current_highest_bid, a.end_time, a.status
-		FROM products p
-		LEFT JOIN auctions a ON a.product_id = p.id AND a.status = 'ACTIVE'
+		       a.id, a.current_highest_bid, a.end_time, a.status,
+		       COALESCE(p.featured_until > NOW(), FALSE) AS featured,
+		       (` + sort.expr + `)::text` + from + `
 		WHERE ` + strings.Join(where, " AND ") + `
-		ORDER BY p.created_at DESC
-		LIMIT 50`
+		ORDER BY ` + orderBy + `
+		LIMIT $` + itoa(i) + `::int`
+	args = append(args, limit+1)
 
 	rows, err := db.Pool.Query(ctx, query, args...)
 	if err != nil {
-		http.Error(w, "database error", http.StatusInternalServerError)
+		writeError(w, http.StatusInternalServerError, "database_error", "database error")
 		return
 	}
 	defer rows.Close()
@@ -65,6 +207,7 @@ func ListProducts(w http.ResponseWriter, r *http.Request) {
 		Category      string   `json:"category"`
 		Type          string   `json:"type"`
 		Price         float64  `json:"price"`
+		Quantity      int      `json:"quantity"` // units left, FIXED listings
 		ImageURL      *string  `json:"image_url"`
 		Location      string   `json:"location"`
 		CreatedAt     string   `json:"created_at"`
@@ -72,21 +215,33 @@ func ListProducts(w http.ResponseWriter, r *http.Request) {
 		CurrentBid    *float64 `json:"current_bid"`
 		EndTime       *string  `json:"end_time"`
 		AuctionStatus *string  `json:"auction_status"`
+		Featured      bool     `json:"featured"`
 	}
 
 	var items []ProductRow
+	var last productCursor
+	var nextCursor *string
 	for rows.Next() {
+		if len(items) == limit {
+			// The extra row only tells us another page exists.
+			c := last.encode()
+			nextCursor = &c
+			break
+		}
 		var p ProductRow
 		var createdAt time.Time
 		var endTime *time.Time
+		var sortKey string
 		err := rows.Scan(
-			&p.ID, &p.Title, &p.Description, &p.Category, &p.Type, &p.Price,
+			&p.ID, &p.Title, &p.Description, &p.Category, &p.Type, &p.Price, &p.Quantity,
 			&p.ImageURL, &p.Location, &createdAt,
 			&p.AuctionID, &p.CurrentBid, &endTime, &p.AuctionStatus,
+			&p.Featured, &sortKey,
 		)
 		if err != nil {
 			continue
 		}
+		last = productCursor{Sort: sortName, Featured: p.Featured, Key: sortKey, ID: p.ID}
 		p.CreatedAt = createdAt.UTC().Format(time.RFC3339)
 		if endTime != nil {
 			s := endTime.UTC().Format(time.RFC3339)
@@ -99,60 +254,326 @@ func ListProducts(w http.ResponseWriter, r *http.Request) {
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(items)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"items":       items,
+		"next_cursor": nextCursor,
+		"total":       total,
+	})
+}
+
+// listingPriceExpr is what a buyer would currently pay: the fixed price, or
+// the highest bid on an auction that has received one.
+const listingPriceExpr = "(CASE WHEN a.current_highest_bid > 0 THEN a.current_highest_bid ELSE p.price END)"
+
+// parsePriceParam parses an optional non-negative amount. A nil result means
+// the parameter was absent; false means it was malformed.
+func parsePriceParam(v string) (*float64, bool) {
+	if v == "" {
+		return nil, true
+	}
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil || f < 0 || math.IsNaN(f) || math.IsInf(f, 0) {
+		return nil, false
+	}
+	return &f, true
+}
+
+const (
+	defaultProductPageSize = 50
+	maxProductPageSize     = 100
+)
+
+// productSort is one allowlisted ListProducts ordering. expr is a SQL
+// expression over p/a whose text form is cast back with cast for the cursor.
+type productSort struct {
+	expr          string
+	cast          string
+	desc          bool
+	featuredFirst bool
+}
+
+var productSorts = map[string]productSort{
+	"newest":     {expr: "p.created_at", cast: "timestamptz", desc: true, featuredFirst: true},
+	"price_asc":  {expr: "p.price", cast: "numeric"},
+	"price_desc": {expr: "p.price", cast: "numeric", desc: true},
+	// Fixed-price listings have no end_time and sort after every auction.
+	"ending_soon": {expr: "COALESCE(a.end_time, 'infinity'::timestamptz)", cast: "timestamptz"},
+}
+
+// productCursor is the sort key of the last row on a ListProducts page.
+type productCursor struct {
+	Sort     string `json:"s"`
+	Featured bool   `json:"f"`
+	Key      string `json:"k"`
+	ID       string `json:"id"`
+}
+
+func (c productCursor) encode() string {
+	b, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// cursorKeyLayouts are the text forms Postgres gives a timestamptz.
+var cursorKeyLayouts = []string{
+	"2006-01-02 15:04:05.999999-07",
+	"2006-01-02 15:04:05.999999-07:00",
+	"2006-01-02 15:04:05.999999-07:00:00",
+}
+
+// validCursorKey reports whether key parses as the sort's cast, so a forged
+// cursor is rejected up front instead of failing inside the query.
+func validCursorKey(cast, key string) bool {
+	switch cast {
+	case "numeric":
+		f, err := strconv.ParseFloat(key, 64)
+		return err == nil && !math.IsNaN(f) && !math.IsInf(f, 0)
+	case "timestamptz":
+		if key == "infinity" {
+			return true
+		}
+		for _, layout := range cursorKeyLayouts {
+			if _, err := time.Parse(layout, key); err == nil {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func decodeProductCursor(s string) (productCursor, error) {
+	var c productCursor
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return c, err
+	}
+	if err := json.Unmarshal(b, &c); err != nil {
+		return c, err
+	}
+	if _, err := uuid.Parse(c.ID); err != nil {
+		return c, err
+	}
+	return c, nil
 }
 
 // ── Get Single Product ────────────────────────────────────────────────────────
 // GET /api/products/:id
-func GetProduct(w http.ResponseWriter, r *http.Request) {
+func (h *Handler) GetProduct(w http.ResponseWriter, r *http.Request) {
 	id := chi.URLParam(r, "id")
 	ctx := r.Context()
 
-	type ProductDetail struct {
-		ID            string   `json:"id"`
-		SellerID      string   `json:"seller_id"`
-		SellerName    string   `json:"seller_name"`
-		SellerUPIID   *string  `json:"seller_upi_id"`
-		Title         string   `json:"title"`
-		Description   string   `json:"description"`
-		Category      string   `json:"category"`
-		Type          string   `json:"type"`
-		Price         float64  `json:"price"`
-		ImageURL      *string  `json:"image_url"`
-		Location      string   `json:"location"`
-		AuctionID     *string  `json:"auction_id"`
-		CurrentBid    *float64 `json:"current_bid"`
-		EndTime       *string  `json:"end_time"`
-		AuctionStatus *string  `json:"auction_status"`
+	p, err := h.scanProductDetail(db.Pool.QueryRow(ctx,
+		productDetailSelect+` WHERE p.id = $1 AND p.deleted_at IS NULL`, id))
+	if err != nil {
+		writeError(w, http.StatusNotFound, "product_not_found", "product not found")
+		return
 	}
 
-	var p ProductDetail
-	var endTime *time.Time
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(p)
+}
+
+// productDetail is a product with its seller and latest auction, as returned
+// by GetProduct and GetProductsBatch.
+type productDetail struct {
+	ID                  string   `json:"id"`
+	SellerID            string   `json:"seller_id"`
+	SellerName          string   `json:"seller_name"`
+	SellerUPIID         *string  `json:"seller_upi_id"`
+	Title               string   `json:"title"`
+	Description         string   `json:"description"`
+	Category            string   `json:"category"`
+	Type                string   `json:"type"`
+	Status              string   `json:"status"`   // AVAILABLE, or SOLD_OUT once a FIXED listing's quantity hits 0
+	Quantity            int      `json:"quantity"` // units left, FIXED listings
+	Price               float64  `json:"price"`
+	PriceFormatted      string   `json:"price_formatted"`
+	ImageURL            *string  `json:"image_url"`
+	Location            string   `json:"location"`
+	AuctionID           *string  `json:"auction_id"`
+	CurrentBid          *float64 `json:"current_bid"`
+	CurrentBidFormatted *string  `json:"current_bid_formatted"`
+	EndTime             *string  `json:"end_time"`
+	AuctionStatus       *string  `json:"auction_status"`
+	Currency            string   `json:"currency"`
+}
 
-	err := db.Pool.QueryRow(ctx, `
+// productDetailSelect selects the columns scanProductDetail reads; callers
+// append the WHERE clause.
+const productDetailSelect = `
 		SELECT p.id, p.seller_id, u.name, u.upi_id, p.title, p.description, p.category,
-		       p.type, p.price, p.image_url, p.location,
+		       p.type, p.status, p.quantity, p.price, p.image_url, p.location,
 		       a.id, a.current_highest_bid, a.end_time, a.status
 		FROM products p
 		JOIN users u ON u.id = p.seller_id
-		LEFT JOIN auctions a ON a.product_id = p.id
-		WHERE p.id = $1`, id,
-	).Scan(
+		-- a product may have several auctions once relisted; show the latest
+		LEFT JOIN LATERAL (
+		    SELECT * FROM auctions
+		    WHERE product_id = p.id
+		    ORDER BY created_at DESC
+		    LIMIT 1
+		) a ON TRUE`
+
+func (h *Handler) scanProductDetail(row pgx.Row) (productDetail, error) {
+	var p productDetail
+	var endTime *time.Time
+	err := row.Scan(
 		&p.ID, &p.SellerID, &p.SellerName, &p.SellerUPIID, &p.Title, &p.Description, &p.Category,
-		&p.Type, &p.Price, &p.ImageURL, &p.Location,
+		&p.Type, &p.Status, &p.Quantity, &p.Price, &p.ImageURL, &p.Location,
 		&p.AuctionID, &p.CurrentBid, &endTime, &p.AuctionStatus,
 	)
-	if err != nil {
-		http.Error(w, "product not found", http.StatusNotFound)
-		return
-	}
 	if endTime != nil {
 		s := endTime.UTC().Format(time.RFC3339)
 		p.EndTime = &s
 	}
+	p.Currency = h.Config.Currency
+	p.PriceFormatted = h.formatMoney(p.Price)
+	if p.CurrentBid != nil {
+		s := h.formatMoney(*p.CurrentBid)
+		p.CurrentBidFormatted = &s
+	}
+	return p, err
+}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(p)
+// maxBatchProducts bounds how many ids GetProductsBatch accepts.
+const maxBatchProducts = 50
+
+// ── Batch Products ────────────────────────────────────────────────────────────
+// GET /api/products/batch?ids=a,b,c
+// Returns {items, missing}: items holds the details of the requested products
+// in request order, missing the ids that don't exist or were deleted.
+func (h *Handler) GetProductsBatch(w http.ResponseWriter, r *http.Request) {
+	var ids []string
+	seen := make(map[string]bool)
+	for _, id := range strings.Split(r.URL.Query().Get("ids"), ",") {
+		// Lowercased to match the canonical form ids are scanned back in.
+		if id = strings.ToLower(strings.TrimSpace(id)); id != "" && !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+	if len(ids) == 0 {
+		writeError(w, http.StatusBadRequest, "missing_ids", "ids is required")
+		return
+	}
+	if len(ids) > maxBatchProducts {
+		writeError(w, http.StatusBadRequest, "too_many_ids", fmt.Sprintf("at most %d ids per request", maxBatchProducts))
+		return
+	}
+
+	rows, err := db.Pool.Query(r.Context(),
+		productDetailSelect+` WHERE p.id::text = ANY($1::text[]) AND p.deleted_at IS NULL`, ids)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "database_error", "database error")
+		return
+	}
+	defer rows.Close()
+
+	found := make(map[string]productDetail, len(ids))
+	for rows.Next() {
+		p, err := h.scanProductDetail(rows)
+		if err != nil {
+			continue
+		}
+		found[p.ID] = p
+	}
+	if err := rows.Err(); err != nil {
+		writeError(w, http.StatusInternalServerError, "database_error", "database error")
+		return
+	}
+
+	resp := struct {
+		Items   []productDetail `json:"items"`
+		Missing []string        `json:"missing"`
+	}{Items: []productDetail{}, Missing: []string{}}
+	for _, id := range ids {
+		if p, ok := found[id]; ok {
+			resp.Items = append(resp.Items, p)
+		} else {
+			resp.Missing = append(resp.Missing, id)
+		}
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// ── Search Suggestions ────────────────────────────────────────────────────────
+
+// liveListingCond matches products a buyer can still act on, for queries
+// over products p.
+const liveListingCond = `p.deleted_at IS NULL AND p.status = 'AVAILABLE' AND (p.type = 'FIXED' OR EXISTS (
+		SELECT 1 FROM auctions la
+		WHERE la.product_id = p.id AND la.status = 'ACTIVE' AND la.end_time > NOW()))`
+
+// GET /api/products/suggest?q=
+// Typeahead for the search box: up to maxSuggestions product titles starting
+// with q (most-bid first), plus matching category names. Only live listings
+// count: in-stock fixed-price items and auctions still taking bids.
+func (h *Handler) SuggestProducts(w http.ResponseWriter, r *http.Request) {
+	q := strings.ToLower(strings.TrimSpace(r.URL.Query().Get("q")))
+
+	resp := struct {
+		Titles     []string `json:"titles"`
+		Categories []string `json:"categories"`
+	}{Titles: []string{}, Categories: []string{}}
+
+	if q == "" {
+		writeJSON(w, http.StatusOK, resp)
+		return
+	}
+	prefix := escapeLike(q) + "%"
+	ctx := r.Context()
+
+	rows, err := db.Pool.Query(ctx, `
+		SELECT p.title
+		FROM products p
+		LEFT JOIN auctions a ON a.product_id = p.id AND a.status = 'ACTIVE' AND a.end_time > NOW()
+		LEFT JOIN bids b ON b.auction_id = a.id
+		WHERE lower(p.title) LIKE $1 AND `+liveListingCond+`
+		GROUP BY p.id, p.title, p.created_at
+		ORDER BY COUNT(b.id) DESC, p.created_at DESC
+		LIMIT $2`, prefix, maxSuggestions)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "database_error", "database error")
+		return
+	}
+	for rows.Next() {
+		var title string
+		if err := rows.Scan(&title); err != nil {
+			continue
+		}
+		resp.Titles = append(resp.Titles, title)
+	}
+	rows.Close()
+
+	rows, err = db.Pool.Query(ctx, `
+		SELECT p.category
+		FROM products p
+		WHERE lower(p.category) LIKE $1 AND `+liveListingCond+`
+		GROUP BY p.category
+		ORDER BY COUNT(*) DESC
+		LIMIT 3`, prefix)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "database_error", "database error")
+		return
+	}
+	for rows.Next() {
+		var category string
+		if err := rows.Scan(&category); err != nil {
+			continue
+		}
+		resp.Categories = append(resp.Categories, category)
+	}
+	rows.Close()
+
+	w.Header().Set("Cache-Control", "public, max-age=60")
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// maxSuggestions caps the number of titles returned by SuggestProducts.
+const maxSuggestions = 8
+
+// escapeLike escapes LIKE wildcards so user input matches literally.
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
 }
 
 func itoa(i int) string {
@@ -0,0 +1,312 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// listedProduct is the part of a ListProducts item the tests look at.
+type listedProduct struct {
+	ID       string  `json:"id"`
+	Title    string  `json:"title"`
+	Price    float64 `json:"price"`
+	Featured bool    `json:"featured"`
+}
+
+// listProducts calls ListProducts with the raw query string and returns the
+// status code and the page of items.
+func listProducts(t *testing.T, query string) (int, []listedProduct) {
+	t.Helper()
+	w := httptest.NewRecorder()
+	testHandler.ListProducts(w, httptest.NewRequest(http.MethodGet, "/api/products?"+query, nil))
+	var body struct {
+		Items []listedProduct `json:"items"`
+	}
+	if w.Code == http.StatusOK {
+		if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+			t.Fatalf("body %q: %v", w.Body, err)
+		}
+	}
+	return w.Code, body.Items
+}
+
+func TestValidCursorKey(t *testing.T) {
+	cases := []struct {
+		cast, key string
+		want      bool
+	}{
+		{"numeric", "1499.50", true},
+		{"numeric", "0", true},
+		{"numeric", "abc", false},
+		{"numeric", "NaN", false},
+		{"numeric", "", false},
+		{"timestamptz", "2026-03-01 10:15:30.123456+00", true},
+		{"timestamptz", "2026-03-01 10:15:30+05:30", true},
+		{"timestamptz", "infinity", true},
+		{"timestamptz", "yesterday", false},
+		{"timestamptz", "1499.50", false},
+		{"text", "anything", false},
+	}
+	for _, c := range cases {
+		if got := validCursorKey(c.cast, c.key); got != c.want {
+			t.Errorf("validCursorKey(%q, %q) = %v, want %v", c.cast, c.key, got, c.want)
+		}
+	}
+}
+
+// TestListProductsFiltersUnderSimpleProtocol runs each dynamic filter through
+// testPool, which interpolates arguments client-side like the pooler does.
+func TestListProductsFiltersUnderSimpleProtocol(t *testing.T) {
+	requireDB(t)
+	ctx := context.Background()
+	seller := newTestUser(t, testPool, 0)
+	t.Cleanup(func() { testPool.Exec(ctx, `DELETE FROM users WHERE id = $1`, seller) })
+	tag := fmt.Sprintf("filt%d", time.Now().UnixNano())
+	category := tag + "-cat"
+
+	var cheap, mid, dear string
+	for _, p := range []struct {
+		id       *string
+		title    string
+		price    float64
+		category *string
+		created  string
+	}{
+		{&cheap, tag + " o'clock", 10, &category, "2024-01-10T12:00:00Z"},
+		{&mid, tag + " mid", 50, &category, "2024-02-10T12:00:00Z"},
+		{&dear, tag + " dear", 200, nil, "2024-03-10T12:00:00Z"},
+	} {
+		if err := testPool.QueryRow(ctx, `
+			INSERT INTO products (seller_id, title, type, price, category, created_at)
+			VALUES ($1, $2, 'FIXED', $3, $4, $5::timestamptz) RETURNING id`,
+			seller, p.title, p.price, p.category, p.created,
+		).Scan(p.id); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, tc := range []struct {
+		query string
+		want  []string
+	}{
+		{"", []string{dear, mid, cheap}},
+		{"category=" + url.QueryEscape(category), []string{mid, cheap}},
+		{"type=AUCTION", nil},
+		{"type=FIXED&sort=price_asc", []string{cheap, mid, dear}},
+		{"sort=price_desc", []string{dear, mid, cheap}},
+		{"min_price=20", []string{dear, mid}},
+		{"max_price=50", []string{mid, cheap}},
+		{"min_price=20&max_price=50", []string{mid}},
+		{"created_from=2024-02-01", []string{dear, mid}},
+		{"created_to=2024-02-10", []string{mid, cheap}},
+		{"created_from=2024-02-01T00:00:00Z&created_to=2024-02-28T00:00:00Z", []string{mid}},
+	} {
+		code, items := listProducts(t, "q="+tag+"&"+tc.query)
+		if code != http.StatusOK {
+			t.Errorf("%q: status %d", tc.query, code)
+			continue
+		}
+		var got []string
+		for _, p := range items {
+			got = append(got, p.ID)
+		}
+		if !slices.Equal(got, tc.want) {
+			t.Errorf("%q: got %v, want %v", tc.query, got, tc.want)
+		}
+	}
+
+	// A quote in the search term must survive client-side interpolation.
+	code, items := listProducts(t, "q="+url.QueryEscape(tag+" o'clock"))
+	if code != http.StatusOK || len(items) != 1 || items[0].ID != cheap {
+		t.Errorf("quoted search: status %d, items %v", code, items)
+	}
+}
+
+// TestSuggestProductsLiveListingsByPopularity checks that suggestions match
+// the prefix, put the most-bid listing first and leave out listings nobody
+// can buy any more.
+func TestSuggestProductsLiveListingsByPopularity(t *testing.T) {
+	requireDB(t)
+	ctx := context.Background()
+	seller := newTestUser(t, testPool, 0)
+	first := newTestUser(t, testPool, 1000)
+	second := newTestUser(t, testPool, 1000)
+	t.Cleanup(func() {
+		testPool.Exec(ctx, `DELETE FROM users WHERE id IN ($1, $2, $3)`, seller, first, second)
+	})
+	tag := fmt.Sprintf("sugg%d", time.Now().UnixNano())
+	rename := func(productID, title, category string) {
+		t.Helper()
+		if _, err := testPool.Exec(ctx, `UPDATE products SET title = $1, category = $2 WHERE id = $3`,
+			title, category, productID); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	rename(newTestFixedProduct(t, seller, 1, "AVAILABLE"), tag+" plain", tag+"-live")
+	popular, popularAuction := newTestAuctionListing(t, seller)
+	rename(popular, tag+" popular", tag+"-live")
+	rename(newTestFixedProduct(t, seller, 0, "SOLD_OUT"), tag+" sold", tag+"-gone")
+	ended, endedAuction := newTestAuctionListing(t, seller)
+	rename(ended, tag+" ended", tag+"-gone")
+	if _, err := testPool.Exec(ctx, `UPDATE auctions SET status = 'ENDED' WHERE id = $1`, endedAuction); err != nil {
+		t.Fatal(err)
+	}
+	tx, err := testPool.Begin(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bidOn(t, tx, popularAuction, first, 20)
+	bidOn(t, tx, popularAuction, second, 30)
+	if err := tx.Commit(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	w := httptest.NewRecorder()
+	testHandler.SuggestProducts(w, httptest.NewRequest(http.MethodGet, "/api/products/suggest?q="+strings.ToUpper(tag), nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", w.Code, w.Body)
+	}
+	var body struct {
+		Titles     []string `json:"titles"`
+		Categories []string `json:"categories"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{tag + " popular", tag + " plain"}; !slices.Equal(body.Titles, want) {
+		t.Errorf("titles = %v, want %v", body.Titles, want)
+	}
+	if want := []string{tag + "-live"}; !slices.Equal(body.Categories, want) {
+		t.Errorf("categories = %v, want %v", body.Categories, want)
+	}
+}
+
+func TestListProductsRejectsBadDates(t *testing.T) {
+	for _, query := range []string{
+		"created_from=last-week",
+		"created_to=2026-13-01",
+		"created_from=2026-03-02&created_to=2026-03-01",
+	} {
+		if code, _ := listProducts(t, query); code != http.StatusBadRequest {
+			t.Errorf("%s: status %d, want 400", query, code)
+		}
+	}
+}
+
+// TestListProductsCreatedRange checks the edges of the creation-date filter:
+// a date-only created_to covers that whole day, an RFC3339 one is exact.
+func TestListProductsCreatedRange(t *testing.T) {
+	requireDB(t)
+	ctx := context.Background()
+	seller := newTestUser(t, testPool, 0)
+	t.Cleanup(func() { testPool.Exec(ctx, `DELETE FROM users WHERE id = $1`, seller) })
+	tag := fmt.Sprintf("dates%d", time.Now().UnixNano())
+
+	var before, first, last, after string
+	for _, p := range []struct {
+		id      *string
+		created string
+	}{
+		{&before, "2025-06-30T23:59:59Z"},
+		{&first, "2025-07-01T00:00:00Z"},
+		{&last, "2025-07-07T23:59:00Z"},
+		{&after, "2025-07-08T00:00:00Z"},
+	} {
+		if err := testPool.QueryRow(ctx, `
+			INSERT INTO products (seller_id, title, type, price, created_at)
+			VALUES ($1, $2, 'FIXED', 10, $3::timestamptz) RETURNING id`,
+			seller, tag, p.created,
+		).Scan(p.id); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, tc := range []struct {
+		query string
+		want  []string
+	}{
+		{"created_from=2025-07-01&created_to=2025-07-07", []string{last, first}},
+		{"created_from=2025-07-01", []string{after, last, first}},
+		{"created_to=2025-07-07", []string{last, first, before}},
+		{"created_from=2025-07-01T00:00:00Z&created_to=2025-07-07T23:00:00Z", []string{first}},
+	} {
+		code, items := listProducts(t, "q="+tag+"&"+tc.query)
+		if code != http.StatusOK {
+			t.Errorf("%s: status %d", tc.query, code)
+			continue
+		}
+		var got []string
+		for _, p := range items {
+			got = append(got, p.ID)
+		}
+		if !slices.Equal(got, tc.want) {
+			t.Errorf("%s: got %v, want %v", tc.query, got, tc.want)
+		}
+	}
+}
+
+// TestGetProductsBatchOrderAndMissing fetches live, deleted and unknown ids
+// together and checks items keep the requested order while everything else
+// is reported missing.
+func TestGetProductsBatchOrderAndMissing(t *testing.T) {
+	requireDB(t)
+	ctx := context.Background()
+	seller := newTestUser(t, testPool, 0)
+	t.Cleanup(func() { testPool.Exec(ctx, `DELETE FROM users WHERE id = $1`, seller) })
+	fixed := newTestFixedProduct(t, seller, 2, "AVAILABLE")
+	lot, auctionID := newTestAuctionListing(t, seller)
+	deleted := newTestFixedProduct(t, seller, 1, "AVAILABLE")
+	if _, err := testPool.Exec(ctx, `UPDATE products SET deleted_at = NOW() WHERE id = $1`, deleted); err != nil {
+		t.Fatal(err)
+	}
+	unknown := "00000000-0000-0000-0000-000000000000"
+
+	ids := []string{lot, unknown, strings.ToUpper(fixed), deleted, lot}
+	w := httptest.NewRecorder()
+	testHandler.GetProductsBatch(w, httptest.NewRequest(http.MethodGet, "/api/products/batch?ids="+strings.Join(ids, ","), nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", w.Code, w.Body)
+	}
+	var body struct {
+		Items []struct {
+			ID        string  `json:"id"`
+			AuctionID *string `json:"auction_id"`
+		} `json:"items"`
+		Missing []string `json:"missing"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+	if len(body.Items) != 2 || body.Items[0].ID != lot || body.Items[1].ID != fixed {
+		t.Fatalf("items = %+v, want the auction lot then the fixed listing", body.Items)
+	}
+	if body.Items[0].AuctionID == nil || *body.Items[0].AuctionID != auctionID || body.Items[1].AuctionID != nil {
+		t.Errorf("auction summaries = %v, %v; want %s and none", body.Items[0].AuctionID, body.Items[1].AuctionID, auctionID)
+	}
+	if !slices.Equal(body.Missing, []string{unknown, deleted}) {
+		t.Errorf("missing = %v, want %v", body.Missing, []string{unknown, deleted})
+	}
+}
+
+func TestGetProductsBatchLimits(t *testing.T) {
+	ids := make([]string, maxBatchProducts+1)
+	for i := range ids {
+		ids[i] = fmt.Sprintf("00000000-0000-0000-0000-%012d", i)
+	}
+	for _, query := range []string{"", "ids=,,", "ids=" + strings.Join(ids, ",")} {
+		w := httptest.NewRecorder()
+		testHandler.GetProductsBatch(w, httptest.NewRequest(http.MethodGet, "/api/products/batch?"+query, nil))
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%.40s: status %d, want 400", query, w.Code)
+		}
+	}
+}
@@ -0,0 +1,194 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/jackc/pgx/v5"
+	"github.com/karti/orange-city-mart/backend/db"
+	authmw "github.com/karti/orange-city-mart/backend/middleware"
+)
+
+// ─────────────────────────────────────────────────────────────────────────────
+// BuyProduct  POST /api/products/{id}/buy
+//
+// Body (optional): { "quantity": 1 }
+// Buys units of a FIXED-price listing outright. In one transaction it locks
+// the product row so concurrent buyers can't oversell it, moves the total
+// from the buyer's wallet to the seller's, records a purchases row with a
+// TRANSFER transaction for each party, and decrements the stock, marking
+// the product SOLD_OUT when none is left.
+// ─────────────────────────────────────────────────────────────────────────────
+func (h *Handler) BuyProduct(w http.ResponseWriter, r *http.Request) {
+	buyerID, ok := authmw.UserIDFromContext(r.Context())
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "unauthorized", "unauthorized")
+		return
+	}
+	productID := chi.URLParam(r, "id")
+
+	var req struct {
+		Quantity int `json:"quantity"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		writeError(w, http.StatusBadRequest, "invalid_body", "invalid request body")
+		return
+	}
+	if req.Quantity == 0 {
+		req.Quantity = 1
+	}
+	if req.Quantity < 0 {
+		writeError(w, http.StatusBadRequest, "invalid_quantity", "quantity must be at least 1")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	tx, err := db.Pool.Begin(ctx)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "database_error", "database error")
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	var sellerID, productType, status string
+	var unitPrice float64
+	var stock int
+	err = tx.QueryRow(ctx, `
+		SELECT seller_id, type, price::float8, status, quantity FROM products
+		WHERE id = $1 AND deleted_at IS NULL
+		FOR UPDATE`, productID,
+	).Scan(&sellerID, &productType, &unitPrice, &status, &stock)
+	if err == pgx.ErrNoRows {
+		writeError(w, http.StatusNotFound, "product_not_found", "product not found")
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "database_error", "database error")
+		return
+	}
+	if productType != "FIXED" {
+		writeError(w, http.StatusBadRequest, "not_fixed_price", "only fixed-price products can be bought directly")
+		return
+	}
+	if sellerID == buyerID {
+		writeError(w, http.StatusBadRequest, "own_listing", "you cannot buy your own listing")
+		return
+	}
+	if status != "AVAILABLE" || stock == 0 {
+		writeError(w, http.StatusConflict, "sold_out", "product is sold out")
+		return
+	}
+	if req.Quantity > stock {
+		writeError(w, http.StatusConflict, "insufficient_stock", "only "+itoa(stock)+" left")
+		return
+	}
+	price := h.roundMoney(unitPrice * float64(req.Quantity))
+
+	// Lock both wallets in a stable (id) order so two opposing purchases
+	// can't deadlock.
+	rows, err := tx.Query(ctx, `
+		SELECT id, wallet_balance, is_frozen FROM users
+		WHERE id IN ($1, $2)
+		ORDER BY id
+		FOR UPDATE`,
+		buyerID, sellerID,
+	)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "database_error", "database error")
+		return
+	}
+	type walletRow struct {
+		balance float64
+		frozen  bool
+	}
+	wallets := map[string]walletRow{}
+	for rows.Next() {
+		var id string
+		var wr walletRow
+		if err := rows.Scan(&id, &wr.balance, &wr.frozen); err != nil {
+			rows.Close()
+			writeError(w, http.StatusInternalServerError, "database_error", "database error")
+			return
+		}
+		wallets[id] = wr
+	}
+	rows.Close()
+
+	buyer, ok := wallets[buyerID]
+	if !ok {
+		writeError(w, http.StatusNotFound, "user_not_found", "user not found")
+		return
+	}
+	if buyer.frozen || wallets[sellerID].frozen {
+		writeError(w, http.StatusForbidden, "account_frozen", "account is frozen")
+		return
+	}
+	if buyer.balance < price {
+		writeError(w, http.StatusPaymentRequired, "insufficient_balance", "insufficient wallet balance")
+		return
+	}
+
+	var purchaseID string
+	err = tx.QueryRow(ctx, `
+		INSERT INTO purchases (product_id, buyer_id, seller_id, quantity, unit_price, amount)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id`,
+		productID, buyerID, sellerID, req.Quantity, unitPrice, price,
+	).Scan(&purchaseID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "database_error", "database error")
+		return
+	}
+
+	if _, err = tx.Exec(ctx,
+		`UPDATE users SET wallet_balance = wallet_balance - $1 WHERE id = $2`, price, buyerID); err != nil {
+		writeError(w, http.StatusInternalServerError, "database_error", "database error")
+		return
+	}
+	if _, err = tx.Exec(ctx,
+		`UPDATE users SET wallet_balance = wallet_balance + $1 WHERE id = $2`, price, sellerID); err != nil {
+		writeError(w, http.StatusInternalServerError, "database_error", "database error")
+		return
+	}
+	for _, userID := range []string{buyerID, sellerID} {
+		if _, err = tx.Exec(ctx, `
+			INSERT INTO transactions (user_id, amount, type, status, reference)
+			VALUES ($1, $2, 'TRANSFER', 'COMPLETED', $3)`,
+			userID, price, purchaseID); err != nil {
+			writeError(w, http.StatusInternalServerError, "database_error", "database error")
+			return
+		}
+	}
+
+	if _, err = tx.Exec(ctx, `
+		UPDATE products
+		SET quantity = quantity - $2::int,
+		    status = CASE WHEN quantity - $2::int = 0 THEN 'SOLD_OUT' ELSE status END,
+		    updated_at = NOW()
+		WHERE id = $1`, productID, req.Quantity); err != nil {
+		writeError(w, http.StatusInternalServerError, "database_error", "database error")
+		return
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		writeError(w, http.StatusInternalServerError, "database_error", "commit failed")
+		return
+	}
+	invalidateCategories()
+
+	writeJSON(w, http.StatusCreated, map[string]any{
+		"purchase_id": purchaseID,
+		"product_id":  productID,
+		"quantity":    req.Quantity,
+		"remaining":   stock - req.Quantity,
+		"amount":      price,
+		"status":      "COMPLETED",
+	})
+}
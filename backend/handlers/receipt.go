@@ -0,0 +1,117 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/jackc/pgx/v5"
+	"github.com/karti/orange-city-mart/backend/db"
+	authmw "github.com/karti/orange-city-mart/backend/middleware"
+)
+
+// listingFeesSQL sums the listing and feature fees the seller paid on the
+// product aliased p, which is what a sale's fees are: the marketplace takes
+// no commission on the sale itself.
+const listingFeesSQL = `(SELECT COALESCE(SUM(f.amount), 0) FROM transactions f
+	WHERE f.reference = p.id::text AND f.user_id = p.seller_id
+	  AND f.type IN ('LISTING_FEE', 'FEATURE_FEE') AND f.status = 'COMPLETED')`
+
+// ─────────────────────────────────────────────────────────────────────────────
+// GetReceipt  GET /api/auctions/{id}/receipt
+//
+// Returns a receipt for a COMPLETED settlement. Only the winner and seller may
+// fetch it; the counterparty's name is masked on each side's copy. fees are
+// the listing and feature fees the seller paid on the product.
+// ─────────────────────────────────────────────────────────────────────────────
+func (h *AuctionHandler) GetReceipt(w http.ResponseWriter, r *http.Request) {
+	auctionID := chi.URLParam(r, "id")
+	callerID, ok := authmw.UserIDFromContext(r.Context())
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "unauthorized", "unauthorized")
+		return
+	}
+	ctx := r.Context()
+
+	type party struct {
+		ID   string `json:"id"`
+		Name string `json:"name"`
+	}
+	var receipt struct {
+		SettlementID     string  `json:"settlement_id"`
+		AuctionID        string  `json:"auction_id"`
+		ProductID        string  `json:"product_id"`
+		ItemTitle        string  `json:"item_title"`
+		FinalPrice       float64 `json:"final_price"`
+		Fees             float64 `json:"fees"`
+		NetToSeller      float64 `json:"net_to_seller"`
+		Winner           party   `json:"winner"`
+		Seller           party   `json:"seller"`
+		AuctionEndedAt   string  `json:"auction_ended_at"`
+		WinnerApprovedAt string  `json:"winner_approved_at"`
+		SellerApprovedAt string  `json:"seller_approved_at"`
+		CompletedAt      string  `json:"completed_at"`
+		IssuedAt         string  `json:"issued_at"`
+	}
+
+	var (
+		status             string
+		endTime            time.Time
+		winnerAt, sellerAt *time.Time
+		completedAt        *time.Time
+	)
+	err := db.Pool.QueryRow(ctx, `
+		SELECT s.id, s.auction_id, p.id, p.title, s.amount, s.status,
+		       s.winner_id, wu.name, s.seller_id, su.name,
+		       a.end_time, s.winner_approved_at, s.seller_approved_at,
+		       (SELECT MAX(t.created_at) FROM transactions t
+		        WHERE t.reference = s.auction_id::text AND t.type = 'TRANSFER'),
+		       `+listingFeesSQL+`
+		FROM settlements s
+		JOIN auctions a ON a.id = s.auction_id
+		JOIN products p ON p.id = a.product_id
+		JOIN users wu ON wu.id = s.winner_id
+		JOIN users su ON su.id = s.seller_id
+		WHERE s.auction_id = $1`, auctionID,
+	).Scan(&receipt.SettlementID, &receipt.AuctionID, &receipt.ProductID,
+		&receipt.ItemTitle, &receipt.FinalPrice, &status,
+		&receipt.Winner.ID, &receipt.Winner.Name, &receipt.Seller.ID, &receipt.Seller.Name,
+		&endTime, &winnerAt, &sellerAt, &completedAt, &receipt.Fees)
+	if err == pgx.ErrNoRows {
+		writeError(w, http.StatusNotFound, "settlement_not_found", "settlement not found")
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "database_error", "database error")
+		return
+	}
+
+	switch callerID {
+	case receipt.Winner.ID:
+		receipt.Seller.Name = maskName(receipt.Seller.Name)
+	case receipt.Seller.ID:
+		receipt.Winner.Name = maskName(receipt.Winner.Name)
+	default:
+		writeError(w, http.StatusForbidden, "not_settlement_party", "you are not a party to this settlement")
+		return
+	}
+	if status != "COMPLETED" {
+		writeError(w, http.StatusConflict, "settlement_not_completed", "settlement is not completed yet")
+		return
+	}
+
+	receipt.NetToSeller = h.roundMoney(receipt.FinalPrice - receipt.Fees)
+	receipt.AuctionEndedAt = endTime.UTC().Format(time.RFC3339)
+	if winnerAt != nil {
+		receipt.WinnerApprovedAt = winnerAt.UTC().Format(time.RFC3339)
+	}
+	if sellerAt != nil {
+		receipt.SellerApprovedAt = sellerAt.UTC().Format(time.RFC3339)
+	}
+	if completedAt != nil {
+		receipt.CompletedAt = completedAt.UTC().Format(time.RFC3339)
+	}
+	receipt.IssuedAt = time.Now().UTC().Format(time.RFC3339)
+
+	writeJSON(w, http.StatusOK, receipt)
+}
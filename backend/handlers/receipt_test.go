@@ -0,0 +1,78 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// newCompletedSale commits a product sold by auction to winner for amount,
+// with a COMPLETED settlement, and returns the product and auction ids.
+// Both users are deleted, seller first, when the test ends.
+func newCompletedSale(t *testing.T, seller, winner string, amount float64) (productID, auctionID string) {
+	t.Helper()
+	ctx := context.Background()
+	t.Cleanup(func() {
+		testPool.Exec(ctx, `DELETE FROM users WHERE id = $1`, seller)
+		testPool.Exec(ctx, `DELETE FROM users WHERE id = $1`, winner)
+	})
+	err := testPool.QueryRow(ctx, `
+		INSERT INTO products (seller_id, title, type, price) VALUES ($1, 'Sold item', 'AUCTION', 10)
+		RETURNING id`, seller).Scan(&productID)
+	if err != nil {
+		t.Fatalf("insert product: %v", err)
+	}
+	err = testPool.QueryRow(ctx, `
+		INSERT INTO auctions (product_id, start_price, current_highest_bid, highest_bidder_id, end_time, status)
+		VALUES ($1, 10, $2, $3, NOW() - INTERVAL '1 day', 'ENDED') RETURNING id`,
+		productID, amount, winner).Scan(&auctionID)
+	if err != nil {
+		t.Fatalf("insert auction: %v", err)
+	}
+	_, err = testPool.Exec(ctx, `
+		INSERT INTO settlements (auction_id, winner_id, seller_id, amount, status, winner_approved_at, seller_approved_at)
+		VALUES ($1, $2, $3, $4, 'COMPLETED', NOW(), NOW())`,
+		auctionID, winner, seller, amount)
+	if err != nil {
+		t.Fatalf("insert settlement: %v", err)
+	}
+	return productID, auctionID
+}
+
+// chargeFee records a completed fee transaction of type typ on productID.
+func chargeFee(t *testing.T, userID, typ, productID string, amount float64) {
+	t.Helper()
+	_, err := testPool.Exec(context.Background(), `
+		INSERT INTO transactions (user_id, amount, type, status, reference)
+		VALUES ($1, $2, $3, 'COMPLETED', $4)`, userID, amount, typ, productID)
+	if err != nil {
+		t.Fatalf("insert %s: %v", typ, err)
+	}
+}
+
+func TestReceiptIncludesListingAndFeatureFees(t *testing.T) {
+	requireDB(t)
+	seller := newTestUser(t, testPool, 0)
+	winner := newTestUser(t, testPool, 0)
+	productID, auctionID := newCompletedSale(t, seller, winner, 1000)
+	chargeFee(t, seller, "LISTING_FEE", productID, 10)
+	chargeFee(t, seller, "FEATURE_FEE", productID, 99)
+
+	r := httptest.NewRequest(http.MethodGet, "/api/auctions/"+auctionID+"/receipt", nil)
+	r = withURLParam(asUser(r, seller), "id", auctionID)
+	w := httptest.NewRecorder()
+	(&AuctionHandler{Handler: testHandler}).GetReceipt(w, r)
+	if w.Code != http.StatusOK {
+		t.Fatalf("GetReceipt = %d: %s", w.Code, w.Body)
+	}
+	var got struct {
+		Fees        float64 `json:"fees"`
+		NetToSeller float64 `json:"net_to_seller"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &got)
+	if got.Fees != 109 || got.NetToSeller != 891 {
+		t.Fatalf("fees = %.2f, net = %.2f; want 109 and 891", got.Fees, got.NetToSeller)
+	}
+}
@@ -0,0 +1,162 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/karti/orange-city-mart/backend/db"
+)
+
+// issueRefreshToken stores a new refresh token for userID, valid for
+// REFRESH_TOKEN_TTL, and returns the raw value to hand to the client, along
+// with its id, which identifies the session in the access tokens minted for it.
+func (h *Handler) issueRefreshToken(ctx context.Context, q querier, userID string) (raw, id string, err error) {
+	raw, hash, err := newOpaqueToken()
+	if err != nil {
+		return "", "", err
+	}
+	err = q.QueryRow(ctx, `
+		INSERT INTO refresh_tokens (user_id, token_hash, expires_at)
+		VALUES ($1, $2, NOW() + make_interval(secs => $3))
+		RETURNING id`,
+		userID, hash, h.Config.RefreshTokenTTL.Seconds(),
+	).Scan(&id)
+	if err != nil {
+		return "", "", err
+	}
+	return raw, id, nil
+}
+
+// newSession mints the access/refresh token pair returned by every sign-in.
+func (h *Handler) newSession(ctx context.Context, u userInfo) (authResponse, error) {
+	refresh, sessionID, err := h.issueRefreshToken(ctx, db.Pool, u.ID)
+	if err != nil {
+		return authResponse{}, err
+	}
+	token, err := h.Auth.SignToken(u.ID, u.Role, sessionID)
+	if err != nil {
+		return authResponse{}, err
+	}
+	return authResponse{Token: token, RefreshToken: refresh, User: u}, nil
+}
+
+// ─────────────────────────────────────────────────────────────────────────────
+// Refresh  POST /api/auth/refresh
+//
+// Exchanges a valid refresh token for a new access token. Tokens rotate: the
+// presented one is revoked and a fresh one is returned alongside the JWT.
+// With SESSION_IDLE_TIMEOUT set, a token unused for longer is rejected.
+// ─────────────────────────────────────────────────────────────────────────────
+func (h *Handler) Refresh(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		RefreshToken string `json:"refresh_token"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.RefreshToken == "" {
+		writeError(w, http.StatusBadRequest, "missing_refresh_token", "refresh_token is required")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	tx, err := db.Pool.Begin(ctx)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "database_error", "database error")
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	// Revoking in the same statement that validates makes the old token
+	// single-use even under concurrent refreshes.
+	var userID string
+	err = tx.QueryRow(ctx, `
+		UPDATE refresh_tokens SET revoked_at = NOW()
+		WHERE token_hash = $1 AND revoked_at IS NULL AND expires_at > NOW()
+		  AND ($2::float8 = 0 OR last_used_at > NOW() - make_interval(secs => $2::float8))
+		RETURNING user_id`,
+		hashToken(req.RefreshToken), h.Config.SessionIdleTimeout.Seconds(),
+	).Scan(&userID)
+	if err == pgx.ErrNoRows {
+		writeError(w, http.StatusUnauthorized, "invalid_refresh_token", "invalid or expired refresh token")
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "database_error", "database error")
+		return
+	}
+
+	var u userInfo
+	err = tx.QueryRow(ctx,
+		`SELECT id, name, email, wallet_balance, role FROM users WHERE id = $1`, userID,
+	).Scan(&u.ID, &u.Name, &u.Email, &u.WalletBalance, &u.Role)
+	if err == pgx.ErrNoRows {
+		writeError(w, http.StatusUnauthorized, "invalid_refresh_token", "invalid or expired refresh token")
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "database_error", "database error")
+		return
+	}
+
+	refresh, sessionID, err := h.issueRefreshToken(ctx, tx, u.ID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "database_error", "database error")
+		return
+	}
+	if err = tx.Commit(ctx); err != nil {
+		writeError(w, http.StatusInternalServerError, "database_error", "commit failed")
+		return
+	}
+
+	token, err := h.Auth.SignToken(u.ID, u.Role, sessionID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "internal_error", "could not generate token")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, authResponse{Token: token, RefreshToken: refresh, User: u})
+}
+
+// Logout handles POST /api/auth/logout
+// Revokes the bearer access token (by jti) and, if given, the refresh token,
+// so neither can be used again.
+func (h *Handler) Logout(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		RefreshToken string `json:"refresh_token"`
+	}
+	// The body is optional when only the access token is being revoked.
+	json.NewDecoder(r.Body).Decode(&req)
+
+	accessToken := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if accessToken == "" && req.RefreshToken == "" {
+		writeError(w, http.StatusBadRequest, "missing_token", "nothing to revoke")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	if accessToken != "" {
+		if err := h.Auth.RevokeToken(ctx, accessToken); err != nil {
+			writeError(w, http.StatusInternalServerError, "database_error", "database error")
+			return
+		}
+	}
+
+	if req.RefreshToken != "" {
+		_, err := db.Pool.Exec(ctx,
+			`UPDATE refresh_tokens SET revoked_at = NOW() WHERE token_hash = $1 AND revoked_at IS NULL`,
+			hashToken(req.RefreshToken),
+		)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "database_error", "database error")
+			return
+		}
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
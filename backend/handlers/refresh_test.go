@@ -0,0 +1,67 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/karti/orange-city-mart/backend/config"
+	authmw "github.com/karti/orange-city-mart/backend/middleware"
+)
+
+// TestSlidingRenewalTouchesOnlyPresentingSession checks that renewing an
+// access token near expiry marks only its own session as used, not every
+// session the user has open.
+func TestSlidingRenewalTouchesOnlyPresentingSession(t *testing.T) {
+	requireDB(t)
+	api := newTestHandler(func(c *config.Config) { c.AccessTokenTTL = time.Minute })
+	ctx := context.Background()
+	userID := newTestUser(t, testPool, 0)
+	t.Cleanup(func() { testPool.Exec(ctx, `DELETE FROM users WHERE id = $1`, userID) })
+
+	_, presenting, err := api.issueRefreshToken(ctx, testPool, userID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, other, err := api.issueRefreshToken(ctx, testPool, userID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := testPool.Exec(ctx, `
+		UPDATE refresh_tokens SET last_used_at = NOW() - INTERVAL '1 hour' WHERE user_id = $1`,
+		userID); err != nil {
+		t.Fatal(err)
+	}
+
+	token, err := api.Auth.SignToken(userID, "user", presenting)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := api.Auth.RequireAuth(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("Authorization", "Bearer "+token)
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	if w.Code != http.StatusOK {
+		t.Fatalf("RequireAuth = %d: %s", w.Code, w.Body)
+	}
+	if w.Header().Get(authmw.RenewedTokenHeader) == "" {
+		t.Fatal("token near expiry was not renewed")
+	}
+
+	lastUsed := func(id string) time.Time {
+		var at time.Time
+		if err := testPool.QueryRow(ctx, `SELECT last_used_at FROM refresh_tokens WHERE id = $1`, id).Scan(&at); err != nil {
+			t.Fatal(err)
+		}
+		return at
+	}
+	if time.Since(lastUsed(presenting)) > time.Minute {
+		t.Error("presenting session was not marked as used")
+	}
+	if time.Since(lastUsed(other)) < 30*time.Minute {
+		t.Error("renewal touched another session of the same user")
+	}
+}
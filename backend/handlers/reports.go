@@ -0,0 +1,296 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/karti/orange-city-mart/backend/db"
+	authmw "github.com/karti/orange-city-mart/backend/middleware"
+)
+
+// reportReasons are the categories a listing can be reported under.
+var reportReasons = map[string]bool{
+	"SCAM":        true,
+	"PROHIBITED":  true,
+	"COUNTERFEIT": true,
+	"MISLEADING":  true,
+	"OTHER":       true,
+}
+
+// ─────────────────────────────────────────────────────────────────────────────
+// ReportProduct  POST /api/products/{id}/report
+//
+// Body: { "reason": "SCAM", "note": "optional details" }
+// Files a report against a listing for the moderation queue. Each user can
+// report a given listing once; a repeat returns 409.
+// ─────────────────────────────────────────────────────────────────────────────
+func (h *Handler) ReportProduct(w http.ResponseWriter, r *http.Request) {
+	userID, ok := authmw.UserIDFromContext(r.Context())
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "unauthorized", "unauthorized")
+		return
+	}
+	productID := chi.URLParam(r, "id")
+
+	var req struct {
+		Reason string `json:"reason"`
+		Note   string `json:"note"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_body", "invalid request body")
+		return
+	}
+	req.Reason = strings.ToUpper(strings.TrimSpace(req.Reason))
+	if !reportReasons[req.Reason] {
+		writeError(w, http.StatusBadRequest, "invalid_reason", "reason must be one of SCAM, PROHIBITED, COUNTERFEIT, MISLEADING, OTHER")
+		return
+	}
+	req.Note = strings.TrimSpace(req.Note)
+	if len(req.Note) > 1000 {
+		writeError(w, http.StatusBadRequest, "note_too_long", "note must be at most 1000 characters")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	var sellerID string
+	err := db.Pool.QueryRow(ctx,
+		`SELECT seller_id FROM products WHERE id = $1 AND deleted_at IS NULL`, productID,
+	).Scan(&sellerID)
+	if err == pgx.ErrNoRows {
+		writeError(w, http.StatusNotFound, "product_not_found", "product not found")
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "database_error", "database error")
+		return
+	}
+	if sellerID == userID {
+		writeError(w, http.StatusBadRequest, "own_listing", "you cannot report your own listing")
+		return
+	}
+
+	var reportID string
+	err = db.Pool.QueryRow(ctx, `
+		INSERT INTO reports (product_id, reporter_id, reason, note)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id`,
+		productID, userID, req.Reason, nullableString(req.Note),
+	).Scan(&reportID)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			writeError(w, http.StatusConflict, "already_reported", "you have already reported this listing")
+			return
+		}
+		writeError(w, http.StatusInternalServerError, "database_error", "database error")
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, map[string]string{"id": reportID, "status": "PENDING"})
+}
+
+// ─────────────────────────────────────────────────────────────────────────────
+// ListReports  GET /api/admin/reports?status=PENDING
+//
+// Admin moderation queue, oldest first, with the reported listing and the
+// reporter. status defaults to PENDING; RESOLVED and DISMISSED show history.
+// ─────────────────────────────────────────────────────────────────────────────
+func (h *Handler) ListReports(w http.ResponseWriter, r *http.Request) {
+	status := strings.ToUpper(r.URL.Query().Get("status"))
+	if status == "" {
+		status = "PENDING"
+	}
+	if status != "PENDING" && status != "RESOLVED" && status != "DISMISSED" {
+		writeError(w, http.StatusBadRequest, "invalid_status", "status must be PENDING, RESOLVED or DISMISSED")
+		return
+	}
+
+	rows, err := db.Pool.Query(r.Context(), `
+		SELECT rp.id, rp.reason, rp.note, rp.status, rp.created_at, rp.resolved_at,
+		       p.id, p.title, p.seller_id, s.name, p.deleted_at IS NOT NULL,
+		       u.id, u.name,
+		       (SELECT COUNT(*) FROM reports o
+		        WHERE o.product_id = p.id AND o.status = 'PENDING')
+		FROM reports rp
+		JOIN products p ON p.id = rp.product_id
+		JOIN users s ON s.id = p.seller_id
+		JOIN users u ON u.id = rp.reporter_id
+		WHERE rp.status = $1
+		ORDER BY rp.created_at ASC
+		LIMIT 200`, status)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "database_error", "database error")
+		return
+	}
+	defer rows.Close()
+
+	type reportItem struct {
+		ID             string  `json:"id"`
+		Reason         string  `json:"reason"`
+		Note           *string `json:"note"`
+		Status         string  `json:"status"`
+		CreatedAt      string  `json:"created_at"`
+		ResolvedAt     *string `json:"resolved_at"`
+		ProductID      string  `json:"product_id"`
+		ProductTitle   string  `json:"product_title"`
+		SellerID       string  `json:"seller_id"`
+		SellerName     string  `json:"seller_name"`
+		ProductRemoved bool    `json:"product_removed"`
+		ReporterID     string  `json:"reporter_id"`
+		ReporterName   string  `json:"reporter_name"`
+		PendingReports int     `json:"pending_reports"` // open reports on the same listing
+	}
+
+	items := []reportItem{}
+	for rows.Next() {
+		var it reportItem
+		var createdAt time.Time
+		var resolvedAt *time.Time
+		if err := rows.Scan(&it.ID, &it.Reason, &it.Note, &it.Status, &createdAt, &resolvedAt,
+			&it.ProductID, &it.ProductTitle, &it.SellerID, &it.SellerName, &it.ProductRemoved,
+			&it.ReporterID, &it.ReporterName, &it.PendingReports); err != nil {
+			continue
+		}
+		it.CreatedAt = createdAt.UTC().Format(time.RFC3339)
+		if resolvedAt != nil {
+			s := resolvedAt.UTC().Format(time.RFC3339)
+			it.ResolvedAt = &s
+		}
+		items = append(items, it)
+	}
+
+	writeJSON(w, http.StatusOK, items)
+}
+
+// ─────────────────────────────────────────────────────────────────────────────
+// ResolveReport  POST /api/admin/reports/{id}/resolve
+//
+// Body: { "action": "resolve" | "dismiss", "takedown": true }
+// Closes a pending report. Resolving with takedown removes the listing: its
+// live auction is cancelled with every soft hold refunded, and all other
+// pending reports on it are resolved too.
+// ─────────────────────────────────────────────────────────────────────────────
+func (h *Handler) ResolveReport(w http.ResponseWriter, r *http.Request) {
+	adminID, ok := authmw.UserIDFromContext(r.Context())
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "unauthorized", "unauthorized")
+		return
+	}
+	reportID := chi.URLParam(r, "id")
+
+	var req struct {
+		Action   string `json:"action"`
+		Takedown bool   `json:"takedown"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_body", "invalid request body")
+		return
+	}
+	var status string
+	switch req.Action {
+	case "resolve":
+		status = "RESOLVED"
+	case "dismiss":
+		status = "DISMISSED"
+		if req.Takedown {
+			writeError(w, http.StatusBadRequest, "invalid_action", "a dismissed report cannot take down the listing")
+			return
+		}
+	default:
+		writeError(w, http.StatusBadRequest, "invalid_action", "action must be resolve or dismiss")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	tx, err := db.Pool.Begin(ctx)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "database_error", "database error")
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	var productID, current string
+	err = tx.QueryRow(ctx, `
+		SELECT product_id, status FROM reports WHERE id = $1 FOR UPDATE`, reportID,
+	).Scan(&productID, &current)
+	if err == pgx.ErrNoRows {
+		writeError(w, http.StatusNotFound, "report_not_found", "report not found")
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "database_error", "database error")
+		return
+	}
+	if current != "PENDING" {
+		writeError(w, http.StatusConflict, "report_closed", "report is already closed")
+		return
+	}
+
+	_, err = tx.Exec(ctx, `
+		UPDATE reports SET status = $2, resolved_by = $3, resolved_at = NOW()
+		WHERE id = $1`, reportID, status, adminID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "database_error", "database error")
+		return
+	}
+
+	if req.Takedown {
+		if err := takedownListing(ctx, tx, productID); err != nil && err != pgx.ErrNoRows {
+			writeAuctionError(w, err)
+			return
+		}
+		_, err = tx.Exec(ctx, `
+			UPDATE reports SET status = 'RESOLVED', resolved_by = $2, resolved_at = NOW()
+			WHERE product_id = $1 AND status = 'PENDING'`, productID, adminID)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "database_error", "database error")
+			return
+		}
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		writeError(w, http.StatusInternalServerError, "database_error", "commit failed")
+		return
+	}
+	if req.Takedown {
+		invalidateCategories()
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"id":       reportID,
+		"status":   status,
+		"takedown": req.Takedown,
+	})
+}
+
+// takedownListing soft-deletes a product for moderation. Unlike a seller's
+// DeleteProduct it also removes listings with bids: the live auction is
+// cancelled and its soft holds are refunded. Returns pgx.ErrNoRows when the
+// listing is already gone.
+func takedownListing(ctx context.Context, tx pgx.Tx, productID string) error {
+	l, err := lockListing(ctx, tx, productID)
+	if err != nil {
+		return err
+	}
+	if l.AuctionID != nil {
+		if err := updateAuctionVersioned(ctx, tx, l.auction(), `status = 'CANCELLED'`); err != nil {
+			return err
+		}
+		if err := releaseSoftHolds(ctx, tx, *l.AuctionID); err != nil {
+			return err
+		}
+	}
+	_, err = tx.Exec(ctx, `UPDATE products SET deleted_at = NOW() WHERE id = $1`, productID)
+	return err
+}
@@ -0,0 +1,132 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// reportProduct calls ReportProduct as userID and returns the status code.
+func reportProduct(userID, productID, body string) int {
+	r := httptest.NewRequest(http.MethodPost, "/api/products/"+productID+"/report", strings.NewReader(body))
+	r = withURLParam(asUser(r, userID), "id", productID)
+	w := httptest.NewRecorder()
+	testHandler.ReportProduct(w, r)
+	return w.Code
+}
+
+type queuedReport struct {
+	ID             string `json:"id"`
+	Reason         string `json:"reason"`
+	ProductID      string `json:"product_id"`
+	ReporterID     string `json:"reporter_id"`
+	PendingReports int    `json:"pending_reports"`
+}
+
+// pendingReportsFor returns the moderation queue's entries for productID.
+func pendingReportsFor(t *testing.T, productID string) []queuedReport {
+	t.Helper()
+	w := httptest.NewRecorder()
+	testHandler.ListReports(w, httptest.NewRequest(http.MethodGet, "/api/admin/reports", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("ListReports: status %d: %s", w.Code, w.Body)
+	}
+	var all []queuedReport
+	if err := json.Unmarshal(w.Body.Bytes(), &all); err != nil {
+		t.Fatal(err)
+	}
+	var out []queuedReport
+	for _, rp := range all {
+		if rp.ProductID == productID {
+			out = append(out, rp)
+		}
+	}
+	return out
+}
+
+func TestReportListingDedupeAndQueue(t *testing.T) {
+	requireDB(t)
+	ctx := context.Background()
+	seller := newTestUser(t, testPool, 0)
+	first := newTestUser(t, testPool, 0)
+	second := newTestUser(t, testPool, 0)
+	t.Cleanup(func() {
+		testPool.Exec(ctx, `DELETE FROM users WHERE id IN ($1, $2, $3)`, seller, first, second)
+	})
+	productID := newTestFixedProduct(t, seller, 1, "AVAILABLE")
+
+	if code := reportProduct(first, productID, `{"reason": "SCAM", "note": "asks for payment off-site"}`); code != http.StatusCreated {
+		t.Fatalf("first report: status %d", code)
+	}
+	if code := reportProduct(first, productID, `{"reason": "OTHER"}`); code != http.StatusConflict {
+		t.Errorf("repeat report: status %d, want 409", code)
+	}
+	if code := reportProduct(seller, productID, `{"reason": "SCAM"}`); code != http.StatusBadRequest {
+		t.Errorf("own listing: status %d, want 400", code)
+	}
+	if code := reportProduct(second, productID, `{"reason": "NOT_A_REASON"}`); code != http.StatusBadRequest {
+		t.Errorf("unknown reason: status %d, want 400", code)
+	}
+	if code := reportProduct(second, productID, `{"reason": "COUNTERFEIT"}`); code != http.StatusCreated {
+		t.Fatalf("second reporter: status %d", code)
+	}
+
+	queue := pendingReportsFor(t, productID)
+	if len(queue) != 2 || queue[0].ReporterID != first || queue[0].Reason != "SCAM" || queue[1].ReporterID != second {
+		t.Fatalf("queue = %+v, want both reports, oldest first", queue)
+	}
+	if queue[0].PendingReports != 2 {
+		t.Errorf("pending_reports = %d, want 2", queue[0].PendingReports)
+	}
+}
+
+func TestResolveReportWithTakedown(t *testing.T) {
+	requireDB(t)
+	ctx := context.Background()
+	admin := newTestUser(t, testPool, 0)
+	seller := newTestUser(t, testPool, 0)
+	first := newTestUser(t, testPool, 0)
+	second := newTestUser(t, testPool, 0)
+	t.Cleanup(func() {
+		testPool.Exec(ctx, `DELETE FROM users WHERE id IN ($1, $2, $3, $4)`, admin, seller, first, second)
+	})
+	productID := newTestFixedProduct(t, seller, 1, "AVAILABLE")
+	for _, reporter := range []string{first, second} {
+		if code := reportProduct(reporter, productID, `{"reason": "PROHIBITED"}`); code != http.StatusCreated {
+			t.Fatalf("report: status %d", code)
+		}
+	}
+	queue := pendingReportsFor(t, productID)
+	if len(queue) != 2 {
+		t.Fatalf("queue = %+v, want two reports", queue)
+	}
+
+	resolve := func(body string) int {
+		r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+		r = withURLParam(asUser(r, admin), "id", queue[0].ID)
+		w := httptest.NewRecorder()
+		testHandler.ResolveReport(w, r)
+		return w.Code
+	}
+	if code := resolve(`{"action": "dismiss", "takedown": true}`); code != http.StatusBadRequest {
+		t.Errorf("dismiss with takedown: status %d, want 400", code)
+	}
+	if code := resolve(`{"action": "resolve", "takedown": true}`); code != http.StatusOK {
+		t.Fatalf("resolve: status %d", code)
+	}
+	if code := resolve(`{"action": "resolve"}`); code != http.StatusConflict {
+		t.Errorf("resolving again: status %d, want 409", code)
+	}
+
+	if got := pendingReportsFor(t, productID); len(got) != 0 {
+		t.Errorf("queue after takedown = %+v, want every report on the listing closed", got)
+	}
+	var removed bool
+	testPool.QueryRow(ctx, `SELECT deleted_at IS NOT NULL FROM products WHERE id = $1`, productID).Scan(&removed)
+	if !removed {
+		t.Error("listing still live after takedown")
+	}
+}
@@ -0,0 +1,116 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/karti/orange-city-mart/backend/db"
+	authmw "github.com/karti/orange-city-mart/backend/middleware"
+)
+
+// csvText neutralises a free-text CSV cell that a spreadsheet would
+// otherwise run as a formula (one starting with =, +, -, @, tab or CR) by
+// prefixing it with a quote. Numeric cells are written by us and left as is.
+func csvText(s string) string {
+	if s != "" && strings.ContainsRune("=+-@\t\r", rune(s[0])) {
+		return "'" + s
+	}
+	return s
+}
+
+// ─────────────────────────────────────────────────────────────────────────────
+// ExportSales  GET /api/my/sales/export?format=csv&from=&to=
+//
+// Streams the caller's completed sales (settlements where they are the
+// seller) as CSV, one row per sale, oldest payout first. from/to take
+// YYYY-MM-DD (inclusive) or RFC3339 and filter on the payout date, i.e. when the second
+// party approved. fees are the listing and feature fees paid on the product.
+// Buyers are identified only by a masked id.
+// ─────────────────────────────────────────────────────────────────────────────
+func (h *Handler) ExportSales(w http.ResponseWriter, r *http.Request) {
+	userID, ok := authmw.UserIDFromContext(r.Context())
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "unauthorized", "unauthorized")
+		return
+	}
+	if f := r.URL.Query().Get("format"); f != "" && f != "csv" {
+		writeError(w, http.StatusBadRequest, "invalid_format", "format must be csv")
+		return
+	}
+	from, ok := parseDateParam(r.URL.Query().Get("from"), time.Time{})
+	if !ok {
+		writeError(w, http.StatusBadRequest, "invalid_from", "from must be YYYY-MM-DD or RFC3339")
+		return
+	}
+	toRaw := r.URL.Query().Get("to")
+	to, ok := parseDateParam(toRaw, time.Time{})
+	if !ok {
+		writeError(w, http.StatusBadRequest, "invalid_to", "to must be YYYY-MM-DD or RFC3339")
+		return
+	}
+
+	var fromArg, toArg interface{}
+	if !from.IsZero() {
+		fromArg = from
+	}
+	if !to.IsZero() {
+		// A bare date covers that whole day.
+		if len(toRaw) == len("2006-01-02") {
+			to = to.Add(24 * time.Hour)
+		}
+		toArg = to
+	}
+
+	rows, err := db.Pool.Query(r.Context(), `
+		SELECT s.auction_id, p.title, s.amount, `+listingFeesSQL+`::float8, s.refunded_amount,
+		       s.winner_id::text, GREATEST(s.winner_approved_at, s.seller_approved_at) AS paid_at
+		FROM settlements s
+		JOIN auctions a ON a.id = s.auction_id
+		JOIN products p ON p.id = a.product_id
+		WHERE s.seller_id = $1
+		  AND s.status = 'COMPLETED'
+		  AND ($2::timestamptz IS NULL OR GREATEST(s.winner_approved_at, s.seller_approved_at) >= $2::timestamptz)
+		  AND ($3::timestamptz IS NULL OR GREATEST(s.winner_approved_at, s.seller_approved_at) < $3::timestamptz)
+		ORDER BY paid_at ASC`,
+		userID, fromArg, toArg,
+	)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "database_error", "database error")
+		return
+	}
+	defer rows.Close()
+
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", `attachment; filename="sales.csv"`)
+
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"auction_id", "item", "final_price", "fees", "refunded", "net", "buyer", "payout_date"})
+	for rows.Next() {
+		var (
+			auctionID, title, buyerID string
+			amount, fees, refunded    float64
+			paidAt                    time.Time
+		)
+		if err := rows.Scan(&auctionID, &title, &amount, &fees, &refunded, &buyerID, &paidAt); err != nil {
+			continue
+		}
+		cw.Write([]string{
+			auctionID,
+			csvText(title),
+			formatAmount(amount),
+			formatAmount(fees),
+			formatAmount(refunded),
+			formatAmount(h.roundMoney(amount - fees - refunded)),
+			maskName(buyerID),
+			paidAt.UTC().Format(time.RFC3339),
+		})
+		cw.Flush()
+	}
+	cw.Flush()
+	if err := rows.Err(); err != nil {
+		// Headers are already sent; all we can do is log the truncation.
+		logf(r.Context(), "sales export for %s: %v", userID, err)
+	}
+}
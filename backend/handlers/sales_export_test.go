@@ -0,0 +1,55 @@
+package handlers
+
+import (
+	"context"
+	"encoding/csv"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCSVText(t *testing.T) {
+	for in, want := range map[string]string{
+		"=HYPERLINK(\"x\")": "'=HYPERLINK(\"x\")",
+		"+1+1":              "'+1+1",
+		"-2+3":              "'-2+3",
+		"@SUM(A1)":          "'@SUM(A1)",
+		"\tcmd":             "'\tcmd",
+		"Vintage lamp":      "Vintage lamp",
+		"":                  "",
+	} {
+		if got := csvText(in); got != want {
+			t.Errorf("csvText(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestExportSalesFeesAndFormulaTitles(t *testing.T) {
+	requireDB(t)
+	seller := newTestUser(t, testPool, 0)
+	winner := newTestUser(t, testPool, 0)
+	productID, _ := newCompletedSale(t, seller, winner, 500)
+	chargeFee(t, seller, "LISTING_FEE", productID, 20)
+	chargeFee(t, seller, "FEATURE_FEE", productID, 30)
+	if _, err := testPool.Exec(context.Background(),
+		`UPDATE products SET title = '=cmd|'' /C calc''!A0' WHERE id = $1`, productID); err != nil {
+		t.Fatal(err)
+	}
+
+	w := httptest.NewRecorder()
+	testHandler.ExportSales(w, asUser(httptest.NewRequest(http.MethodGet, "/api/my/sales/export", nil), seller))
+	if w.Code != http.StatusOK {
+		t.Fatalf("ExportSales = %d: %s", w.Code, w.Body)
+	}
+	rows, err := csv.NewReader(w.Body).ReadAll()
+	if err != nil || len(rows) != 2 {
+		t.Fatalf("csv = %v, %v; want header and one row", rows, err)
+	}
+	row := rows[1] // auction_id, item, final_price, fees, refunded, net, ...
+	if row[1][0] != '\'' {
+		t.Errorf("formula title exported as %q", row[1])
+	}
+	if row[3] != "50.00" || row[5] != "450.00" {
+		t.Errorf("fees = %s, net = %s; want 50.00 and 450.00", row[3], row[5])
+	}
+}
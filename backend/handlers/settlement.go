@@ -0,0 +1,439 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/karti/orange-city-mart/backend/db"
+	authmw "github.com/karti/orange-city-mart/backend/middleware"
+)
+
+var (
+	errSettlementNotFound  = errors.New("settlement not found — auction may still be active")
+	errSettlementCompleted = errors.New("settlement already completed")
+	errAlreadyApproved     = errors.New("you have already approved")
+	errNotSettlementParty  = errors.New("you are not a party to this settlement")
+	errSettlementVoid      = errors.New("settlement was voided")
+	errSettlementExpired   = errors.New("settlement expired")
+	errSettlementDisputed  = errors.New("settlement is under dispute")
+	errSettlementRefunded  = errors.New("settlement was refunded to the winner")
+)
+
+// settlementErrorStatus maps an approveSettlement error to its HTTP status
+// and error code.
+func settlementErrorStatus(err error) (int, string) {
+	switch {
+	case errors.Is(err, errSettlementNotFound):
+		return http.StatusNotFound, "settlement_not_found"
+	case errors.Is(err, errSettlementCompleted):
+		return http.StatusConflict, "settlement_completed"
+	case errors.Is(err, errAlreadyApproved):
+		return http.StatusConflict, "already_approved"
+	case errors.Is(err, errNotSettlementParty):
+		return http.StatusForbidden, "not_settlement_party"
+	case errors.Is(err, errSettlementVoid):
+		return http.StatusConflict, "settlement_void"
+	case errors.Is(err, errSettlementExpired):
+		return http.StatusConflict, "settlement_expired"
+	case errors.Is(err, errSettlementDisputed):
+		return http.StatusConflict, "settlement_disputed"
+	case errors.Is(err, errSettlementRefunded):
+		return http.StatusConflict, "settlement_refunded"
+	}
+	return http.StatusInternalServerError, "database_error"
+}
+
+// settlementApproval is the state of a settlement after an approval.
+type settlementApproval struct {
+	BothApproved   bool
+	WinnerApproved bool
+	SellerApproved bool
+	Status         string
+}
+
+// approveSettlement records callerID's approval of the settlement for an
+// auction in its own transaction. When both parties have approved, the
+// winner's HARD hold is settled and the amount is credited to the seller.
+func approveSettlement(ctx context.Context, auctionID, callerID string) (*settlementApproval, error) {
+	tx, err := db.Pool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	// Lock settlement row
+	var (
+		settlementID     string
+		winnerID         string
+		sellerID         string
+		amount           float64
+		winnerApprovedAt *time.Time
+		sellerApprovedAt *time.Time
+		settlementStatus string
+	)
+	err = tx.QueryRow(ctx, `
+		SELECT id, winner_id, seller_id, amount,
+		       winner_approved_at, seller_approved_at, status
+		FROM settlements
+		WHERE auction_id = $1
+		FOR UPDATE`, auctionID,
+	).Scan(&settlementID, &winnerID, &sellerID, &amount,
+		&winnerApprovedAt, &sellerApprovedAt, &settlementStatus)
+	if err == pgx.ErrNoRows {
+		return nil, errSettlementNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if settlementStatus == "COMPLETED" {
+		return nil, errSettlementCompleted
+	}
+	if settlementStatus == "VOID" {
+		return nil, errSettlementVoid
+	}
+	if settlementStatus == "EXPIRED" {
+		return nil, errSettlementExpired
+	}
+	if settlementStatus == "DISPUTED" {
+		return nil, errSettlementDisputed
+	}
+	if settlementStatus == "REFUNDED" {
+		return nil, errSettlementRefunded
+	}
+
+	// Record the caller's approval. Each update is conditional on the column
+	// still being NULL so a retried request can't approve twice.
+	var tag pgconn.CommandTag
+	switch callerID {
+	case winnerID:
+		if winnerApprovedAt != nil {
+			return nil, errAlreadyApproved
+		}
+		now := time.Now()
+		winnerApprovedAt = &now
+		tag, err = tx.Exec(ctx, `
+			UPDATE settlements SET winner_approved_at = NOW()
+			WHERE id = $1 AND winner_approved_at IS NULL`, settlementID)
+	case sellerID:
+		if sellerApprovedAt != nil {
+			return nil, errAlreadyApproved
+		}
+		now := time.Now()
+		sellerApprovedAt = &now
+		tag, err = tx.Exec(ctx, `
+			UPDATE settlements SET seller_approved_at = NOW()
+			WHERE id = $1 AND seller_approved_at IS NULL`, settlementID)
+	default:
+		return nil, errNotSettlementParty
+	}
+	if err != nil {
+		return nil, err
+	}
+	if tag.RowsAffected() != 1 {
+		return nil, errAlreadyApproved
+	}
+
+	res := &settlementApproval{
+		BothApproved:   winnerApprovedAt != nil && sellerApprovedAt != nil,
+		WinnerApproved: winnerApprovedAt != nil,
+		SellerApproved: sellerApprovedAt != nil,
+		Status:         "PENDING",
+	}
+
+	// If both parties approved, execute the transfer
+	if res.BothApproved {
+		if err = completeSettlement(ctx, tx, settlementID, auctionID, winnerID, sellerID, amount); err != nil {
+			return nil, err
+		}
+		res.Status = "COMPLETED"
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// completeSettlement marks a settlement COMPLETED inside tx and moves the
+// winner's HARD hold to the seller, recording a TRANSFER for each party.
+func completeSettlement(ctx context.Context, tx pgx.Tx, settlementID, auctionID, winnerID, sellerID string, amount float64) error {
+	// The status guard plus the affected-rows check make the transfer below
+	// run at most once per settlement.
+	tag, err := tx.Exec(ctx, `
+		UPDATE settlements SET status = 'COMPLETED'
+		WHERE id = $1 AND status <> 'COMPLETED'`, settlementID)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() != 1 {
+		return errSettlementCompleted
+	}
+
+	// Mark the winner's HARD hold as SETTLED
+	_, err = tx.Exec(ctx, `
+		UPDATE bid_holds SET status = 'SETTLED', updated_at = NOW()
+		WHERE auction_id = $1 AND user_id = $2 AND status = 'HARD'`,
+		auctionID, winnerID,
+	)
+	if err != nil {
+		return err
+	}
+
+	// Credit the seller's wallet
+	_, err = tx.Exec(ctx, `
+		UPDATE users SET wallet_balance = wallet_balance + $1 WHERE id = $2`,
+		amount, sellerID,
+	)
+	if err != nil {
+		return err
+	}
+
+	// Record TRANSFER transactions for both parties
+	_, err = tx.Exec(ctx, `
+		INSERT INTO transactions (user_id, amount, type, status, reference)
+		VALUES ($1, $2, 'TRANSFER', 'COMPLETED', $3)`,
+		winnerID, amount, auctionID)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(ctx, `
+		INSERT INTO transactions (user_id, amount, type, status, reference)
+		VALUES ($1, $2, 'TRANSFER', 'COMPLETED', $3)`,
+		sellerID, amount, auctionID)
+	return err
+}
+
+// maxBulkApprovals caps how many settlements one bulk request may touch.
+const maxBulkApprovals = 100
+
+// ─────────────────────────────────────────────────────────────────────────────
+// ApproveSettlementsBulk  POST /api/settlements/approve-bulk
+//
+// Records the caller's approval on many settlements at once, identified by
+// auction id. Each approval runs in its own transaction, so one failure
+// doesn't undo the others; the response reports the outcome per id.
+// ─────────────────────────────────────────────────────────────────────────────
+func (h *AuctionHandler) ApproveSettlementsBulk(w http.ResponseWriter, r *http.Request) {
+	callerID, ok := authmw.UserIDFromContext(r.Context())
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "unauthorized", "unauthorized")
+		return
+	}
+
+	var req struct {
+		AuctionIDs []string `json:"auction_ids"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || len(req.AuctionIDs) == 0 {
+		writeError(w, http.StatusBadRequest, "missing_ids", "auction_ids is required")
+		return
+	}
+	if len(req.AuctionIDs) > maxBulkApprovals {
+		writeError(w, http.StatusBadRequest, "too_many_ids", "too many auction_ids in one request")
+		return
+	}
+
+	type Result struct {
+		AuctionID        string `json:"auction_id"`
+		Success          bool   `json:"success"`
+		Code             string `json:"code,omitempty"`
+		Error            string `json:"error,omitempty"`
+		BothApproved     bool   `json:"both_approved,omitempty"`
+		SettlementStatus string `json:"settlement_status,omitempty"`
+	}
+
+	results := make([]Result, 0, len(req.AuctionIDs))
+	seen := make(map[string]bool, len(req.AuctionIDs))
+	for _, id := range req.AuctionIDs {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+
+		ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+		res, err := approveSettlement(ctx, id, callerID)
+		cancel()
+
+		out := Result{AuctionID: id}
+		if err == nil {
+			out.Success = true
+			out.BothApproved = res.BothApproved
+			out.SettlementStatus = res.Status
+			if res.Status == "COMPLETED" {
+				h.notifySettlementCompletedOffsite(id)
+			}
+		} else {
+			status, code := settlementErrorStatus(err)
+			out.Code, out.Error = code, err.Error()
+			if status == http.StatusInternalServerError {
+				out.Error = "database error"
+			}
+		}
+		results = append(results, out)
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{"results": results})
+}
+
+// ─────────────────────────────────────────────────────────────────────────────
+// RefundSettlement  POST /api/auctions/{id}/settlement/refund
+//
+// Post-sale adjustment: the seller sends part of a completed sale back to the
+// buyer. Refunds accumulate against the settlement and can never exceed its
+// amount in total. Records an ADJUSTMENT_OUT / ADJUSTMENT_IN pair whose
+// references point at each other.
+// ─────────────────────────────────────────────────────────────────────────────
+func (h *AuctionHandler) RefundSettlement(w http.ResponseWriter, r *http.Request) {
+	auctionID := chi.URLParam(r, "id")
+	callerID, ok := authmw.UserIDFromContext(r.Context())
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "unauthorized", "unauthorized")
+		return
+	}
+
+	var req struct {
+		Amount float64 `json:"amount"`
+	}
+	err := json.NewDecoder(r.Body).Decode(&req)
+	req.Amount = h.roundMoney(req.Amount)
+	if err != nil || req.Amount <= 0 {
+		writeError(w, http.StatusBadRequest, "invalid_amount", "amount must be positive")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	tx, err := db.Pool.Begin(ctx)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "database_error", "database error")
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	var (
+		settlementID string
+		winnerID     string
+		sellerID     string
+		amount       float64
+		refunded     float64
+		status       string
+	)
+	err = tx.QueryRow(ctx, `
+		SELECT id, winner_id, seller_id, amount, refunded_amount, status
+		FROM settlements
+		WHERE auction_id = $1
+		FOR UPDATE`, auctionID,
+	).Scan(&settlementID, &winnerID, &sellerID, &amount, &refunded, &status)
+	if err == pgx.ErrNoRows {
+		writeError(w, http.StatusNotFound, "settlement_not_found", "settlement not found")
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "database_error", "database error")
+		return
+	}
+	if callerID != sellerID {
+		writeError(w, http.StatusForbidden, "not_seller", "only the seller can refund this sale")
+		return
+	}
+	if status != "COMPLETED" {
+		writeError(w, http.StatusConflict, "settlement_not_completed", "settlement is not completed yet")
+		return
+	}
+	if h.roundMoney(refunded+req.Amount) > amount {
+		writeError(w, http.StatusUnprocessableEntity, "refund_exceeds_amount", "refund exceeds the remaining settlement amount")
+		return
+	}
+
+	// Lock both wallets in id order, as Transfer does.
+	var sellerBalance float64
+	rows, err := tx.Query(ctx, `
+		SELECT id, wallet_balance FROM users
+		WHERE id IN ($1, $2)
+		ORDER BY id
+		FOR UPDATE`,
+		sellerID, winnerID,
+	)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "database_error", "database error")
+		return
+	}
+	for rows.Next() {
+		var id string
+		var balance float64
+		if err := rows.Scan(&id, &balance); err != nil {
+			rows.Close()
+			writeError(w, http.StatusInternalServerError, "database_error", "database error")
+			return
+		}
+		if id == sellerID {
+			sellerBalance = balance
+		}
+	}
+	rows.Close()
+	if sellerBalance < req.Amount {
+		writeError(w, http.StatusPaymentRequired, "insufficient_balance", "insufficient balance")
+		return
+	}
+
+	_, err = tx.Exec(ctx,
+		`UPDATE users SET wallet_balance = wallet_balance - $1 WHERE id = $2`, req.Amount, sellerID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "database_error", "database error")
+		return
+	}
+	_, err = tx.Exec(ctx,
+		`UPDATE users SET wallet_balance = wallet_balance + $1 WHERE id = $2`, req.Amount, winnerID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "database_error", "database error")
+		return
+	}
+	_, err = tx.Exec(ctx,
+		`UPDATE settlements SET refunded_amount = refunded_amount + $1 WHERE id = $2`, req.Amount, settlementID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "database_error", "database error")
+		return
+	}
+
+	var outID, inID string
+	err = tx.QueryRow(ctx,
+		`INSERT INTO transactions (user_id, amount, type, status, reference) VALUES ($1, $2, 'ADJUSTMENT_OUT', 'COMPLETED', $3) RETURNING id`,
+		sellerID, req.Amount, auctionID,
+	).Scan(&outID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "database_error", "database error")
+		return
+	}
+	err = tx.QueryRow(ctx,
+		`INSERT INTO transactions (user_id, amount, type, status, reference) VALUES ($1, $2, 'ADJUSTMENT_IN', 'COMPLETED', $3) RETURNING id`,
+		winnerID, req.Amount, outID,
+	).Scan(&inID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "database_error", "database error")
+		return
+	}
+	_, err = tx.Exec(ctx, `UPDATE transactions SET reference = $1 WHERE id = $2`, inID, outID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "database_error", "database error")
+		return
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		writeError(w, http.StatusInternalServerError, "database_error", "commit failed")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"success":          true,
+		"refunded":         req.Amount,
+		"total_refunded":   h.roundMoney(refunded + req.Amount),
+		"remaining_amount": h.roundMoney(amount - refunded - req.Amount),
+		"transaction_id":   outID,
+	})
+}
@@ -0,0 +1,130 @@
+package handlers
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/karti/orange-city-mart/backend/db"
+)
+
+const notifySettlementExpired = "settlement_expired"
+
+// Settlement expiry policies, chosen with SETTLEMENT_EXPIRY_POLICY. Refund
+// is the default, so an unapproved sale never pays out unless configured to.
+const (
+	// expiryRefund returns the winner's funds, marks the settlement EXPIRED
+	// and flags it as disputed for an admin to review.
+	expiryRefund = "refund"
+	// expiryComplete settles the sale as if both parties had approved. It
+	// pays the seller without the winner's approval, so it is opt-in.
+	expiryComplete = "complete"
+)
+
+// expireSettlements resolves up to batch overdue PENDING settlements, each in
+// its own transaction, and notifies both parties. A settlement that fails is
+// logged and skipped for the rest of the tick so it can't hold up the others.
+func (h *AuctionHandler) expireSettlements(ctx context.Context, batch int) {
+	expired := 0
+	failed := []string{}
+	for attempt := 0; attempt < batch; attempt++ {
+		id, notices, err := h.expireNextSettlement(ctx, failed)
+		if err != nil {
+			if id == "" {
+				log.Printf("sweeper: failed to claim an expired settlement: %v", err)
+				break
+			}
+			log.Printf("sweeper: failed to expire settlement %s: %v", id, err)
+			failed = append(failed, id)
+			continue
+		}
+		if notices == nil {
+			break
+		}
+		for _, un := range notices {
+			pushNotification(h.Hub, un.UserID, un.Notification)
+		}
+		expired++
+	}
+	if expired > 0 {
+		log.Printf("sweeper: resolved %d expired settlement(s)", expired)
+	}
+}
+
+// expireNextSettlement claims one overdue PENDING settlement, other than
+// those in skip, and applies SETTLEMENT_EXPIRY_POLICY. SKIP LOCKED keeps
+// concurrent sweepers, and an approval racing with the sweeper, from touching
+// the same row. It returns the claimed settlement's id, also on error once a
+// row was claimed, and nil notices when none are due.
+func (h *Handler) expireNextSettlement(ctx context.Context, skip []string) (string, []userNotification, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	tx, err := db.Pool.Begin(ctx)
+	if err != nil {
+		return "", nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	var settlementID, auctionID, winnerID, sellerID string
+	var amount float64
+	err = tx.QueryRow(ctx, `
+		SELECT id, auction_id, winner_id, seller_id, amount::float8
+		FROM settlements
+		WHERE status = 'PENDING' AND expires_at <= NOW()
+		  AND id <> ALL($1::uuid[])
+		ORDER BY expires_at
+		LIMIT 1
+		FOR UPDATE SKIP LOCKED`, skip,
+	).Scan(&settlementID, &auctionID, &winnerID, &sellerID, &amount)
+	if err == pgx.ErrNoRows {
+		return "", nil, nil
+	}
+	if err != nil {
+		return "", nil, err
+	}
+	notices, err := h.applySettlementExpiry(ctx, tx, settlementID, auctionID, winnerID, sellerID, amount)
+	return settlementID, notices, err
+}
+
+// applySettlementExpiry resolves one claimed settlement under
+// SETTLEMENT_EXPIRY_POLICY and commits tx.
+func (h *Handler) applySettlementExpiry(ctx context.Context, tx pgx.Tx, settlementID, auctionID, winnerID, sellerID string, amount float64) ([]userNotification, error) {
+	var err error
+	policy := h.Config.SettlementExpiryPolicy
+	status := "COMPLETED"
+	if policy == expiryRefund {
+		status = "EXPIRED"
+		if err = releaseHardHolds(ctx, tx, auctionID); err != nil {
+			return nil, err
+		}
+		if _, err = tx.Exec(ctx, `
+			UPDATE settlements
+			SET status = 'EXPIRED', disputed_at = NOW(),
+			    dispute_reason = 'settlement expired before both parties approved'
+			WHERE id = $1`, settlementID); err != nil {
+			return nil, err
+		}
+	} else if err = completeSettlement(ctx, tx, settlementID, auctionID, winnerID, sellerID, amount); err != nil {
+		return nil, err
+	}
+
+	payload := map[string]any{"auction_id": auctionID, "amount": amount, "status": status}
+	var notices []userNotification
+	for _, userID := range []string{winnerID, sellerID} {
+		n, err := createNotification(ctx, tx, userID, notifySettlementExpired, payload)
+		if err != nil {
+			return nil, err
+		}
+		notices = append(notices, userNotification{UserID: userID, Notification: n})
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	if status == "COMPLETED" {
+		h.notifySettlementCompletedOffsite(auctionID)
+	}
+	return notices, nil
+}
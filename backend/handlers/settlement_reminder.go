@@ -0,0 +1,136 @@
+package handlers
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/jackc/pgx/v5"
+	"github.com/karti/orange-city-mart/backend/db"
+	authmw "github.com/karti/orange-city-mart/backend/middleware"
+)
+
+// ─────────────────────────────────────────────────────────────────────────────
+// RemindSettlement  POST /api/auctions/{id}/settlement/remind
+//
+// A party who has approved the settlement nudges the one who hasn't: the
+// counterparty gets a persisted settlement_reminder notification, pushed
+// live if they are connected. Limited to one reminder per
+// SETTLEMENT_REMINDER_INTERVAL; a premature retry gets 429 with Retry-After.
+// ─────────────────────────────────────────────────────────────────────────────
+func (h *AuctionHandler) RemindSettlement(w http.ResponseWriter, r *http.Request) {
+	auctionID := chi.URLParam(r, "id")
+	callerID, ok := authmw.UserIDFromContext(r.Context())
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "unauthorized", "unauthorized")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	tx, err := db.Pool.Begin(ctx)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "database_error", "database error")
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	var (
+		settlementID, winnerID, sellerID, status string
+		winnerApprovedAt, sellerApprovedAt       *time.Time
+		remindedAt                               *time.Time
+		amount                                   float64
+	)
+	err = tx.QueryRow(ctx, `
+		SELECT id, winner_id, seller_id, status, winner_approved_at,
+		       seller_approved_at, reminded_at, amount
+		FROM settlements
+		WHERE auction_id = $1
+		FOR UPDATE`, auctionID,
+	).Scan(&settlementID, &winnerID, &sellerID, &status, &winnerApprovedAt,
+		&sellerApprovedAt, &remindedAt, &amount)
+	if err == pgx.ErrNoRows {
+		writeError(w, http.StatusNotFound, "settlement_not_found", errSettlementNotFound.Error())
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "database_error", "database error")
+		return
+	}
+	if status == "COMPLETED" {
+		writeError(w, http.StatusConflict, "settlement_completed", errSettlementCompleted.Error())
+		return
+	}
+	if status == "VOID" {
+		writeError(w, http.StatusConflict, "settlement_void", errSettlementVoid.Error())
+		return
+	}
+	if status == "EXPIRED" {
+		writeError(w, http.StatusConflict, "settlement_expired", errSettlementExpired.Error())
+		return
+	}
+	if status != "PENDING" {
+		writeError(w, http.StatusConflict, "settlement_not_pending", "settlement is "+status)
+		return
+	}
+
+	var recipientID string
+	switch callerID {
+	case winnerID:
+		if winnerApprovedAt == nil {
+			writeError(w, http.StatusConflict, "not_approved", "approve the settlement before sending a reminder")
+			return
+		}
+		recipientID = sellerID
+	case sellerID:
+		if sellerApprovedAt == nil {
+			writeError(w, http.StatusConflict, "not_approved", "approve the settlement before sending a reminder")
+			return
+		}
+		recipientID = winnerID
+	default:
+		writeError(w, http.StatusForbidden, "not_settlement_party", errNotSettlementParty.Error())
+		return
+	}
+
+	if remindedAt != nil {
+		next := remindedAt.Add(h.Config.SettlementReminderInterval)
+		if wait := time.Until(next); wait > 0 {
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+			writeError(w, http.StatusTooManyRequests, "reminder_too_soon",
+				"a reminder was sent recently; try again after "+next.UTC().Format(time.RFC3339))
+			return
+		}
+	}
+
+	if _, err = tx.Exec(ctx,
+		`UPDATE settlements SET reminded_at = NOW() WHERE id = $1`, settlementID); err != nil {
+		writeError(w, http.StatusInternalServerError, "database_error", "database error")
+		return
+	}
+	n, err := createNotification(ctx, tx, recipientID, notifySettlementReminder, map[string]any{
+		"auction_id": auctionID,
+		"from":       callerID,
+		"amount":     amount,
+	})
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "database_error", "database error")
+		return
+	}
+	if err = tx.Commit(ctx); err != nil {
+		writeError(w, http.StatusInternalServerError, "database_error", "commit failed")
+		return
+	}
+
+	pushNotification(h.Hub, recipientID, n)
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"success":         true,
+		"notification_id": n.ID,
+		"next_allowed_at": time.Now().Add(h.Config.SettlementReminderInterval).UTC().Format(time.RFC3339),
+	})
+}
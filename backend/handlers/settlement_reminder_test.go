@@ -0,0 +1,60 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/karti/orange-city-mart/backend/hub"
+)
+
+func TestRemindSettlement(t *testing.T) {
+	requireDB(t)
+	ctx := context.Background()
+	seller := newTestUser(t, testPool, 0)
+	winner := newTestUser(t, testPool, 0)
+	auctionID := newPendingSettlement(t, seller, winner, 100)
+	h := &AuctionHandler{Handler: testHandler, Hub: hub.NewHub(nil)}
+	remind := func(userID string) *httptest.ResponseRecorder {
+		r := withURLParam(asUser(httptest.NewRequest(http.MethodPost, "/", nil), userID), "id", auctionID)
+		w := httptest.NewRecorder()
+		h.RemindSettlement(w, r)
+		return w
+	}
+	reminders := func() int {
+		var n int
+		testPool.QueryRow(ctx, `
+			SELECT COUNT(*) FROM notifications WHERE user_id = $1 AND type = $2`,
+			winner, notifySettlementReminder).Scan(&n)
+		return n
+	}
+
+	if w := remind(seller); w.Code != http.StatusConflict {
+		t.Errorf("reminder before approving: status %d, want 409", w.Code)
+	}
+	if _, err := approveSettlement(ctx, auctionID, seller); err != nil {
+		t.Fatal(err)
+	}
+	if w := remind(seller); w.Code != http.StatusOK {
+		t.Fatalf("reminder: status %d: %s", w.Code, w.Body)
+	}
+	if n := reminders(); n != 1 {
+		t.Errorf("winner has %d reminder notifications, want 1", n)
+	}
+
+	w := remind(seller)
+	if w.Code != http.StatusTooManyRequests || w.Header().Get("Retry-After") == "" {
+		t.Errorf("second reminder: status %d, Retry-After %q; want 429 with a wait", w.Code, w.Header().Get("Retry-After"))
+	}
+	if n := reminders(); n != 1 {
+		t.Errorf("rate-limited reminder still notified: %d notifications", n)
+	}
+
+	if _, err := approveSettlement(ctx, auctionID, winner); err != nil {
+		t.Fatal(err)
+	}
+	if w := remind(winner); w.Code != http.StatusConflict {
+		t.Errorf("reminder on a completed settlement: status %d, want 409", w.Code)
+	}
+}
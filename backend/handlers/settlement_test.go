@@ -0,0 +1,204 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// newPendingSettlement commits an ended auction won by winner for amount,
+// with the winner's HARD hold and a PENDING settlement, and returns the
+// auction id. Both users are deleted, seller first, when the test ends.
+func newPendingSettlement(t *testing.T, seller, winner string, amount float64) string {
+	t.Helper()
+	ctx := context.Background()
+	t.Cleanup(func() {
+		testPool.Exec(ctx, `DELETE FROM users WHERE id = $1`, seller)
+		testPool.Exec(ctx, `DELETE FROM users WHERE id = $1`, winner)
+	})
+	var productID, auctionID string
+	err := testPool.QueryRow(ctx, `
+		INSERT INTO products (seller_id, title, type, price) VALUES ($1, 'Lot', 'AUCTION', 10)
+		RETURNING id`, seller).Scan(&productID)
+	if err != nil {
+		t.Fatalf("insert product: %v", err)
+	}
+	err = testPool.QueryRow(ctx, `
+		INSERT INTO auctions (product_id, start_price, current_highest_bid, highest_bidder_id, end_time, status)
+		VALUES ($1, 10, $2, $3, NOW() - INTERVAL '1 minute', 'ENDED') RETURNING id`,
+		productID, amount, winner).Scan(&auctionID)
+	if err != nil {
+		t.Fatalf("insert auction: %v", err)
+	}
+	if _, err := testPool.Exec(ctx, `
+		INSERT INTO bid_holds (auction_id, user_id, amount, status) VALUES ($1, $2, $3, 'HARD')`,
+		auctionID, winner, amount); err != nil {
+		t.Fatalf("insert hold: %v", err)
+	}
+	if _, err := testPool.Exec(ctx, `
+		INSERT INTO settlements (auction_id, winner_id, seller_id, amount) VALUES ($1, $2, $3, $4)`,
+		auctionID, winner, seller, amount); err != nil {
+		t.Fatalf("insert settlement: %v", err)
+	}
+	return auctionID
+}
+
+// TestConcurrentApprovalsTransferOnce races both parties' approvals, each
+// sent several times, and checks the seller is paid exactly once.
+func TestConcurrentApprovalsTransferOnce(t *testing.T) {
+	requireDB(t)
+	ctx := context.Background()
+	seller := newTestUser(t, testPool, 0)
+	winner := newTestUser(t, testPool, 0)
+	auctionID := newPendingSettlement(t, seller, winner, 250)
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	completed := 0
+	for i := 0; i < 4; i++ {
+		for _, caller := range []string{winner, seller} {
+			wg.Add(1)
+			go func(caller string) {
+				defer wg.Done()
+				res, err := approveSettlement(ctx, auctionID, caller)
+				if err != nil && err != errAlreadyApproved && err != errSettlementCompleted {
+					t.Errorf("approve as %s: %v", caller, err)
+					return
+				}
+				if err == nil && res.Status == "COMPLETED" {
+					mu.Lock()
+					completed++
+					mu.Unlock()
+				}
+			}(caller)
+		}
+	}
+	wg.Wait()
+
+	if completed != 1 {
+		t.Errorf("%d approvals completed the settlement, want 1", completed)
+	}
+	if got := walletBalance(t, testPool, seller); got != 250 {
+		t.Errorf("seller balance = %.2f, want 250", got)
+	}
+	var transfers int
+	testPool.QueryRow(ctx, `
+		SELECT COUNT(*) FROM transactions WHERE type = 'TRANSFER' AND reference = $1`,
+		auctionID).Scan(&transfers)
+	if transfers != 2 {
+		t.Errorf("recorded %d TRANSFER rows, want one per party", transfers)
+	}
+	var status string
+	testPool.QueryRow(ctx, `SELECT status FROM bid_holds WHERE auction_id = $1`, auctionID).Scan(&status)
+	if status != "SETTLED" {
+		t.Errorf("winner's hold is %s, want SETTLED", status)
+	}
+}
+
+// TestBulkApprovalReportsEachSettlement approves a mix of settlements in one
+// request and checks each id gets its own outcome.
+func TestBulkApprovalReportsEachSettlement(t *testing.T) {
+	requireDB(t)
+	ctx := context.Background()
+	seller := newTestUser(t, testPool, 0)
+	otherSeller := newTestUser(t, testPool, 0)
+	winner := newTestUser(t, testPool, 0)
+	pending := newPendingSettlement(t, seller, winner, 100)
+	winnerApproved := newPendingSettlement(t, seller, winner, 40)
+	notMine := newPendingSettlement(t, otherSeller, winner, 60)
+	if _, err := approveSettlement(ctx, winnerApproved, winner); err != nil {
+		t.Fatal(err)
+	}
+	unknown := "00000000-0000-0000-0000-000000000000"
+
+	body := fmt.Sprintf(`{"auction_ids": [%q, %q, %q, %q, %q]}`, pending, winnerApproved, notMine, unknown, pending)
+	r := asUser(httptest.NewRequest(http.MethodPost, "/api/settlements/approve-bulk", strings.NewReader(body)), seller)
+	w := httptest.NewRecorder()
+	(&AuctionHandler{Handler: testHandler}).ApproveSettlementsBulk(w, r)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", w.Code, w.Body)
+	}
+	var resp struct {
+		Results []struct {
+			AuctionID        string `json:"auction_id"`
+			Success          bool   `json:"success"`
+			Code             string `json:"code"`
+			SettlementStatus string `json:"settlement_status"`
+		} `json:"results"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	type outcome struct {
+		success      bool
+		code, status string
+	}
+	want := map[string]outcome{
+		pending:        {true, "", "PENDING"},
+		winnerApproved: {true, "", "COMPLETED"},
+		notMine:        {false, "not_settlement_party", ""},
+		unknown:        {false, "settlement_not_found", ""},
+	}
+	if len(resp.Results) != len(want) {
+		t.Fatalf("got %d results, want one per distinct id: %+v", len(resp.Results), resp.Results)
+	}
+	for _, res := range resp.Results {
+		if got := (outcome{res.Success, res.Code, res.SettlementStatus}); got != want[res.AuctionID] {
+			t.Errorf("%s: %+v, want %+v", res.AuctionID, got, want[res.AuctionID])
+		}
+	}
+	if got := walletBalance(t, testPool, seller); got != 40 {
+		t.Errorf("seller balance = %.2f, want only the completed sale paid", got)
+	}
+}
+
+func TestRefundSettlementPartialAndOverLimit(t *testing.T) {
+	requireDB(t)
+	ctx := context.Background()
+	seller := newTestUser(t, testPool, 0)
+	winner := newTestUser(t, testPool, 0)
+	auctionID := newPendingSettlement(t, seller, winner, 100)
+	for _, caller := range []string{winner, seller} {
+		if _, err := approveSettlement(ctx, auctionID, caller); err != nil {
+			t.Fatal(err)
+		}
+	}
+	refund := func(amount string) int {
+		r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"amount": `+amount+`}`))
+		r = withURLParam(asUser(r, seller), "id", auctionID)
+		w := httptest.NewRecorder()
+		(&AuctionHandler{Handler: testHandler}).RefundSettlement(w, r)
+		return w.Code
+	}
+
+	if code := refund("30"); code != http.StatusOK {
+		t.Fatalf("partial refund: status %d", code)
+	}
+	if got := walletBalance(t, testPool, seller); got != 70 {
+		t.Errorf("seller balance = %.2f, want 70", got)
+	}
+	if got := walletBalance(t, testPool, winner); got != 30 {
+		t.Errorf("winner balance = %.2f, want 30", got)
+	}
+	var pairs int
+	testPool.QueryRow(ctx, `
+		SELECT COUNT(*) FROM transactions o
+		JOIN transactions i ON i.reference = o.id::text AND i.type = 'ADJUSTMENT_IN'
+		WHERE o.type = 'ADJUSTMENT_OUT' AND o.reference = $1`, auctionID).Scan(&pairs)
+	if pairs != 1 {
+		t.Errorf("recorded %d adjustment pairs, want 1", pairs)
+	}
+
+	// 70 remains refundable; more than that is refused and moves nothing.
+	if code := refund("70.01"); code != http.StatusUnprocessableEntity {
+		t.Errorf("refund over the remaining amount: status %d, want 422", code)
+	}
+	if got := walletBalance(t, testPool, seller); got != 70 {
+		t.Errorf("seller balance after refused refund = %.2f, want 70", got)
+	}
+}
@@ -0,0 +1,177 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/jackc/pgx/v5"
+	"github.com/karti/orange-city-mart/backend/db"
+	authmw "github.com/karti/orange-city-mart/backend/middleware"
+)
+
+// ─────────────────────────────────────────────────────────────────────────────
+// GetShipping  GET /api/auctions/{id}/settlement/shipping
+//
+// Delivery details for the two parties to a settlement. The winner always
+// sees their own address; the seller only sees it once the settlement is
+// COMPLETED. Both see the tracking number.
+// ─────────────────────────────────────────────────────────────────────────────
+func (h *AuctionHandler) GetShipping(w http.ResponseWriter, r *http.Request) {
+	auctionID := chi.URLParam(r, "id")
+	callerID, ok := authmw.UserIDFromContext(r.Context())
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "unauthorized", "unauthorized")
+		return
+	}
+
+	var (
+		winnerID, sellerID, status string
+		address, tracking          *string
+	)
+	err := db.Pool.QueryRow(r.Context(), `
+		SELECT winner_id, seller_id, status, shipping_address, tracking_number
+		FROM settlements WHERE auction_id = $1`, auctionID,
+	).Scan(&winnerID, &sellerID, &status, &address, &tracking)
+	if err == pgx.ErrNoRows {
+		writeError(w, http.StatusNotFound, "settlement_not_found", "settlement not found")
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "database_error", "database error")
+		return
+	}
+	if callerID != winnerID && callerID != sellerID {
+		writeError(w, http.StatusForbidden, "not_settlement_party", "you are not a party to this settlement")
+		return
+	}
+
+	submitted := address != nil
+	if callerID == sellerID && status != "COMPLETED" {
+		address = nil
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"auction_id":        auctionID,
+		"settlement_status": status,
+		"address_submitted": submitted,
+		"shipping_address":  address,
+		"tracking_number":   tracking,
+	})
+}
+
+// SetShippingAddress handles POST /api/auctions/{id}/settlement/shipping
+// The winner submits (or corrects) where the item should be delivered.
+// Refused once the seller has recorded a tracking number.
+func (h *AuctionHandler) SetShippingAddress(w http.ResponseWriter, r *http.Request) {
+	auctionID := chi.URLParam(r, "id")
+	callerID, ok := authmw.UserIDFromContext(r.Context())
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "unauthorized", "unauthorized")
+		return
+	}
+
+	var req struct {
+		Address string `json:"address"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || strings.TrimSpace(req.Address) == "" {
+		writeError(w, http.StatusBadRequest, "missing_address", "address is required")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	var winnerID string
+	var tracking *string
+	err := db.Pool.QueryRow(ctx,
+		`SELECT winner_id, tracking_number FROM settlements WHERE auction_id = $1`, auctionID,
+	).Scan(&winnerID, &tracking)
+	if err == pgx.ErrNoRows {
+		writeError(w, http.StatusNotFound, "settlement_not_found", "settlement not found")
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "database_error", "database error")
+		return
+	}
+	if callerID != winnerID {
+		writeError(w, http.StatusForbidden, "not_winner", "only the winner can set the shipping address")
+		return
+	}
+	if tracking != nil {
+		writeError(w, http.StatusConflict, "already_shipped", "item has already shipped")
+		return
+	}
+
+	_, err = db.Pool.Exec(ctx, `
+		UPDATE settlements SET shipping_address = $1
+		WHERE auction_id = $2 AND tracking_number IS NULL`,
+		strings.TrimSpace(req.Address), auctionID,
+	)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "database_error", "database error")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]bool{"success": true})
+}
+
+// SetTrackingNumber handles POST /api/auctions/{id}/settlement/tracking
+// The seller records the shipment's tracking number for the winner. Only
+// possible after the settlement completes, since that's when the seller can
+// see the address.
+func (h *AuctionHandler) SetTrackingNumber(w http.ResponseWriter, r *http.Request) {
+	auctionID := chi.URLParam(r, "id")
+	callerID, ok := authmw.UserIDFromContext(r.Context())
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "unauthorized", "unauthorized")
+		return
+	}
+
+	var req struct {
+		TrackingNumber string `json:"tracking_number"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || strings.TrimSpace(req.TrackingNumber) == "" {
+		writeError(w, http.StatusBadRequest, "missing_tracking_number", "tracking_number is required")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	var sellerID, status string
+	err := db.Pool.QueryRow(ctx,
+		`SELECT seller_id, status FROM settlements WHERE auction_id = $1`, auctionID,
+	).Scan(&sellerID, &status)
+	if err == pgx.ErrNoRows {
+		writeError(w, http.StatusNotFound, "settlement_not_found", "settlement not found")
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "database_error", "database error")
+		return
+	}
+	if callerID != sellerID {
+		writeError(w, http.StatusForbidden, "not_seller", "only the seller can set the tracking number")
+		return
+	}
+	if status != "COMPLETED" {
+		writeError(w, http.StatusConflict, "settlement_not_completed", "settlement is not completed yet")
+		return
+	}
+
+	_, err = db.Pool.Exec(ctx,
+		`UPDATE settlements SET tracking_number = $1 WHERE auction_id = $2`,
+		strings.TrimSpace(req.TrackingNumber), auctionID,
+	)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "database_error", "database error")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]bool{"success": true})
+}
@@ -0,0 +1,102 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// shippingCall runs one of the shipping handlers as userID and returns the
+// recorder.
+func shippingCall(handler http.HandlerFunc, userID, auctionID, body string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+	r = withURLParam(asUser(r, userID), "id", auctionID)
+	w := httptest.NewRecorder()
+	handler(w, r)
+	return w
+}
+
+type shippingView struct {
+	AddressSubmitted bool    `json:"address_submitted"`
+	ShippingAddress  *string `json:"shipping_address"`
+	TrackingNumber   *string `json:"tracking_number"`
+}
+
+func getShipping(t *testing.T, h *AuctionHandler, userID, auctionID string) shippingView {
+	t.Helper()
+	w := shippingCall(h.GetShipping, userID, auctionID, "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("GetShipping: status %d: %s", w.Code, w.Body)
+	}
+	var v shippingView
+	if err := json.Unmarshal(w.Body.Bytes(), &v); err != nil {
+		t.Fatal(err)
+	}
+	return v
+}
+
+func TestShippingAddressHiddenUntilCompletion(t *testing.T) {
+	requireDB(t)
+	ctx := context.Background()
+	seller := newTestUser(t, testPool, 0)
+	winner := newTestUser(t, testPool, 0)
+	stranger := newTestUser(t, testPool, 0)
+	t.Cleanup(func() { testPool.Exec(ctx, `DELETE FROM users WHERE id = $1`, stranger) })
+	auctionID := newPendingSettlement(t, seller, winner, 100)
+	h := &AuctionHandler{Handler: testHandler}
+
+	if w := shippingCall(h.SetShippingAddress, winner, auctionID, `{"address": " 1 Mall Rd, Pune "}`); w.Code != http.StatusOK {
+		t.Fatalf("SetShippingAddress: status %d: %s", w.Code, w.Body)
+	}
+	if v := getShipping(t, h, winner, auctionID); v.ShippingAddress == nil || *v.ShippingAddress != "1 Mall Rd, Pune" {
+		t.Errorf("winner sees %+v, want their trimmed address", v)
+	}
+	if v := getShipping(t, h, seller, auctionID); !v.AddressSubmitted || v.ShippingAddress != nil {
+		t.Errorf("seller before completion sees %+v, want only that an address exists", v)
+	}
+	if w := shippingCall(h.GetShipping, stranger, auctionID, ""); w.Code != http.StatusForbidden {
+		t.Errorf("stranger: status %d, want 403", w.Code)
+	}
+	if w := shippingCall(h.SetTrackingNumber, seller, auctionID, `{"tracking_number": "AWB1"}`); w.Code != http.StatusConflict {
+		t.Errorf("tracking before completion: status %d, want 409", w.Code)
+	}
+
+	for _, caller := range []string{winner, seller} {
+		if _, err := approveSettlement(ctx, auctionID, caller); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if v := getShipping(t, h, seller, auctionID); v.ShippingAddress == nil || *v.ShippingAddress != "1 Mall Rd, Pune" {
+		t.Errorf("seller after completion sees %+v, want the address", v)
+	}
+}
+
+func TestTrackingNumberReachesWinner(t *testing.T) {
+	requireDB(t)
+	ctx := context.Background()
+	seller := newTestUser(t, testPool, 0)
+	winner := newTestUser(t, testPool, 0)
+	auctionID := newPendingSettlement(t, seller, winner, 100)
+	for _, caller := range []string{winner, seller} {
+		if _, err := approveSettlement(ctx, auctionID, caller); err != nil {
+			t.Fatal(err)
+		}
+	}
+	h := &AuctionHandler{Handler: testHandler}
+
+	if w := shippingCall(h.SetTrackingNumber, winner, auctionID, `{"tracking_number": "AWB1"}`); w.Code != http.StatusForbidden {
+		t.Errorf("winner setting tracking: status %d, want 403", w.Code)
+	}
+	if w := shippingCall(h.SetTrackingNumber, seller, auctionID, `{"tracking_number": "AWB123"}`); w.Code != http.StatusOK {
+		t.Fatalf("SetTrackingNumber: status %d: %s", w.Code, w.Body)
+	}
+	if v := getShipping(t, h, winner, auctionID); v.TrackingNumber == nil || *v.TrackingNumber != "AWB123" {
+		t.Errorf("winner sees tracking %v, want AWB123", v.TrackingNumber)
+	}
+	if w := shippingCall(h.SetShippingAddress, winner, auctionID, `{"address": "elsewhere"}`); w.Code != http.StatusConflict {
+		t.Errorf("address change after shipping: status %d, want 409", w.Code)
+	}
+}
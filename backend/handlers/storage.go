@@ -0,0 +1,232 @@
+package handlers
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/karti/orange-city-mart/backend/config"
+)
+
+// Storage holds uploaded files. Objects are addressed by a flat name such as
+// "<uuid>.jpg"; Put returns the URL clients should use to fetch one. Swap the
+// implementation with SetStorage; the default writes to ./uploads.
+type Storage interface {
+	Put(ctx context.Context, name string, r io.Reader, size int64, contentType string) (string, error)
+	Delete(ctx context.Context, name string) error
+	Exists(ctx context.Context, name string) (bool, error)
+	// Name maps a URL returned by Put back to the object name. ok is false
+	// for URLs this backend did not produce.
+	Name(url string) (name string, ok bool)
+}
+
+var storage Storage = LocalStorage{Dir: uploadsDir}
+
+// SetStorage replaces the Storage used for uploads.
+func SetStorage(s Storage) { storage = s }
+
+// validObjectName rejects anything that isn't a single plain path element,
+// so names taken from client URLs can't traverse out of the store.
+func validObjectName(name string) bool {
+	return name != "" && name == filepath.Base(name) && !strings.HasPrefix(name, ".") &&
+		!strings.ContainsAny(name, `/\`)
+}
+
+// ── Local disk ────────────────────────────────────────────────────────────────
+
+// LocalStorage keeps files in Dir, served by main.go under /uploads/.
+type LocalStorage struct {
+	Dir string
+}
+
+func (s LocalStorage) Put(_ context.Context, name string, r io.Reader, _ int64, _ string) (string, error) {
+	if err := os.MkdirAll(s.Dir, 0755); err != nil {
+		return "", fmt.Errorf("server storage error")
+	}
+	destPath := filepath.Join(s.Dir, name)
+	dest, err := os.Create(destPath)
+	if err != nil {
+		return "", fmt.Errorf("could not save file")
+	}
+	defer dest.Close()
+	if _, err = io.Copy(dest, r); err != nil {
+		os.Remove(destPath)
+		return "", fmt.Errorf("could not write file")
+	}
+	return "/uploads/" + name, nil
+}
+
+func (s LocalStorage) Delete(_ context.Context, name string) error {
+	return os.Remove(filepath.Join(s.Dir, name))
+}
+
+func (s LocalStorage) Exists(_ context.Context, name string) (bool, error) {
+	info, err := os.Stat(filepath.Join(s.Dir, name))
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return info.Mode().IsRegular(), nil
+}
+
+func (s LocalStorage) Name(u string) (string, bool) {
+	name, ok := strings.CutPrefix(u, "/uploads/")
+	return name, ok && validObjectName(name)
+}
+
+// ── S3-compatible ─────────────────────────────────────────────────────────────
+
+// S3Storage stores files in an S3-compatible bucket (AWS, R2, MinIO, ...)
+// using path-style requests signed with SigV4. The bucket must be publicly
+// readable at PublicURL; the URLs handed out are not signed.
+type S3Storage struct {
+	Endpoint  string // e.g. https://s3.ap-south-1.amazonaws.com
+	Region    string
+	Bucket    string
+	AccessKey string
+	SecretKey string
+	PublicURL string // base URL objects are served from, without trailing slash
+	Client    *http.Client
+}
+
+// NewS3Storage builds an S3Storage from the S3_* settings, which config.Load
+// has already defaulted and validated. It returns nil when S3_BUCKET is unset,
+// leaving uploads on local disk.
+func NewS3Storage(cfg *config.Config) *S3Storage {
+	if cfg.S3Bucket == "" {
+		return nil
+	}
+	return &S3Storage{
+		Endpoint:  cfg.S3Endpoint,
+		Region:    cfg.S3Region,
+		Bucket:    cfg.S3Bucket,
+		AccessKey: cfg.S3AccessKeyID,
+		SecretKey: cfg.S3SecretAccessKey,
+		PublicURL: cfg.S3PublicURL,
+		Client:    &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+func (s *S3Storage) Put(ctx context.Context, name string, r io.Reader, size int64, contentType string) (string, error) {
+	req, err := s.newRequest(ctx, http.MethodPut, name, r)
+	if err != nil {
+		return "", err
+	}
+	req.ContentLength = size
+	req.Header.Set("Content-Type", contentType)
+	resp, err := s.do(req)
+	if err != nil {
+		return "", fmt.Errorf("could not save file")
+	}
+	resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return "", fmt.Errorf("could not save file")
+	}
+	return s.PublicURL + "/" + name, nil
+}
+
+func (s *S3Storage) Delete(ctx context.Context, name string) error {
+	req, err := s.newRequest(ctx, http.MethodDelete, name, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := s.do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("s3 delete %s: %s", name, resp.Status)
+	}
+	return nil
+}
+
+func (s *S3Storage) Exists(ctx context.Context, name string) (bool, error) {
+	req, err := s.newRequest(ctx, http.MethodHead, name, nil)
+	if err != nil {
+		return false, err
+	}
+	resp, err := s.do(req)
+	if err != nil {
+		return false, err
+	}
+	resp.Body.Close()
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return false, nil
+	case resp.StatusCode/100 == 2:
+		return true, nil
+	}
+	return false, fmt.Errorf("s3 head %s: %s", name, resp.Status)
+}
+
+func (s *S3Storage) Name(u string) (string, bool) {
+	name, ok := strings.CutPrefix(u, s.PublicURL+"/")
+	return name, ok && validObjectName(name)
+}
+
+func (s *S3Storage) newRequest(ctx context.Context, method, name string, body io.Reader) (*http.Request, error) {
+	return http.NewRequestWithContext(ctx, method,
+		s.Endpoint+"/"+s3Escape(s.Bucket)+"/"+s3Escape(name), body)
+}
+
+// do signs req with AWS Signature Version 4 and sends it. The payload is
+// left unsigned so uploads can stream without being buffered for hashing.
+func (s *S3Storage) do(req *http.Request) (*http.Response, error) {
+	now := time.Now().UTC()
+	amzDate := now.Format("20060102T150405Z")
+	day := now.Format("20060102")
+	const payloadHash = "UNSIGNED-PAYLOAD"
+
+	req.Header.Set("x-amz-date", amzDate)
+	req.Header.Set("x-amz-content-sha256", payloadHash)
+
+	const signedHeaders = "host;x-amz-content-sha256;x-amz-date"
+	canonical := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		"",
+		"host:" + req.URL.Host,
+		"x-amz-content-sha256:" + payloadHash,
+		"x-amz-date:" + amzDate,
+		"",
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+	scope := day + "/" + s.Region + "/s3/aws4_request"
+	sum := sha256.Sum256([]byte(canonical))
+	toSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(sum[:])
+
+	key := hmacSHA256([]byte("AWS4"+s.SecretKey), day)
+	key = hmacSHA256(key, s.Region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	sig := hex.EncodeToString(hmacSHA256(key, toSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf(
+		"AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s.AccessKey, scope, signedHeaders, sig))
+	return s.Client.Do(req)
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// s3Escape percent-encodes a single path segment the way SigV4 expects.
+func s3Escape(seg string) string {
+	return strings.ReplaceAll(url.PathEscape(seg), "+", "%2B")
+}
@@ -0,0 +1,24 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+// newOpaqueToken returns a random URL-safe token for the client and the
+// SHA-256 hash that is persisted in its place. Raw tokens are never stored.
+func newOpaqueToken() (raw, hash string, err error) {
+	b := make([]byte, 32)
+	if _, err = rand.Read(b); err != nil {
+		return "", "", err
+	}
+	raw = hex.EncodeToString(b)
+	return raw, hashToken(raw), nil
+}
+
+// hashToken returns the hex SHA-256 digest used to look up a stored token.
+func hashToken(raw string) string {
+	sum := sha256.Sum256([]byte(raw))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,530 @@
+package handlers
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/karti/orange-city-mart/backend/db"
+	authmw "github.com/karti/orange-city-mart/backend/middleware"
+)
+
+const (
+	totpIssuer        = "Orange City Mart"
+	totpPeriod        = 30 // seconds per code
+	totpSkew          = 1  // codes accepted either side of the current one
+	recoveryCodeCount = 10
+	challengeTTL      = 5 * time.Minute
+	challengeAttempts = 5 // wrong codes before a challenge is burned
+)
+
+// ── TOTP (RFC 6238) ───────────────────────────────────────────────────────────
+
+// totpCode returns the 6-digit code for a secret at the given time step.
+func totpCode(secret []byte, step int64) string {
+	var msg [8]byte
+	binary.BigEndian.PutUint64(msg[:], uint64(step))
+	mac := hmac.New(sha1.New, secret)
+	mac.Write(msg[:])
+	sum := mac.Sum(nil)
+	off := sum[len(sum)-1] & 0x0f
+	v := binary.BigEndian.Uint32(sum[off:off+4]) & 0x7fffffff
+	return fmt.Sprintf("%06d", v%1000000)
+}
+
+// matchTOTP checks code against the steps around now and returns the step it
+// matched. Steps at or before lastStep are refused so a code can't be replayed.
+func matchTOTP(secret []byte, code string, lastStep int64) (int64, bool) {
+	now := time.Now().Unix() / totpPeriod
+	for step := now - totpSkew; step <= now+totpSkew; step++ {
+		if step <= lastStep {
+			continue
+		}
+		if subtle.ConstantTimeCompare([]byte(totpCode(secret, step)), []byte(code)) == 1 {
+			return step, true
+		}
+	}
+	return 0, false
+}
+
+// ── Secret encryption ─────────────────────────────────────────────────────────
+
+// errTwoFactorUnavailable means no TOTP_ENCRYPTION_KEY is configured.
+var errTwoFactorUnavailable = errors.New("two-factor authentication is not configured")
+
+// totpKey is the AES-256 key for stored TOTP secrets, derived from
+// TOTP_ENCRYPTION_KEY. There is deliberately no fallback to JWT_SECRET: a key
+// shared with token signing would make one leak expose both.
+func (h *Handler) totpKey() ([]byte, error) {
+	if len(h.Config.TOTPEncryptionKey) == 0 {
+		return nil, errTwoFactorUnavailable
+	}
+	sum := sha256.Sum256(h.Config.TOTPEncryptionKey)
+	return sum[:], nil
+}
+
+// sealSecret encrypts a TOTP secret with AES-GCM as base64(nonce||ciphertext).
+func (h *Handler) sealSecret(secret []byte) (string, error) {
+	key, err := h.totpKey()
+	if err != nil {
+		return "", err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return "", err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(gcm.Seal(nonce, nonce, secret, nil)), nil
+}
+
+// openSecret reverses sealSecret.
+func (h *Handler) openSecret(sealed string) ([]byte, error) {
+	key, err := h.totpKey()
+	if err != nil {
+		return nil, err
+	}
+	data, err := base64.StdEncoding.DecodeString(sealed)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < gcm.NonceSize() {
+		return nil, errors.New("sealed secret too short")
+	}
+	return gcm.Open(nil, data[:gcm.NonceSize()], data[gcm.NonceSize():], nil)
+}
+
+// normalizeRecoveryCode lowercases a recovery code and drops the separators
+// users tend to type, so "ABCD-1234-EF" and "abcd1234ef" match.
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(code)
+	return strings.NewReplacer("-", "", " ", "").Replace(code)
+}
+
+// writeSecretError reports a failure to seal or open a TOTP secret.
+func writeSecretError(w http.ResponseWriter, err error) {
+	if errors.Is(err, errTwoFactorUnavailable) {
+		writeError(w, http.StatusServiceUnavailable, "2fa_unavailable", err.Error())
+		return
+	}
+	writeError(w, http.StatusInternalServerError, "internal_error", "internal error")
+}
+
+// checkSecondFactor reports whether code is a current TOTP code for the user
+// or one of their unused recovery codes, and consumes whichever matched.
+// sealed and lastStep are the user's totp_secret and totp_last_step, read
+// under a row lock in tx.
+func (h *Handler) checkSecondFactor(ctx context.Context, tx pgx.Tx, userID string, sealed *string, lastStep int64, code string) (bool, error) {
+	code = strings.TrimSpace(code)
+	if sealed != nil {
+		secret, err := h.openSecret(*sealed)
+		if err != nil {
+			return false, err
+		}
+		if step, ok := matchTOTP(secret, code, lastStep); ok {
+			_, err := tx.Exec(ctx,
+				`UPDATE users SET totp_last_step = $2 WHERE id = $1`, userID, step)
+			return err == nil, err
+		}
+	}
+	tag, err := tx.Exec(ctx, `
+		UPDATE recovery_codes SET used_at = NOW()
+		WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL`,
+		userID, hashToken(normalizeRecoveryCode(code)),
+	)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() == 1, nil
+}
+
+// ─────────────────────────────────────────────────────────────────────────────
+// EnableTwoFactor  POST /api/me/2fa/enable
+//
+// Generates a new TOTP secret for the caller and returns it with an otpauth://
+// URI for authenticator apps (clients render the URI as a QR code). 2FA is not
+// active until the first code is confirmed via VerifyTwoFactor; calling this
+// again before then replaces the pending secret.
+// ─────────────────────────────────────────────────────────────────────────────
+func (h *Handler) EnableTwoFactor(w http.ResponseWriter, r *http.Request) {
+	userID, ok := authmw.UserIDFromContext(r.Context())
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "unauthorized", "unauthorized")
+		return
+	}
+
+	secret := make([]byte, 20)
+	if _, err := rand.Read(secret); err != nil {
+		writeError(w, http.StatusInternalServerError, "internal_error", "internal error")
+		return
+	}
+	sealed, err := h.sealSecret(secret)
+	if err != nil {
+		writeSecretError(w, err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	var email string
+	err = db.Pool.QueryRow(ctx, `
+		UPDATE users SET totp_secret = $2, totp_last_step = 0
+		WHERE id = $1 AND NOT totp_enabled
+		RETURNING email`,
+		userID, sealed,
+	).Scan(&email)
+	if err == pgx.ErrNoRows {
+		writeError(w, http.StatusConflict, "2fa_already_enabled", "two-factor authentication is already enabled")
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "database_error", "database error")
+		return
+	}
+
+	encoded := base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(secret)
+	q := url.Values{}
+	q.Set("secret", encoded)
+	q.Set("issuer", totpIssuer)
+	q.Set("period", fmt.Sprint(totpPeriod))
+	uri := "otpauth://totp/" + url.PathEscape(totpIssuer+":"+email) + "?" + q.Encode()
+
+	writeJSON(w, http.StatusOK, map[string]string{
+		"secret":      encoded,
+		"otpauth_uri": uri,
+	})
+}
+
+// ─────────────────────────────────────────────────────────────────────────────
+// VerifyTwoFactor  POST /api/me/2fa/verify
+//
+// Body: { "code": "123456" }
+// Confirms the pending secret with a current code, turns 2FA on and returns a
+// fresh set of single-use recovery codes. They are only shown this once.
+// ─────────────────────────────────────────────────────────────────────────────
+func (h *Handler) VerifyTwoFactor(w http.ResponseWriter, r *http.Request) {
+	userID, ok := authmw.UserIDFromContext(r.Context())
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "unauthorized", "unauthorized")
+		return
+	}
+	var req struct {
+		Code string `json:"code"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Code == "" {
+		writeError(w, http.StatusBadRequest, "missing_code", "code is required")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	tx, err := db.Pool.Begin(ctx)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "database_error", "database error")
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	var sealed *string
+	var enabled bool
+	var lastStep int64
+	err = tx.QueryRow(ctx, `
+		SELECT totp_secret, totp_enabled, totp_last_step
+		FROM users WHERE id = $1 FOR UPDATE`, userID,
+	).Scan(&sealed, &enabled, &lastStep)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "database_error", "database error")
+		return
+	}
+	if enabled {
+		writeError(w, http.StatusConflict, "2fa_already_enabled", "two-factor authentication is already enabled")
+		return
+	}
+	if sealed == nil {
+		writeError(w, http.StatusBadRequest, "2fa_not_started", "call /api/me/2fa/enable first")
+		return
+	}
+	secret, err := h.openSecret(*sealed)
+	if err != nil {
+		writeSecretError(w, err)
+		return
+	}
+	step, ok := matchTOTP(secret, strings.TrimSpace(req.Code), lastStep)
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "invalid_2fa_code", "invalid code")
+		return
+	}
+
+	if _, err := tx.Exec(ctx, `DELETE FROM recovery_codes WHERE user_id = $1`, userID); err != nil {
+		writeError(w, http.StatusInternalServerError, "database_error", "database error")
+		return
+	}
+	codes := make([]string, 0, recoveryCodeCount)
+	for i := 0; i < recoveryCodeCount; i++ {
+		b := make([]byte, 5)
+		if _, err := rand.Read(b); err != nil {
+			writeError(w, http.StatusInternalServerError, "internal_error", "internal error")
+			return
+		}
+		raw := hex.EncodeToString(b)
+		if _, err := tx.Exec(ctx, `
+			INSERT INTO recovery_codes (user_id, code_hash) VALUES ($1, $2)`,
+			userID, hashToken(raw),
+		); err != nil {
+			writeError(w, http.StatusInternalServerError, "database_error", "database error")
+			return
+		}
+		codes = append(codes, raw[:5]+"-"+raw[5:])
+	}
+
+	if _, err := tx.Exec(ctx, `
+		UPDATE users SET totp_enabled = TRUE, totp_last_step = $2 WHERE id = $1`,
+		userID, step,
+	); err != nil {
+		writeError(w, http.StatusInternalServerError, "database_error", "database error")
+		return
+	}
+	if err := tx.Commit(ctx); err != nil {
+		writeError(w, http.StatusInternalServerError, "database_error", "commit failed")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"enabled":        true,
+		"recovery_codes": codes,
+	})
+}
+
+// ─────────────────────────────────────────────────────────────────────────────
+// DisableTwoFactor  POST /api/me/2fa/disable
+//
+// Body: { "code": "123456" }
+// Turns 2FA off. code is a current TOTP code or an unused recovery code, so a
+// stolen session alone can't strip the second factor. The secret and all
+// recovery codes are discarded; enabling again starts from scratch.
+// ─────────────────────────────────────────────────────────────────────────────
+func (h *Handler) DisableTwoFactor(w http.ResponseWriter, r *http.Request) {
+	userID, ok := authmw.UserIDFromContext(r.Context())
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "unauthorized", "unauthorized")
+		return
+	}
+	var req struct {
+		Code string `json:"code"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Code == "" {
+		writeError(w, http.StatusBadRequest, "missing_code", "code is required")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	tx, err := db.Pool.Begin(ctx)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "database_error", "database error")
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	var sealed *string
+	var enabled bool
+	var lastStep int64
+	err = tx.QueryRow(ctx, `
+		SELECT totp_secret, totp_enabled, totp_last_step
+		FROM users WHERE id = $1 FOR UPDATE`, userID,
+	).Scan(&sealed, &enabled, &lastStep)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "database_error", "database error")
+		return
+	}
+	if !enabled {
+		writeError(w, http.StatusConflict, "2fa_not_enabled", "two-factor authentication is not enabled")
+		return
+	}
+	verified, err := h.checkSecondFactor(ctx, tx, userID, sealed, lastStep, req.Code)
+	if err != nil {
+		writeSecretError(w, err)
+		return
+	}
+	if !verified {
+		writeError(w, http.StatusUnauthorized, "invalid_2fa_code", "invalid code")
+		return
+	}
+
+	if _, err := tx.Exec(ctx, `DELETE FROM recovery_codes WHERE user_id = $1`, userID); err != nil {
+		writeError(w, http.StatusInternalServerError, "database_error", "database error")
+		return
+	}
+	if _, err := tx.Exec(ctx, `
+		UPDATE users SET totp_enabled = FALSE, totp_secret = NULL, totp_last_step = 0
+		WHERE id = $1`, userID,
+	); err != nil {
+		writeError(w, http.StatusInternalServerError, "database_error", "database error")
+		return
+	}
+	if err := tx.Commit(ctx); err != nil {
+		writeError(w, http.StatusInternalServerError, "database_error", "commit failed")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{"enabled": false})
+}
+
+// signIn finishes a successful first-factor login. Users without 2FA get a
+// session straight away; users with it get a short-lived challenge token to
+// redeem at /api/auth/2fa along with a code.
+func (h *Handler) signIn(ctx context.Context, w http.ResponseWriter, u userInfo) {
+	var enabled bool
+	if err := db.Pool.QueryRow(ctx,
+		`SELECT totp_enabled FROM users WHERE id = $1`, u.ID,
+	).Scan(&enabled); err != nil {
+		writeError(w, http.StatusInternalServerError, "database_error", "database error")
+		return
+	}
+
+	if enabled {
+		raw, hash, err := newOpaqueToken()
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "internal_error", "internal error")
+			return
+		}
+		if _, err := db.Pool.Exec(ctx, `
+			INSERT INTO two_factor_challenges (user_id, token_hash, expires_at)
+			VALUES ($1, $2, $3)`,
+			u.ID, hash, time.Now().Add(challengeTTL),
+		); err != nil {
+			writeError(w, http.StatusInternalServerError, "database_error", "database error")
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]any{
+			"two_factor_required": true,
+			"challenge_token":     raw,
+		})
+		return
+	}
+
+	resp, err := h.newSession(ctx, u)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "internal_error", "could not generate token")
+		return
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// ─────────────────────────────────────────────────────────────────────────────
+// LoginTwoFactor  POST /api/auth/2fa
+//
+// Body: { "challenge_token": "...", "code": "123456" }
+// Completes a login that returned two_factor_required. code is either a
+// current TOTP code or one of the user's unused recovery codes. A challenge is
+// single-use and is burned after too many wrong codes.
+// ─────────────────────────────────────────────────────────────────────────────
+func (h *Handler) LoginTwoFactor(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		ChallengeToken string `json:"challenge_token"`
+		Code           string `json:"code"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil ||
+		req.ChallengeToken == "" || req.Code == "" {
+		writeError(w, http.StatusBadRequest, "missing_fields", "challenge_token and code are required")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	tx, err := db.Pool.Begin(ctx)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "database_error", "database error")
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	var challengeID string
+	var u userInfo
+	var sealed *string
+	var lastStep int64
+	err = tx.QueryRow(ctx, `
+		SELECT c.id, u.id, u.name, u.email, u.wallet_balance, u.role, u.totp_secret, u.totp_last_step
+		FROM two_factor_challenges c
+		JOIN users u ON u.id = c.user_id
+		WHERE c.token_hash = $1 AND c.used_at IS NULL
+		  AND c.expires_at > NOW() AND c.attempts < $2
+		FOR UPDATE`,
+		hashToken(req.ChallengeToken), challengeAttempts,
+	).Scan(&challengeID, &u.ID, &u.Name, &u.Email, &u.WalletBalance, &u.Role, &sealed, &lastStep)
+	if err == pgx.ErrNoRows {
+		writeError(w, http.StatusUnauthorized, "invalid_2fa_challenge", "invalid or expired challenge")
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "database_error", "database error")
+		return
+	}
+
+	verified, err := h.checkSecondFactor(ctx, tx, u.ID, sealed, lastStep, req.Code)
+	if err != nil {
+		writeSecretError(w, err)
+		return
+	}
+	if !verified {
+		// Count the failure outside the rolled-back transaction.
+		tx.Rollback(ctx)
+		_, _ = db.Pool.Exec(ctx,
+			`UPDATE two_factor_challenges SET attempts = attempts + 1 WHERE id = $1`, challengeID)
+		writeError(w, http.StatusUnauthorized, "invalid_2fa_code", "invalid code")
+		return
+	}
+
+	if _, err := tx.Exec(ctx,
+		`UPDATE two_factor_challenges SET used_at = NOW() WHERE id = $1`, challengeID,
+	); err != nil {
+		writeError(w, http.StatusInternalServerError, "database_error", "database error")
+		return
+	}
+	if err := tx.Commit(ctx); err != nil {
+		writeError(w, http.StatusInternalServerError, "database_error", "commit failed")
+		return
+	}
+
+	resp, err := h.newSession(ctx, u)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "internal_error", "could not generate token")
+		return
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
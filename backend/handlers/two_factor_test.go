@@ -0,0 +1,151 @@
+package handlers
+
+import (
+	"context"
+	"encoding/base32"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/karti/orange-city-mart/backend/config"
+)
+
+var testTOTPKey = []byte("test-totp-key-0123456789abcdef01234567")
+
+func TestEnableTwoFactorRequiresKey(t *testing.T) {
+	r := asUser(httptest.NewRequest(http.MethodPost, "/", nil), "u1")
+	w := httptest.NewRecorder()
+	testHandler.EnableTwoFactor(w, r)
+	if w.Code != http.StatusServiceUnavailable || !strings.Contains(w.Body.String(), "2fa_unavailable") {
+		t.Fatalf("EnableTwoFactor without a key = %d: %s", w.Code, w.Body)
+	}
+}
+
+// withTOTPKey returns a Handler that can seal TOTP secrets under key.
+func withTOTPKey(key []byte) *Handler {
+	return newTestHandler(func(c *config.Config) { c.TOTPEncryptionKey = key })
+}
+
+func TestSealedSecretNeedsSameKey(t *testing.T) {
+	h := withTOTPKey(testTOTPKey)
+	sealed, err := h.sealSecret([]byte("secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := h.openSecret(sealed); err != nil || string(got) != "secret" {
+		t.Fatalf("openSecret = %q, %v", got, err)
+	}
+	other := withTOTPKey([]byte("another-totp-key-0123456789abcdef0123"))
+	if _, err := other.openSecret(sealed); err == nil {
+		t.Error("secret opened under a different key")
+	}
+}
+
+// TestTwoFactorEnableVerifyDisable walks a user through turning 2FA on and
+// off again, disabling with a recovery code.
+func TestTwoFactorEnableVerifyDisable(t *testing.T) {
+	requireDB(t)
+	h := withTOTPKey(testTOTPKey)
+	ctx := context.Background()
+	userID := newTestUser(t, testPool, 0)
+	t.Cleanup(func() { testPool.Exec(ctx, `DELETE FROM users WHERE id = $1`, userID) })
+
+	call := func(h http.HandlerFunc, body string) *httptest.ResponseRecorder {
+		r := asUser(httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body)), userID)
+		w := httptest.NewRecorder()
+		h(w, r)
+		return w
+	}
+
+	w := call(h.EnableTwoFactor, "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("EnableTwoFactor = %d: %s", w.Code, w.Body)
+	}
+	var enabled struct {
+		Secret string `json:"secret"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &enabled)
+	secret, err := base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(enabled.Secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w = call(h.VerifyTwoFactor, `{"code":"`+totpCode(secret, time.Now().Unix()/totpPeriod)+`"}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("VerifyTwoFactor = %d: %s", w.Code, w.Body)
+	}
+	var verified struct {
+		RecoveryCodes []string `json:"recovery_codes"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &verified)
+	if len(verified.RecoveryCodes) != recoveryCodeCount {
+		t.Fatalf("got %d recovery codes", len(verified.RecoveryCodes))
+	}
+
+	if w := call(h.DisableTwoFactor, `{"code":"000000-wrong"}`); w.Code != http.StatusUnauthorized {
+		t.Fatalf("DisableTwoFactor with a wrong code = %d: %s", w.Code, w.Body)
+	}
+	if w := call(h.DisableTwoFactor, `{"code":"`+verified.RecoveryCodes[0]+`"}`); w.Code != http.StatusOK {
+		t.Fatalf("DisableTwoFactor = %d: %s", w.Code, w.Body)
+	}
+
+	var on, hasSecret bool
+	var codes int
+	if err := testPool.QueryRow(ctx, `
+		SELECT totp_enabled, totp_secret IS NOT NULL,
+		       (SELECT COUNT(*) FROM recovery_codes WHERE user_id = $1)
+		FROM users WHERE id = $1`, userID,
+	).Scan(&on, &hasSecret, &codes); err != nil {
+		t.Fatal(err)
+	}
+	if on || hasSecret || codes != 0 {
+		t.Errorf("after disable: enabled=%t secret=%t recovery codes=%d", on, hasSecret, codes)
+	}
+}
+
+func TestTOTPCodeMatchesRFC6238(t *testing.T) {
+	// RFC 6238 appendix B SHA-1 vectors, truncated to six digits.
+	secret := []byte("12345678901234567890")
+	for unix, want := range map[int64]string{
+		59:         "287082",
+		1111111109: "081804",
+		1234567890: "005924",
+		2000000000: "279037",
+	} {
+		if got := totpCode(secret, unix/totpPeriod); got != want {
+			t.Errorf("totpCode at %d = %s, want %s", unix, got, want)
+		}
+	}
+}
+
+func TestMatchTOTP(t *testing.T) {
+	secret := []byte("12345678901234567890")
+	now := time.Now().Unix() / totpPeriod
+
+	for _, step := range []int64{now - totpSkew, now, now + totpSkew} {
+		got, ok := matchTOTP(secret, totpCode(secret, step), 0)
+		if !ok || got != step {
+			t.Errorf("code for step %+d = (%d, %t), want a match", step-now, got, ok)
+		}
+	}
+	if _, ok := matchTOTP(secret, totpCode(secret, now-totpSkew-1), 0); ok {
+		t.Error("code outside the skew window accepted")
+	}
+	if _, ok := matchTOTP(secret, totpCode(secret, now), now); ok {
+		t.Error("code replayed at an already used step accepted")
+	}
+	if _, ok := matchTOTP(secret, "abcdef", 0); ok {
+		t.Error("non-numeric code accepted")
+	}
+}
+
+func TestNormalizeRecoveryCode(t *testing.T) {
+	for _, in := range []string{"ABCDE-12345", "abcde12345", " abcde 12345", "AbCdE-1234-5"} {
+		if got := normalizeRecoveryCode(in); got != "abcde12345" {
+			t.Errorf("normalizeRecoveryCode(%q) = %q", in, got)
+		}
+	}
+}
@@ -1,85 +1,291 @@
 package handlers
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
+	"mime/multipart"
 	"net/http"
-	"os"
-	"path/filepath"
-	"strings"
+	"time"
 
 	"github.com/google/uuid"
+	"github.com/karti/orange-city-mart/backend/db"
+	authmw "github.com/karti/orange-city-mart/backend/middleware"
 )
 
 const (
-	maxUploadSize = 5 << 20 // 5 MB
+	maxBatchFiles = 10
 	uploadsDir    = "./uploads"
 )
 
+// megabytes formats a byte limit for error messages.
+func megabytes(n int64) string {
+	return fmt.Sprintf("%d MB", n>>20)
+}
+
 // UploadImage handles POST /api/upload
 // Accepts multipart/form-data with field "image".
-// Saves the file to ./uploads/<uuid>.<ext> and returns { "url": "/uploads/<filename>" }.
-func UploadImage(w http.ResponseWriter, r *http.Request) {
+// The image is checked by its actual bytes, size-limited and stripped of
+// metadata (see processImage), then saved as <uuid>.<ext> in the configured
+// Storage. Returns { "url", "width", "height" }; url is e.g.
+// "/uploads/<filename>" for local disk.
+func (h *Handler) UploadImage(w http.ResponseWriter, r *http.Request) {
+	userID, ok := authmw.UserIDFromContext(r.Context())
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "unauthorized", "unauthorized")
+		return
+	}
+
 	// Limit request body size
-	r.Body = http.MaxBytesReader(w, r.Body, maxUploadSize)
+	r.Body = http.MaxBytesReader(w, r.Body, h.Config.UploadMaxBytes)
 
-	if err := r.ParseMultipartForm(maxUploadSize); err != nil {
-		http.Error(w, "file too large (max 5 MB)", http.StatusBadRequest)
+	if err := r.ParseMultipartForm(h.Config.UploadMaxBytes); err != nil {
+		writeError(w, http.StatusBadRequest, "file_too_large", "file too large (max "+megabytes(h.Config.UploadMaxBytes)+")")
 		return
 	}
 
 	file, header, err := r.FormFile("image")
 	if err != nil {
-		http.Error(w, "missing 'image' field", http.StatusBadRequest)
+		writeError(w, http.StatusBadRequest, "missing_file", "missing 'image' field")
 		return
 	}
 	defer file.Close()
 
 	// Validate MIME type
-	contentType := header.Header.Get("Content-Type")
-	if contentType != "image/jpeg" && contentType != "image/png" && contentType != "image/webp" {
-		http.Error(w, "unsupported file type (only JPEG, PNG, WEBP)", http.StatusBadRequest)
+	if !declaredImageType(header) {
+		writeError(w, http.StatusBadRequest, "unsupported_file_type", "unsupported file type (only JPEG, PNG, WEBP)")
+		return
+	}
+	data, err := io.ReadAll(file)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "unreadable_file", "could not read file")
+		return
+	}
+	img, err := processImage(data, h.Config.UploadMaxDimension)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, imageErrorCode(err), err.Error())
 		return
 	}
 
-	// Derive extension
-	ext := strings.ToLower(filepath.Ext(header.Filename))
-	if ext == "" {
-		switch contentType {
-		case "image/jpeg":
-			ext = ".jpg"
-		case "image/png":
-			ext = ".png"
-		case "image/webp":
-			ext = ".webp"
-		}
+	url, err := saveUpload(r.Context(), userID, img)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "storage_error", err.Error())
+		return
 	}
 
-	// Ensure uploads directory exists
-	if err := os.MkdirAll(uploadsDir, 0755); err != nil {
-		http.Error(w, "server storage error", http.StatusInternalServerError)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(uploadedImage{URL: url, Width: img.Width, Height: img.Height})
+}
+
+// uploadedImage describes a stored upload in API responses.
+type uploadedImage struct {
+	URL    string `json:"url"`
+	Width  int    `json:"width"`
+	Height int    `json:"height"`
+}
+
+// UploadImages handles POST /api/upload/batch
+// Accepts multipart/form-data with up to maxBatchFiles files in the "images"
+// field, each under UPLOAD_MAX_BYTES (5 MB) and UPLOAD_MAX_BATCH_BYTES
+// (20 MB) together, and returns { "urls": [...],
+// "images": [{ "url", "width", "height" }] } in upload order. Each file goes
+// through the same checks as UploadImage, all before any is saved; one bad
+// file rejects the whole batch with an error naming it.
+func (h *Handler) UploadImages(w http.ResponseWriter, r *http.Request) {
+	userID, ok := authmw.UserIDFromContext(r.Context())
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "unauthorized", "unauthorized")
 		return
 	}
 
-	// Generate unique filename
-	filename := fmt.Sprintf("%s%s", uuid.New().String(), ext)
-	destPath := filepath.Join(uploadsDir, filename)
+	r.Body = http.MaxBytesReader(w, r.Body, h.Config.UploadMaxBatchBytes)
 
-	dest, err := os.Create(destPath)
-	if err != nil {
-		http.Error(w, "could not save file", http.StatusInternalServerError)
+	if err := r.ParseMultipartForm(h.Config.UploadMaxBatchBytes); err != nil {
+		writeError(w, http.StatusBadRequest, "upload_too_large", "upload too large (max "+megabytes(h.Config.UploadMaxBatchBytes)+" in total)")
 		return
 	}
-	defer dest.Close()
 
-	if _, err = io.Copy(dest, file); err != nil {
-		http.Error(w, "could not write file", http.StatusInternalServerError)
+	headers := r.MultipartForm.File["images"]
+	if len(headers) == 0 {
+		writeError(w, http.StatusBadRequest, "missing_file", "missing 'images' field")
 		return
 	}
+	if len(headers) > maxBatchFiles {
+		writeError(w, http.StatusBadRequest, "too_many_files", fmt.Sprintf("too many files (max %d)", maxBatchFiles))
+		return
+	}
+
+	imgs := make([]*processedImage, len(headers))
+	for i, fh := range headers {
+		if fh.Size > h.Config.UploadMaxBytes {
+			writeError(w, http.StatusBadRequest, "file_too_large", fmt.Sprintf("%s: file too large (max %s)", fh.Filename, megabytes(h.Config.UploadMaxBytes)))
+			return
+		}
+		if !declaredImageType(fh) {
+			writeError(w, http.StatusBadRequest, "unsupported_file_type", fmt.Sprintf("%s: unsupported file type (only JPEG, PNG, WEBP)", fh.Filename))
+			return
+		}
+		data, err := readMultipartFile(fh)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "unreadable_file", fmt.Sprintf("%s: could not read file", fh.Filename))
+			return
+		}
+		if imgs[i], err = processImage(data, h.Config.UploadMaxDimension); err != nil {
+			writeError(w, http.StatusBadRequest, imageErrorCode(err), fmt.Sprintf("%s: %v", fh.Filename, err))
+			return
+		}
+	}
+
+	urls := make([]string, 0, len(headers))
+	images := make([]uploadedImage, 0, len(headers))
+	for i, fh := range headers {
+		url, err := saveUpload(r.Context(), userID, imgs[i])
+		if err != nil {
+			// Don't leave half a batch behind.
+			for _, u := range urls {
+				removeUpload(r.Context(), u)
+			}
+			writeError(w, http.StatusInternalServerError, "storage_error", fmt.Sprintf("%s: %v", fh.Filename, err))
+			return
+		}
+		urls = append(urls, url)
+		images = append(images, uploadedImage{URL: url, Width: imgs[i].Width, Height: imgs[i].Height})
+	}
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]string{
-		"url": "/uploads/" + filename,
+	json.NewEncoder(w).Encode(map[string]any{
+		"urls":   urls,
+		"images": images,
 	})
 }
+
+// DeleteUpload handles DELETE /api/upload
+// Body: { "url": "/uploads/<filename>" }
+// Removes an uploaded image. The caller must have uploaded it, or be an
+// admin. Returns 409 while a live listing or a chat message still shows it,
+// and 404 when the file is already gone.
+func (h *Handler) DeleteUpload(w http.ResponseWriter, r *http.Request) {
+	userID, ok := authmw.UserIDFromContext(r.Context())
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "unauthorized", "unauthorized")
+		return
+	}
+	var req struct {
+		URL string `json:"url"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.URL == "" {
+		writeError(w, http.StatusBadRequest, "missing_url", "url is required")
+		return
+	}
+	// Name rejects anything outside the store, including "../" tricks.
+	name, ok := storage.Name(req.URL)
+	if !ok {
+		writeError(w, http.StatusBadRequest, "invalid_url", "url is not an uploaded file")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	var ownerID *string
+	var inUse bool
+	err := db.Pool.QueryRow(ctx, `
+		SELECT (SELECT owner_id FROM uploads WHERE url = $1),
+		       EXISTS (SELECT 1 FROM products WHERE image_url = $1 AND deleted_at IS NULL)
+		       OR EXISTS (SELECT 1 FROM messages WHERE image_url = $1 AND deleted_at IS NULL)`,
+		req.URL,
+	).Scan(&ownerID, &inUse)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "database_error", "database error")
+		return
+	}
+	claims, _ := authmw.ClaimsFromContext(r.Context())
+	if !claims.IsAdmin() && (ownerID == nil || *ownerID != userID) {
+		writeError(w, http.StatusForbidden, "not_image_owner", "you can only delete images you uploaded")
+		return
+	}
+	if inUse {
+		writeError(w, http.StatusConflict, "image_in_use", "image is still used by a listing or message")
+		return
+	}
+
+	exists, err := storage.Exists(ctx, name)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "storage_error", "server storage error")
+		return
+	}
+	if !exists {
+		writeError(w, http.StatusNotFound, "file_not_found", "file not found")
+		return
+	}
+	if err := storage.Delete(ctx, name); err != nil {
+		writeError(w, http.StatusInternalServerError, "storage_error", "could not delete file")
+		return
+	}
+	if _, err := db.Pool.Exec(ctx, `DELETE FROM uploads WHERE url = $1`, req.URL); err != nil {
+		writeError(w, http.StatusInternalServerError, "database_error", "database error")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// validImageURL reports whether a listing image URL points at a file that
+// userID uploaded to our Storage and that still exists there.
+func validImageURL(ctx context.Context, userID, u string) bool {
+	name, ok := storage.Name(u)
+	if !ok {
+		return false
+	}
+	var owned bool
+	err := db.Pool.QueryRow(ctx,
+		`SELECT EXISTS (SELECT 1 FROM uploads WHERE url = $1 AND owner_id = $2)`, u, userID,
+	).Scan(&owned)
+	if err != nil || !owned {
+		return false
+	}
+	exists, err := storage.Exists(ctx, name)
+	return err == nil && exists
+}
+
+// declaredImageType reports whether an upload claims to be JPEG, PNG or
+// WEBP. It is only a first filter; processImage checks the real bytes.
+func declaredImageType(header *multipart.FileHeader) bool {
+	contentType := header.Header.Get("Content-Type")
+	return contentType == "image/jpeg" || contentType == "image/png" || contentType == "image/webp"
+}
+
+func readMultipartFile(header *multipart.FileHeader) ([]byte, error) {
+	file, err := header.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return io.ReadAll(file)
+}
+
+// saveUpload stores a processed image as <uuid><ext>, records ownerID as its
+// uploader and returns its URL.
+func saveUpload(ctx context.Context, ownerID string, img *processedImage) (string, error) {
+	name := fmt.Sprintf("%s%s", uuid.New().String(), img.Ext)
+	url, err := storage.Put(ctx, name, bytes.NewReader(img.Data), int64(len(img.Data)), img.ContentType)
+	if err != nil {
+		return "", err
+	}
+	if _, err := db.Pool.Exec(ctx,
+		`INSERT INTO uploads (url, owner_id) VALUES ($1, $2)`, url, ownerID); err != nil {
+		storage.Delete(ctx, name)
+		return "", err
+	}
+	return url, nil
+}
+
+// removeUpload deletes a stored upload and its ownership record.
+func removeUpload(ctx context.Context, url string) {
+	if name, ok := storage.Name(url); ok {
+		storage.Delete(ctx, name)
+	}
+	db.Pool.Exec(ctx, `DELETE FROM uploads WHERE url = $1`, url)
+}
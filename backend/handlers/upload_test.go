@@ -0,0 +1,54 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// TestCreateProductImageMustBeOwnUpload checks that a listing image is only
+// accepted when it names a file the seller uploaded and that still exists.
+func TestCreateProductImageMustBeOwnUpload(t *testing.T) {
+	requireDB(t)
+	ctx := context.Background()
+	prev := storage
+	SetStorage(LocalStorage{Dir: t.TempDir()})
+	t.Cleanup(func() { SetStorage(prev) })
+
+	seller := newTestUser(t, testPool, 0)
+	other := newTestUser(t, testPool, 0)
+	t.Cleanup(func() { testPool.Exec(ctx, `DELETE FROM users WHERE id IN ($1, $2)`, seller, other) })
+	upload := func(owner string) string {
+		t.Helper()
+		url, err := saveUpload(ctx, owner, &processedImage{Data: []byte("img"), ContentType: "image/jpeg", Ext: ".jpg"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return url
+	}
+	own, others, removed := upload(seller), upload(other), upload(seller)
+	if name, _ := storage.Name(removed); storage.Delete(ctx, name) != nil {
+		t.Fatal("could not remove upload")
+	}
+
+	for _, tc := range []struct {
+		imageURL string
+		want     int
+	}{
+		{own, http.StatusCreated},
+		{"https://cdn.example.com/photo.jpg", http.StatusBadRequest},
+		{"/uploads/../secrets.jpg", http.StatusBadRequest},
+		{others, http.StatusBadRequest},
+		{removed, http.StatusBadRequest},
+	} {
+		body := `{"title":"T","category":"Misc","location":"Nagpur","type":"FIXED","price":10,"image_url":"` + tc.imageURL + `"}`
+		r := asUser(httptest.NewRequest(http.MethodPost, "/api/products", strings.NewReader(body)), seller)
+		w := httptest.NewRecorder()
+		testHandler.CreateProduct(w, r)
+		if w.Code != tc.want {
+			t.Errorf("image_url %q: status %d, want %d: %s", tc.imageURL, w.Code, tc.want, w.Body)
+		}
+	}
+}
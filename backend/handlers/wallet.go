@@ -6,30 +6,42 @@ import (
 	"crypto/sha256"
 	"encoding/hex"
 	"encoding/json"
+	"math"
 	"net/http"
-	"os"
 	"strconv"
+	"strings"
 	"time"
 
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 	"github.com/karti/orange-city-mart/backend/db"
+	"github.com/karti/orange-city-mart/backend/metrics"
 	authmw "github.com/karti/orange-city-mart/backend/middleware"
 )
 
 // verifySignature validates the HMAC-SHA256 request signature.
-func verifySignature(message, signature string) bool {
-	secret := os.Getenv("JWT_SECRET")
-	mac := hmac.New(sha256.New, []byte(secret))
+// signature is the lowercase hex HMAC-SHA256 of message keyed with key,
+// DEPOSIT_SIGNING_SECRET for deposits.
+func verifySignature(key []byte, message, signature string) bool {
+	mac := hmac.New(sha256.New, key)
 	mac.Write([]byte(message))
 	expected := hex.EncodeToString(mac.Sum(nil))
 	return hmac.Equal([]byte(expected), []byte(signature))
 }
 
 // GetWallet handles GET /api/wallet
-// Returns the authenticated user's wallet balance and transaction history.
-func GetWallet(w http.ResponseWriter, r *http.Request) {
+// Returns the authenticated user's wallet balance and their latest 50
+// transactions; ListTransactions pages through the full history.
+//
+// Bids soft-block funds by deducting them from wallet_balance up front, so
+// balance (and its alias available_balance) is already what the user can
+// spend. held_balance is informational: the sum of their SOFT and HARD
+// bid_holds, which is not included in balance, across held_auctions auctions.
+func (h *Handler) GetWallet(w http.ResponseWriter, r *http.Request) {
 	userID, ok := authmw.UserIDFromContext(r.Context())
 	if !ok {
-		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		writeError(w, http.StatusUnauthorized, "unauthorized", "unauthorized")
 		return
 	}
 
@@ -40,7 +52,19 @@ func GetWallet(w http.ResponseWriter, r *http.Request) {
 		`SELECT wallet_balance FROM users WHERE id = $1`, userID,
 	).Scan(&balance)
 	if err != nil {
-		http.Error(w, "user not found", http.StatusNotFound)
+		writeError(w, http.StatusNotFound, "user_not_found", "user not found")
+		return
+	}
+
+	var held float64
+	var heldAuctions int
+	err = db.Pool.QueryRow(ctx, `
+		SELECT COALESCE(SUM(amount), 0)::float8, COUNT(DISTINCT auction_id)
+		FROM bid_holds
+		WHERE user_id = $1 AND status IN ('SOFT', 'HARD')`, userID,
+	).Scan(&held, &heldAuctions)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "database_error", "database error")
 		return
 	}
 
@@ -51,7 +75,7 @@ func GetWallet(w http.ResponseWriter, r *http.Request) {
 		userID,
 	)
 	if err != nil {
-		http.Error(w, "database error", http.StatusInternalServerError)
+		writeError(w, http.StatusInternalServerError, "database_error", "database error")
 		return
 	}
 	defer rows.Close()
@@ -78,16 +102,66 @@ func GetWallet(w http.ResponseWriter, r *http.Request) {
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]interface{}{
-		"balance":      balance,
-		"transactions": txns,
+		"balance":           balance,
+		"balance_formatted": h.formatMoney(balance),
+		"available_balance": balance,
+		"held_balance":      held,
+		"held_formatted":    h.formatMoney(held),
+		"held_auctions":     heldAuctions,
+		"currency":          h.Config.Currency,
+		"transactions":      txns,
 	})
 }
 
+// dailyHeadroom returns how much more of txType (DEPOSIT or WITHDRAW) the
+// user may move today under limit, counting every non-FAILED transaction of
+// that type since midnight UTC. It returns -1 when limit is 0 (unlimited).
+// Call it with the user's wallet row locked so concurrent requests can't
+// both pass the check.
+func (h *Handler) dailyHeadroom(ctx context.Context, tx pgx.Tx, userID, txType string, limit float64) (float64, error) {
+	if limit <= 0 {
+		return -1, nil
+	}
+	midnight := time.Now().UTC().Truncate(24 * time.Hour)
+	var used float64
+	err := tx.QueryRow(ctx, `
+		SELECT COALESCE(SUM(amount), 0)::float8 FROM transactions
+		WHERE user_id = $1 AND type = $2 AND status <> 'FAILED' AND created_at >= $3`,
+		userID, txType, midnight,
+	).Scan(&used)
+	if err != nil {
+		return 0, err
+	}
+	return math.Max(0, h.roundMoney(limit-used)), nil
+}
+
+// writeDailyLimitExceeded rejects a request that would exceed a daily cap,
+// carrying the remaining headroom so the UI can show it.
+func writeDailyLimitExceeded(w http.ResponseWriter, limit, remaining float64) {
+	writeErrorDetails(w, http.StatusTooManyRequests, "daily_limit_exceeded", "amount exceeds your daily limit",
+		map[string]any{"daily_limit": limit, "daily_remaining": remaining})
+}
+
+// depositSignatureMessage is the canonical message a deposit's X-Signature
+// covers: "<user id>|<amount with two decimals>|<upi_ref>", e.g.
+// "6f1c...|250.00|UPI123456". The payment gateway signs it once the UPI
+// transfer clears, so a client can neither invent a deposit nor change its
+// amount; the reference's idempotency check stops a signed deposit being
+// replayed.
+func depositSignatureMessage(userID string, amount float64, upiRef string) string {
+	return userID + "|" + formatAmount(amount) + "|" + upiRef
+}
+
 // Deposit handles POST /api/wallet/deposit
-func Deposit(w http.ResponseWriter, r *http.Request) {
+// It requires an X-Signature header over depositSignatureMessage, keyed with
+// DEPOSIT_SIGNING_SECRET; a missing or mismatched signature is rejected with
+// 401. With no secret configured (only allowed in LOCAL_MODE) every deposit
+// is rejected, so a client can never credit its own wallet. Deposits are
+// capped per UTC day by DAILY_DEPOSIT_LIMIT (0, the default, means no cap).
+func (h *Handler) Deposit(w http.ResponseWriter, r *http.Request) {
 	userID, ok := authmw.UserIDFromContext(r.Context())
 	if !ok {
-		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		writeError(w, http.StatusUnauthorized, "unauthorized", "unauthorized")
 		return
 	}
 
@@ -96,12 +170,25 @@ func Deposit(w http.ResponseWriter, r *http.Request) {
 		UPIREF string  `json:"upi_ref"`
 	}
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Amount <= 0 {
-		http.Error(w, "invalid request body", http.StatusBadRequest)
+		writeError(w, http.StatusBadRequest, "invalid_body", "invalid request body")
 		return
 	}
 
+	req.UPIREF = strings.TrimSpace(req.UPIREF)
 	if req.UPIREF == "" {
-		req.UPIREF = "FE" + strconv.FormatInt(time.Now().UnixMilli(), 10)
+		writeError(w, http.StatusBadRequest, "missing_upi_ref", "upi_ref is required")
+		return
+	}
+	req.Amount = h.roundMoney(req.Amount)
+	if req.Amount <= 0 {
+		writeError(w, http.StatusBadRequest, "invalid_amount", "positive amount required")
+		return
+	}
+	key := h.Config.DepositSigningSecret
+	sig := r.Header.Get("X-Signature")
+	if len(key) == 0 || sig == "" || !verifySignature(key, depositSignatureMessage(userID, req.Amount, req.UPIREF), strings.ToLower(sig)) {
+		writeError(w, http.StatusUnauthorized, "invalid_signature", "invalid deposit signature")
+		return
 	}
 
 	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
@@ -109,7 +196,7 @@ func Deposit(w http.ResponseWriter, r *http.Request) {
 
 	tx, err := db.Pool.Begin(ctx)
 	if err != nil {
-		http.Error(w, "database error", http.StatusInternalServerError)
+		writeError(w, http.StatusInternalServerError, "database_error", "database error")
 		return
 	}
 	defer tx.Rollback(ctx)
@@ -121,16 +208,35 @@ func Deposit(w http.ResponseWriter, r *http.Request) {
 		req.UPIREF,
 	).Scan(&count)
 	if count > 0 {
-		http.Error(w, "duplicate transaction", http.StatusConflict)
+		writeError(w, http.StatusConflict, "duplicate_transaction", "duplicate transaction")
 		return
 	}
 
+	// Daily cap, checked under the wallet row lock.
+	if _, err = tx.Exec(ctx, `SELECT 1 FROM users WHERE id = $1 FOR UPDATE`, userID); err != nil {
+		writeError(w, http.StatusInternalServerError, "database_error", "database error")
+		return
+	}
+	limit := h.Config.DailyDepositLimit
+	remaining, err := h.dailyHeadroom(ctx, tx, userID, "DEPOSIT", limit)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "database_error", "database error")
+		return
+	}
+	if remaining >= 0 {
+		if req.Amount > remaining {
+			writeDailyLimitExceeded(w, limit, remaining)
+			return
+		}
+		remaining = h.roundMoney(remaining - req.Amount)
+	}
+
 	_, err = tx.Exec(ctx,
 		`UPDATE users SET wallet_balance = wallet_balance + $1 WHERE id = $2`,
 		req.Amount, userID,
 	)
 	if err != nil {
-		http.Error(w, "database error", http.StatusInternalServerError)
+		writeError(w, http.StatusInternalServerError, "database_error", "database error")
 		return
 	}
 	_, err = tx.Exec(ctx,
@@ -138,29 +244,46 @@ func Deposit(w http.ResponseWriter, r *http.Request) {
 		userID, req.Amount, req.UPIREF,
 	)
 	if err != nil {
-		http.Error(w, "database error", http.StatusInternalServerError)
+		writeError(w, http.StatusInternalServerError, "database_error", "database error")
 		return
 	}
 	if err = tx.Commit(ctx); err != nil {
-		http.Error(w, "commit failed", http.StatusInternalServerError)
+		writeError(w, http.StatusInternalServerError, "database_error", "commit failed")
 		return
 	}
+	metrics.Deposit(req.Amount)
 
 	var newBalance float64
 	_ = db.Pool.QueryRow(ctx, `SELECT wallet_balance FROM users WHERE id = $1`, userID).Scan(&newBalance)
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]interface{}{
-		"success":     true,
-		"new_balance": newBalance,
+		"success":         true,
+		"new_balance":     newBalance,
+		"daily_remaining": headroomValue(remaining),
 	})
 }
 
+// headroomValue renders dailyHeadroom's result for a response: nil (JSON
+// null) when there is no cap.
+func headroomValue(remaining float64) *float64 {
+	if remaining < 0 {
+		return nil
+	}
+	return &remaining
+}
+
 // Withdraw handles POST /api/wallet/withdraw
-func Withdraw(w http.ResponseWriter, r *http.Request) {
+// The wallet is debited immediately but the payout itself is asynchronous:
+// the transaction is recorded PENDING until ResolveTransaction marks it
+// COMPLETED or FAILED. A second withdrawal with the same upi_id and amount is
+// rejected while the first is pending or within WITHDRAW_DEDUP_WINDOW.
+// Withdrawals are capped per UTC day by DAILY_WITHDRAW_LIMIT (0, the
+// default, means no cap).
+func (h *Handler) Withdraw(w http.ResponseWriter, r *http.Request) {
 	userID, ok := authmw.UserIDFromContext(r.Context())
 	if !ok {
-		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		writeError(w, http.StatusUnauthorized, "unauthorized", "unauthorized")
 		return
 	}
 
@@ -169,7 +292,17 @@ func Withdraw(w http.ResponseWriter, r *http.Request) {
 		UPIID  string  `json:"upi_id"`
 	}
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Amount <= 0 {
-		http.Error(w, "invalid request body", http.StatusBadRequest)
+		writeError(w, http.StatusBadRequest, "invalid_body", "invalid request body")
+		return
+	}
+	req.UPIID = strings.TrimSpace(req.UPIID)
+	if req.UPIID == "" {
+		writeError(w, http.StatusBadRequest, "missing_upi_id", "upi_id is required")
+		return
+	}
+	req.Amount = h.roundMoney(req.Amount)
+	if req.Amount <= 0 {
+		writeError(w, http.StatusBadRequest, "invalid_amount", "positive amount required")
 		return
 	}
 
@@ -178,51 +311,318 @@ func Withdraw(w http.ResponseWriter, r *http.Request) {
 
 	tx, err := db.Pool.Begin(ctx)
 	if err != nil {
-		http.Error(w, "database error", http.StatusInternalServerError)
+		writeError(w, http.StatusInternalServerError, "database_error", "database error")
 		return
 	}
 	defer tx.Rollback(ctx)
 
+	// The wallet row lock also serialises concurrent withdrawals, so the
+	// idempotency check below can't race.
 	var balance float64
 	err = tx.QueryRow(ctx,
 		`SELECT wallet_balance FROM users WHERE id = $1 FOR UPDATE`, userID,
 	).Scan(&balance)
 	if err != nil {
-		http.Error(w, "user not found", http.StatusNotFound)
+		writeError(w, http.StatusNotFound, "user_not_found", "user not found")
+		return
+	}
+
+	// Idempotency check
+	var existingID string
+	err = tx.QueryRow(ctx, `
+		SELECT id FROM transactions
+		WHERE user_id = $1 AND type = 'WITHDRAW' AND reference = $2 AND amount = $3
+		  AND (status = 'PENDING' OR (status = 'COMPLETED' AND created_at > $4))
+		LIMIT 1`,
+		userID, req.UPIID, req.Amount, time.Now().Add(-h.Config.WithdrawDedupWindow),
+	).Scan(&existingID)
+	if err == nil {
+		writeError(w, http.StatusConflict, "duplicate_withdrawal", "an identical withdrawal is already in progress")
 		return
 	}
+	if err != pgx.ErrNoRows {
+		writeError(w, http.StatusInternalServerError, "database_error", "database error")
+		return
+	}
+
 	if balance < req.Amount {
-		http.Error(w, "insufficient balance", http.StatusPaymentRequired)
+		writeError(w, http.StatusPaymentRequired, "insufficient_balance", "insufficient balance")
 		return
 	}
+	limit := h.Config.DailyWithdrawLimit
+	remaining, err := h.dailyHeadroom(ctx, tx, userID, "WITHDRAW", limit)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "database_error", "database error")
+		return
+	}
+	if remaining >= 0 {
+		if req.Amount > remaining {
+			writeDailyLimitExceeded(w, limit, remaining)
+			return
+		}
+		remaining = h.roundMoney(remaining - req.Amount)
+	}
 	_, err = tx.Exec(ctx,
 		`UPDATE users SET wallet_balance = wallet_balance - $1 WHERE id = $2`,
 		req.Amount, userID,
 	)
 	if err != nil {
-		http.Error(w, "database error", http.StatusInternalServerError)
+		writeError(w, http.StatusInternalServerError, "database_error", "database error")
 		return
 	}
-	_, err = tx.Exec(ctx,
-		`INSERT INTO transactions (user_id, amount, type, status, reference) VALUES ($1, $2, 'WITHDRAW', 'COMPLETED', $3)`,
+	var txnID string
+	err = tx.QueryRow(ctx,
+		`INSERT INTO transactions (user_id, amount, type, status, reference) VALUES ($1, $2, 'WITHDRAW', 'PENDING', $3) RETURNING id`,
 		userID, req.Amount, req.UPIID,
-	)
+	).Scan(&txnID)
 	if err != nil {
-		http.Error(w, "database error", http.StatusInternalServerError)
+		writeError(w, http.StatusInternalServerError, "database_error", "database error")
 		return
 	}
 	if err = tx.Commit(ctx); err != nil {
-		http.Error(w, "commit failed", http.StatusInternalServerError)
+		writeError(w, http.StatusInternalServerError, "database_error", "commit failed")
 		return
 	}
+	metrics.Withdrawal(req.Amount)
 
 	var newBalance float64
 	_ = db.Pool.QueryRow(ctx, `SELECT wallet_balance FROM users WHERE id = $1`, userID).Scan(&newBalance)
 
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success":         true,
+		"transaction_id":  txnID,
+		"status":          "PENDING",
+		"new_balance":     newBalance,
+		"daily_remaining": headroomValue(remaining),
+	})
+}
+
+// ─────────────────────────────────────────────────────────────────────────────
+// ResolveTransaction  POST /api/admin/transactions/{id}/resolve
+//
+// Body: { "status": "COMPLETED" | "FAILED" }
+// Records the outcome of a PENDING transaction, such as an asynchronous
+// payout. A FAILED withdrawal credits the amount back to the user's wallet,
+// since Withdraw debits it up front.
+// ─────────────────────────────────────────────────────────────────────────────
+func (h *Handler) ResolveTransaction(w http.ResponseWriter, r *http.Request) {
+	txnID := chi.URLParam(r, "id")
+
+	var req struct {
+		Status string `json:"status"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_body", "invalid request body")
+		return
+	}
+	req.Status = strings.ToUpper(req.Status)
+	if req.Status != "COMPLETED" && req.Status != "FAILED" {
+		writeError(w, http.StatusBadRequest, "invalid_status", "status must be COMPLETED or FAILED")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	tx, err := db.Pool.Begin(ctx)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "database_error", "database error")
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	var userID, txType, current string
+	var amount float64
+	err = tx.QueryRow(ctx, `
+		SELECT user_id, type, amount, status FROM transactions
+		WHERE id = $1
+		FOR UPDATE`, txnID,
+	).Scan(&userID, &txType, &amount, &current)
+	if err == pgx.ErrNoRows {
+		writeError(w, http.StatusNotFound, "transaction_not_found", "transaction not found")
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "database_error", "database error")
+		return
+	}
+	if current != "PENDING" {
+		writeError(w, http.StatusConflict, "transaction_resolved", "transaction is already "+current)
+		return
+	}
+	refund := req.Status == "FAILED" && txType == "WITHDRAW"
+
+	if _, err = tx.Exec(ctx,
+		`UPDATE transactions SET status = $2 WHERE id = $1`, txnID, req.Status); err != nil {
+		writeError(w, http.StatusInternalServerError, "database_error", "database error")
+		return
+	}
+	if refund {
+		if _, err = tx.Exec(ctx,
+			`UPDATE users SET wallet_balance = wallet_balance + $1 WHERE id = $2`,
+			amount, userID); err != nil {
+			writeError(w, http.StatusInternalServerError, "database_error", "database error")
+			return
+		}
+	}
+	if err = tx.Commit(ctx); err != nil {
+		writeError(w, http.StatusInternalServerError, "database_error", "commit failed")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"transaction_id": txnID,
+		"type":           txType,
+		"status":         req.Status,
+		"refunded":       refund,
+	})
+}
+
+// Transfer handles POST /api/wallet/transfer
+// Moves funds from the caller's wallet to another user's in one transaction,
+// recording a TRANSFER_OUT / TRANSFER_IN pair whose references point at each
+// other.
+func (h *Handler) Transfer(w http.ResponseWriter, r *http.Request) {
+	userID, ok := authmw.UserIDFromContext(r.Context())
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "unauthorized", "unauthorized")
+		return
+	}
+
+	var req struct {
+		RecipientID string  `json:"recipient_id"`
+		Amount      float64 `json:"amount"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Amount <= 0 {
+		writeError(w, http.StatusBadRequest, "invalid_body", "invalid request body")
+		return
+	}
+	if _, err := uuid.Parse(req.RecipientID); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_recipient_id", "invalid recipient_id")
+		return
+	}
+	if req.RecipientID == userID {
+		writeError(w, http.StatusBadRequest, "self_transfer", "cannot transfer to yourself")
+		return
+	}
+	req.Amount = h.roundMoney(req.Amount)
+	if req.Amount <= 0 {
+		writeError(w, http.StatusBadRequest, "invalid_amount", "positive amount required")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	tx, err := db.Pool.Begin(ctx)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "database_error", "database error")
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	// Lock both wallets in a stable (id) order so two opposing transfers
+	// can't deadlock.
+	rows, err := tx.Query(ctx, `
+		SELECT id, wallet_balance, is_frozen FROM users
+		WHERE id IN ($1, $2)
+		ORDER BY id
+		FOR UPDATE`,
+		userID, req.RecipientID,
+	)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "database_error", "database error")
+		return
+	}
+	type walletRow struct {
+		balance float64
+		frozen  bool
+	}
+	wallets := map[string]walletRow{}
+	for rows.Next() {
+		var id string
+		var wr walletRow
+		if err := rows.Scan(&id, &wr.balance, &wr.frozen); err != nil {
+			rows.Close()
+			writeError(w, http.StatusInternalServerError, "database_error", "database error")
+			return
+		}
+		wallets[id] = wr
+	}
+	rows.Close()
+
+	sender, ok := wallets[userID]
+	if !ok {
+		writeError(w, http.StatusNotFound, "user_not_found", "user not found")
+		return
+	}
+	recipient, ok := wallets[req.RecipientID]
+	if !ok {
+		writeError(w, http.StatusNotFound, "recipient_not_found", "recipient not found")
+		return
+	}
+	if sender.frozen || recipient.frozen {
+		writeError(w, http.StatusForbidden, "account_frozen", "account is frozen")
+		return
+	}
+	if sender.balance < req.Amount {
+		writeError(w, http.StatusPaymentRequired, "insufficient_balance", "insufficient balance")
+		return
+	}
+
+	_, err = tx.Exec(ctx,
+		`UPDATE users SET wallet_balance = wallet_balance - $1 WHERE id = $2`,
+		req.Amount, userID,
+	)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "database_error", "database error")
+		return
+	}
+	_, err = tx.Exec(ctx,
+		`UPDATE users SET wallet_balance = wallet_balance + $1 WHERE id = $2`,
+		req.Amount, req.RecipientID,
+	)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "database_error", "database error")
+		return
+	}
+
+	// Record the pair, then point the outgoing leg at the incoming one.
+	var outID, inID string
+	err = tx.QueryRow(ctx,
+		`INSERT INTO transactions (user_id, amount, type, status) VALUES ($1, $2, 'TRANSFER_OUT', 'COMPLETED') RETURNING id`,
+		userID, req.Amount,
+	).Scan(&outID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "database_error", "database error")
+		return
+	}
+	err = tx.QueryRow(ctx,
+		`INSERT INTO transactions (user_id, amount, type, status, reference) VALUES ($1, $2, 'TRANSFER_IN', 'COMPLETED', $3) RETURNING id`,
+		req.RecipientID, req.Amount, outID,
+	).Scan(&inID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "database_error", "database error")
+		return
+	}
+	_, err = tx.Exec(ctx, `UPDATE transactions SET reference = $1 WHERE id = $2`, inID, outID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "database_error", "database error")
+		return
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		writeError(w, http.StatusInternalServerError, "database_error", "commit failed")
+		return
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]interface{}{
-		"success":     true,
-		"new_balance": newBalance,
+		"success":        true,
+		"transaction_id": outID,
+		"new_balance":    h.roundMoney(sender.balance - req.Amount),
 	})
 }
 
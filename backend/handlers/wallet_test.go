@@ -0,0 +1,194 @@
+package handlers
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/karti/orange-city-mart/backend/config"
+)
+
+var testDepositKey = []byte("deposit-key-0123456789abcdef0123456789")
+
+func signDeposit(userID string, amount float64, ref string) string {
+	mac := hmac.New(sha256.New, testDepositKey)
+	mac.Write([]byte(depositSignatureMessage(userID, amount, ref)))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// withDepositKey returns a Handler that accepts deposits signed with
+// testDepositKey.
+func withDepositKey() *Handler {
+	return newTestHandler(func(c *config.Config) { c.DepositSigningSecret = testDepositKey })
+}
+
+func TestVerifySignatureRejectsTamperedAmount(t *testing.T) {
+	sig := signDeposit("u1", 100, "UPI1")
+	if !verifySignature(testDepositKey, depositSignatureMessage("u1", 100, "UPI1"), sig) {
+		t.Fatal("valid signature rejected")
+	}
+	for name, msg := range map[string]string{
+		"amount":    depositSignatureMessage("u1", 1000, "UPI1"),
+		"cents":     depositSignatureMessage("u1", 100.01, "UPI1"),
+		"user":      depositSignatureMessage("u2", 100, "UPI1"),
+		"reference": depositSignatureMessage("u1", 100, "UPI2"),
+	} {
+		if verifySignature(testDepositKey, msg, sig) {
+			t.Errorf("signature accepted with tampered %s", name)
+		}
+	}
+	if verifySignature(testHandler.Config.JWTSecret, depositSignatureMessage("u1", 100, "UPI1"), sig) {
+		t.Error("signature verified under the JWT secret")
+	}
+}
+
+func TestDepositSignatureAndReplay(t *testing.T) {
+	requireDB(t)
+	h := withDepositKey()
+	user := newTestUser(t, testPool, 0)
+
+	deposit := func(amount, ref, sig string) int {
+		req := httptest.NewRequest(http.MethodPost, "/api/wallet/deposit",
+			strings.NewReader(`{"amount":`+amount+`,"upi_ref":"`+ref+`"}`))
+		req.Header.Set("X-Signature", sig)
+		rec := httptest.NewRecorder()
+		h.Deposit(rec, asUser(req, user))
+		return rec.Code
+	}
+
+	ref := "UPI-REPLAY-" + user
+	sig := signDeposit(user, 100, ref)
+	if code := deposit("1000", ref, sig); code != http.StatusUnauthorized {
+		t.Fatalf("tampered amount: status %d, want 401", code)
+	}
+	if code := deposit("100", ref, ""); code != http.StatusUnauthorized {
+		t.Fatalf("missing signature: status %d, want 401", code)
+	}
+	if code := deposit("100", ref, sig); code != http.StatusOK {
+		t.Fatalf("signed deposit: status %d, want 200", code)
+	}
+	if code := deposit("100", ref, sig); code != http.StatusConflict {
+		t.Fatalf("replayed reference: status %d, want 409", code)
+	}
+	if got := walletBalance(t, testPool, user); got != 100 {
+		t.Errorf("balance = %.2f, want 100", got)
+	}
+}
+
+// TestDepositFailsClosed checks that without a configured signing secret no
+// deposit is accepted, signed or not.
+func TestDepositFailsClosed(t *testing.T) {
+	for name, sig := range map[string]string{"unsigned": "", "signed": signDeposit("u1", 100, "UPI-1")} {
+		req := httptest.NewRequest(http.MethodPost, "/api/wallet/deposit",
+			strings.NewReader(`{"amount":100,"upi_ref":"UPI-1"}`))
+		req.Header.Set("X-Signature", sig)
+		rec := httptest.NewRecorder()
+		testHandler.Deposit(rec, asUser(req, "u1"))
+		if rec.Code != http.StatusUnauthorized {
+			t.Errorf("%s deposit without a secret: status %d, want 401", name, rec.Code)
+		}
+	}
+}
+
+func TestDepositRejectsBadSignature(t *testing.T) {
+	h := withDepositKey()
+	for name, sig := range map[string]string{
+		"missing":      "",
+		"garbage":      "not-hex",
+		"other amount": signDeposit("u1", 1000, "UPI-1"),
+		"other user":   signDeposit("u2", 100, "UPI-1"),
+		"other ref":    signDeposit("u1", 100, "UPI-2"),
+	} {
+		req := httptest.NewRequest(http.MethodPost, "/api/wallet/deposit",
+			strings.NewReader(`{"amount":100,"upi_ref":"UPI-1"}`))
+		req.Header.Set("X-Signature", sig)
+		rec := httptest.NewRecorder()
+		h.Deposit(rec, asUser(req, "u1"))
+		if rec.Code != http.StatusUnauthorized {
+			t.Errorf("%s signature: status %d, want 401", name, rec.Code)
+		}
+	}
+}
+
+func transfer(from, to, amount string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/api/wallet/transfer",
+		strings.NewReader(`{"recipient_id":"`+to+`","amount":`+amount+`}`))
+	rec := httptest.NewRecorder()
+	testHandler.Transfer(rec, asUser(req, from))
+	return rec
+}
+
+func TestTransferMovesRoundedAmount(t *testing.T) {
+	requireDB(t)
+	ctx := context.Background()
+	sender := newTestUser(t, testPool, 100)
+	recipient := newTestUser(t, testPool, 0)
+	t.Cleanup(func() { testPool.Exec(ctx, `DELETE FROM users WHERE id IN ($1, $2)`, sender, recipient) })
+
+	if rec := transfer(sender, recipient, "25.004"); rec.Code != http.StatusOK {
+		t.Fatalf("Transfer = %d: %s", rec.Code, rec.Body)
+	}
+	if got := walletBalance(t, testPool, sender); got != 75 {
+		t.Errorf("sender balance = %.4f, want 75", got)
+	}
+	if got := walletBalance(t, testPool, recipient); got != 25 {
+		t.Errorf("recipient balance = %.4f, want 25", got)
+	}
+	var legs int
+	testPool.QueryRow(ctx, `
+		SELECT COUNT(*) FROM transactions
+		WHERE user_id IN ($1, $2) AND type IN ('TRANSFER_OUT', 'TRANSFER_IN') AND amount = 25`,
+		sender, recipient).Scan(&legs)
+	if legs != 2 {
+		t.Errorf("recorded %d transfer legs of 25, want 2", legs)
+	}
+}
+
+func TestTransferInsufficientFunds(t *testing.T) {
+	requireDB(t)
+	ctx := context.Background()
+	sender := newTestUser(t, testPool, 10)
+	recipient := newTestUser(t, testPool, 0)
+	t.Cleanup(func() { testPool.Exec(ctx, `DELETE FROM users WHERE id IN ($1, $2)`, sender, recipient) })
+
+	if rec := transfer(sender, recipient, "10.01"); rec.Code != http.StatusPaymentRequired {
+		t.Fatalf("Transfer beyond balance = %d, want 402: %s", rec.Code, rec.Body)
+	}
+	if got := walletBalance(t, testPool, sender); got != 10 {
+		t.Errorf("sender balance = %.2f, want 10", got)
+	}
+	if got := walletBalance(t, testPool, recipient); got != 0 {
+		t.Errorf("recipient balance = %.2f, want 0", got)
+	}
+}
+
+func TestTransferRejectsSelfAndDust(t *testing.T) {
+	const me = "8d3c6a1e-2f4b-4c1d-9e8f-0a1b2c3d4e5f"
+	const other = "1f2e3d4c-5b6a-4978-8695-a4b3c2d1e0f9"
+	for name, tc := range map[string]struct {
+		to, amount, code string
+	}{
+		"self":      {me, "10", "self_transfer"},
+		"sub-paisa": {other, "0.004", "invalid_amount"},
+	} {
+		rec := transfer(me, tc.to, tc.amount)
+		if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), tc.code) {
+			t.Errorf("%s transfer = %d %s, want 400 %s", name, rec.Code, rec.Body, tc.code)
+		}
+	}
+}
+
+func TestWithdrawRejectsDust(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/api/wallet/withdraw",
+		strings.NewReader(`{"amount":0.004,"upi_id":"me@upi"}`))
+	rec := httptest.NewRecorder()
+	testHandler.Withdraw(rec, asUser(req, "u1"))
+	if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), "invalid_amount") {
+		t.Errorf("Withdraw of 0.004 = %d %s, want 400 invalid_amount", rec.Code, rec.Body)
+	}
+}
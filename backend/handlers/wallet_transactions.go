@@ -0,0 +1,285 @@
+package handlers
+
+import (
+	"encoding/base64"
+	"encoding/csv"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/karti/orange-city-mart/backend/db"
+	authmw "github.com/karti/orange-city-mart/backend/middleware"
+)
+
+const (
+	defaultTransactionPageSize = 50
+	maxTransactionPageSize     = 200
+)
+
+// transactionTypeFilters maps a ?type= value to the transaction types it
+// covers. TRANSFER also matches both legs of a wallet-to-wallet transfer.
+var transactionTypeFilters = map[string][]string{
+	"DEPOSIT":        {"DEPOSIT"},
+	"WITHDRAW":       {"WITHDRAW"},
+	"BID_HOLD":       {"BID_HOLD"},
+	"REFUND":         {"REFUND"},
+	"TRANSFER":       {"TRANSFER", "TRANSFER_OUT", "TRANSFER_IN"},
+	"TRANSFER_OUT":   {"TRANSFER_OUT"},
+	"TRANSFER_IN":    {"TRANSFER_IN"},
+	"FEATURE_FEE":    {"FEATURE_FEE"},
+	"LISTING_FEE":    {"LISTING_FEE"},
+	"ADJUSTMENT_OUT": {"ADJUSTMENT_OUT"},
+	"ADJUSTMENT_IN":  {"ADJUSTMENT_IN"},
+}
+
+// transactionCursor is the (created_at, id) of the last row on a page.
+type transactionCursor struct {
+	CreatedAt time.Time `json:"t"`
+	ID        string    `json:"id"`
+}
+
+func (c transactionCursor) encode() string {
+	b, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func decodeTransactionCursor(s string) (transactionCursor, error) {
+	var c transactionCursor
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return c, err
+	}
+	if err := json.Unmarshal(b, &c); err != nil {
+		return c, err
+	}
+	if _, err := uuid.Parse(c.ID); err != nil {
+		return c, err
+	}
+	return c, nil
+}
+
+// ─────────────────────────────────────────────────────────────────────────────
+// ListTransactions  GET /api/wallet/transactions?type=&cursor=&limit=
+//
+// The caller's wallet history, newest first, with keyset pagination on
+// (created_at, id). type narrows to one kind of transaction. Responds with
+// {items, next_cursor}; pass next_cursor back as cursor for the next page.
+// ─────────────────────────────────────────────────────────────────────────────
+func (h *Handler) ListTransactions(w http.ResponseWriter, r *http.Request) {
+	userID, ok := authmw.UserIDFromContext(r.Context())
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "unauthorized", "unauthorized")
+		return
+	}
+	qs := r.URL.Query()
+
+	limit := defaultTransactionPageSize
+	if v, err := strconv.Atoi(qs.Get("limit")); err == nil && v > 0 {
+		limit = v
+	}
+	if limit > maxTransactionPageSize {
+		limit = maxTransactionPageSize
+	}
+
+	args := []any{userID}
+	where := []string{"user_id = $1::uuid"}
+	if v := strings.ToUpper(qs.Get("type")); v != "" {
+		types, ok := transactionTypeFilters[v]
+		if !ok {
+			writeError(w, http.StatusBadRequest, "invalid_type", "unknown transaction type")
+			return
+		}
+		args = append(args, types)
+		where = append(where, "type = ANY($"+itoa(len(args))+"::text[])")
+	}
+	if v := qs.Get("cursor"); v != "" {
+		c, err := decodeTransactionCursor(v)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "invalid_cursor", "invalid cursor")
+			return
+		}
+		args = append(args, c.CreatedAt, c.ID)
+		where = append(where, "(created_at, id) < ($"+itoa(len(args)-1)+"::timestamptz, $"+itoa(len(args))+"::uuid)")
+	}
+	args = append(args, limit+1)
+
+	rows, err := db.Pool.Query(r.Context(), `
+		SELECT id, amount, type, status, reference, created_at
+		FROM transactions
+		WHERE `+strings.Join(where, " AND ")+`
+		ORDER BY created_at DESC, id DESC
+		LIMIT $`+itoa(len(args))+`::int`, args...)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "database_error", "database error")
+		return
+	}
+	defer rows.Close()
+
+	type txRow struct {
+		ID        string  `json:"id"`
+		Amount    float64 `json:"amount"`
+		Type      string  `json:"type"`
+		Status    string  `json:"status"`
+		Reference *string `json:"reference"`
+		CreatedAt string  `json:"created_at"`
+	}
+
+	items := []txRow{}
+	var last transactionCursor
+	var nextCursor *string
+	for rows.Next() {
+		if len(items) == limit {
+			// The extra row only tells us another page exists.
+			c := last.encode()
+			nextCursor = &c
+			break
+		}
+		var t txRow
+		var ts time.Time
+		if err := rows.Scan(&t.ID, &t.Amount, &t.Type, &t.Status, &t.Reference, &ts); err != nil {
+			writeError(w, http.StatusInternalServerError, "database_error", "database error")
+			return
+		}
+		last = transactionCursor{CreatedAt: ts, ID: t.ID}
+		t.CreatedAt = ts.UTC().Format(time.RFC3339)
+		items = append(items, t)
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"items":       items,
+		"next_cursor": nextCursor,
+	})
+}
+
+// signedAmountExpr is a transaction's effect on wallet_balance, over alias t.
+// FAILED transactions moved nothing. A settlement's TRANSFER rows credit the
+// seller; the winner's copy is informational, as their funds already left
+// with the BID_HOLD. A fixed-price purchase's TRANSFER rows (referencing the
+// purchase) debit the buyer and credit the seller.
+const signedAmountExpr = `
+	CASE
+	    WHEN t.status = 'FAILED' THEN 0
+	    WHEN t.type IN ('DEPOSIT', 'REFUND', 'TRANSFER_IN', 'ADJUSTMENT_IN') THEN t.amount
+	    WHEN t.type = 'TRANSFER' THEN
+	        CASE WHEN EXISTS (
+	            SELECT 1 FROM settlements s
+	            WHERE s.auction_id::text = t.reference AND s.seller_id = t.user_id
+	        ) THEN t.amount
+	        WHEN EXISTS (
+	            SELECT 1 FROM purchases pu
+	            WHERE pu.id::text = t.reference AND pu.seller_id = t.user_id
+	        ) THEN t.amount
+	        WHEN EXISTS (
+	            SELECT 1 FROM purchases pu
+	            WHERE pu.id::text = t.reference AND pu.buyer_id = t.user_id
+	        ) THEN -t.amount
+	        ELSE 0 END
+	    ELSE -t.amount
+	END`
+
+// ─────────────────────────────────────────────────────────────────────────────
+// ExportStatement  GET /api/wallet/statement.csv?from=&to=
+//
+// Streams the caller's transactions in a date range as CSV, oldest first,
+// with each row's signed effect on the wallet and the running balance after
+// it. The running balance starts from the net of everything before from.
+// from/to take YYYY-MM-DD (inclusive) or RFC3339.
+// ─────────────────────────────────────────────────────────────────────────────
+func (h *Handler) ExportStatement(w http.ResponseWriter, r *http.Request) {
+	userID, ok := authmw.UserIDFromContext(r.Context())
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "unauthorized", "unauthorized")
+		return
+	}
+	from, ok := parseDateParam(r.URL.Query().Get("from"), time.Time{})
+	if !ok {
+		writeError(w, http.StatusBadRequest, "invalid_from", "from must be YYYY-MM-DD or RFC3339")
+		return
+	}
+	toRaw := r.URL.Query().Get("to")
+	to, ok := parseDateParam(toRaw, time.Time{})
+	if !ok {
+		writeError(w, http.StatusBadRequest, "invalid_to", "to must be YYYY-MM-DD or RFC3339")
+		return
+	}
+
+	var fromArg, toArg interface{}
+	if !from.IsZero() {
+		fromArg = from
+	}
+	if !to.IsZero() {
+		// A bare date covers that whole day.
+		if len(toRaw) == len("2006-01-02") {
+			to = to.Add(24 * time.Hour)
+		}
+		toArg = to
+	}
+
+	ctx := r.Context()
+
+	var opening float64
+	if fromArg != nil {
+		err := db.Pool.QueryRow(ctx, `
+			SELECT COALESCE(SUM(`+signedAmountExpr+`), 0)::float8
+			FROM transactions t
+			WHERE t.user_id = $1::uuid AND t.created_at < $2::timestamptz`,
+			userID, fromArg,
+		).Scan(&opening)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "database_error", "database error")
+			return
+		}
+	}
+
+	rows, err := db.Pool.Query(ctx, `
+		SELECT t.id, t.created_at, t.type, (`+signedAmountExpr+`)::float8, t.status,
+		       COALESCE(t.reference, '')
+		FROM transactions t
+		WHERE t.user_id = $1::uuid
+		  AND ($2::timestamptz IS NULL OR t.created_at >= $2::timestamptz)
+		  AND ($3::timestamptz IS NULL OR t.created_at < $3::timestamptz)
+		ORDER BY t.created_at ASC, t.id ASC`,
+		userID, fromArg, toArg,
+	)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "database_error", "database error")
+		return
+	}
+	defer rows.Close()
+
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", `attachment; filename="statement.csv"`)
+
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"id", "date", "type", "amount", "status", "reference", "running_balance"})
+	balance := opening
+	for rows.Next() {
+		var (
+			id, typ, status, reference string
+			createdAt                  time.Time
+			amount                     float64
+		)
+		if err := rows.Scan(&id, &createdAt, &typ, &amount, &status, &reference); err != nil {
+			continue
+		}
+		balance = h.roundMoney(balance + amount)
+		cw.Write([]string{
+			id,
+			createdAt.UTC().Format(time.RFC3339),
+			typ,
+			formatAmount(amount),
+			status,
+			csvText(reference), // a deposit's reference is user-supplied
+			formatAmount(balance),
+		})
+		cw.Flush()
+	}
+	cw.Flush()
+	if err := rows.Err(); err != nil {
+		// Headers are already sent; all we can do is log the truncation.
+		logf(ctx, "wallet statement for %s: %v", userID, err)
+	}
+}
@@ -0,0 +1,34 @@
+package handlers
+
+import (
+	"context"
+	"encoding/csv"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestExportStatementNeutralisesFormulaReferences(t *testing.T) {
+	requireDB(t)
+	ctx := context.Background()
+	userID := newTestUser(t, testPool, 100)
+	t.Cleanup(func() { testPool.Exec(ctx, `DELETE FROM users WHERE id = $1`, userID) })
+	if _, err := testPool.Exec(ctx, `
+		INSERT INTO transactions (user_id, amount, type, status, reference)
+		VALUES ($1, 100, 'DEPOSIT', 'COMPLETED', '=HYPERLINK("http://evil")')`, userID); err != nil {
+		t.Fatal(err)
+	}
+
+	w := httptest.NewRecorder()
+	testHandler.ExportStatement(w, asUser(httptest.NewRequest(http.MethodGet, "/api/wallet/statement.csv", nil), userID))
+	if w.Code != http.StatusOK {
+		t.Fatalf("ExportStatement = %d: %s", w.Code, w.Body)
+	}
+	rows, err := csv.NewReader(w.Body).ReadAll()
+	if err != nil || len(rows) != 2 {
+		t.Fatalf("csv = %v, %v; want header and one row", rows, err)
+	}
+	if ref := rows[1][5]; ref != `'=HYPERLINK("http://evil")` {
+		t.Fatalf("reference exported as %q", ref)
+	}
+}
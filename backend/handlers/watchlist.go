@@ -0,0 +1,245 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/karti/orange-city-mart/backend/db"
+	"github.com/karti/orange-city-mart/backend/hub"
+	authmw "github.com/karti/orange-city-mart/backend/middleware"
+)
+
+// WatchlistAlertPayload is pushed to a watcher when a watched auction gets a
+// new bid ("new_bid") or is about to end ("ending_soon").
+type WatchlistAlertPayload struct {
+	Event          string  `json:"event"`
+	ProductID      string  `json:"product_id"`
+	ProductTitle   string  `json:"product_title"`
+	AuctionID      string  `json:"auction_id"`
+	CurrentHighBid float64 `json:"current_high_bid"`
+	EndTime        string  `json:"end_time"`
+}
+
+// AddToWatchlist handles POST /api/watchlist/{productId} (requires auth)
+// Bookmarks a live listing. Watching something twice is a no-op.
+func (h *Handler) AddToWatchlist(w http.ResponseWriter, r *http.Request) {
+	userID, ok := authmw.UserIDFromContext(r.Context())
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "unauthorized", "unauthorized")
+		return
+	}
+	productID := chi.URLParam(r, "productId")
+	if _, err := uuid.Parse(productID); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_product_id", "invalid product id")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	var exists bool
+	err := db.Pool.QueryRow(ctx, `
+		SELECT EXISTS (SELECT 1 FROM products WHERE id = $1::uuid AND deleted_at IS NULL)`,
+		productID,
+	).Scan(&exists)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "database_error", "database error")
+		return
+	}
+	if !exists {
+		writeError(w, http.StatusNotFound, "product_not_found", "product not found")
+		return
+	}
+
+	_, err = db.Pool.Exec(ctx, `
+		INSERT INTO watchlist (user_id, product_id) VALUES ($1::uuid, $2::uuid)
+		ON CONFLICT DO NOTHING`, userID, productID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "database_error", "database error")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{"product_id": productID, "watching": true})
+}
+
+// RemoveFromWatchlist handles DELETE /api/watchlist/{productId} (requires auth)
+func (h *Handler) RemoveFromWatchlist(w http.ResponseWriter, r *http.Request) {
+	userID, ok := authmw.UserIDFromContext(r.Context())
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "unauthorized", "unauthorized")
+		return
+	}
+	productID := chi.URLParam(r, "productId")
+	if _, err := uuid.Parse(productID); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_product_id", "invalid product id")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	_, err := db.Pool.Exec(ctx,
+		`DELETE FROM watchlist WHERE user_id = $1::uuid AND product_id = $2::uuid`, userID, productID,
+	)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "database_error", "database error")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ListWatchlist handles GET /api/watchlist (requires auth)
+// Returns the caller's watched listings, newest first, enriched with the
+// product and its latest auction like ListMyBids.
+func (h *Handler) ListWatchlist(w http.ResponseWriter, r *http.Request) {
+	userID, ok := authmw.UserIDFromContext(r.Context())
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "unauthorized", "unauthorized")
+		return
+	}
+
+	rows, err := db.Pool.Query(r.Context(), `
+		SELECT
+			w.created_at,
+			p.id, p.title, p.image_url, p.type, p.price,
+			a.id, a.current_highest_bid, a.end_time, a.status, a.highest_bidder_id
+		FROM watchlist w
+		JOIN products p ON p.id = w.product_id
+		LEFT JOIN LATERAL (
+			SELECT * FROM auctions
+			WHERE product_id = p.id
+			ORDER BY created_at DESC
+			LIMIT 1
+		) a ON TRUE
+		WHERE w.user_id = $1::uuid AND p.deleted_at IS NULL
+		ORDER BY w.created_at DESC`, userID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "database_error", "database error")
+		return
+	}
+	defer rows.Close()
+
+	type WatchRow struct {
+		WatchedAt       string   `json:"watched_at"`
+		ProductID       string   `json:"product_id"`
+		ProductTitle    string   `json:"product_title"`
+		ProductImageURL *string  `json:"product_image_url"`
+		ProductType     string   `json:"product_type"`
+		Price           *float64 `json:"price"`
+		AuctionID       *string  `json:"auction_id"`
+		CurrentHighBid  *float64 `json:"current_high_bid"`
+		EndTime         *string  `json:"end_time"`
+		AuctionStatus   *string  `json:"auction_status"`
+		HighestBidderID *string  `json:"highest_bidder_id"`
+		// Computed
+		IsWinning bool `json:"is_winning"`
+	}
+
+	items := []WatchRow{}
+	for rows.Next() {
+		var it WatchRow
+		var watchedAt time.Time
+		var endTime *time.Time
+		err := rows.Scan(
+			&watchedAt,
+			&it.ProductID, &it.ProductTitle, &it.ProductImageURL, &it.ProductType, &it.Price,
+			&it.AuctionID, &it.CurrentHighBid, &endTime, &it.AuctionStatus, &it.HighestBidderID,
+		)
+		if err != nil {
+			continue
+		}
+		it.WatchedAt = watchedAt.UTC().Format(time.RFC3339)
+		if endTime != nil {
+			s := endTime.UTC().Format(time.RFC3339)
+			it.EndTime = &s
+		}
+		it.IsWinning = it.HighestBidderID != nil && *it.HighestBidderID == userID
+		items = append(items, it)
+	}
+
+	writeJSON(w, http.StatusOK, items)
+}
+
+// alertWatchers pushes a new_bid watchlist_alert to everyone watching the
+// auction's product, except the bidder. Call only after commit.
+func (h *AuctionHandler) alertWatchers(pb placedBid) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	rows, err := db.Pool.Query(ctx, `
+		SELECT w.user_id, p.id, p.title, a.end_time
+		FROM auctions a
+		JOIN products p ON p.id = a.product_id
+		JOIN watchlist w ON w.product_id = p.id
+		WHERE a.id = $1::uuid AND w.user_id <> $2::uuid`,
+		pb.AuctionID, pb.BidderID)
+	if err != nil {
+		log.Printf("watchlist alert for auction %s: %v", pb.AuctionID, err)
+		return
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var watcherID string
+		var endTime time.Time
+		alert := WatchlistAlertPayload{
+			Event:          "new_bid",
+			AuctionID:      pb.AuctionID,
+			CurrentHighBid: pb.Amount,
+		}
+		if err := rows.Scan(&watcherID, &alert.ProductID, &alert.ProductTitle, &endTime); err != nil {
+			continue
+		}
+		alert.EndTime = endTime.UTC().Format(time.RFC3339)
+		h.sendWatchlistAlert(watcherID, alert)
+	}
+}
+
+// alertWatchersEndingSoon sends each watcher one ending_soon alert per
+// auction once it is within WATCHLIST_ENDING_WINDOW of its end. Rows are
+// marked before sending, so a watcher is alerted at most once per auction.
+func (h *AuctionHandler) alertWatchersEndingSoon(ctx context.Context) {
+	rows, err := db.Pool.Query(ctx, `
+		UPDATE watchlist w
+		SET ending_alerted_for = a.id
+		FROM auctions a
+		JOIN products p ON p.id = a.product_id
+		WHERE a.product_id = w.product_id
+		  AND a.status = 'ACTIVE'
+		  AND a.end_time > NOW()
+		  AND a.end_time <= $1::timestamptz
+		  AND w.ending_alerted_for IS DISTINCT FROM a.id
+		RETURNING w.user_id, p.id, p.title, a.id, a.current_highest_bid, a.end_time`,
+		time.Now().Add(h.Config.WatchlistEndingWindow))
+	if err != nil {
+		log.Printf("sweeper: failed to send ending-soon alerts: %v", err)
+		return
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var watcherID string
+		var endTime time.Time
+		alert := WatchlistAlertPayload{Event: "ending_soon"}
+		if err := rows.Scan(&watcherID, &alert.ProductID, &alert.ProductTitle,
+			&alert.AuctionID, &alert.CurrentHighBid, &endTime); err != nil {
+			continue
+		}
+		alert.EndTime = endTime.UTC().Format(time.RFC3339)
+		h.sendWatchlistAlert(watcherID, alert)
+	}
+}
+
+func (h *AuctionHandler) sendWatchlistAlert(userID string, alert WatchlistAlertPayload) {
+	payload, _ := json.Marshal(alert)
+	h.Hub.SendToUser(userID, hub.Message{
+		Type:    hub.TypeWatchlistAlert,
+		Payload: json.RawMessage(payload),
+	})
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/karti/orange-city-mart/backend/hub"
+)
+
+// livez reports that the process is up; a failure here means restart it.
+func livez(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Write([]byte(`{"status":"ok"}`))
+}
+
+// readyz reports whether the server can take traffic: the database answers
+// a ping and the hub's event loop is running. A failure means stop routing
+// to it, not restart it.
+func readyz(pool *pgxpool.Pool, appHub *hub.Hub) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), 2*time.Second)
+		defer cancel()
+		checks := map[string]string{"database": "ok", "hub": "ok"}
+		ready := true
+		if err := pool.Ping(ctx); err != nil {
+			checks["database"] = err.Error()
+			ready = false
+		}
+		if !appHub.Running() {
+			checks["hub"] = "not running"
+			ready = false
+		}
+		status, code := "ok", http.StatusOK
+		if !ready {
+			status, code = "unavailable", http.StatusServiceUnavailable
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(code)
+		json.NewEncoder(w).Encode(map[string]any{"status": status, "checks": checks})
+	}
+}
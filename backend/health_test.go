@@ -0,0 +1,76 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/karti/orange-city-mart/backend/hub"
+)
+
+func get(h http.HandlerFunc) (int, map[string]any) {
+	w := httptest.NewRecorder()
+	h(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	var body map[string]any
+	json.Unmarshal(w.Body.Bytes(), &body)
+	return w.Code, body
+}
+
+func runningHub(t *testing.T) *hub.Hub {
+	t.Helper()
+	h := hub.NewHub(nil)
+	go h.Run()
+	for deadline := time.Now().Add(time.Second); !h.Running(); time.Sleep(time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatal("hub did not start")
+		}
+	}
+	return h
+}
+
+// TestLivezUpWhileDatabaseDown checks that losing the database takes the
+// server out of rotation without telling the orchestrator to restart it.
+func TestLivezUpWhileDatabaseDown(t *testing.T) {
+	// Nothing listens on port 1, so the ping fails.
+	pool, err := pgxpool.New(context.Background(), "postgres://test@127.0.0.1:1/test?connect_timeout=1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pool.Close()
+
+	if code, _ := get(livez); code != http.StatusOK {
+		t.Errorf("livez = %d, want 200", code)
+	}
+	code, body := get(readyz(pool, runningHub(t)))
+	if code != http.StatusServiceUnavailable {
+		t.Fatalf("readyz with the database down = %d, want 503", code)
+	}
+	checks, _ := body["checks"].(map[string]any)
+	if checks["database"] == "ok" || checks["hub"] != "ok" {
+		t.Errorf("checks = %v, want only the database failing", checks)
+	}
+}
+
+func TestReadyzNeedsRunningHub(t *testing.T) {
+	dsn := os.Getenv("TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_URL not set")
+	}
+	pool, err := pgxpool.New(context.Background(), dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pool.Close()
+
+	if code, body := get(readyz(pool, hub.NewHub(nil))); code != http.StatusServiceUnavailable {
+		t.Errorf("readyz with the hub stopped = %d %v, want 503", code, body)
+	}
+	if code, body := get(readyz(pool, runningHub(t))); code != http.StatusOK {
+		t.Errorf("readyz = %d %v, want 200", code, body)
+	}
+}
@@ -4,18 +4,46 @@ import (
 	"context"
 	"encoding/json"
 	"log"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/google/uuid"
 	"github.com/gorilla/websocket"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 // MessageType constants for WebSocket payloads.
 const (
-	TypeBroadcastNewBid = "broadcast_new_bid"
-	TypeOutbidAlert     = "outbid_alert"
-	TypeChatMessage     = "chat_message"
+	TypeBroadcastNewBid  = "broadcast_new_bid"
+	TypeOutbidAlert      = "outbid_alert"
+	TypeChatMessage      = "chat_message"
+	TypeAuctionEnded     = "auction_ended"
+	TypeAuctionExtended  = "auction_extended"
+	TypeAuctionCancelled = "auction_cancelled"
+	TypeChatError        = "chat_error"
+	TypeChatEdited       = "chat_edited"
+	TypeChatDeleted      = "chat_deleted"
+	TypeTyping           = "typing"
+	TypePresence         = "presence"
+	TypeNotification     = "notification"
+	TypeWatchlistAlert   = "watchlist_alert"
+)
+
+// Room types accepted by subscribe/unsubscribe frames.
+const (
+	RoomAuction = "auction"
+	RoomChat    = "chat"
+)
+
+// Heartbeat timings. The server pings every pingPeriod; a client that sends
+// nothing (not even a pong) for pongWait is treated as dead and unregistered.
+const (
+	writeWait  = 10 * time.Second    // max time to write a single frame
+	pongWait   = 60 * time.Second    // max time between frames from the client
+	pingPeriod = (pongWait * 9) / 10 // must be shorter than pongWait
 )
 
 // Message is the generic WebSocket message envelope.
@@ -26,12 +54,16 @@ type Message struct {
 
 // Client represents a single connected WebSocket client.
 type Client struct {
-	ID        string // user ID from JWT
-	AuctionID string // optional: auction room the client is watching
-	RoomID    string // optional: chat room
+	ID        string              // user ID from JWT
+	AuctionID string              // optional: auction room the client connected to
+	RoomID    string              // optional: chat room the client connected to; the default for chat_send
+	auctions  map[string]struct{} // every auction room joined, guarded by hub.mu
+	chats     map[string]struct{} // every chat room joined, guarded by hub.mu
 	conn      *websocket.Conn
 	send      chan []byte
+	closed    bool // set by removeClient, under hub.mu, when send is closed
 	hub       *Hub
+	name      string // display name, loaded on first use by readPump
 }
 
 // Hub manages all WebSocket connections with two room types:
@@ -39,21 +71,22 @@ type Client struct {
 //   - ChatRooms:    keyed by chat "room"  → peer-to-peer chat
 type Hub struct {
 	mu           sync.RWMutex
-	clients      map[*Client]struct{} // all connected clients
-	userIndex    map[string]*Client   // userID → client (for targeted messages)
-	auctionRooms map[string][]*Client // auctionID → clients watching it
-	chatRooms    map[string][]*Client // roomID    → clients in it
-	db           *pgxpool.Pool        // for persisting chat messages
+	clients      map[*Client]struct{}            // all connected clients
+	userIndex    map[string]map[*Client]struct{} // userID → every socket they have open
+	auctionRooms map[string][]*Client            // auctionID → clients watching it
+	chatRooms    map[string][]*Client            // roomID    → clients in it
+	db           *pgxpool.Pool                   // for persisting chat messages and flushing notifications
 
 	register   chan *Client
 	unregister chan *Client
+	running    atomic.Bool // set while Run's event loop is active
 }
 
 // NewHub creates and returns an initialised Hub.
 func NewHub(db *pgxpool.Pool) *Hub {
 	return &Hub{
 		clients:      make(map[*Client]struct{}),
-		userIndex:    make(map[string]*Client),
+		userIndex:    make(map[string]map[*Client]struct{}),
 		auctionRooms: make(map[string][]*Client),
 		chatRooms:    make(map[string][]*Client),
 		db:           db,
@@ -62,36 +95,92 @@ func NewHub(db *pgxpool.Pool) *Hub {
 	}
 }
 
+// Running reports whether the event loop started by Run is active.
+func (h *Hub) Running() bool {
+	return h.running.Load()
+}
+
 // Run is the central event loop. It must be started in its own goroutine.
 func (h *Hub) Run() {
+	h.running.Store(true)
+	defer h.running.Store(false)
 	for {
 		select {
 		case c := <-h.register:
-			h.mu.Lock()
-			h.clients[c] = struct{}{}
-			if c.ID != "" {
-				h.userIndex[c.ID] = c
-			}
-			if c.AuctionID != "" {
-				h.auctionRooms[c.AuctionID] = append(h.auctionRooms[c.AuctionID], c)
-			}
-			if c.RoomID != "" {
-				h.chatRooms[c.RoomID] = append(h.chatRooms[c.RoomID], c)
-			}
-			h.mu.Unlock()
-
+			h.addClient(c)
 		case c := <-h.unregister:
-			h.mu.Lock()
-			if _, ok := h.clients[c]; ok {
-				delete(h.clients, c)
-				delete(h.userIndex, c.ID)
-				h.removeFromSlice(h.auctionRooms, c.AuctionID, c)
-				h.removeFromSlice(h.chatRooms, c.RoomID, c)
-				close(c.send)
-			}
-			h.mu.Unlock()
+			h.removeClient(c)
+		}
+	}
+}
+
+// addClient registers c and its rooms. The user is announced online only
+// when this is their first open socket.
+func (h *Hub) addClient(c *Client) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.clients[c] = struct{}{}
+	if c.ID != "" {
+		sockets := h.userIndex[c.ID]
+		if sockets == nil {
+			sockets = make(map[*Client]struct{})
+			h.userIndex[c.ID] = sockets
+		}
+		sockets[c] = struct{}{}
+		if len(sockets) == 1 {
+			h.broadcastPresence(c.ID, true)
 		}
 	}
+	if c.AuctionID != "" {
+		h.join(c, RoomAuction, c.AuctionID)
+	}
+	if c.RoomID != "" {
+		h.join(c, RoomChat, c.RoomID)
+	}
+}
+
+// removeClient unregisters c and closes its send channel. The user stays
+// online while any of their other sockets remain.
+func (h *Hub) removeClient(c *Client) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if _, ok := h.clients[c]; !ok {
+		return
+	}
+	delete(h.clients, c)
+	if sockets := h.userIndex[c.ID]; c.ID != "" && sockets != nil {
+		delete(sockets, c)
+		if len(sockets) == 0 {
+			delete(h.userIndex, c.ID)
+			h.broadcastPresence(c.ID, false)
+		}
+	}
+	for id := range c.auctions {
+		h.removeFromSlice(h.auctionRooms, id, c)
+	}
+	for id := range c.chats {
+		h.removeFromSlice(h.chatRooms, id, c)
+	}
+	c.auctions, c.chats = nil, nil
+	c.closed = true
+	close(c.send)
+}
+
+// trySend queues data for c without blocking and reports whether it was
+// queued. It holds h.mu, so it can't race removeClient closing c.send; code
+// that sends from outside the hub lock and outside c's own pumps must use it.
+func (h *Hub) trySend(c *Client, data []byte) bool {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	if c.closed {
+		return false
+	}
+	select {
+	case c.send <- data:
+		return true
+	default:
+		return false
+	}
 }
 
 func (h *Hub) removeFromSlice(m map[string][]*Client, key string, c *Client) {
@@ -110,6 +199,33 @@ func (h *Hub) removeFromSlice(m map[string][]*Client, key string, c *Client) {
 	}
 }
 
+// join adds c to a room and records the membership on the client.
+// Joining a room twice is a no-op. Caller must hold h.mu.
+func (h *Hub) join(c *Client, roomType, id string) {
+	rooms, joined := h.auctionRooms, c.auctions
+	if roomType == RoomChat {
+		rooms, joined = h.chatRooms, c.chats
+	}
+	if _, ok := joined[id]; ok {
+		return
+	}
+	joined[id] = struct{}{}
+	rooms[id] = append(rooms[id], c)
+}
+
+// leave removes c from a room it joined. Caller must hold h.mu.
+func (h *Hub) leave(c *Client, roomType, id string) {
+	rooms, joined := h.auctionRooms, c.auctions
+	if roomType == RoomChat {
+		rooms, joined = h.chatRooms, c.chats
+	}
+	if _, ok := joined[id]; !ok {
+		return
+	}
+	delete(joined, id)
+	h.removeFromSlice(rooms, id, c)
+}
+
 // BroadcastToAuction sends a message to every client watching an auction.
 // Non-blocking: slow clients whose send buffer is full are skipped.
 func (h *Hub) BroadcastToAuction(auctionID string, msg Message) {
@@ -125,15 +241,61 @@ func (h *Hub) BroadcastToAuction(auctionID string, msg Message) {
 	h.mu.RUnlock()
 
 	for _, c := range clients {
-		select {
-		case c.send <- data:
-		default:
+		if !h.trySend(c, data) {
 			log.Printf("hub: dropped message for slow client %s", c.ID)
 		}
 	}
 }
 
-// SendToUser sends a targeted message to a single user by their ID.
+// IsOnline reports whether the user has at least one live WebSocket
+// connection.
+func (h *Hub) IsOnline(userID string) bool {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return len(h.userIndex[userID]) > 0
+}
+
+// broadcastPresence tells everyone else in the user's open chat rooms that
+// the user came online or went offline. Caller must hold h.mu.
+func (h *Hub) broadcastPresence(userID string, online bool) {
+	payload, _ := json.Marshal(struct {
+		UserID string `json:"user_id"`
+		Online bool   `json:"online"`
+	}{userID, online})
+	data, _ := json.Marshal(Message{Type: TypePresence, Payload: payload})
+	for roomID, clients := range h.chatRooms {
+		a, b, _ := strings.Cut(roomID, "_")
+		if a != userID && b != userID {
+			continue
+		}
+		for _, cl := range clients {
+			if cl.ID == userID {
+				continue
+			}
+			select {
+			case cl.send <- data:
+			default:
+			}
+		}
+	}
+}
+
+// AuctionViewers returns how many clients are currently watching an auction.
+func (h *Hub) AuctionViewers(auctionID string) int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return len(h.auctionRooms[auctionID])
+}
+
+// ClientCount returns how many WebSocket clients are connected.
+func (h *Hub) ClientCount() int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return len(h.clients)
+}
+
+// SendToUser sends a targeted message to every socket the user has open
+// (each tab and device).
 func (h *Hub) SendToUser(userID string, msg Message) {
 	data, err := json.Marshal(msg)
 	if err != nil {
@@ -141,22 +303,27 @@ func (h *Hub) SendToUser(userID string, msg Message) {
 	}
 
 	h.mu.RLock()
-	c, ok := h.userIndex[userID]
-	h.mu.RUnlock()
-
-	if !ok {
-		return // user not connected — that's fine
+	clients := make([]*Client, 0, len(h.userIndex[userID]))
+	for c := range h.userIndex[userID] {
+		clients = append(clients, c)
 	}
+	h.mu.RUnlock()
 
-	select {
-	case c.send <- data:
-	default:
-		log.Printf("hub: dropped targeted message for user %s", userID)
+	// No sockets means the user isn't connected — that's fine.
+	for _, c := range clients {
+		if !h.trySend(c, data) {
+			log.Printf("hub: dropped targeted message for user %s", userID)
+		}
 	}
 }
 
 // BroadcastToChat sends a message to every client in a chat room.
 func (h *Hub) BroadcastToChat(roomID string, msg Message) {
+	h.broadcastToChat(roomID, msg, nil)
+}
+
+// broadcastToChat sends msg to every client in a chat room except skip.
+func (h *Hub) broadcastToChat(roomID string, msg Message, skip *Client) {
 	data, _ := json.Marshal(msg)
 
 	h.mu.RLock()
@@ -165,19 +332,25 @@ func (h *Hub) BroadcastToChat(roomID string, msg Message) {
 	h.mu.RUnlock()
 
 	for _, c := range clients {
-		select {
-		case c.send <- data:
-		default:
+		if c == skip {
+			continue
 		}
+		h.trySend(c, data)
 	}
 }
 
 // NewClient creates a new client, registers it, and starts its read/write pumps.
+// A chat room the user isn't a member of is ignored.
 func (h *Hub) NewClient(userID, auctionID, roomID string, conn *websocket.Conn) *Client {
+	if !isChatMember(roomID, userID) {
+		roomID = ""
+	}
 	c := &Client{
 		ID:        userID,
 		AuctionID: auctionID,
 		RoomID:    roomID,
+		auctions:  make(map[string]struct{}),
+		chats:     make(map[string]struct{}),
 		conn:      conn,
 		send:      make(chan []byte, 256),
 		hub:       h,
@@ -188,31 +361,102 @@ func (h *Hub) NewClient(userID, auctionID, roomID string, conn *websocket.Conn)
 	return c
 }
 
-// readPump drains incoming messages and handles chat_send frames.
+// maxFlushedNotifications caps how many unread notifications are replayed
+// to a newly connected client; the rest stay available over the REST API.
+const maxFlushedNotifications = 50
+
+// FlushNotifications sends c's unread notifications, oldest first, so
+// events raised while the user was offline are delivered on connect. Only
+// call it for a client whose identity has been verified. It may run while
+// the client disconnects; frames for a closed client are dropped.
+func (h *Hub) FlushNotifications(c *Client) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	rows, err := h.db.Query(ctx, `
+		SELECT id, type, payload, created_at FROM (
+		    SELECT id, type, payload, created_at FROM notifications
+		    WHERE user_id = $1::uuid AND read_at IS NULL
+		    ORDER BY created_at DESC
+		    LIMIT $2::int
+		) n ORDER BY created_at ASC`, c.ID, maxFlushedNotifications)
+	if err != nil {
+		log.Printf("hub: flush notifications for %s: %v", c.ID, err)
+		return
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var n struct {
+			ID        string          `json:"id"`
+			Type      string          `json:"type"`
+			Payload   json.RawMessage `json:"payload"`
+			CreatedAt string          `json:"created_at"`
+			ReadAt    *string         `json:"read_at"`
+		}
+		var createdAt time.Time
+		if err := rows.Scan(&n.ID, &n.Type, &n.Payload, &createdAt); err != nil {
+			continue
+		}
+		n.CreatedAt = createdAt.UTC().Format(time.RFC3339)
+		payload, _ := json.Marshal(n)
+		data, _ := json.Marshal(Message{Type: TypeNotification, Payload: payload})
+		if !h.trySend(c, data) {
+			log.Printf("hub: dropped flushed notification for user %s", c.ID)
+		}
+	}
+}
+
+// readPump drains incoming messages and handles subscribe, unsubscribe,
+// typing and chat_send frames.
 func (c *Client) readPump() {
 	defer func() {
 		c.hub.unregister <- c
 		c.conn.Close()
 	}()
+	c.conn.SetReadDeadline(time.Now().Add(pongWait))
+	c.conn.SetPongHandler(func(string) error {
+		return c.conn.SetReadDeadline(time.Now().Add(pongWait))
+	})
 	for {
 		_, data, err := c.conn.ReadMessage()
 		if err != nil {
+			// Includes read-deadline timeouts on half-open connections.
 			break
 		}
-		// Only process messages from authenticated chat clients.
-		if c.ID == "" || c.RoomID == "" {
-			continue
-		}
+		c.conn.SetReadDeadline(time.Now().Add(pongWait))
 		var frame struct {
 			Type    string `json:"type"`
 			Payload struct {
+				RoomType string  `json:"room_type"` // subscribe/unsubscribe
+				ID       string  `json:"id"`        // subscribe/unsubscribe
+				RoomID   string  `json:"room_id"`   // chat frames; defaults to c.RoomID
 				Body     *string `json:"body"`
 				ImageURL *string `json:"image_url"`
+				TempID   string  `json:"temp_id"`
 			} `json:"payload"`
 		}
 		if err := json.Unmarshal(data, &frame); err != nil {
 			continue
 		}
+		if frame.Type == "subscribe" || frame.Type == "unsubscribe" {
+			c.handleSubscription(frame.Type == "subscribe", frame.Payload.RoomType, frame.Payload.ID)
+			continue
+		}
+
+		// Chat frames are only processed for authenticated clients in a
+		// room they have joined.
+		roomID := frame.Payload.RoomID
+		if roomID == "" {
+			roomID = c.RoomID
+		}
+		if c.ID == "" || roomID == "" || !c.inChat(roomID) {
+			continue
+		}
+		if frame.Type == "typing" {
+			c.broadcastTyping(roomID)
+			continue
+		}
 		if frame.Type != "chat_send" {
 			continue
 		}
@@ -220,19 +464,34 @@ func (c *Client) readPump() {
 			continue
 		}
 
-		// Persist message to DB.
+		// Persist message to DB, unless the recipient has blocked the sender.
 		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 		var msgID, senderName string
 		var createdAt time.Time
+		var seq int64
+		blocked := false
 		err = c.hub.db.QueryRow(ctx, `
 			INSERT INTO messages (room_id, sender_id, body, image_url)
-			VALUES ($1, $2, $3, $4)
-			RETURNING id, created_at`,
-			c.RoomID, c.ID, frame.Payload.Body, frame.Payload.ImageURL,
-		).Scan(&msgID, &createdAt)
+			SELECT $1::text, $2::uuid, $3::text, $4::text
+			WHERE NOT EXISTS (
+			    SELECT 1 FROM user_blocks
+			    WHERE blocker_id::text = $5::text AND blocked_id = $2::uuid)
+			RETURNING id, created_at, seq`,
+			roomID, c.ID, frame.Payload.Body, frame.Payload.ImageURL, RoomPeer(roomID, c.ID),
+		).Scan(&msgID, &createdAt, &seq)
+		if err == pgx.ErrNoRows {
+			// Look like a normal send to the blocked user so the block
+			// isn't revealed; nothing is stored or delivered.
+			blocked = true
+			msgID, createdAt = uuid.NewString(), time.Now()
+			seq, err = NextMessageSeq(ctx, c.hub.db)
+		}
 		if err != nil {
 			cancel()
 			log.Printf("hub: failed to persist chat message: %v", err)
+			// Tell the sender so their optimistic copy can be marked failed;
+			// nothing is broadcast for a message that wasn't stored.
+			c.sendError(frame.Payload.TempID, "message could not be saved")
 			continue
 		}
 		_ = c.hub.db.QueryRow(ctx, `SELECT name FROM users WHERE id = $1`, c.ID).Scan(&senderName)
@@ -247,29 +506,150 @@ func (c *Client) readPump() {
 			Body       *string `json:"body"`
 			ImageURL   *string `json:"image_url"`
 			CreatedAt  string  `json:"created_at"`
+			Seq        int64   `json:"seq"`
+			TempID     string  `json:"temp_id,omitempty"`
 		}
 		payloadBytes, _ := json.Marshal(chatPayload{
 			ID:         msgID,
-			RoomID:     c.RoomID,
+			RoomID:     roomID,
 			SenderID:   c.ID,
 			SenderName: senderName,
 			Body:       frame.Payload.Body,
 			ImageURL:   frame.Payload.ImageURL,
-			CreatedAt:  createdAt.UTC().Format(time.RFC3339),
+			CreatedAt:  createdAt.UTC().Format(time.RFC3339Nano),
+			Seq:        seq,
+			TempID:     frame.Payload.TempID,
 		})
-		c.hub.BroadcastToChat(c.RoomID, Message{
+		msg := Message{
 			Type:    TypeChatMessage,
 			Payload: json.RawMessage(payloadBytes),
-		})
+		}
+		if blocked {
+			data, _ := json.Marshal(msg)
+			select {
+			case c.send <- data:
+			default:
+			}
+			continue
+		}
+		c.hub.BroadcastToChat(roomID, msg)
+	}
+}
+
+// handleSubscription joins or leaves a room on behalf of the client. Auction
+// rooms are public; chat rooms may only be joined by one of their members.
+func (c *Client) handleSubscription(subscribe bool, roomType, id string) {
+	if id == "" || (roomType != RoomAuction && roomType != RoomChat) {
+		return
+	}
+	if subscribe && roomType == RoomChat && !isChatMember(id, c.ID) {
+		return
+	}
+	c.hub.mu.Lock()
+	defer c.hub.mu.Unlock()
+	if subscribe {
+		c.hub.join(c, roomType, id)
+	} else {
+		c.hub.leave(c, roomType, id)
+	}
+}
+
+// inChat reports whether the client has joined the given chat room. The room
+// from the connect URL always counts, even before Run has registered it.
+func (c *Client) inChat(roomID string) bool {
+	if roomID == c.RoomID {
+		return true
+	}
+	c.hub.mu.RLock()
+	defer c.hub.mu.RUnlock()
+	_, ok := c.chats[roomID]
+	return ok
+}
+
+// NextMessageSeq draws a value from the messages.seq sequence without
+// inserting a row, so a message swallowed by a block still gets a plausible
+// seq. The gap it leaves is harmless; seq only orders, it doesn't count.
+func NextMessageSeq(ctx context.Context, db *pgxpool.Pool) (int64, error) {
+	var seq int64
+	err := db.QueryRow(ctx,
+		`SELECT nextval(pg_get_serial_sequence('messages', 'seq'))`).Scan(&seq)
+	return seq, err
+}
+
+// isChatMember reports whether userID is one of the two members of a chat
+// room. Anonymous clients are never members.
+func isChatMember(roomID, userID string) bool {
+	a, b, _ := strings.Cut(roomID, "_")
+	return userID != "" && (a == userID || b == userID)
+}
+
+// RoomPeer returns the other member of a chat room, whose id is the two
+// members' ids joined by "_".
+func RoomPeer(roomID, userID string) string {
+	a, b, _ := strings.Cut(roomID, "_")
+	if a == userID {
+		return b
+	}
+	return a
+}
+
+// broadcastTyping tells the other members of a chat room that the client is
+// typing. Typing events are ephemeral and never persisted.
+func (c *Client) broadcastTyping(roomID string) {
+	if c.name == "" {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		_ = c.hub.db.QueryRow(ctx, `SELECT name FROM users WHERE id = $1`, c.ID).Scan(&c.name)
+		cancel()
+	}
+	payload, _ := json.Marshal(struct {
+		RoomID   string `json:"room_id"`
+		UserID   string `json:"user_id"`
+		UserName string `json:"user_name"`
+	}{roomID, c.ID, c.name})
+	c.hub.broadcastToChat(roomID, Message{Type: TypeTyping, Payload: payload}, c)
+}
+
+// sendError queues a chat_error frame for this client only. tempID is the
+// client's id for the failed message, echoed back so it can be matched.
+func (c *Client) sendError(tempID, reason string) {
+	payload, _ := json.Marshal(struct {
+		TempID string `json:"temp_id,omitempty"`
+		Error  string `json:"error"`
+	}{tempID, reason})
+	data, _ := json.Marshal(Message{Type: TypeChatError, Payload: payload})
+	select {
+	case c.send <- data:
+	default:
+		log.Printf("hub: dropped error frame for user %s", c.ID)
 	}
 }
 
-// writePump sends queued messages to the WebSocket connection.
+// writePump sends queued messages to the WebSocket connection and pings the
+// client every pingPeriod. A failed write closes the connection, which makes
+// readPump return and unregister the client.
 func (c *Client) writePump() {
-	defer c.conn.Close()
-	for msg := range c.send {
-		if err := c.conn.WriteMessage(websocket.TextMessage, msg); err != nil {
-			break
+	ticker := time.NewTicker(pingPeriod)
+	defer func() {
+		ticker.Stop()
+		c.conn.Close()
+	}()
+	for {
+		select {
+		case msg, ok := <-c.send:
+			c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+			if !ok {
+				// The hub closed the channel.
+				c.conn.WriteMessage(websocket.CloseMessage, []byte{})
+				return
+			}
+			if err := c.conn.WriteMessage(websocket.TextMessage, msg); err != nil {
+				return
+			}
+		case <-ticker.C:
+			c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+			if err := c.conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				return
+			}
 		}
 	}
 }
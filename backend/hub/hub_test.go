@@ -0,0 +1,199 @@
+package hub
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+func testClient(h *Hub, userID, roomID string) *Client {
+	return &Client{
+		ID:       userID,
+		RoomID:   roomID,
+		auctions: make(map[string]struct{}),
+		chats:    make(map[string]struct{}),
+		send:     make(chan []byte, 8),
+		hub:      h,
+	}
+}
+
+func drain(c *Client) []Message {
+	var out []Message
+	for {
+		select {
+		case data, ok := <-c.send:
+			if !ok {
+				return out
+			}
+			var m Message
+			json.Unmarshal(data, &m)
+			out = append(out, m)
+		default:
+			return out
+		}
+	}
+}
+
+func TestSendToUserReachesEverySocket(t *testing.T) {
+	h := NewHub(nil)
+	tab, phone := testClient(h, "u1", ""), testClient(h, "u1", "")
+	other := testClient(h, "u2", "")
+	h.addClient(tab)
+	h.addClient(phone)
+	h.addClient(other)
+
+	h.SendToUser("u1", Message{Type: TypeNotification, Payload: json.RawMessage(`{}`)})
+
+	for name, c := range map[string]*Client{"tab": tab, "phone": phone} {
+		if got := drain(c); len(got) != 1 || got[0].Type != TypeNotification {
+			t.Errorf("%s got %v, want one notification", name, got)
+		}
+	}
+	if got := drain(other); len(got) != 0 {
+		t.Errorf("other user got %v", got)
+	}
+}
+
+func TestPresenceStaysOnlineWhileASocketRemains(t *testing.T) {
+	h := NewHub(nil)
+	room := "u1_u2"
+	peer := testClient(h, "u2", room)
+	h.addClient(peer)
+
+	first, second := testClient(h, "u1", room), testClient(h, "u1", room)
+	h.addClient(first)
+	h.addClient(second)
+	if got := presences(drain(peer)); len(got) != 1 || !got[0] {
+		t.Fatalf("presence after two sockets = %v, want one online", got)
+	}
+
+	h.removeClient(first)
+	if !h.IsOnline("u1") {
+		t.Fatal("user went offline with a socket still open")
+	}
+	if got := presences(drain(peer)); len(got) != 0 {
+		t.Fatalf("closing one of two sockets announced %v", got)
+	}
+
+	h.removeClient(second)
+	if h.IsOnline("u1") {
+		t.Fatal("user still online after every socket closed")
+	}
+	if got := presences(drain(peer)); len(got) != 1 || got[0] {
+		t.Fatalf("presence after last socket = %v, want one offline", got)
+	}
+}
+
+func presences(msgs []Message) []bool {
+	var out []bool
+	for _, m := range msgs {
+		if m.Type != TypePresence {
+			continue
+		}
+		var p struct {
+			Online bool `json:"online"`
+		}
+		json.Unmarshal(m.Payload, &p)
+		out = append(out, p.Online)
+	}
+	return out
+}
+
+// TestTrySendAfterDisconnect checks that a send racing a disconnect, as a
+// notification flush can, is dropped instead of panicking on the closed
+// channel.
+func TestTrySendAfterDisconnect(t *testing.T) {
+	h := NewHub(nil)
+	c := testClient(h, "u1", "")
+	h.addClient(c)
+	if !h.trySend(c, []byte(`{}`)) {
+		t.Fatal("send to a live client dropped")
+	}
+	h.removeClient(c)
+	if h.trySend(c, []byte(`{}`)) {
+		t.Error("send to a disconnected client reported as queued")
+	}
+}
+
+// TestChatRoomsNeedMembership checks that only the two members of a chat
+// room can subscribe to it; anonymous sockets and strangers are ignored.
+func TestChatRoomsNeedMembership(t *testing.T) {
+	h := NewHub(nil)
+	for _, user := range []string{"", "u3", "u1"} {
+		c := testClient(h, user, "")
+		h.addClient(c)
+		c.handleSubscription(true, RoomChat, "u1_u2")
+		if got, want := c.inChat("u1_u2"), user == "u1"; got != want {
+			t.Errorf("user %q in room = %v, want %v", user, got, want)
+		}
+	}
+}
+
+// TestFailedChatInsertSendsErrorFrame checks that when a chat message can't
+// be stored the sender gets a chat_error echoing its temp_id and the other
+// member of the room gets nothing.
+func TestFailedChatInsertSendsErrorFrame(t *testing.T) {
+	// Nothing listens on port 1, so every query fails to connect.
+	pool, err := pgxpool.New(context.Background(), "postgres://test@127.0.0.1:1/test?connect_timeout=1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pool.Close()
+	h := NewHub(pool)
+	go h.Run()
+	peer := testClient(h, "u2", "u1_u2")
+	h.addClient(peer)
+
+	upgrader := websocket.Upgrader{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		h.NewClient("u1", "", "u1_u2", conn)
+	}))
+	defer srv.Close()
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	if err := conn.WriteJSON(map[string]any{
+		"type":    "chat_send",
+		"payload": map[string]any{"body": "hello", "temp_id": "tmp-1"},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	conn.SetReadDeadline(time.Now().Add(10 * time.Second))
+	for {
+		var m Message
+		if err := conn.ReadJSON(&m); err != nil {
+			t.Fatalf("no chat_error frame: %v", err)
+		}
+		if m.Type != TypeChatError {
+			continue
+		}
+		var p struct {
+			TempID string `json:"temp_id"`
+			Error  string `json:"error"`
+		}
+		json.Unmarshal(m.Payload, &p)
+		if p.TempID != "tmp-1" || p.Error == "" {
+			t.Errorf("chat_error payload = %+v, want temp_id tmp-1 and a reason", p)
+		}
+		break
+	}
+	for _, m := range drain(peer) {
+		if m.Type == TypeChatMessage {
+			t.Error("unsaved message was broadcast to the room")
+		}
+	}
+}
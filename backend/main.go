@@ -4,17 +4,19 @@ import (
 	"context"
 	"log"
 	"net/http"
-	"os"
 	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 	"github.com/go-chi/cors"
 	"github.com/gorilla/websocket"
+	"github.com/karti/orange-city-mart/backend/config"
 	"github.com/karti/orange-city-mart/backend/db"
 	"github.com/karti/orange-city-mart/backend/handlers"
 	"github.com/karti/orange-city-mart/backend/hub"
+	"github.com/karti/orange-city-mart/backend/metrics"
 	authmw "github.com/karti/orange-city-mart/backend/middleware"
+	"github.com/karti/orange-city-mart/backend/requestid"
 )
 
 var upgrader = websocket.Upgrader{
@@ -26,9 +28,23 @@ var upgrader = websocket.Upgrader{
 }
 
 func main() {
+	// ── Config ────────────────────────────────────────────────────────────
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("invalid configuration: %v", err)
+	}
+	auth := authmw.NewAuth(cfg)
+	api := &handlers.Handler{Config: cfg, Auth: auth}
+	if len(cfg.DepositSigningSecret) == 0 {
+		log.Println("⚠️  DEPOSIT_SIGNING_SECRET is not set: wallet deposits are disabled")
+	}
+	if len(cfg.TOTPEncryptionKey) == 0 {
+		log.Println("⚠️  TOTP_ENCRYPTION_KEY is not set: two-factor authentication is unavailable")
+	}
+
 	// ── Database ──────────────────────────────────────────────────────────
 	ctx := context.Background()
-	if err := db.Connect(ctx); err != nil {
+	if err := db.Connect(ctx, cfg.DatabaseURL); err != nil {
 		log.Fatalf("cannot connect to database: %v", err)
 	}
 	log.Println("✅ Connected to PostgreSQL")
@@ -38,100 +54,203 @@ func main() {
 	go appHub.Run()
 
 	// ── Handlers ──────────────────────────────────────────────────────────
-	auctionHandler := &handlers.AuctionHandler{Hub: appHub}
-	chatHandler := &handlers.ChatHandler{Hub: appHub}
+	if m := handlers.NewSMTPMailer(cfg); m != nil {
+		handlers.SetMailer(m)
+		handlers.SetNotifier(handlers.SMTPNotifier{Mailer: m})
+	}
+	s3Storage := handlers.NewS3Storage(cfg)
+	if s3Storage != nil {
+		handlers.SetStorage(s3Storage)
+	}
+	auctionHandler := &handlers.AuctionHandler{Handler: api, Hub: appHub}
+	chatHandler := &handlers.ChatHandler{Handler: api, Hub: appHub}
+
+	// ── Background workers ────────────────────────────────────────────────
+	go auctionHandler.RunAuctionSweeper(ctx, cfg.SweepInterval, cfg.SweepBatch)
+	go authmw.RunRevocationCleanup(ctx, time.Hour)
+	handlers.RunNotifyWorkers(ctx, cfg.NotifyWorkers)
 
 	// ── Router ────────────────────────────────────────────────────────────
 	r := chi.NewRouter()
 
 	// Middleware must all come before any route/handle registrations
-	r.Use(middleware.Logger)
+	r.Use(authmw.RequestID)
 	r.Use(middleware.Recoverer)
 	r.Use(middleware.Timeout(30 * time.Second))
-	isLocal := os.Getenv("FRONTEND_URL") == ""
+	if cfg.MetricsEnabled {
+		r.Use(metrics.Middleware)
+	}
 
 	corsOptions := cors.Options{
-		AllowedMethods: []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowedHeaders: []string{"Accept", "Authorization", "Content-Type", "multipart/form-data"},
+		AllowedMethods: []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
+		AllowedHeaders: []string{"Accept", "Authorization", "Content-Type", "multipart/form-data", "X-Signature", requestid.Header},
+		ExposedHeaders: []string{authmw.RenewedTokenHeader, requestid.Header},
 	}
-	if isLocal {
+	if cfg.Local() {
 		// Accept any origin locally — needed for Cloudflare tunnel (trycloudflare.com)
 		corsOptions.AllowOriginFunc = func(r *http.Request, origin string) bool { return true }
 	} else {
-		corsOptions.AllowedOrigins = []string{
-			"http://localhost:5173",
-			"http://frontend:5173",
-			"https://kartnagrale.github.io",
-		}
-		if frontendURL := os.Getenv("FRONTEND_URL"); frontendURL != "" {
-			corsOptions.AllowedOrigins = append(corsOptions.AllowedOrigins, frontendURL)
-		}
+		corsOptions.AllowedOrigins = cfg.CORSOrigins
 		corsOptions.AllowCredentials = true
 	}
 
 	r.Use(cors.Handler(corsOptions))
 
 	// ── Static file server for uploaded images ─────────────────────────────
-	uploadsFS := http.FileServer(http.Dir("./uploads"))
-	r.Handle("/uploads/*", http.StripPrefix("/uploads/", uploadsFS))
+	// Only needed for local-disk storage; S3 objects are served by the bucket.
+	if s3Storage == nil {
+		uploadsFS := http.FileServer(http.Dir("./uploads"))
+		r.Handle("/uploads/*", http.StripPrefix("/uploads/", uploadsFS))
+	}
 
-	// Health
-	r.Get("/health", func(w http.ResponseWriter, r *http.Request) {
-		w.Write([]byte(`{"status":"ok"}`))
-	})
+	// ── Health ────────────────────────────────────────────────────────────
+	// /health is kept as an alias of /livez.
+	r.Get("/health", livez)
+	if cfg.MetricsEnabled {
+		// Unauthenticated; keep it off the public internet at the proxy.
+		r.Get("/metrics", metrics.Handler(appHub, db.Pool))
+	}
+	r.Get("/livez", livez)
+	r.Get("/readyz", readyz(db.Pool, appHub))
 
 	// ── Auth (public) ─────────────────────────────────────────────────────
-	r.Post("/api/auth/register", handlers.Register)
-	r.Post("/api/auth/login", handlers.Login)
+	authLimiter := authmw.NewAuthRateLimiter(cfg)
+	r.With(authLimiter.Limit).Post("/api/auth/register", api.Register)
+	r.With(authLimiter.Login).Post("/api/auth/login", api.Login)
+	r.With(authmw.NewIPRateLimiter(10, time.Minute).Limit).Get("/api/auth/check-email", api.CheckEmail)
+	r.With(authLimiter.Limit).Post("/api/auth/2fa", api.LoginTwoFactor)
+	r.With(authLimiter.Limit).Post("/api/auth/magic-link", api.RequestMagicLink)
+	r.Post("/api/auth/magic-login", api.MagicLogin)
+	r.Post("/api/auth/refresh", api.Refresh)
+	r.Post("/api/auth/logout", api.Logout)
+	r.With(authLimiter.Limit).Post("/api/auth/forgot-password", api.ForgotPassword)
+	r.With(authLimiter.Limit).Post("/api/auth/reset-password", api.ResetPassword)
 
 	// ── Products (public read) ────────────────────────────────────────────
-	r.Get("/api/products", handlers.ListProducts)
-	r.Get("/api/products/{id}", handlers.GetProduct)
+	r.Get("/api/products", api.ListProducts)
+	r.Get("/api/products/suggest", api.SuggestProducts)
+	r.Get("/api/products/batch", api.GetProductsBatch)
+	r.Get("/api/products/{id}", api.GetProduct)
+	r.Get("/api/categories", api.GetCategories)
+
+	// ── Feed (public read) ────────────────────────────────────────────────
+	r.Get("/api/feed/bids", api.GetBidFeed)
 
 	// ── WebSocket ─────────────────────────────────────────────────────────
 	r.Get("/ws", func(w http.ResponseWriter, r *http.Request) {
+		// The identity comes only from a verified ?token=. Without one the
+		// socket is anonymous: it can watch auctions but gets no targeted
+		// events and can't join chat rooms. A bad token is refused outright
+		// rather than downgraded, so the client knows to refresh it.
+		var userID string
+		if token := r.URL.Query().Get("token"); token != "" {
+			id, err := auth.VerifyToken(r.Context(), token)
+			if err != nil {
+				http.Error(w, "invalid or expired token", http.StatusUnauthorized)
+				return
+			}
+			userID = id
+		}
 		conn, err := upgrader.Upgrade(w, r, nil)
 		if err != nil {
 			log.Printf("ws upgrade error: %v", err)
 			return
 		}
-		userID := r.URL.Query().Get("user_id")
 		auctionID := r.URL.Query().Get("auction_id")
 		roomID := r.URL.Query().Get("room_id")
-		appHub.NewClient(userID, auctionID, roomID, conn)
+		c := appHub.NewClient(userID, auctionID, roomID, conn)
+		if userID != "" {
+			go appHub.FlushNotifications(c)
+		}
 	})
 
 	// ── Auctions ──────────────────────────────────────────────────────────
 	r.Route("/api/auctions", func(r chi.Router) {
+		r.Get("/calendar", auctionHandler.GetAuctionCalendar)
 		r.Get("/{id}", auctionHandler.GetAuction)
-		r.Get("/{id}/bids", auctionHandler.GetAuctionBids)
-		r.With(authmw.RequireAuth).Post("/{id}/bid", auctionHandler.PlaceBid)
-		r.With(authmw.RequireAuth).Post("/{id}/settle", auctionHandler.ApproveSettlement)
+		r.With(auth.OptionalAuth).Get("/{id}/bids", auctionHandler.GetAuctionBids)
+		r.Get("/{id}/stats", auctionHandler.GetAuctionStats)
+		r.With(auth.RequireAuth).Get("/{id}/my-position", auctionHandler.GetMyPosition)
+		r.With(auth.RequireAuth).Get("/{id}/my-status", auctionHandler.GetMyStatus)
+		r.With(auth.RequireAuth).Post("/{id}/bid", auctionHandler.PlaceBid)
+		r.With(auth.RequireAuth).Post("/{id}/autobid", auctionHandler.SetAutoBid)
+		r.With(auth.RequireAuth).Post("/{id}/buynow", auctionHandler.BuyNow)
+		r.With(auth.RequireAuth).Post("/{id}/cancel", auctionHandler.CancelAuction)
+		r.With(auth.RequireAuth).Post("/{id}/settle", auctionHandler.ApproveSettlement)
+		r.With(auth.RequireAuth).Post("/{id}/settlement/remind", auctionHandler.RemindSettlement)
+		r.With(auth.RequireAuth).Post("/{id}/dispute", auctionHandler.DisputeSettlement)
+		r.With(auth.RequireAuth).Post("/{id}/settlement/refund", auctionHandler.RefundSettlement)
+		r.With(auth.RequireAuth).Get("/{id}/settlement/shipping", auctionHandler.GetShipping)
+		r.With(auth.RequireAuth).Post("/{id}/settlement/shipping", auctionHandler.SetShippingAddress)
+		r.With(auth.RequireAuth).Post("/{id}/settlement/tracking", auctionHandler.SetTrackingNumber)
+		r.With(auth.RequireAuth).Get("/{id}/receipt", auctionHandler.GetReceipt)
 	})
 
 	// ── Protected routes ──────────────────────────────────────────────────
 	r.Group(func(r chi.Router) {
-		r.Use(authmw.RequireAuth)
-		r.Post("/api/upload", handlers.UploadImage)
-		r.Post("/api/products", handlers.CreateProduct)
-		r.Get("/api/wallet", handlers.GetWallet)
-		r.Post("/api/wallet/deposit", handlers.Deposit)
-		r.Post("/api/wallet/withdraw", handlers.Withdraw)
-		r.Get("/api/bids", handlers.ListMyBids)
+		r.Use(auth.RequireAuth)
+		r.Post("/api/upload", api.UploadImage)
+		r.Post("/api/upload/batch", api.UploadImages)
+		r.Delete("/api/upload", api.DeleteUpload)
+		r.Post("/api/products", api.CreateProduct)
+		r.Put("/api/products/{id}", api.UpdateProduct)
+		r.Delete("/api/products/{id}", api.DeleteProduct)
+		r.Post("/api/products/{id}/feature", api.FeatureProduct)
+		r.Post("/api/products/{id}/report", api.ReportProduct)
+		r.Post("/api/products/{id}/buy", api.BuyProduct)
+		r.Get("/api/wallet", api.GetWallet)
+		r.Get("/api/wallet/transactions", api.ListTransactions)
+		r.Get("/api/wallet/statement.csv", api.ExportStatement)
+		r.Post("/api/wallet/deposit", api.Deposit)
+		r.Post("/api/wallet/withdraw", api.Withdraw)
+		r.Post("/api/wallet/transfer", api.Transfer)
+		r.Get("/api/bids", api.ListMyBids)
+		r.Post("/api/settlements/approve-bulk", auctionHandler.ApproveSettlementsBulk)
+		r.Get("/api/my/auctions", auctionHandler.ListMyAuctions)
+		r.Get("/api/my-products", api.ListMyProducts)
+		r.Get("/api/my-purchases", api.ListMyPurchases)
+		r.Get("/api/my/sales/export", api.ExportSales)
+		r.Delete("/api/me", api.DeleteAccount)
+		r.Post("/api/me/2fa/enable", api.EnableTwoFactor)
+		r.Post("/api/me/2fa/verify", api.VerifyTwoFactor)
+		r.Post("/api/me/2fa/disable", api.DisableTwoFactor)
+		r.Put("/api/me/notification-preferences", api.UpdateNotificationPreferences)
+		r.Get("/api/onboarding", api.GetOnboarding)
+		r.Get("/api/notifications", api.ListNotifications)
+		r.Post("/api/notifications/{id}/read", api.MarkNotificationRead)
+		r.Get("/api/watchlist", api.ListWatchlist)
+		r.Post("/api/watchlist/{productId}", api.AddToWatchlist)
+		r.Delete("/api/watchlist/{productId}", api.RemoveFromWatchlist)
 
 		// ── Chat ──────────────────────────────────────────────────────────
 		r.Get("/api/chat/conversations", chatHandler.GetConversations)
 		r.Get("/api/chat/rooms/{roomId}/messages", chatHandler.GetMessages)
 		r.Post("/api/chat/rooms/{roomId}/messages", chatHandler.SendMessage)
+		r.Patch("/api/chat/rooms/{roomId}/messages/{id}", chatHandler.EditMessage)
+		r.Delete("/api/chat/rooms/{roomId}/messages/{id}", chatHandler.DeleteMessage)
+		r.Post("/api/chat/rooms/{roomId}/hide", chatHandler.HideConversation)
+		r.Post("/api/chat/rooms/{roomId}/read", chatHandler.MarkRoomRead)
+		r.Post("/api/chat/blocks", chatHandler.BlockUser)
+		r.Delete("/api/chat/blocks/{userId}", chatHandler.UnblockUser)
+	})
+
+	// ── Admin ─────────────────────────────────────────────────────────────
+	r.Group(func(r chi.Router) {
+		r.Use(auth.RequireAuth, authmw.RequireAdmin)
+		r.Get("/api/admin/users", api.ListUsers)
+		r.Post("/api/admin/users/{id}/freeze", api.SetUserFrozen)
+		r.Get("/api/admin/holds", api.ListHolds)
+		r.Get("/api/admin/reports", api.ListReports)
+		r.Post("/api/admin/reports/{id}/resolve", api.ResolveReport)
+		r.Post("/api/admin/withdrawals/{id}/resolve", api.ResolveTransaction)
+		r.Post("/api/admin/transactions/{id}/resolve", api.ResolveTransaction)
+		r.Post("/api/admin/auctions/{id}/cancel", auctionHandler.AdminCancelAuction)
+		r.Post("/api/admin/auctions/{id}/dispute/resolve", auctionHandler.ResolveDispute)
 	})
 
 	// ── Server ────────────────────────────────────────────────────────────
-	port := os.Getenv("PORT")
-	if port == "" {
-		port = "8080"
-	}
-	log.Printf("🚀 Orange City Mart backend listening on :%s", port)
-	if err := http.ListenAndServe(":"+port, r); err != nil {
+	log.Printf("🚀 Orange City Mart backend listening on :%s", cfg.Port)
+	if err := http.ListenAndServe(":"+cfg.Port, r); err != nil {
 		log.Fatalf("server error: %v", err)
 	}
 }
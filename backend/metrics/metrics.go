@@ -0,0 +1,219 @@
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// latencyBuckets are the upper bounds, in seconds, of the request latency
+// histogram (the Prometheus client defaults).
+var latencyBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+type routeKey struct{ method, route string }
+
+// routeStats is one route's request counts by status and latency histogram.
+type routeStats struct {
+	byStatus map[int]uint64
+	buckets  []uint64 // cumulative counts per latencyBuckets bound
+	count    uint64
+	sum      float64
+}
+
+var (
+	mu     sync.Mutex
+	routes = map[routeKey]*routeStats{}
+
+	bids             uint64
+	deposits         uint64
+	depositAmount    float64
+	withdrawals      uint64
+	withdrawalAmount float64
+)
+
+// Middleware records each request's count and latency under its chi route
+// pattern, so path parameters don't fan out into one series per id.
+// Requests that match no route share the "unmatched" label.
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+		next.ServeHTTP(ww, r)
+
+		route := "unmatched"
+		if rctx := chi.RouteContext(r.Context()); rctx != nil {
+			if p := rctx.RoutePattern(); p != "" {
+				route = p
+			}
+		}
+		status := ww.Status()
+		if status == 0 {
+			status = http.StatusOK
+		}
+		observe(routeKey{methodLabel(r.Method), route}, status, time.Since(start).Seconds())
+	})
+}
+
+// knownMethods are the request methods recorded under their own name.
+var knownMethods = map[string]bool{
+	http.MethodGet: true, http.MethodHead: true, http.MethodPost: true,
+	http.MethodPut: true, http.MethodPatch: true, http.MethodDelete: true,
+	http.MethodConnect: true, http.MethodOptions: true, http.MethodTrace: true,
+}
+
+// methodLabel maps any other method, which a client can make up freely, to
+// "OTHER" so it can't grow the routes map without bound.
+func methodLabel(m string) string {
+	if knownMethods[m] {
+		return m
+	}
+	return "OTHER"
+}
+
+func observe(k routeKey, status int, secs float64) {
+	mu.Lock()
+	defer mu.Unlock()
+	s := routes[k]
+	if s == nil {
+		s = &routeStats{byStatus: map[int]uint64{}, buckets: make([]uint64, len(latencyBuckets))}
+		routes[k] = s
+	}
+	s.byStatus[status]++
+	s.count++
+	s.sum += secs
+	for i, le := range latencyBuckets {
+		if secs <= le {
+			s.buckets[i]++
+		}
+	}
+}
+
+// BidPlaced counts one committed bid, manual or automatic.
+func BidPlaced() {
+	mu.Lock()
+	bids++
+	mu.Unlock()
+}
+
+// Deposit counts one completed wallet deposit of amount.
+func Deposit(amount float64) {
+	mu.Lock()
+	deposits++
+	depositAmount += amount
+	mu.Unlock()
+}
+
+// Withdrawal counts one accepted withdrawal request of amount.
+func Withdrawal(amount float64) {
+	mu.Lock()
+	withdrawals++
+	withdrawalAmount += amount
+	mu.Unlock()
+}
+
+// ClientCounter reports the number of live WebSocket connections.
+type ClientCounter interface {
+	ClientCount() int
+}
+
+// Handler serves every metric in the Prometheus text exposition format.
+// clients and pool are sampled at scrape time.
+func Handler(clients ClientCounter, pool *pgxpool.Pool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		writeRoutes(w)
+
+		mu.Lock()
+		b, d, da, wd, wa := bids, deposits, depositAmount, withdrawals, withdrawalAmount
+		mu.Unlock()
+		writeMetric(w, "bids_placed_total", "counter", "Bids placed, including automatic bids.", b)
+		writeMetric(w, "wallet_deposits_total", "counter", "Completed wallet deposits.", d)
+		writeMetric(w, "wallet_deposit_amount_total", "counter", "Sum of completed wallet deposits.", da)
+		writeMetric(w, "wallet_withdrawals_total", "counter", "Withdrawal requests accepted.", wd)
+		writeMetric(w, "wallet_withdrawal_amount_total", "counter", "Sum of withdrawal requests accepted.", wa)
+
+		if clients != nil {
+			writeMetric(w, "websocket_clients", "gauge", "Connected WebSocket clients.", clients.ClientCount())
+		}
+
+		if pool != nil {
+			st := pool.Stat()
+			writeMetric(w, "db_pool_acquired_conns", "gauge", "Connections currently checked out of the pool.", st.AcquiredConns())
+			writeMetric(w, "db_pool_idle_conns", "gauge", "Idle connections in the pool.", st.IdleConns())
+			writeMetric(w, "db_pool_total_conns", "gauge", "Open connections in the pool.", st.TotalConns())
+			writeMetric(w, "db_pool_max_conns", "gauge", "Maximum size of the pool.", st.MaxConns())
+			writeMetric(w, "db_pool_acquires_total", "counter", "Successful connection acquires.", st.AcquireCount())
+			writeMetric(w, "db_pool_empty_acquires_total", "counter", "Acquires that had to wait for a connection.", st.EmptyAcquireCount())
+			writeMetric(w, "db_pool_canceled_acquires_total", "counter", "Acquires canceled by their context.", st.CanceledAcquireCount())
+			writeMetric(w, "db_pool_acquire_duration_seconds_total", "counter", "Time spent acquiring connections.", st.AcquireDuration().Seconds())
+		}
+	}
+}
+
+// writeRoutes writes the per-route request counter and latency histogram,
+// sorted so scrapes diff cleanly.
+func writeRoutes(w io.Writer) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	keys := make([]routeKey, 0, len(routes))
+	for k := range routes {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].route != keys[j].route {
+			return keys[i].route < keys[j].route
+		}
+		return keys[i].method < keys[j].method
+	})
+
+	fmt.Fprintln(w, "# HELP http_requests_total HTTP requests by method, route and status.")
+	fmt.Fprintln(w, "# TYPE http_requests_total counter")
+	for _, k := range keys {
+		s := routes[k]
+		statuses := make([]int, 0, len(s.byStatus))
+		for st := range s.byStatus {
+			statuses = append(statuses, st)
+		}
+		sort.Ints(statuses)
+		for _, st := range statuses {
+			fmt.Fprintf(w, "http_requests_total{method=%s,route=%s,status=\"%d\"} %d\n",
+				quote(k.method), quote(k.route), st, s.byStatus[st])
+		}
+	}
+
+	fmt.Fprintln(w, "# HELP http_request_duration_seconds HTTP request latency by method and route.")
+	fmt.Fprintln(w, "# TYPE http_request_duration_seconds histogram")
+	for _, k := range keys {
+		s := routes[k]
+		labels := "method=" + quote(k.method) + ",route=" + quote(k.route)
+		for i, le := range latencyBuckets {
+			fmt.Fprintf(w, "http_request_duration_seconds_bucket{%s,le=\"%s\"} %d\n",
+				labels, strconv.FormatFloat(le, 'g', -1, 64), s.buckets[i])
+		}
+		fmt.Fprintf(w, "http_request_duration_seconds_bucket{%s,le=\"+Inf\"} %d\n", labels, s.count)
+		fmt.Fprintf(w, "http_request_duration_seconds_sum{%s} %s\n", labels, strconv.FormatFloat(s.sum, 'g', -1, 64))
+		fmt.Fprintf(w, "http_request_duration_seconds_count{%s} %d\n", labels, s.count)
+	}
+}
+
+func writeMetric(w io.Writer, name, typ, help string, v any) {
+	if f, ok := v.(float64); ok {
+		v = strconv.FormatFloat(f, 'g', -1, 64)
+	}
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n%s %v\n", name, help, name, typ, name, v)
+}
+
+// quote renders a label value with the escapes the text format requires.
+func quote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(s) + `"`
+}
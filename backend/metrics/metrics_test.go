@@ -0,0 +1,37 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestMethodLabel(t *testing.T) {
+	for in, want := range map[string]string{
+		"GET": "GET", "POST": "POST", "OPTIONS": "OPTIONS",
+		"get": "OTHER", "BREW": "OTHER", "X" + strings.Repeat("A", 100): "OTHER",
+	} {
+		if got := methodLabel(in); got != want {
+			t.Errorf("methodLabel(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestMiddlewareFoldsUnknownMethods(t *testing.T) {
+	h := Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	for _, m := range []string{"FOO1", "FOO2", "FOO3"} {
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(m, "/", nil))
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	for k := range routes {
+		if strings.HasPrefix(k.method, "FOO") {
+			t.Fatalf("method %q recorded verbatim", k.method)
+		}
+	}
+	if s := routes[routeKey{"OTHER", "unmatched"}]; s == nil || s.count < 3 {
+		t.Fatalf("OTHER series = %+v, want 3 requests", s)
+	}
+}
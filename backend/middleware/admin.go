@@ -0,0 +1,20 @@
+package middleware
+
+import "net/http"
+
+// RequireAdmin rejects callers whose users.role is not 'admin' with 403.
+// It must run after RequireAuth, which loads the caller's claims.
+func RequireAdmin(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		claims, ok := ClaimsFromContext(r.Context())
+		if !ok {
+			WriteError(w, http.StatusUnauthorized, "unauthorized", "unauthorized")
+			return
+		}
+		if !claims.IsAdmin() {
+			WriteError(w, http.StatusForbidden, "admin_required", "admin access required")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -3,10 +3,12 @@ package middleware
 import (
 	"context"
 	"net/http"
-	"os"
 	"strings"
+	"time"
 
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/jackc/pgx/v5"
+	"github.com/karti/orange-city-mart/backend/config"
 )
 
 // contextKey is an unexported type for context keys in this package.
@@ -14,48 +16,155 @@ type contextKey string
 
 const UserIDKey contextKey = "userID"
 
+// Auth signs, verifies and revokes access tokens with the configuration
+// loaded at startup. main builds one with NewAuth and hands it to the router
+// and the handlers.
+type Auth struct {
+	cfg *config.Config
+}
+
+// NewAuth returns an Auth using cfg. It panics when cfg carries no JWT
+// secret, since every token would then be forgeable.
+func NewAuth(cfg *config.Config) *Auth {
+	if cfg == nil || len(cfg.JWTSecret) == 0 {
+		panic("middleware: NewAuth needs a configuration with a JWT secret")
+	}
+	return &Auth{cfg: cfg}
+}
+
 // RequireAuth validates the Authorization: Bearer <token> header.
-// On success it stores the userID (JWT "sub" claim) in the request context.
+// Tokens whose "jti" has been revoked by logout are rejected.
+// On success it stores the userID (JWT "sub" claim) and the user's current
+// Claims, read from the database, in the request context and, when the token
+// is about to expire, sets RenewedTokenHeader.
 // On failure it responds with 401.
-func RequireAuth(next http.Handler) http.Handler {
+func (a *Auth) RequireAuth(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		authHeader := r.Header.Get("Authorization")
 		if authHeader == "" || !strings.HasPrefix(authHeader, "Bearer ") {
-			http.Error(w, "missing or invalid Authorization header", http.StatusUnauthorized)
+			WriteError(w, http.StatusUnauthorized, "missing_token", "missing or invalid Authorization header")
 			return
 		}
 
 		tokenStr := strings.TrimPrefix(authHeader, "Bearer ")
-		secret := os.Getenv("JWT_SECRET")
 
-		token, err := jwt.Parse(tokenStr, func(t *jwt.Token) (interface{}, error) {
-			if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
-				return nil, jwt.ErrSignatureInvalid
-			}
-			return []byte(secret), nil
-		})
-		if err != nil || !token.Valid {
-			http.Error(w, "invalid or expired token", http.StatusUnauthorized)
+		claims, err := a.parseToken(tokenStr)
+		if err != nil {
+			WriteError(w, http.StatusUnauthorized, "invalid_token", "invalid or expired token")
 			return
 		}
 
-		claims, ok := token.Claims.(jwt.MapClaims)
-		if !ok {
-			http.Error(w, "invalid token claims", http.StatusUnauthorized)
+		userID, ok := claims["sub"].(string)
+		if !ok || userID == "" {
+			WriteError(w, http.StatusUnauthorized, "invalid_token", "invalid token subject")
 			return
 		}
 
-		userID, ok := claims["sub"].(string)
-		if !ok || userID == "" {
-			http.Error(w, "invalid token subject", http.StatusUnauthorized)
+		jti, _ := claims["jti"].(string)
+		if jti == "" {
+			WriteError(w, http.StatusUnauthorized, "invalid_token", "invalid token id")
 			return
 		}
+		revoked, err := isRevoked(r.Context(), jti)
+		if err != nil {
+			WriteError(w, http.StatusInternalServerError, "database_error", "database error")
+			return
+		}
+		if revoked {
+			WriteError(w, http.StatusUnauthorized, "token_revoked", "token has been revoked")
+			return
+		}
+
+		account, err := a.loadClaims(r.Context(), userID)
+		if err == pgx.ErrNoRows {
+			WriteError(w, http.StatusUnauthorized, "account_deleted", "account no longer exists")
+			return
+		}
+		if err != nil {
+			WriteError(w, http.StatusInternalServerError, "database_error", "database error")
+			return
+		}
+
+		// Sliding session: a request made close to expiry gets a renewed
+		// token, so only an idle client is eventually logged out. Tokens
+		// minted without a session id aren't renewed.
+		sid, _ := claims["sid"].(string)
+		if window := a.cfg.SessionRenewWindow; window > 0 && sid != "" {
+			if exp, err := claims.GetExpirationTime(); err == nil && exp != nil && time.Until(exp.Time) < window {
+				if renewed := a.renewSession(r.Context(), sid, userID, account.Role); renewed != "" {
+					w.Header().Set(RenewedTokenHeader, renewed)
+				}
+			}
+		}
 
 		ctx := context.WithValue(r.Context(), UserIDKey, userID)
+		ctx = context.WithValue(ctx, claimsKey, account)
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
 
+// OptionalAuth identifies the caller when a valid bearer token is present,
+// storing the userID like RequireAuth, but lets anonymous requests (and
+// ones with a bad token) through unidentified. For public routes whose
+// response is richer for a signed-in caller.
+func (a *Auth) OptionalAuth(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authHeader := r.Header.Get("Authorization")
+		if !strings.HasPrefix(authHeader, "Bearer ") {
+			next.ServeHTTP(w, r)
+			return
+		}
+		userID, err := a.VerifyToken(r.Context(), strings.TrimPrefix(authHeader, "Bearer "))
+		if err != nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+		ctx := context.WithValue(r.Context(), UserIDKey, userID)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// parseToken verifies an HS256 access token and returns its claims.
+func (a *Auth) parseToken(tokenStr string) (jwt.MapClaims, error) {
+	token, err := jwt.Parse(tokenStr, func(t *jwt.Token) (interface{}, error) {
+		if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, jwt.ErrSignatureInvalid
+		}
+		return a.cfg.JWTSecret, nil
+	})
+	if err != nil || !token.Valid {
+		return nil, jwt.ErrTokenInvalidClaims
+	}
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return nil, jwt.ErrTokenInvalidClaims
+	}
+	return claims, nil
+}
+
+// VerifyToken checks an access token presented outside the Authorization
+// header, such as on a WebSocket upgrade, and returns its user id. Revoked
+// tokens are rejected as in RequireAuth.
+func (a *Auth) VerifyToken(ctx context.Context, tokenStr string) (string, error) {
+	claims, err := a.parseToken(tokenStr)
+	if err != nil {
+		return "", err
+	}
+	userID, _ := claims["sub"].(string)
+	jti, _ := claims["jti"].(string)
+	if userID == "" || jti == "" {
+		return "", jwt.ErrTokenInvalidClaims
+	}
+	revoked, err := isRevoked(ctx, jti)
+	if err != nil {
+		return "", err
+	}
+	if revoked {
+		return "", jwt.ErrTokenInvalidClaims
+	}
+	return userID, nil
+}
+
 // UserIDFromContext extracts the userID that RequireAuth stored in the context.
 func UserIDFromContext(ctx context.Context) (string, bool) {
 	id, ok := ctx.Value(UserIDKey).(string)
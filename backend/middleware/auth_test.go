@@ -0,0 +1,54 @@
+package middleware
+
+import (
+	"testing"
+	"time"
+
+	"github.com/karti/orange-city-mart/backend/config"
+)
+
+func testAuth(secret string) *Auth {
+	c := config.Default()
+	c.JWTSecret = []byte(secret)
+	return NewAuth(c)
+}
+
+func TestNewAuthRejectsEmptyJWTSecret(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatal("NewAuth accepted a configuration without a JWT secret")
+		}
+	}()
+	NewAuth(config.Default())
+}
+
+// TestSignTokenUsesConfiguredKeyAndTTL checks that a token verifies only
+// under the key it was signed with and expires after AccessTokenTTL.
+func TestSignTokenUsesConfiguredKeyAndTTL(t *testing.T) {
+	a := testAuth("0123456789abcdef0123456789abcdef")
+	a.cfg.AccessTokenTTL = time.Minute
+	token, err := a.SignToken("u1", "user", "s1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	claims, err := a.parseToken(token)
+	if err != nil {
+		t.Fatalf("parseToken: %v", err)
+	}
+	if claims["sub"] != "u1" || claims["sid"] != "s1" {
+		t.Errorf("claims = %v", claims)
+	}
+	exp, err := claims.GetExpirationTime()
+	if err != nil || exp == nil {
+		t.Fatalf("exp: %v", err)
+	}
+	if left := time.Until(exp.Time); left > time.Minute || left < 50*time.Second {
+		t.Errorf("token lives %v, want about a minute", left)
+	}
+
+	other := testAuth("fedcba9876543210fedcba9876543210")
+	if _, err := other.parseToken(token); err == nil {
+		t.Error("token verified under a different key")
+	}
+}
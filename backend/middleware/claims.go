@@ -0,0 +1,88 @@
+package middleware
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/karti/orange-city-mart/backend/db"
+)
+
+// Claims is the caller's current account state. RequireAuth loads it from
+// the database rather than trusting the token, so a role change or email
+// verification applies on the user's next request, not their next login.
+type Claims struct {
+	Role          string
+	EmailVerified bool
+	IsFrozen      bool
+}
+
+// IsAdmin reports whether the caller has the admin role.
+func (c Claims) IsAdmin() bool { return c.Role == "admin" }
+
+const claimsKey contextKey = "claims"
+
+type cachedClaims struct {
+	claims  Claims
+	expires time.Time
+}
+
+var claimsCache = struct {
+	sync.Mutex
+	m map[string]cachedClaims
+}{m: make(map[string]cachedClaims)}
+
+// loadClaims returns the user's claims, from the short-lived cache when
+// possible; ClaimsCacheTTL bounds how stale they may be and zero disables
+// caching. A deleted user yields pgx.ErrNoRows.
+func (a *Auth) loadClaims(ctx context.Context, userID string) (Claims, error) {
+	now := time.Now()
+	claimsCache.Lock()
+	if c, ok := claimsCache.m[userID]; ok && now.Before(c.expires) {
+		claimsCache.Unlock()
+		return c.claims, nil
+	}
+	claimsCache.Unlock()
+
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+
+	var c Claims
+	err := db.Pool.QueryRow(ctx, `
+		SELECT role, email_verified, is_frozen FROM users WHERE id = $1`, userID,
+	).Scan(&c.Role, &c.EmailVerified, &c.IsFrozen)
+	if err != nil {
+		return Claims{}, err
+	}
+
+	if ttl := a.cfg.ClaimsCacheTTL; ttl > 0 {
+		claimsCache.Lock()
+		// Drop expired entries opportunistically so the map can't grow
+		// without bound.
+		if len(claimsCache.m) > 10000 {
+			for id, e := range claimsCache.m {
+				if now.After(e.expires) {
+					delete(claimsCache.m, id)
+				}
+			}
+		}
+		claimsCache.m[userID] = cachedClaims{claims: c, expires: now.Add(ttl)}
+		claimsCache.Unlock()
+	}
+	return c, nil
+}
+
+// InvalidateClaims drops a user's cached claims. Call it after changing a
+// user's role, verification or frozen status so the change applies at once
+// on this instance.
+func InvalidateClaims(userID string) {
+	claimsCache.Lock()
+	delete(claimsCache.m, userID)
+	claimsCache.Unlock()
+}
+
+// ClaimsFromContext returns the claims RequireAuth stored in the context.
+func ClaimsFromContext(ctx context.Context) (Claims, bool) {
+	c, ok := ctx.Value(claimsKey).(Claims)
+	return c, ok
+}
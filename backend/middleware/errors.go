@@ -0,0 +1,21 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// WriteError sends the JSON error envelope used across the API:
+//
+//	{ "error": { "code": "auction_not_found", "message": "auction not found" } }
+//
+// code is a stable machine-readable identifier for the failure; message is
+// for humans and may change.
+func WriteError(w http.ResponseWriter, status int, code, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]any{
+		"error": map[string]string{"code": code, "message": message},
+	})
+}
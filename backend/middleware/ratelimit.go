@@ -0,0 +1,207 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/karti/orange-city-mart/backend/config"
+)
+
+// bucket is a token bucket: it refills continuously at the limiter's rate up
+// to its burst size and each attempt spends one token.
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// limiter is an in-memory set of token buckets keyed by an arbitrary string.
+// State is per-process, which is acceptable for throttling brute force.
+type limiter struct {
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	burst     float64
+	perSecond float64
+	lastSweep time.Time
+}
+
+func newLimiter(attempts int, window time.Duration) *limiter {
+	return &limiter{
+		buckets:   make(map[string]*bucket),
+		burst:     float64(attempts),
+		perSecond: float64(attempts) / window.Seconds(),
+		lastSweep: time.Now(),
+	}
+}
+
+// allow spends a token for key. When none is left it returns false and how
+// long until one is available.
+func (l *limiter) allow(key string) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	l.sweep(now)
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: l.burst, last: now}
+		l.buckets[key] = b
+	}
+	b.tokens = math.Min(l.burst, b.tokens+now.Sub(b.last).Seconds()*l.perSecond)
+	b.last = now
+
+	if b.tokens < 1 {
+		wait := time.Duration((1 - b.tokens) / l.perSecond * float64(time.Second))
+		return false, wait
+	}
+	b.tokens--
+	return true, 0
+}
+
+// reset forgets key, giving it a full bucket again.
+func (l *limiter) reset(key string) {
+	l.mu.Lock()
+	delete(l.buckets, key)
+	l.mu.Unlock()
+}
+
+// sweep drops buckets that would have refilled completely, at most once a
+// minute, so the map doesn't grow with every IP ever seen.
+func (l *limiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < time.Minute {
+		return
+	}
+	l.lastSweep = now
+	full := time.Duration(l.burst / l.perSecond * float64(time.Second))
+	for k, b := range l.buckets {
+		if now.Sub(b.last) > full {
+			delete(l.buckets, k)
+		}
+	}
+}
+
+// AuthRateLimiter throttles credential endpoints per client IP and per
+// (IP, submitted email) pair. Only a successful login clears anything, and
+// then only that pair's counter: the IP bucket always drains at its own
+// rate, so one valid account can't be used to reset a spraying IP.
+type AuthRateLimiter struct {
+	byIP   *limiter
+	byPair *limiter
+}
+
+// NewAuthRateLimiter allows cfg.AuthRateLimitIP attempts per IP and
+// cfg.AuthRateLimitEmail per IP and email in each cfg.AuthRateWindow.
+func NewAuthRateLimiter(cfg *config.Config) *AuthRateLimiter {
+	return &AuthRateLimiter{
+		byIP:   newLimiter(cfg.AuthRateLimitIP, cfg.AuthRateWindow),
+		byPair: newLimiter(cfg.AuthRateLimitEmail, cfg.AuthRateWindow),
+	}
+}
+
+// Limit is the middleware wrapping a credential endpoint that doesn't
+// authenticate the submitted email (registration, 2FA, password reset).
+// Every attempt counts, whatever the response.
+func (a *AuthRateLimiter) Limit(next http.Handler) http.Handler {
+	return a.limit(next, false)
+}
+
+// Login is Limit for the password login route: a 2xx response proves the
+// (IP, email) pair and clears that pair's counter.
+func (a *AuthRateLimiter) Login(next http.Handler) http.Handler {
+	return a.limit(next, true)
+}
+
+func (a *AuthRateLimiter) limit(next http.Handler, clearOnSuccess bool) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ip := clientIP(r)
+
+		// Peek at the email without consuming the body for the handler.
+		body, _ := io.ReadAll(io.LimitReader(r.Body, 1<<20))
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		var creds struct {
+			Email string `json:"email"`
+		}
+		json.Unmarshal(body, &creds)
+		email := strings.ToLower(strings.TrimSpace(creds.Email))
+		pair := ip + "|" + email
+
+		if ok, wait := a.byIP.allow(ip); !ok {
+			tooManyAttempts(w, wait)
+			return
+		}
+		if email != "" {
+			if ok, wait := a.byPair.allow(pair); !ok {
+				tooManyAttempts(w, wait)
+				return
+			}
+		}
+
+		if !clearOnSuccess || email == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(rec, r)
+		if rec.status >= 200 && rec.status < 300 {
+			a.byPair.reset(pair)
+		}
+	})
+}
+
+// IPRateLimiter throttles an endpoint per client IP only.
+type IPRateLimiter struct {
+	byIP *limiter
+}
+
+// NewIPRateLimiter allows attempts requests per window from each IP.
+func NewIPRateLimiter(attempts int, window time.Duration) *IPRateLimiter {
+	return &IPRateLimiter{byIP: newLimiter(attempts, window)}
+}
+
+// Limit is the middleware wrapping the throttled handler.
+func (l *IPRateLimiter) Limit(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if ok, wait := l.byIP.allow(clientIP(r)); !ok {
+			tooManyAttempts(w, wait)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func tooManyAttempts(w http.ResponseWriter, wait time.Duration) {
+	secs := int(math.Ceil(wait.Seconds()))
+	if secs < 1 {
+		secs = 1
+	}
+	w.Header().Set("Retry-After", strconv.Itoa(secs))
+	WriteError(w, http.StatusTooManyRequests, "rate_limited", "too many attempts, try again later")
+}
+
+// clientIP is the remote address without its port.
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// statusRecorder remembers the status code a handler wrote.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (s *statusRecorder) WriteHeader(code int) {
+	s.status = code
+	s.ResponseWriter.WriteHeader(code)
+}
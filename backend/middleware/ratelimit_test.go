@@ -0,0 +1,79 @@
+package middleware
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestAuthLimiter(perIP, perPair int) *AuthRateLimiter {
+	return &AuthRateLimiter{
+		byIP:   newLimiter(perIP, time.Hour),
+		byPair: newLimiter(perPair, time.Hour),
+	}
+}
+
+// statusFor answers 200 for the email "good@x.com" and 401 otherwise.
+var statusFor = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	if strings.Contains(string(body), "good@x.com") {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	w.WriteHeader(http.StatusUnauthorized)
+})
+
+func attempt(h http.Handler, ip, email string) int {
+	r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"email":"`+email+`"}`))
+	r.RemoteAddr = ip + ":1234"
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	return w.Code
+}
+
+func TestLoginSuccessDoesNotResetIPBucket(t *testing.T) {
+	h := newTestAuthLimiter(3, 10).Login(statusFor)
+
+	attempt(h, "10.0.0.1", "victim@x.com")
+	attempt(h, "10.0.0.1", "victim@x.com")
+	if got := attempt(h, "10.0.0.1", "good@x.com"); got != http.StatusOK {
+		t.Fatalf("own login = %d, want 200", got)
+	}
+	if got := attempt(h, "10.0.0.1", "victim@x.com"); got != http.StatusTooManyRequests {
+		t.Fatalf("after a valid login the IP bucket was refilled: got %d, want 429", got)
+	}
+}
+
+func TestLoginSuccessClearsOnlyItsPair(t *testing.T) {
+	h := newTestAuthLimiter(100, 2).Login(statusFor)
+
+	attempt(h, "10.0.0.1", "good@x.com")
+	attempt(h, "10.0.0.1", "good@x.com")
+	// Both succeeded, so the pair is still open.
+	if got := attempt(h, "10.0.0.1", "good@x.com"); got != http.StatusOK {
+		t.Fatalf("pair after successes = %d, want 200", got)
+	}
+
+	attempt(h, "10.0.0.1", "victim@x.com")
+	attempt(h, "10.0.0.1", "victim@x.com")
+	attempt(h, "10.0.0.1", "good@x.com")
+	if got := attempt(h, "10.0.0.1", "victim@x.com"); got != http.StatusTooManyRequests {
+		t.Fatalf("another pair was cleared: got %d, want 429", got)
+	}
+	if got := attempt(h, "10.0.0.2", "victim@x.com"); got != http.StatusUnauthorized {
+		t.Fatalf("other IP = %d, want 401", got)
+	}
+}
+
+func TestLimitNeverClears(t *testing.T) {
+	h := newTestAuthLimiter(100, 2).Limit(statusFor)
+
+	attempt(h, "10.0.0.1", "good@x.com")
+	attempt(h, "10.0.0.1", "good@x.com")
+	if got := attempt(h, "10.0.0.1", "good@x.com"); got != http.StatusTooManyRequests {
+		t.Fatalf("non-login route cleared on 2xx: got %d, want 429", got)
+	}
+}
@@ -0,0 +1,52 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/karti/orange-city-mart/backend/requestid"
+)
+
+func TestValidRequestID(t *testing.T) {
+	for id, want := range map[string]bool{
+		"abc-123":                               true,
+		"7f1c2a3e-1d2b-4c5d-8e9f-0a1b2c3d4e5f":  true,
+		"svc.api:req_42":                        true,
+		"":                                      false,
+		"has space":                             false,
+		"line\nbreak":                           false,
+		"quote\"":                               false,
+		"ünïcode":                               false,
+		strings.Repeat("a", maxRequestIDLength): true,
+		strings.Repeat("a", maxRequestIDLength+1): false,
+	} {
+		if got := validRequestID(id); got != want {
+			t.Errorf("validRequestID(%q) = %t, want %t", id, got, want)
+		}
+	}
+}
+
+func TestRequestIDReplacesInvalidHeader(t *testing.T) {
+	var seen string
+	h := RequestID(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = requestid.FromContext(r.Context())
+	}))
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set(requestid.Header, "client-id-1")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	if seen != "client-id-1" || w.Header().Get(requestid.Header) != "client-id-1" {
+		t.Errorf("valid id: context %q, header %q", seen, w.Header().Get(requestid.Header))
+	}
+
+	r = httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set(requestid.Header, "bad id\r\n")
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	if seen == "" || seen == "bad id\r\n" || w.Header().Get(requestid.Header) != seen {
+		t.Errorf("invalid id: context %q, header %q", seen, w.Header().Get(requestid.Header))
+	}
+}
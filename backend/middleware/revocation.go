@@ -0,0 +1,68 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/karti/orange-city-mart/backend/db"
+)
+
+var errNoTokenID = errors.New("token has no jti")
+
+// RevokeToken adds an access token's jti to the denylist until the token
+// would have expired anyway. Invalid or already-expired tokens are ignored.
+func (a *Auth) RevokeToken(ctx context.Context, tokenStr string) error {
+	claims, err := a.parseToken(tokenStr)
+	if err != nil {
+		return nil
+	}
+	jti, _ := claims["jti"].(string)
+	if jti == "" {
+		return errNoTokenID
+	}
+	exp, err := claims.GetExpirationTime()
+	if err != nil || exp == nil {
+		return err
+	}
+	_, err = db.Pool.Exec(ctx, `
+		INSERT INTO revoked_tokens (jti, expires_at) VALUES ($1, $2)
+		ON CONFLICT (jti) DO NOTHING`,
+		jti, exp.Time,
+	)
+	return err
+}
+
+// isRevoked reports whether jti is on the denylist.
+func isRevoked(ctx context.Context, jti string) (bool, error) {
+	var revoked bool
+	err := db.Pool.QueryRow(ctx,
+		`SELECT EXISTS (SELECT 1 FROM revoked_tokens WHERE jti = $1)`, jti,
+	).Scan(&revoked)
+	return revoked, err
+}
+
+// RunRevocationCleanup periodically deletes denylist entries whose tokens
+// have expired, since an expired token is rejected regardless. It blocks
+// until ctx is cancelled.
+func RunRevocationCleanup(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			tag, err := db.Pool.Exec(ctx, `DELETE FROM revoked_tokens WHERE expires_at <= NOW()`)
+			if err != nil {
+				log.Printf("revocation cleanup: %v", err)
+				continue
+			}
+			if n := tag.RowsAffected(); n > 0 {
+				log.Printf("revocation cleanup: removed %d expired entries", n)
+			}
+		}
+	}
+}
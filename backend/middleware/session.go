@@ -0,0 +1,74 @@
+package middleware
+
+import (
+	"context"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/karti/orange-city-mart/backend/db"
+)
+
+// RenewedTokenHeader carries a fresh access token when RequireAuth slides a
+// session forward. Clients should replace their stored token with it.
+const RenewedTokenHeader = "X-Access-Token"
+
+// sessionTouchInterval is how often sliding renewal records activity on a
+// session's refresh token; renewals in between don't write.
+const sessionTouchInterval = time.Minute
+
+// SignToken issues an access token for userID valid for the configured
+// AccessTokenTTL, kept short because a leaked access token can't be revoked;
+// refresh tokens keep users signed in.
+// role is carried as a "role" claim for clients to adapt their UI; the
+// server itself authorises from the database (see Claims), so a token
+// minted before a role change can't keep or gain privileges. sessionID is
+// the id of the refresh token the session was issued with, carried as the
+// "sid" claim so renewal touches only that session; "" omits it.
+func (a *Auth) SignToken(userID, role, sessionID string) (string, error) {
+	claims := jwt.MapClaims{
+		"sub":  userID,
+		"role": role,
+		"exp":  time.Now().Add(a.cfg.AccessTokenTTL).Unix(),
+		"iat":  time.Now().Unix(),
+		"jti":  uuid.NewString(),
+	}
+	if sessionID != "" {
+		claims["sid"] = sessionID
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return token.SignedString(a.cfg.JWTSecret)
+}
+
+// renewSession slides the presenting session forward and returns a new
+// access token for it. Only that session's refresh token is marked as used,
+// and at most once per sessionTouchInterval, so a burst of requests near
+// expiry doesn't write on each one. It returns "" when the session is no
+// longer live (logged out or expired), so the current access token is left
+// to run out.
+func (a *Auth) renewSession(ctx context.Context, sessionID, userID, role string) string {
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+
+	var live bool
+	err := db.Pool.QueryRow(ctx, `
+		WITH touched AS (
+		    UPDATE refresh_tokens SET last_used_at = NOW()
+		    WHERE id = $1::uuid AND user_id = $2::uuid AND revoked_at IS NULL AND expires_at > NOW()
+		      AND last_used_at < NOW() - make_interval(secs => $3::float8)
+		    RETURNING 1
+		)
+		SELECT EXISTS (SELECT 1 FROM touched) OR EXISTS (
+		    SELECT 1 FROM refresh_tokens
+		    WHERE id = $1::uuid AND user_id = $2::uuid AND revoked_at IS NULL AND expires_at > NOW())`,
+		sessionID, userID, sessionTouchInterval.Seconds(),
+	).Scan(&live)
+	if err != nil || !live {
+		return ""
+	}
+	token, err := a.SignToken(userID, role, sessionID)
+	if err != nil {
+		return ""
+	}
+	return token
+}
@@ -0,0 +1,20 @@
+package requestid
+
+import "context"
+
+// Header carries the correlation id on requests and responses.
+const Header = "X-Request-ID"
+
+type contextKey struct{}
+
+// NewContext returns ctx carrying the request id.
+func NewContext(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, contextKey{}, id)
+}
+
+// FromContext returns the request id stored by the middleware, or "" outside
+// a request.
+func FromContext(ctx context.Context) string {
+	id, _ := ctx.Value(contextKey{}).(string)
+	return id
+}
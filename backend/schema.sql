@@ -11,6 +11,13 @@ CREATE TABLE IF NOT EXISTS users (
     password_hash TEXT NOT NULL,
     wallet_balance NUMERIC(12, 2) NOT NULL DEFAULT 0.00,
     upi_id        VARCHAR(100),
+    email_verified BOOLEAN NOT NULL DEFAULT FALSE,
+    is_frozen     BOOLEAN NOT NULL DEFAULT FALSE, -- frozen accounts can't send or receive transfers
+    role          VARCHAR(20) NOT NULL DEFAULT 'user' CHECK (role IN ('user', 'admin')),
+    totp_secret   TEXT, -- AES-GCM sealed TOTP secret; set by /api/me/2fa/enable
+    totp_enabled  BOOLEAN NOT NULL DEFAULT FALSE, -- login requires a second factor once true
+    totp_last_step BIGINT NOT NULL DEFAULT 0, -- last accepted TOTP time step, blocks code replay
+    email_notifications BOOLEAN NOT NULL DEFAULT FALSE, -- opt-in to emailed outbid/win/settlement alerts
     created_at    TIMESTAMPTZ NOT NULL DEFAULT NOW(),
     updated_at    TIMESTAMPTZ NOT NULL DEFAULT NOW()
 );
@@ -26,6 +33,11 @@ CREATE TABLE IF NOT EXISTS products (
     price       NUMERIC(12, 2) NOT NULL DEFAULT 0.00,
     image_url   TEXT,
     location    VARCHAR(200) DEFAULT 'Nagpur',
+    featured_until TIMESTAMPTZ, -- paid boost to the top of listings until this time
+    deleted_at  TIMESTAMPTZ, -- soft delete; hidden from listings once set
+    quantity    INT NOT NULL DEFAULT 1 CHECK (quantity >= 0), -- units left; FIXED listings only
+    status      VARCHAR(20) NOT NULL DEFAULT 'AVAILABLE'
+                CHECK (status IN ('AVAILABLE', 'SOLD_OUT')), -- FIXED listings only; SOLD_OUT once quantity hits 0
     created_at  TIMESTAMPTZ NOT NULL DEFAULT NOW(),
     updated_at  TIMESTAMPTZ NOT NULL DEFAULT NOW()
 );
@@ -38,7 +50,17 @@ CREATE TABLE IF NOT EXISTS auctions (
     current_highest_bid NUMERIC(12, 2) NOT NULL DEFAULT 0.00,
     highest_bidder_id   UUID REFERENCES users(id),
     end_time            TIMESTAMPTZ NOT NULL,
-    status              VARCHAR(20) NOT NULL DEFAULT 'ACTIVE' CHECK (status IN ('ACTIVE', 'ENDED', 'CANCELLED')),
+    status              VARCHAR(20) NOT NULL DEFAULT 'ACTIVE' CHECK (status IN ('ACTIVE', 'ENDED', 'ENDED_NO_SALE', 'CANCELLED')),
+    reserve_price       NUMERIC(12, 2), -- hidden floor; below it the auction ends ENDED_NO_SALE
+    auto_relist         BOOLEAN NOT NULL DEFAULT FALSE, -- reopen automatically when ending without a sale
+    relists_remaining   INT NOT NULL DEFAULT 0 CHECK (relists_remaining >= 0),
+    buy_now_price       NUMERIC(12, 2), -- optional price at which a buyer ends the auction instantly
+    anti_snipe          BOOLEAN NOT NULL DEFAULT TRUE, -- extend end_time on late bids; FALSE = hard deadline
+    min_bid_interval    INT NOT NULL DEFAULT 0 CHECK (min_bid_interval >= 0), -- seconds between one user's bids
+    -- INSTANT: outbid holds are refunded immediately
+    -- AT_END:  every bidder's holds stay in place until the auction ends
+    refund_policy       VARCHAR(10) NOT NULL DEFAULT 'INSTANT' CHECK (refund_policy IN ('INSTANT', 'AT_END')),
+    version             INT NOT NULL DEFAULT 0, -- bumped by trigger on every update; guards conditional writes
     created_at          TIMESTAMPTZ NOT NULL DEFAULT NOW(),
     updated_at          TIMESTAMPTZ NOT NULL DEFAULT NOW()
 );
@@ -57,14 +79,28 @@ CREATE TABLE IF NOT EXISTS transactions (
     id         UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
     user_id    UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
     amount     NUMERIC(12, 2) NOT NULL,
-    type       VARCHAR(20) NOT NULL CHECK (type IN ('DEPOSIT', 'WITHDRAW', 'BID_HOLD', 'REFUND', 'TRANSFER')),
+    type       VARCHAR(20) NOT NULL CHECK (type IN ('DEPOSIT', 'WITHDRAW', 'BID_HOLD', 'REFUND', 'TRANSFER', 'FEATURE_FEE', 'LISTING_FEE', 'TRANSFER_OUT', 'TRANSFER_IN', 'ADJUSTMENT_OUT', 'ADJUSTMENT_IN')),
     status     VARCHAR(20) NOT NULL DEFAULT 'COMPLETED' CHECK (status IN ('PENDING', 'COMPLETED', 'FAILED')),
     reference  TEXT,
     created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
 );
 
+-- Auto-bids (proxy bidding)
+-- One standing ceiling per (auction, user). The engine bids on the user's
+-- behalf up to max_amount; equal ceilings resolve by earliest created_at.
+CREATE TABLE IF NOT EXISTS auto_bids (
+    id          UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    auction_id  UUID NOT NULL REFERENCES auctions(id) ON DELETE CASCADE,
+    user_id     UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    max_amount  NUMERIC(12, 2) NOT NULL CHECK (max_amount > 0),
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at  TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (auction_id, user_id)
+);
+
 -- Bid Holds table
--- One active hold per (auction, user) at any time.
+-- One active hold per (auction, user) at any time; a user's raise tops their
+-- SOFT hold up to the new bid rather than adding another.
 -- SOFT  = money deducted from wallet while auction is live (can be released on outbid)
 -- HARD  = auction ended, winner's hold is locked until settlement
 -- RELEASED = outbid / refunded; wallet already credited back
@@ -91,37 +127,243 @@ CREATE TABLE IF NOT EXISTS settlements (
     winner_approved_at  TIMESTAMPTZ,
     seller_approved_at  TIMESTAMPTZ,
     status              VARCHAR(10) NOT NULL DEFAULT 'PENDING'
-                CHECK (status IN ('PENDING', 'COMPLETED')),
+                CHECK (status IN ('PENDING', 'COMPLETED', 'VOID', 'EXPIRED', 'DISPUTED', 'REFUNDED')), -- VOID: auction cancelled by an admin; EXPIRED: refunded at expires_at; REFUNDED: dispute resolved for the winner
+    refunded_amount     NUMERIC(12, 2) NOT NULL DEFAULT 0.00, -- post-sale partial refunds, never above amount
+    -- Delivery: the winner's address is shown to the seller only once COMPLETED
+    shipping_address    TEXT,
+    tracking_number     VARCHAR(100),
+    reminded_at         TIMESTAMPTZ, -- last approval reminder, for rate limiting
+    expires_at          TIMESTAMPTZ, -- approval deadline from the category's settlement window
+    disputed_at         TIMESTAMPTZ, -- set when the settlement is flagged for review
+    disputed_by         UUID REFERENCES users(id), -- NULL when flagged by expiry
+    dispute_reason      TEXT,
+    dispute_resolved_by UUID REFERENCES users(id),
+    dispute_resolved_at TIMESTAMPTZ,
+    dispute_resolution  TEXT, -- admin's note
     created_at          TIMESTAMPTZ NOT NULL DEFAULT NOW()
 );
 
 -- Messages table for peer-to-peer chat
 -- room_id = sorted(userA_id, userB_id) joined by "_"
--- either body OR image_url is set per message (never both null)
+-- either body OR image_url is set per message (never both null, unless deleted)
 CREATE TABLE IF NOT EXISTS messages (
     id          UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
     room_id     TEXT NOT NULL,
     sender_id   UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
     body        TEXT,
     image_url   TEXT,
+    edited_at   TIMESTAMPTZ,
+    deleted_at  TIMESTAMPTZ, -- soft delete: body and image_url are blanked
+    seq         BIGSERIAL NOT NULL, -- insertion order; tiebreak for equal created_at
+    product_id  UUID REFERENCES products(id) ON DELETE SET NULL, -- listing the message is about, if any
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CONSTRAINT chk_body_or_image CHECK (deleted_at IS NOT NULL OR body IS NOT NULL OR image_url IS NOT NULL)
+);
+
+-- Per-category policy overrides
+-- NULL columns fall back to the global defaults (MIN_BID_INCREMENT, LISTING_FEE,
+-- SETTLEMENT_WINDOW env vars).
+CREATE TABLE IF NOT EXISTS category_rules (
+    category          VARCHAR(100) PRIMARY KEY,
+    min_increment     NUMERIC(12, 2) CHECK (min_increment > 0),
+    listing_fee       NUMERIC(12, 2) CHECK (listing_fee >= 0),
+    settlement_window INTERVAL,
+    updated_at        TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- Per-user hidden conversations
+-- A room is hidden from user_id's list until a message newer than hidden_at arrives.
+CREATE TABLE IF NOT EXISTS conversation_hides (
+    room_id     TEXT NOT NULL,
+    user_id     UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    hidden_at   TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (room_id, user_id)
+);
+
+-- Chat blocks: messages from blocked_id to blocker_id are silently dropped
+CREATE TABLE IF NOT EXISTS user_blocks (
+    blocker_id  UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    blocked_id  UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (blocker_id, blocked_id),
+    CHECK (blocker_id <> blocked_id)
+);
+
+-- Per-user read position in a chat room
+-- Messages from the other party newer than last_read_at count as unread.
+CREATE TABLE IF NOT EXISTS room_reads (
+    room_id      TEXT NOT NULL,
+    user_id      UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    last_read_at TIMESTAMPTZ NOT NULL,
+    PRIMARY KEY (room_id, user_id)
+);
+
+-- Login tokens for password-less magic-link sign-in
+-- Only the SHA-256 of the token is stored; used_at marks it consumed.
+CREATE TABLE IF NOT EXISTS login_tokens (
+    id          UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    email       VARCHAR(255) NOT NULL,
+    token_hash  TEXT UNIQUE NOT NULL,
+    expires_at  TIMESTAMPTZ NOT NULL,
+    used_at     TIMESTAMPTZ,
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- Single-use password reset tokens, stored hashed like login_tokens.
+CREATE TABLE IF NOT EXISTS password_reset_tokens (
+    id          UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id     UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    token_hash  TEXT UNIQUE NOT NULL,
+    expires_at  TIMESTAMPTZ NOT NULL,
+    used_at     TIMESTAMPTZ,
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- Refresh tokens exchanged at /api/auth/refresh for new access tokens.
+-- Stored hashed; each refresh revokes the presented token (rotation).
+CREATE TABLE IF NOT EXISTS refresh_tokens (
+    id          UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id     UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    token_hash  TEXT UNIQUE NOT NULL,
+    expires_at  TIMESTAMPTZ NOT NULL,
+    revoked_at  TIMESTAMPTZ,
+    last_used_at TIMESTAMPTZ NOT NULL DEFAULT NOW(), -- bumped on sliding renewal
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- Access tokens revoked by logout, keyed by their jti claim. Rows are only
+-- needed until expires_at and are purged periodically after that.
+CREATE TABLE IF NOT EXISTS revoked_tokens (
+    jti         TEXT PRIMARY KEY,
+    expires_at  TIMESTAMPTZ NOT NULL,
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- Emails freed by account deletion. Register refuses them until
+-- REREGISTER_COOLDOWN has passed since deleted_at.
+CREATE TABLE IF NOT EXISTS deleted_emails (
+    email       TEXT PRIMARY KEY,
+    deleted_at  TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- Single-use 2FA recovery codes, stored hashed.
+CREATE TABLE IF NOT EXISTS recovery_codes (
+    id          UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id     UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    code_hash   TEXT NOT NULL,
+    used_at     TIMESTAMPTZ,
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- Pending second-factor logins: issued after a correct password for a 2FA
+-- account and redeemed at /api/auth/2fa.
+CREATE TABLE IF NOT EXISTS two_factor_challenges (
+    id          UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id     UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    token_hash  TEXT UNIQUE NOT NULL,
+    attempts    INT NOT NULL DEFAULT 0, -- wrong codes so far
+    expires_at  TIMESTAMPTZ NOT NULL,
+    used_at     TIMESTAMPTZ,
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- Listing reports feeding the admin moderation queue. One report per user
+-- per listing.
+CREATE TABLE IF NOT EXISTS reports (
+    id          UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    product_id  UUID NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    reporter_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    reason      VARCHAR(20) NOT NULL
+                CHECK (reason IN ('SCAM', 'PROHIBITED', 'COUNTERFEIT', 'MISLEADING', 'OTHER')),
+    note        TEXT,
+    status      VARCHAR(20) NOT NULL DEFAULT 'PENDING'
+                CHECK (status IN ('PENDING', 'RESOLVED', 'DISMISSED')),
+    resolved_by UUID REFERENCES users(id),
+    resolved_at TIMESTAMPTZ,
     created_at  TIMESTAMPTZ NOT NULL DEFAULT NOW(),
-    CONSTRAINT chk_body_or_image CHECK (body IS NOT NULL OR image_url IS NOT NULL)
+    UNIQUE (product_id, reporter_id)
+);
+
+-- Notifications
+-- Persisted per-user events; also pushed over the WebSocket when connected.
+CREATE TABLE IF NOT EXISTS notifications (
+    id         UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id    UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    type       VARCHAR(40) NOT NULL,
+    payload    JSONB NOT NULL DEFAULT '{}',
+    read_at    TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- Watchlist
+-- Products a user has bookmarked. Watchers of an auction get watchlist_alert
+-- pushes on new bids and shortly before it ends.
+CREATE TABLE IF NOT EXISTS watchlist (
+    user_id            UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    product_id         UUID NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    ending_alerted_for UUID REFERENCES auctions(id) ON DELETE SET NULL, -- auction last sent an ending-soon alert
+    created_at         TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (user_id, product_id)
+);
+
+-- Purchases
+-- Fixed-price sales. Funds move at purchase time, so a purchase is recorded
+-- already COMPLETED; its TRANSFER transactions reference the purchase id.
+CREATE TABLE IF NOT EXISTS purchases (
+    id         UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    product_id UUID NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    buyer_id   UUID NOT NULL REFERENCES users(id),
+    seller_id  UUID NOT NULL REFERENCES users(id),
+    quantity   INT NOT NULL DEFAULT 1 CHECK (quantity > 0),
+    unit_price NUMERIC(12, 2) NOT NULL,
+    amount     NUMERIC(12, 2) NOT NULL, -- quantity * unit_price
+    status     VARCHAR(20) NOT NULL DEFAULT 'COMPLETED' CHECK (status IN ('COMPLETED')),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- Uploads
+-- Every stored image and who uploaded it. Listings may only use, and only the
+-- uploader may delete, their own uploads.
+CREATE TABLE IF NOT EXISTS uploads (
+    url        TEXT PRIMARY KEY, -- as returned by /api/upload
+    owner_id   UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
 );
 
 -- Indexes for performance
+-- Emails are matched case-insensitively (see normalizeEmail), so they must
+-- also be unique case-insensitively.
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_email_lower ON users(lower(email));
 CREATE INDEX IF NOT EXISTS idx_products_seller_id    ON products(seller_id);
 CREATE INDEX IF NOT EXISTS idx_products_type         ON products(type);
 CREATE INDEX IF NOT EXISTS idx_products_category     ON products(category);
+CREATE INDEX IF NOT EXISTS idx_products_created_at   ON products(created_at);
+CREATE INDEX IF NOT EXISTS idx_products_featured     ON products(featured_until) WHERE featured_until IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_products_title_prefix ON products(lower(title) text_pattern_ops);
 CREATE INDEX IF NOT EXISTS idx_auctions_product_id   ON auctions(product_id);
 CREATE INDEX IF NOT EXISTS idx_auctions_status       ON auctions(status);
 CREATE INDEX IF NOT EXISTS idx_auctions_end_time     ON auctions(end_time);
 CREATE INDEX IF NOT EXISTS idx_bids_auction_id       ON bids(auction_id);
 CREATE INDEX IF NOT EXISTS idx_bids_user_id          ON bids(user_id);
+CREATE INDEX IF NOT EXISTS idx_bids_created_at       ON bids(created_at DESC);
 CREATE INDEX IF NOT EXISTS idx_transactions_user_id  ON transactions(user_id);
+CREATE INDEX IF NOT EXISTS idx_auto_bids_auction_id  ON auto_bids(auction_id, max_amount DESC);
 CREATE INDEX IF NOT EXISTS idx_bid_holds_auction_id  ON bid_holds(auction_id);
 CREATE INDEX IF NOT EXISTS idx_bid_holds_user_id     ON bid_holds(user_id);
 CREATE INDEX IF NOT EXISTS idx_bid_holds_status      ON bid_holds(status);
 CREATE INDEX IF NOT EXISTS idx_settlements_auction   ON settlements(auction_id);
+CREATE INDEX IF NOT EXISTS idx_messages_room_created  ON messages(room_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_login_tokens_email    ON login_tokens(email, created_at);
+CREATE INDEX IF NOT EXISTS idx_refresh_tokens_user   ON refresh_tokens(user_id);
+CREATE INDEX IF NOT EXISTS idx_revoked_tokens_exp    ON revoked_tokens(expires_at);
+CREATE INDEX IF NOT EXISTS idx_recovery_codes_user   ON recovery_codes(user_id);
+CREATE INDEX IF NOT EXISTS idx_reports_status        ON reports(status, created_at);
+CREATE INDEX IF NOT EXISTS idx_notifications_user    ON notifications(user_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_messages_product       ON messages(product_id) WHERE product_id IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_watchlist_product     ON watchlist(product_id);
+CREATE INDEX IF NOT EXISTS idx_settlements_expiry    ON settlements(expires_at) WHERE status = 'PENDING';
+CREATE INDEX IF NOT EXISTS idx_purchases_buyer       ON purchases(buyer_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_uploads_owner         ON uploads(owner_id);
 
 -- Trigger to auto-update updated_at
 CREATE OR REPLACE FUNCTION update_updated_at_column()
@@ -132,14 +374,27 @@ BEGIN
 END;
 $$ language 'plpgsql';
 
+-- Trigger to bump the optimistic-lock version
+CREATE OR REPLACE FUNCTION bump_version_column()
+RETURNS TRIGGER AS $$
+BEGIN
+    NEW.version = OLD.version + 1;
+    RETURN NEW;
+END;
+$$ language 'plpgsql';
+
 CREATE TRIGGER update_users_updated_at
     BEFORE UPDATE ON users FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
 CREATE TRIGGER update_products_updated_at
     BEFORE UPDATE ON products FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
 CREATE TRIGGER update_auctions_updated_at
     BEFORE UPDATE ON auctions FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+CREATE TRIGGER bump_auctions_version
+    BEFORE UPDATE ON auctions FOR EACH ROW EXECUTE FUNCTION bump_version_column();
 CREATE TRIGGER update_bid_holds_updated_at
     BEFORE UPDATE ON bid_holds FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+CREATE TRIGGER update_auto_bids_updated_at
+    BEFORE UPDATE ON auto_bids FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
 
 -- ─── Seed Data ────────────────────────────────────────────────────────────────
 -- Password for all demo users: demo1234
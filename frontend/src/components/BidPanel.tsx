@@ -2,7 +2,7 @@ import { useState, useEffect } from 'react'
 import { Gavel, Wifi, WifiOff, Clock, Wallet, AlertCircle } from 'lucide-react'
 import toast from 'react-hot-toast'
 import { useAuctionSocket } from '../hooks/useAuctionSocket'
-import { API_URL } from '../config'
+import { API_URL, apiError } from '../config'
 
 interface BidPanelProps {
     auctionId: string
@@ -41,7 +41,7 @@ export default function BidPanel({
     walletBalance,
     token,
 }: BidPanelProps) {
-    const { currentBid, isConnected } = useAuctionSocket({ auctionId, userId, initialBid })
+    const { currentBid, isConnected } = useAuctionSocket({ auctionId, userId, token, initialBid })
     const { hours, minutes, seconds, expired } = useCountdown(endTime)
     const [bidAmount, setBidAmount] = useState('')
     const [isSubmitting, setIsSubmitting] = useState(false)
@@ -71,8 +71,7 @@ export default function BidPanel({
                 body: JSON.stringify({ amount: parsedAmount }),
             })
             if (!res.ok) {
-                const text = await res.text()
-                throw new Error(text.trim() || 'Bid failed')
+                throw new Error(await apiError(res, 'Bid failed'))
             }
             toast.success(`Bid of ₹${parsedAmount.toLocaleString('en-IN')} placed! Funds soft-blocked.`)
             setBidAmount('')
@@ -15,3 +15,14 @@ function buildWsUrl(): string {
 }
 
 export const WS_URL = buildWsUrl()
+
+// apiError extracts the message from the API's error envelope,
+// { "error": { "code", "message" } }, falling back when the body isn't one.
+export async function apiError(res: Response, fallback: string): Promise<string> {
+    try {
+        const body = await res.json()
+        return body?.error?.message || fallback
+    } catch {
+        return fallback
+    }
+}
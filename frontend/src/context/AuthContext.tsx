@@ -1,4 +1,4 @@
-import { createContext, useContext, useState, useEffect } from 'react'
+import { createContext, useContext, useState, useEffect, useRef } from 'react'
 import type { ReactNode } from 'react'
 
 interface User {
@@ -18,7 +18,7 @@ interface AuthContextType {
 
 const AuthContext = createContext<AuthContextType | null>(null)
 
-import { API_URL as API } from '../config'
+import { API_URL as API, apiError } from '../config'
 
 export function AuthProvider({ children }: { children: ReactNode }) {
     const [user, setUser] = useState<User | null>(() => {
@@ -32,22 +32,86 @@ export function AuthProvider({ children }: { children: ReactNode }) {
     const [token, setToken] = useState<string | null>(
         () => localStorage.getItem('ocm_token')
     )
+    const [refreshToken, setRefreshToken] = useState<string | null>(
+        () => localStorage.getItem('ocm_refresh')
+    )
 
     useEffect(() => {
         if (user && token) {
             localStorage.setItem('ocm_user', JSON.stringify(user))
             localStorage.setItem('ocm_token', token)
+            if (refreshToken) localStorage.setItem('ocm_refresh', refreshToken)
         } else {
             localStorage.removeItem('ocm_user')
             localStorage.removeItem('ocm_token')
+            localStorage.removeItem('ocm_refresh')
         }
-    }, [user, token])
+    }, [user, token, refreshToken])
 
-    const persist = (data: { token: string; user: User }) => {
+    const persist = (data: { token: string; refresh_token: string; user: User }) => {
         setToken(data.token)
+        setRefreshToken(data.refresh_token)
         setUser(data.user)
     }
 
+    // Access tokens are short-lived. The interceptor below keeps the session
+    // going: it adopts the renewed token the API sends near expiry, and on a
+    // 401 trades the refresh token for a new pair and retries once. The refs
+    // let it read the latest tokens without being reinstalled.
+    const refreshRef = useRef(refreshToken)
+    refreshRef.current = refreshToken
+    const renewing = useRef<Promise<string | null> | null>(null)
+
+    useEffect(() => {
+        const original = window.fetch
+
+        const renew = (): Promise<string | null> => {
+            if (!renewing.current) {
+                renewing.current = (async () => {
+                    const refresh = refreshRef.current
+                    if (!refresh) return null
+                    try {
+                        const res = await original(`${API}/auth/refresh`, {
+                            method: 'POST',
+                            headers: { 'Content-Type': 'application/json' },
+                            body: JSON.stringify({ refresh_token: refresh }),
+                        })
+                        if (!res.ok) {
+                            setUser(null)
+                            setToken(null)
+                            setRefreshToken(null)
+                            return null
+                        }
+                        const data = await res.json()
+                        refreshRef.current = data.refresh_token
+                        persist(data)
+                        return data.token as string
+                    } catch {
+                        return null
+                    }
+                })().finally(() => { renewing.current = null })
+            }
+            return renewing.current
+        }
+
+        window.fetch = async (input, init) => {
+            const url = typeof input === 'string' ? input : input instanceof URL ? input.href : input.url
+            const res = await original(input, init)
+            if (!url.startsWith(API) || url === `${API}/auth/logout`) return res
+
+            const renewed = res.headers.get('X-Access-Token')
+            if (renewed) setToken(renewed)
+
+            const headers = new Headers(init?.headers)
+            if (res.status !== 401 || !headers.has('Authorization')) return res
+            const fresh = await renew()
+            if (!fresh) return res
+            headers.set('Authorization', `Bearer ${fresh}`)
+            return original(input, { ...init, headers })
+        }
+        return () => { window.fetch = original }
+    }, [])
+
     // Fetch with 60 s timeout — Render free tier can take 50 s to cold-start
     const fetchWithTimeout = async (url: string, options: RequestInit) => {
         const controller = new AbortController()
@@ -71,8 +135,7 @@ export function AuthProvider({ children }: { children: ReactNode }) {
             body: JSON.stringify({ email, password }),
         })
         if (!res.ok) {
-            const msg = await res.text()
-            throw new Error(msg.trim() || 'Login failed')
+            throw new Error(await apiError(res, 'Login failed'))
         }
         persist(await res.json())
     }
@@ -84,15 +147,25 @@ export function AuthProvider({ children }: { children: ReactNode }) {
             body: JSON.stringify({ name, email, password }),
         })
         if (!res.ok) {
-            const msg = await res.text()
-            throw new Error(msg.trim() || 'Registration failed')
+            throw new Error(await apiError(res, 'Registration failed'))
         }
         persist(await res.json())
     }
 
     const logout = () => {
+        // Best effort: revoke both tokens server-side, but sign out locally
+        // whatever the outcome.
+        fetch(`${API}/auth/logout`, {
+            method: 'POST',
+            headers: {
+                'Content-Type': 'application/json',
+                ...(token ? { Authorization: `Bearer ${token}` } : {}),
+            },
+            body: JSON.stringify({ refresh_token: refreshToken ?? '' }),
+        }).catch(() => {})
         setUser(null)
         setToken(null)
+        setRefreshToken(null)
     }
 
     return (
@@ -8,7 +8,7 @@ import Navbar from '../components/Navbar'
 import BidPanel from '../components/BidPanel'
 import { useAuth } from '../context/AuthContext'
 import toast from 'react-hot-toast'
-import { API_URL } from '../config'
+import { API_URL, apiError } from '../config'
 
 interface AuctionData {
     id: string
@@ -101,8 +101,7 @@ function SettlementPanel({
                 headers: { Authorization: `Bearer ${token}` },
             })
             if (!res.ok) {
-                const text = await res.text()
-                throw new Error(text.trim() || 'Approval failed')
+                throw new Error(await apiError(res, 'Approval failed'))
             }
             toast.success('Approval recorded!')
             onApproved()
@@ -8,7 +8,7 @@ import Navbar from '../components/Navbar'
 import { useAuth } from '../context/AuthContext'
 import { useChatSocket, type ChatMessage } from '../hooks/useChatSocket'
 import toast from 'react-hot-toast'
-import { API_URL } from '../config'
+import { API_URL, apiError } from '../config'
 
 // ── Helpers ───────────────────────────────────────────────────────────────────
 
@@ -143,7 +143,7 @@ export default function ChatPage() {
     // ── WebSocket ──────────────────────────────────────────────────────────
     const { lastMessage, isConnected } = useChatSocket({
         roomId: currentRoomId,
-        userId: myId,
+        token,
     })
 
     useEffect(() => {
@@ -203,10 +203,10 @@ export default function ChatPage() {
         fetch(`${API_URL}/chat/rooms/${rid}/messages`, { headers: authHeaders() })
             .then(async r => {
                 if (!r.ok) throw new Error()
-                return r.json() as Promise<ChatMessage[]>
+                return r.json() as Promise<{ messages: ChatMessage[]; has_more: boolean }>
             })
-            .then(msgs => {
-                setMessages(msgs)
+            .then(page => {
+                setMessages(page.messages ?? [])
                 const conv = conversations.find(c => c.other_user_id === activeOtherId)
                 if (conv) setActiveOtherName(conv.other_name)
             })
@@ -234,7 +234,7 @@ export default function ChatPage() {
                 headers: authHeaders(),
                 body: JSON.stringify({ body }),
             })
-            if (!res.ok) throw new Error(await res.text())
+            if (!res.ok) throw new Error(await apiError(res, 'Failed to send message'))
             const msg: ChatMessage = await res.json()
             setMessages(prev => prev.some(m => m.id === msg.id) ? prev : [...prev, msg])
             fetchConversations()
@@ -280,7 +280,7 @@ export default function ChatPage() {
                 headers: authHeaders(),
                 body: JSON.stringify({ image_url: url }),
             })
-            if (!msgRes.ok) throw new Error(await msgRes.text())
+            if (!msgRes.ok) throw new Error(await apiError(msgRes, 'Failed to send image'))
             const msg: ChatMessage = await msgRes.json()
             setMessages(prev => prev.some(m => m.id === msg.id) ? prev : [...prev, msg])
             fetchConversations()
@@ -57,7 +57,7 @@ export default function Dashboard() {
             headers: token ? { Authorization: `Bearer ${token}` } : {},
         })
             .then(r => r.json())
-            .then(setProducts)
+            .then(data => setProducts(data.items ?? []))
             .catch(console.error)
             .finally(() => setLoading(false))
     }, [activeCategory, token])
@@ -7,7 +7,7 @@ import { useAuth } from '../context/AuthContext'
 
 const CATEGORIES = ['Electronics', 'Furniture', 'Fashion', 'Vehicles', 'Properties', 'Sports', 'Books', 'Other']
 // Use empty string so all fetch calls use relative URLs → proxied by Vite to the backend
-import { API_URL as API } from '../config'
+import { API_URL as API, apiError } from '../config'
 
 export default function PostListing() {
     const navigate = useNavigate()
@@ -53,7 +53,7 @@ export default function PostListing() {
                 headers: { Authorization: `Bearer ${token}` },
                 body: form,
             })
-            if (!res.ok) throw new Error(await res.text())
+            if (!res.ok) throw new Error(await apiError(res, 'Image upload failed'))
             const data = await res.json()
             setImageUrl(data.url)
             toast.success('Photo uploaded!')
@@ -117,7 +117,7 @@ export default function PostListing() {
                 body: JSON.stringify(payload),
             })
 
-            if (!res.ok) throw new Error(await res.text())
+            if (!res.ok) throw new Error(await apiError(res, 'Failed to post listing'))
 
             toast.success('🎉 Listing posted!')
             navigate('/dashboard')
@@ -45,7 +45,7 @@ export default function SearchResults() {
         })
             .then(r => r.json())
             .then(data => {
-                let results: Product[] = data
+                let results: Product[] = data.items ?? []
                 if (sort === 'Price: Low to High') results = [...results].sort((a, b) => a.price - b.price)
                 if (sort === 'Price: High to Low') results = [...results].sort((a, b) => b.price - a.price)
                 setItems(results)
@@ -3,7 +3,7 @@ import type { ReactNode } from 'react'
 import { Wallet, ArrowDownCircle, ArrowUpCircle, TrendingUp, Clock, ChevronRight, Loader2 } from 'lucide-react'
 import Navbar from '../components/Navbar'
 import { useAuth } from '../context/AuthContext'
-import { API_URL } from '../config'
+import { API_URL, apiError } from '../config'
 
 interface Transaction {
     id: string
@@ -63,7 +63,7 @@ export default function WalletPage() {
                 headers: { 'Content-Type': 'application/json', Authorization: `Bearer ${token}` },
                 body: JSON.stringify(body),
             })
-            if (!r.ok) throw new Error(await r.text())
+            if (!r.ok) throw new Error(await apiError(r, 'Request failed'))
             setModal(null); setAmount('')
             fetchWallet()
         } catch (e: any) {
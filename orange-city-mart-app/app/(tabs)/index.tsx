@@ -31,7 +31,7 @@ export default function Dashboard() {
       });
 
       if (res.ok) {
-        const data = await res.json() || [];
+        const data = (await res.json())?.items || [];
         setAuctions(data.filter((p: any) => p.type === 'AUCTION'));
         setProducts(data.filter((p: any) => p.type === 'FIXED'));
       }
@@ -26,7 +26,7 @@ export default function Search() {
         const res = await fetch(`${API_URL}/products?q=${encodeURIComponent(query)}`);
         if (res.ok) {
           const data = await res.json();
-          setResults(data || []);
+          setResults(data?.items || []);
         }
       } catch (e) {} finally {
         setLoading(false);
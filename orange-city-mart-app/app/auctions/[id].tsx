@@ -2,7 +2,7 @@ import { useState, useEffect, useCallback } from 'react';
 import { View, Text, ScrollView, Image, TouchableOpacity, ActivityIndicator, TextInput, KeyboardAvoidingView, Platform, Alert, Dimensions } from 'react-native';
 import { useLocalSearchParams, useRouter } from 'expo-router';
 import { useAuth } from '../../context/AuthContext';
-import { API_URL, WS_URL } from '../../lib/config';
+import { API_URL, WS_URL, apiError } from '../../lib/config';
 import { ArrowLeft, Clock, ShieldCheck, MapPin, Tag } from 'lucide-react-native';
 import { BlurView } from 'expo-blur';
 import { LinearGradient } from 'expo-linear-gradient';
@@ -71,8 +71,7 @@ export default function AuctionDetail() {
       });
       
       if (!res.ok) {
-        const txt = await res.text();
-        throw new Error(txt || 'Failed to place bid');
+        throw new Error(await apiError(res, 'Failed to place bid'));
       }
       
       Alert.alert('Success', 'Bid placed successfully!');
@@ -37,7 +37,7 @@ export default function ChatDetail() {
       const res = await fetch(`${API_URL}/chat/${id}/messages`, {
         headers: { Authorization: `Bearer ${token}` }
       });
-      if (res.ok) setMessages(await res.json() || []);
+      if (res.ok) setMessages((await res.json())?.messages || []);
     } catch(e) { }
   };
 
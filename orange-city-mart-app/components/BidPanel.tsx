@@ -2,7 +2,7 @@ import { useState, useEffect } from 'react';
 import { View, Text, TextInput, TouchableOpacity, Alert, ActivityIndicator } from 'react-native';
 import { Gavel, Wifi, WifiOff, Clock, Wallet, AlertCircle } from 'lucide-react-native';
 import { useAuctionSocket } from '../hooks/useAuctionSocket';
-import { API_URL } from '../lib/config';
+import { API_URL, apiError } from '../lib/config';
 
 interface BidPanelProps {
   auctionId: string;
@@ -42,7 +42,7 @@ export default function BidPanel({
   walletBalance,
   token,
 }: BidPanelProps) {
-  const { currentBid, isConnected } = useAuctionSocket({ auctionId, userId, initialBid });
+  const { currentBid, isConnected } = useAuctionSocket({ auctionId, userId, token, initialBid });
   const { hours, minutes, seconds, expired } = useCountdown(endTime);
   const [bidAmount, setBidAmount] = useState('');
   const [isSubmitting, setIsSubmitting] = useState(false);
@@ -72,8 +72,7 @@ export default function BidPanel({
         body: JSON.stringify({ amount: parsedAmount }),
       });
       if (!res.ok) {
-        const text = await res.text();
-        throw new Error(text.trim() || 'Bid failed');
+        throw new Error(await apiError(res, 'Bid failed'));
       }
       Alert.alert('Success', `Bid of ₹${parsedAmount.toLocaleString('en-IN')} placed! Funds soft-blocked.`);
       setBidAmount('');
@@ -1,7 +1,7 @@
-import { createContext, useContext, useState, useEffect } from 'react';
+import { createContext, useContext, useState, useEffect, useRef } from 'react';
 import type { ReactNode } from 'react';
 import AsyncStorage from '@react-native-async-storage/async-storage';
-import { API_URL } from '../lib/config';
+import { API_URL, apiError } from '../lib/config';
 
 interface User {
   id: string;
@@ -26,6 +26,10 @@ export function AuthProvider({ children }: { children: ReactNode }) {
   const [user, setUser] = useState<User | null>(null);
   const [token, setToken] = useState<string | null>(null);
   const [isLoading, setIsLoading] = useState(true);
+  // The refresh token is only read by the fetch interceptor, so it lives in
+  // a ref rather than state.
+  const refreshToken = useRef<string | null>(null);
+  const renewing = useRef<Promise<string | null> | null>(null);
 
   useEffect(() => {
     async function loadAuth() {
@@ -35,6 +39,7 @@ export function AuthProvider({ children }: { children: ReactNode }) {
         if (storedUser && storedToken) {
           setUser(JSON.parse(storedUser));
           setToken(storedToken);
+          refreshToken.current = await AsyncStorage.getItem('ocm_refresh');
         }
       } catch (err) {
         console.error('Failed to load auth state', err);
@@ -45,13 +50,79 @@ export function AuthProvider({ children }: { children: ReactNode }) {
     loadAuth();
   }, []);
 
-  const persist = async (data: { token: string; user: User }) => {
+  const persist = async (data: { token: string; refresh_token: string; user: User }) => {
     setToken(data.token);
     setUser(data.user);
+    refreshToken.current = data.refresh_token;
     await AsyncStorage.setItem('ocm_token', data.token);
+    await AsyncStorage.setItem('ocm_refresh', data.refresh_token);
     await AsyncStorage.setItem('ocm_user', JSON.stringify(data.user));
   };
 
+  const clear = async () => {
+    setUser(null);
+    setToken(null);
+    refreshToken.current = null;
+    await AsyncStorage.multiRemove(['ocm_user', 'ocm_token', 'ocm_refresh']);
+  };
+
+  // Access tokens are short-lived. The interceptor below keeps the session
+  // going: it adopts the renewed token the API sends near expiry, and on a
+  // 401 trades the refresh token for a new pair and retries once.
+  useEffect(() => {
+    const original = globalThis.fetch;
+
+    const renew = (): Promise<string | null> => {
+      if (!renewing.current) {
+        renewing.current = (async () => {
+          const refresh = refreshToken.current;
+          if (!refresh) return null;
+          try {
+            const res = await original(`${API_URL}/auth/refresh`, {
+              method: 'POST',
+              headers: { 'Content-Type': 'application/json' },
+              body: JSON.stringify({ refresh_token: refresh }),
+            });
+            if (!res.ok) {
+              await clear();
+              return null;
+            }
+            const data = await res.json();
+            await persist(data);
+            return data.token as string;
+          } catch {
+            return null;
+          }
+        })().finally(() => {
+          renewing.current = null;
+        });
+      }
+      return renewing.current;
+    };
+
+    globalThis.fetch = async (input, init) => {
+      const url = typeof input === 'string' ? input : input instanceof URL ? input.href : input.url;
+      const res = await original(input, init);
+      if (!url.startsWith(API_URL) || url === `${API_URL}/auth/logout`) return res;
+
+      const renewed = res.headers.get('X-Access-Token');
+      if (renewed) {
+        setToken(renewed);
+        AsyncStorage.setItem('ocm_token', renewed);
+      }
+
+      const headers = new Headers(init?.headers);
+      if (res.status !== 401 || !headers.has('Authorization')) return res;
+      const fresh = await renew();
+      if (!fresh) return res;
+      headers.set('Authorization', `Bearer ${fresh}`);
+      return original(input, { ...init, headers });
+    };
+    return () => {
+      globalThis.fetch = original;
+    };
+  }, []);
+
   const login = async (email: string, password: string) => {
     const res = await fetch(`${API_URL}/auth/login`, {
       method: 'POST',
@@ -59,8 +130,7 @@ export function AuthProvider({ children }: { children: ReactNode }) {
       body: JSON.stringify({ email, password }),
     });
     if (!res.ok) {
-      const msg = await res.text();
-      throw new Error(msg.trim() || 'Login failed');
+      throw new Error(await apiError(res, 'Login failed'));
     }
     await persist(await res.json());
   };
@@ -72,17 +142,23 @@ export function AuthProvider({ children }: { children: ReactNode }) {
       body: JSON.stringify({ name, email, password }),
     });
     if (!res.ok) {
-      const msg = await res.text();
-      throw new Error(msg.trim() || 'Registration failed');
+      throw new Error(await apiError(res, 'Registration failed'));
     }
     await persist(await res.json());
   };
 
   const logout = async () => {
-    setUser(null);
-    setToken(null);
-    await AsyncStorage.removeItem('ocm_user');
-    await AsyncStorage.removeItem('ocm_token');
+    // Best effort: revoke both tokens server-side, but sign out locally
+    // whatever the outcome.
+    fetch(`${API_URL}/auth/logout`, {
+      method: 'POST',
+      headers: {
+        'Content-Type': 'application/json',
+        ...(token ? { Authorization: `Bearer ${token}` } : {}),
+      },
+      body: JSON.stringify({ refresh_token: refreshToken.current ?? '' }),
+    }).catch(() => {});
+    await clear();
   };
 
   const refresh = async () => {
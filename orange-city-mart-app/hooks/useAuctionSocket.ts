@@ -19,6 +19,7 @@ export interface OutbidAlert {
 interface UseAuctionSocketOptions {
   auctionId: string;
   userId?: string;
+  token?: string | null;
   initialBid?: number;
 }
 
@@ -31,6 +32,7 @@ interface UseAuctionSocketReturn {
 export function useAuctionSocket({
   auctionId,
   userId = '',
+  token,
   initialBid = 0,
 }: UseAuctionSocketOptions): UseAuctionSocketReturn {
   const [currentBid, setCurrentBid] = useState(initialBid);
@@ -41,7 +43,10 @@ export function useAuctionSocket({
   const retryTimer = useRef<ReturnType<typeof setTimeout> | null>(null);
 
   const connect = useCallback(() => {
-    const url = `${WS_URL}?auction_id=${auctionId}&user_id=${userId}`;
+    // The hub takes the caller's identity only from a verified token;
+    // without one the socket still receives public bid updates.
+    let url = `${WS_URL}?auction_id=${auctionId}`;
+    if (token) url += `&token=${encodeURIComponent(token)}`;
 
     const ws = new WebSocket(url);
     wsRef.current = ws;
@@ -87,7 +92,7 @@ export function useAuctionSocket({
     ws.onerror = () => {
       ws.close();
     };
-  }, [auctionId, userId]);
+  }, [auctionId, userId, token]);
 
   useEffect(() => {
     connect();
@@ -13,10 +13,10 @@ export interface ChatMessage {
 
 interface UseChatSocketOptions {
   roomId: string;
-  userId: string;
+  token: string | null;
 }
 
-export function useChatSocket({ roomId, userId }: UseChatSocketOptions) {
+export function useChatSocket({ roomId, token }: UseChatSocketOptions) {
   const [lastMessage, setLastMessage] = useState<ChatMessage | null>(null);
   const [isConnected, setIsConnected] = useState(false);
   const wsRef = useRef<WebSocket | null>(null);
@@ -24,9 +24,9 @@ export function useChatSocket({ roomId, userId }: UseChatSocketOptions) {
   const retryTimer = useRef<ReturnType<typeof setTimeout> | null>(null);
 
   const connect = useCallback(() => {
-    if (!roomId || !userId) return;
+    if (!roomId || !token) return;
 
-    const url = `${WS_URL}?user_id=${userId}&room_id=${roomId}`;
+    const url = `${WS_URL}?token=${encodeURIComponent(token)}&room_id=${roomId}`;
 
     const ws = new WebSocket(url);
     wsRef.current = ws;
@@ -59,7 +59,7 @@ export function useChatSocket({ roomId, userId }: UseChatSocketOptions) {
     };
 
     ws.onerror = () => ws.close();
-  }, [roomId, userId]);
+  }, [roomId, token]);
 
   useEffect(() => {
     connect();
@@ -6,3 +6,14 @@ const localhost = isWeb ? '127.0.0.1' : '10.0.2.2';
 
 export const API_URL = process.env.EXPO_PUBLIC_API_URL || `http://${localhost}/api`;
 export const WS_URL = process.env.EXPO_PUBLIC_WS_URL || `ws://${localhost}/ws`;
+
+// apiError extracts the message from the API's error envelope,
+// { "error": { "code", "message" } }, falling back when the body isn't one.
+export async function apiError(res: Response, fallback: string): Promise<string> {
+  try {
+    const body = await res.json();
+    return body?.error?.message || fallback;
+  } catch {
+    return fallback;
+  }
+}